@@ -0,0 +1,181 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"net"
+	"net/http"
+	"nuvlaedge-go/types/jobs"
+	"nuvlaedge-go/types/metrics"
+	"nuvlaedge-go/types/settings"
+	"nuvlaedge-go/types/worker"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+var (
+	ErrWorkerNotFound   = errors.New("worker not found")
+	ErrWorkerNotRunning = errors.New("worker not running")
+	ErrNotTriggerable   = errors.New("worker cannot be triggered")
+)
+
+// WorkerInfo describes a worker of the running NuvlaEdge
+type WorkerInfo struct {
+	Name    string              `json:"name"`
+	Status  worker.WorkerStatus `json:"status"`
+	Period  int                 `json:"period,omitempty"`
+	LastRun *time.Time          `json:"last-run,omitempty"`
+}
+
+// Health is the summary returned by the health endpoint
+type Health struct {
+	Healthy bool     `json:"healthy"`
+	Offline bool     `json:"offline"`
+	Failing []string `json:"failing,omitempty"`
+}
+
+// Agent provides the NuvlaEdge information exposed by the admin API
+type Agent interface {
+	Status() metrics.NuvlaEdgeStatus
+	Jobs() []jobs.RunningJob
+	WorkersInfo() []WorkerInfo
+	// Settings must return the settings with the secrets already redacted
+	Settings() settings.NuvlaEdgeSettings
+	IsOffline() bool
+	// TriggerWorker requests an immediate run of the given worker
+	TriggerWorker(wType worker.WorkerType) error
+}
+
+// Server serves the local admin API over a Unix socket. It is meant for operators on site and local tooling, so
+// access control relies on the socket file permissions.
+type Server struct {
+	socket string
+	agent  Agent
+	server *http.Server
+}
+
+func NewServer(socket string, agent Agent) *Server {
+	s := &Server{
+		socket: socket,
+		agent:  agent,
+	}
+	s.server = &http.Server{
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	return s
+}
+
+// Handler returns the admin API routes
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /health", s.handleHealth)
+	mux.HandleFunc("GET /status", s.handleStatus)
+	mux.HandleFunc("GET /jobs", s.handleJobs)
+	mux.HandleFunc("GET /workers", s.handleWorkers)
+	mux.HandleFunc("POST /workers/{name}/trigger", s.handleTrigger)
+	mux.HandleFunc("GET /settings", s.handleSettings)
+	return mux
+}
+
+// Start listens on the Unix socket and serves the API in the background. A stale socket file left by a previous
+// run is removed.
+func (s *Server) Start() error {
+	if err := os.MkdirAll(filepath.Dir(s.socket), 0750); err != nil {
+		return fmt.Errorf("error creating admin socket directory: %w", err)
+	}
+	if err := os.Remove(s.socket); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("error removing stale admin socket: %w", err)
+	}
+
+	l, err := net.Listen("unix", s.socket)
+	if err != nil {
+		return fmt.Errorf("error listening on admin socket: %w", err)
+	}
+	if err := os.Chmod(s.socket, 0660); err != nil {
+		_ = l.Close()
+		return fmt.Errorf("error setting admin socket permissions: %w", err)
+	}
+
+	go func() {
+		if err := s.server.Serve(l); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Errorf("Admin API stopped with error: %s", err)
+		}
+	}()
+	log.Infof("Admin API listening on %s", s.socket)
+	return nil
+}
+
+// Stop gracefully shuts down the server and removes the socket file
+func (s *Server) Stop(ctx context.Context) error {
+	err := s.server.Shutdown(ctx)
+	if rmErr := os.Remove(s.socket); rmErr != nil && !errors.Is(rmErr, os.ErrNotExist) {
+		err = errors.Join(err, rmErr)
+	}
+	return err
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, _ *http.Request) {
+	h := Health{Healthy: true, Offline: s.agent.IsOffline()}
+	for _, wi := range s.agent.WorkersInfo() {
+		if !wi.Status.Healthy() {
+			h.Healthy = false
+			h.Failing = append(h.Failing, wi.Name)
+		}
+	}
+
+	code := http.StatusOK
+	if !h.Healthy {
+		code = http.StatusServiceUnavailable
+	}
+	writeJSON(w, code, h)
+}
+
+func (s *Server) handleStatus(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, s.agent.Status())
+}
+
+func (s *Server) handleJobs(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, s.agent.Jobs())
+}
+
+func (s *Server) handleWorkers(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, s.agent.WorkersInfo())
+}
+
+func (s *Server) handleSettings(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, s.agent.Settings())
+}
+
+func (s *Server) handleTrigger(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	err := s.agent.TriggerWorker(worker.WorkerType(name))
+
+	switch {
+	case err == nil:
+		log.Infof("Worker %s triggered from admin API", name)
+		writeJSON(w, http.StatusAccepted, map[string]string{"message": fmt.Sprintf("worker %s triggered", name)})
+	case errors.Is(err, ErrWorkerNotFound):
+		writeError(w, http.StatusNotFound, err)
+	case errors.Is(err, ErrNotTriggerable), errors.Is(err, ErrWorkerNotRunning):
+		writeError(w, http.StatusConflict, err)
+	default:
+		writeError(w, http.StatusInternalServerError, err)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Errorf("Error writing admin API response: %s", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, map[string]string{"message": err.Error()})
+}
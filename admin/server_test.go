@@ -0,0 +1,177 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"nuvlaedge-go/types/jobs"
+	"nuvlaedge-go/types/metrics"
+	"nuvlaedge-go/types/settings"
+	"nuvlaedge-go/types/worker"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func init() {
+	log.SetLevel(log.PanicLevel)
+}
+
+type agentMock struct {
+	workers   []WorkerInfo
+	offline   bool
+	triggered []worker.WorkerType
+}
+
+func (a *agentMock) Status() metrics.NuvlaEdgeStatus {
+	return metrics.NuvlaEdgeStatus{Status: "OPERATIONAL", NodeId: "node-1"}
+}
+
+func (a *agentMock) Jobs() []jobs.RunningJob {
+	return []jobs.RunningJob{{JobId: "job/1", JobType: jobs.RebootJob}}
+}
+
+func (a *agentMock) WorkersInfo() []WorkerInfo {
+	return a.workers
+}
+
+func (a *agentMock) Settings() settings.NuvlaEdgeSettings {
+	return settings.NuvlaEdgeSettings{NuvlaEndpoint: "https://nuvla.io", Irs: "<redacted>"}
+}
+
+func (a *agentMock) IsOffline() bool {
+	return a.offline
+}
+
+func (a *agentMock) TriggerWorker(wType worker.WorkerType) error {
+	switch wType {
+	case worker.Heartbeat, worker.Telemetry:
+		a.triggered = append(a.triggered, wType)
+		return nil
+	case worker.JobProcessor:
+		return fmt.Errorf("%w: %s", ErrNotTriggerable, wType)
+	default:
+		return fmt.Errorf("%w: %s", ErrWorkerNotFound, wType)
+	}
+}
+
+func newTestServer(a Agent) *httptest.Server {
+	return httptest.NewServer(NewServer("", a).Handler())
+}
+
+func getJSON(t *testing.T, url string, v interface{}) int {
+	res, err := http.Get(url)
+	assert.NoError(t, err)
+	defer res.Body.Close()
+	assert.Equal(t, "application/json", res.Header.Get("Content-Type"))
+	assert.NoError(t, json.NewDecoder(res.Body).Decode(v))
+	return res.StatusCode
+}
+
+func Test_Server_Endpoints(t *testing.T) {
+	lastRun := time.Now()
+	a := &agentMock{workers: []WorkerInfo{
+		{Name: "heartbeat", Status: worker.WorkerStatus{State: worker.WorkerRunning}, Period: 20, LastRun: &lastRun},
+	}}
+	ts := newTestServer(a)
+	defer ts.Close()
+
+	var status metrics.NuvlaEdgeStatus
+	assert.Equal(t, http.StatusOK, getJSON(t, ts.URL+"/status", &status))
+	assert.Equal(t, "node-1", status.NodeId)
+
+	var js []jobs.RunningJob
+	assert.Equal(t, http.StatusOK, getJSON(t, ts.URL+"/jobs", &js))
+	assert.Len(t, js, 1)
+	assert.Equal(t, "job/1", js[0].JobId)
+
+	var ws []WorkerInfo
+	assert.Equal(t, http.StatusOK, getJSON(t, ts.URL+"/workers", &ws))
+	assert.Len(t, ws, 1)
+	assert.Equal(t, 20, ws[0].Period)
+	assert.NotNil(t, ws[0].LastRun)
+
+	var set map[string]interface{}
+	assert.Equal(t, http.StatusOK, getJSON(t, ts.URL+"/settings", &set))
+	assert.Equal(t, "<redacted>", set["irs"])
+}
+
+func Test_Server_Health(t *testing.T) {
+	a := &agentMock{workers: []WorkerInfo{
+		{Name: "heartbeat", Status: worker.WorkerStatus{State: worker.WorkerRunning}},
+		{Name: "telemetry", Status: worker.WorkerStatus{State: worker.WorkerRunning}},
+	}}
+	ts := newTestServer(a)
+	defer ts.Close()
+
+	var h Health
+	assert.Equal(t, http.StatusOK, getJSON(t, ts.URL+"/health", &h))
+	assert.True(t, h.Healthy)
+
+	a.offline = true
+	a.workers[1].Status.State = worker.WorkerFailed
+	h = Health{}
+	assert.Equal(t, http.StatusServiceUnavailable, getJSON(t, ts.URL+"/health", &h))
+	assert.False(t, h.Healthy)
+	assert.True(t, h.Offline)
+	assert.Equal(t, []string{"telemetry"}, h.Failing)
+}
+
+func Test_Server_Trigger(t *testing.T) {
+	a := &agentMock{}
+	ts := newTestServer(a)
+	defer ts.Close()
+
+	tests := map[string]int{
+		"heartbeat":     http.StatusAccepted,
+		"telemetry":     http.StatusAccepted,
+		"job-processor": http.StatusConflict,
+		"unknown":       http.StatusNotFound,
+	}
+	for name, code := range tests {
+		res, err := http.Post(ts.URL+"/workers/"+name+"/trigger", "", nil)
+		assert.NoError(t, err)
+		_ = res.Body.Close()
+		assert.Equal(t, code, res.StatusCode, name)
+	}
+	assert.ElementsMatch(t, []worker.WorkerType{worker.Heartbeat, worker.Telemetry}, a.triggered)
+
+	res, err := http.Get(ts.URL + "/workers/heartbeat/trigger")
+	assert.NoError(t, err)
+	_ = res.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, res.StatusCode)
+}
+
+func Test_Server_UnixSocket(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "run", "admin.sock")
+	// A stale socket file should not prevent the server from starting
+	assert.NoError(t, os.MkdirAll(filepath.Dir(socket), 0750))
+	assert.NoError(t, os.WriteFile(socket, []byte{}, 0600))
+
+	s := NewServer(socket, &agentMock{})
+	assert.NoError(t, s.Start())
+
+	fi, err := os.Stat(socket)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0660), fi.Mode().Perm())
+
+	c := http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", socket)
+		},
+	}}
+	res, err := c.Get("http://admin/health")
+	assert.NoError(t, err)
+	_ = res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	assert.NoError(t, s.Stop(context.Background()))
+	_, err = os.Stat(socket)
+	assert.ErrorIs(t, err, os.ErrNotExist, "socket file should be removed on stop")
+}
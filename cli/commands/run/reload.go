@@ -0,0 +1,77 @@
+package run
+
+import (
+	"context"
+	"github.com/fsnotify/fsnotify"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+	runFlags "nuvlaedge-go/cli/flags"
+	"nuvlaedge-go/nuvlaedge"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"time"
+)
+
+// reloadDebounce groups the bursts of file events produced by editors when saving the configuration file
+const reloadDebounce = time.Second
+
+// notifyReload catches SIGHUP, which would otherwise terminate the process, and relays it to the returned channel
+func notifyReload() chan os.Signal {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, unix.SIGHUP)
+	return sigCh
+}
+
+// watchSettings reloads the settings on SIGHUP or when the configuration file changes, until the context is done.
+// The directory of the file is watched instead of the file itself so that atomic replacements are also detected.
+func watchSettings(ctx context.Context, ne *nuvlaedge.NuvlaEdge, configFile string, sigCh <-chan os.Signal) {
+	var events chan fsnotify.Event
+	var errs chan error
+	if configFile != "" {
+		configFile = filepath.Clean(configFile)
+		w, err := fsnotify.NewWatcher()
+		if err != nil {
+			log.Errorf("Error creating config file watcher, only SIGHUP will reload the settings: %s", err)
+		} else if err := w.Add(filepath.Dir(configFile)); err != nil {
+			log.Errorf("Error watching config file %s, only SIGHUP will reload the settings: %s", configFile, err)
+			_ = w.Close()
+		} else {
+			defer w.Close()
+			events, errs = w.Events, w.Errors
+		}
+	}
+
+	var debounce <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-sigCh:
+			log.Info("SIGHUP received, reloading settings")
+			reloadSettings(ne)
+		case ev := <-events:
+			if filepath.Clean(ev.Name) == configFile && ev.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) != 0 {
+				debounce = time.After(reloadDebounce)
+			}
+		case err := <-errs:
+			log.Errorf("Error watching config file: %s", err)
+		case <-debounce:
+			debounce = nil
+			log.Infof("Config file %s changed, reloading settings", configFile)
+			reloadSettings(ne)
+		}
+	}
+}
+
+func reloadSettings(ne *nuvlaedge.NuvlaEdge) {
+	s, err := runFlags.ReloadSettings()
+	if err != nil {
+		log.Errorf("Error reloading settings, keeping the current ones: %s", err)
+		return
+	}
+
+	if err := ne.Reload(s); err != nil {
+		log.Errorf("Error applying reloaded settings: %s", err)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"nuvlaedge-go/common"
 	"nuvlaedge-go/nuvlaedge"
 	"nuvlaedge-go/types/settings"
+	"os/signal"
 )
 
 func NewRunCommand() *cobra.Command {
@@ -38,6 +39,10 @@ func NewRunCommand() *cobra.Command {
 func nuvlaEdgeMain(ctx context.Context, settings *settings.NuvlaEdgeSettings) error {
 	log.Infof("Running NuvlaEdge with settings: %v", settings)
 
+	// A SIGHUP received while starting is kept until the NuvlaEdge is ready to reload the settings
+	sigCh := notifyReload()
+	defer signal.Stop(sigCh)
+
 	ne, err := nuvlaedge.NewNuvlaEdge(ctx, settings)
 	if err != nil {
 		log.Errorf("Failed to create NuvlaEdge: %s", err)
@@ -49,7 +54,7 @@ func nuvlaEdgeMain(ctx context.Context, settings *settings.NuvlaEdgeSettings) er
 		return err
 	}
 
-	<-ctx.Done()
+	go watchSettings(ctx, ne, settings.ConfigFile, sigCh)
 
-	return nil
+	return ne.Run(ctx)
 }
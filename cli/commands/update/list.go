@@ -0,0 +1,100 @@
+package update
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"io"
+	"nuvlaedge-go/cli/flags"
+	"nuvlaedge-go/types/options/command"
+	"nuvlaedge-go/updater/release"
+	"strings"
+	"text/tabwriter"
+)
+
+// notesSummaryLength is the length of the release notes summary shown in the list of versions
+const notesSummaryLength = 60
+
+func newListCommand() *cobra.Command {
+	var opts command.UpdateListCmdOptions
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the NuvlaEdge versions available for updates",
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := flags.ParseUpdateListFlags(cmd.Flags(), &opts); err != nil {
+				return err
+			}
+
+			versions, err := release.ListVersions(opts.NuvlaReleases, opts.GitHubReleases)
+			if err != nil {
+				return err
+			}
+			versions, err = filterVersions(versions, opts.Channel)
+			if err != nil {
+				return err
+			}
+			return printVersions(cmd.OutOrStdout(), versions, opts.Notes)
+		},
+	}
+
+	flags.AddUpdateListFlags(cmd)
+
+	return cmd
+}
+
+// filterVersions keeps the published versions of the channel, or all of them if the channel is empty
+func filterVersions(versions []release.Version, channel string) ([]release.Version, error) {
+	switch channel {
+	case "":
+		return versions, nil
+	case release.ChannelStable, release.ChannelPreRelease:
+	default:
+		return nil, fmt.Errorf("invalid channel %q, expected %s or %s",
+			channel, release.ChannelStable, release.ChannelPreRelease)
+	}
+
+	var filtered []release.Version
+	for _, v := range versions {
+		if v.Published && (!v.PreRelease || channel == release.ChannelPreRelease) {
+			filtered = append(filtered, v)
+		}
+	}
+	return filtered, nil
+}
+
+// printVersions writes a table of the versions with a summary of their release notes, or the full notes below it
+func printVersions(out io.Writer, versions []release.Version, notes bool) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "VERSION\tPRE-RELEASE\tPUBLISHED\tDATE\tNOTES")
+	for _, v := range versions {
+		_, _ = fmt.Fprintf(w, "%s\t%t\t%t\t%s\t%s\n",
+			v.Version, v.PreRelease, v.Published, v.ReleaseDate, notesSummary(v.Notes))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if !notes {
+		return nil
+	}
+	for _, v := range versions {
+		if v.Notes == "" {
+			continue
+		}
+		if _, err := fmt.Fprintf(out, "\n%s\n%s\n", v.Version, strings.TrimSpace(v.Notes)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// notesSummary returns the first line of the release notes, shortened to notesSummaryLength
+func notesSummary(notes string) string {
+	summary, _, _ := strings.Cut(strings.TrimSpace(notes), "\n")
+	summary = strings.TrimSpace(strings.TrimLeft(summary, "#"))
+	if r := []rune(summary); len(r) > notesSummaryLength {
+		summary = string(r[:notesSummaryLength-3]) + "..."
+	}
+	return summary
+}
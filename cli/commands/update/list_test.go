@@ -0,0 +1,46 @@
+package update
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"nuvlaedge-go/updater/release"
+	"strings"
+	"testing"
+)
+
+var testVersions = []release.Version{
+	{Version: "2.6.1-rc1", PreRelease: true, Published: true, Notes: "## Release 2.6.1-rc1\n\n- Fix"},
+	{Version: "2.6.0", Published: false},
+	{Version: "2.5.1", Published: true, Notes: strings.Repeat("a", 100)},
+}
+
+func Test_FilterVersions(t *testing.T) {
+	versions, err := filterVersions(testVersions, "")
+	assert.NoError(t, err)
+	assert.Len(t, versions, 3)
+
+	versions, err = filterVersions(testVersions, release.ChannelStable)
+	assert.NoError(t, err)
+	assert.Len(t, versions, 1)
+	assert.Equal(t, "2.5.1", versions[0].Version)
+
+	versions, err = filterVersions(testVersions, release.ChannelPreRelease)
+	assert.NoError(t, err)
+	assert.Len(t, versions, 2)
+
+	_, err = filterVersions(testVersions, "nightly")
+	assert.Error(t, err)
+}
+
+func Test_PrintVersions(t *testing.T) {
+	var out bytes.Buffer
+	assert.NoError(t, printVersions(&out, testVersions, false))
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	assert.Len(t, lines, 4)
+	assert.Contains(t, lines[1], "Release 2.6.1-rc1")
+	assert.Contains(t, lines[3], strings.Repeat("a", notesSummaryLength-3)+"...")
+
+	out.Reset()
+	assert.NoError(t, printVersions(&out, testVersions, true))
+	assert.Contains(t, out.String(), "\n2.6.1-rc1\n## Release 2.6.1-rc1\n\n- Fix\n")
+}
@@ -1,6 +1,7 @@
 package update
 
 import (
+	"context"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"nuvlaedge-go/cli/flags"
@@ -21,20 +22,21 @@ func NewUpdateCommand() *cobra.Command {
 				return err
 			}
 			log.Info("Triggering update")
-			return updateMain(&opts)
+			return updateMain(cmd.Context(), &opts)
 		},
 	}
 
 	flags.AddUpdateFlags(cmd)
+	cmd.AddCommand(newListCommand())
 
 	return cmd
 }
 
-func updateMain(opts *command.UpdateCmdOptions) error {
+func updateMain(ctx context.Context, opts *command.UpdateCmdOptions) error {
 	log.Infof("Triggering update")
-	updaterFunc := updater.GetUpdater()
+	updaterFunc := updater.GetUpdater(opts)
 
-	if err := updaterFunc(opts); err != nil {
+	if err := updaterFunc(ctx, opts); err != nil {
 		log.Errorf("Error updating NuvlaEdge: %s", err)
 		return err
 	}
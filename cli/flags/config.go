@@ -0,0 +1,105 @@
+package flags
+
+import (
+	"errors"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"nuvlaedge-go/common/constants"
+	"nuvlaedge-go/types/settings"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+var supportedConfigTypes = []string{"toml", "yaml", "yml"}
+
+// settingsKeys returns the configuration keys of the NuvlaEdge settings, as defined by their mapstructure tags
+func settingsKeys() []string {
+	t := reflect.TypeOf(settings.NuvlaEdgeSettings{})
+	keys := make([]string, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		if k := t.Field(i).Tag.Get("mapstructure"); k != "" {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// checkConfigFile validates the format of the configuration file and makes sure it only contains known settings,
+// so that typos are reported instead of silently ignored.
+func checkConfigFile(path string) error {
+	ext := strings.TrimPrefix(filepath.Ext(path), ".")
+	if !slices.Contains(supportedConfigTypes, ext) {
+		return fmt.Errorf("unsupported config file type %q, expected any of %v", ext, supportedConfigTypes)
+	}
+
+	fv := viper.New()
+	fv.SetConfigFile(path)
+	if err := fv.ReadInConfig(); err != nil {
+		return err
+	}
+
+	known := settingsKeys()
+	var errList []error
+	for _, k := range fv.AllKeys() {
+		if !slices.Contains(known, k) {
+			errList = append(errList, fmt.Errorf("unknown setting %q", k))
+		}
+	}
+	return errors.Join(errList...)
+}
+
+// readConfigFile loads the configuration file into viper. The file is taken from the config-file setting or, if not
+// set, from the default location when it exists. Without a file, the settings come from flags, env and defaults only.
+func readConfigFile() error {
+	path := viper.GetString("config-file")
+	if path == "" {
+		if _, err := os.Stat(constants.DefaultConfigFile); err != nil {
+			log.Debugf("No configuration file provided")
+			return nil
+		}
+		path = constants.DefaultConfigFile
+		viper.SetDefault("config-file", path)
+	}
+
+	if err := checkConfigFile(path); err != nil {
+		return fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+
+	viper.SetConfigFile(path)
+	if err := viper.ReadInConfig(); err != nil {
+		return fmt.Errorf("error reading config file %s: %w", path, err)
+	}
+	log.Infof("Loaded settings from config file %s", path)
+	return nil
+}
+
+func unmarshalSettings(opts *settings.NuvlaEdgeSettings) error {
+	if opts == nil {
+		return errors.New("nil settings")
+	}
+	if err := viper.Unmarshal(opts); err != nil {
+		return fmt.Errorf("error unmarshaling settings: %w", err)
+	}
+	if err := opts.Validate(); err != nil {
+		return fmt.Errorf("invalid settings: %w", err)
+	}
+	return nil
+}
+
+// ReloadSettings reads the configuration file again and returns the resulting settings. Flags and environment
+// variables keep their precedence over the file. ParseSettings must have been called before.
+func ReloadSettings() (*settings.NuvlaEdgeSettings, error) {
+	if err := readConfigFile(); err != nil {
+		return nil, err
+	}
+
+	opts := &settings.NuvlaEdgeSettings{}
+	if err := unmarshalSettings(opts); err != nil {
+		return nil, err
+	}
+	return opts, nil
+}
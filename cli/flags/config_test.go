@@ -0,0 +1,118 @@
+package flags
+
+import (
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"nuvlaedge-go/types/settings"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const tomlConfig = `
+nuvla-endpoint = "https://nuvla.example.com"
+nuvlaedge-uuid = "nuvlabox/1234"
+heartbeat-period = 30
+log-level = "debug"
+resources = ["images", "volumes"]
+`
+
+const yamlConfig = `
+nuvla-endpoint: https://nuvla.example.com
+nuvlaedge-uuid: nuvlabox/1234
+heartbeat-period: 30
+log-level: debug
+resources:
+  - images
+  - volumes
+`
+
+func writeConfig(t *testing.T, name, content string) string {
+	p := filepath.Join(t.TempDir(), name)
+	assert.NoError(t, os.WriteFile(p, []byte(content), 0600))
+	return p
+}
+
+func parseWithConfig(t *testing.T, path string, args ...string) (*settings.NuvlaEdgeSettings, error) {
+	flags := &pflag.FlagSet{}
+	AddRunFlags(flags)
+	assert.NoError(t, flags.Parse(append([]string{"--config-file", path}, args...)))
+
+	set := &settings.NuvlaEdgeSettings{}
+	return set, ParseSettings(flags, set)
+}
+
+func TestParseSettings_ConfigFile(t *testing.T) {
+	for name, content := range map[string]string{"config.toml": tomlConfig, "config.yaml": yamlConfig} {
+		t.Run(name, func(t *testing.T) {
+			defer viper.Reset()
+
+			set, err := parseWithConfig(t, writeConfig(t, name, content))
+			assert.NoError(t, err)
+			assert.Equal(t, "https://nuvla.example.com", set.NuvlaEndpoint)
+			assert.Equal(t, "nuvlabox/1234", set.NuvlaEdgeUUID)
+			assert.Equal(t, 30, set.HeartbeatPeriod)
+			assert.Equal(t, "debug", set.LogLevel)
+			assert.Equal(t, []string{"images", "volumes"}, set.Resources)
+			assert.Equal(t, 60, set.TelemetryPeriod, "settings not in the file should keep their defaults")
+		})
+	}
+}
+
+func TestParseSettings_ConfigFilePrecedence(t *testing.T) {
+	defer viper.Reset()
+	_ = os.Setenv("HEARTBEAT_PERIOD", "40")
+	defer os.Unsetenv("HEARTBEAT_PERIOD")
+	_ = os.Setenv("NUVLAEDGE_LOG_LEVEL", "warn")
+	defer os.Unsetenv("NUVLAEDGE_LOG_LEVEL")
+
+	set, err := parseWithConfig(t, writeConfig(t, "config.toml", tomlConfig), "--log-level", "error")
+	assert.NoError(t, err)
+	assert.Equal(t, 40, set.HeartbeatPeriod, "env should take precedence over the config file")
+	assert.Equal(t, "error", set.LogLevel, "flags should take precedence over env and config file")
+	assert.Equal(t, "https://nuvla.example.com", set.NuvlaEndpoint, "config file should take precedence over defaults")
+}
+
+func TestParseSettings_InvalidConfigFile(t *testing.T) {
+	tests := map[string]struct {
+		name    string
+		content string
+		errMsg  string
+	}{
+		"unknown key":      {"config.toml", "heartbeat-perod = 10\n", `unknown setting "heartbeat-perod"`},
+		"unsupported type": {"config.json", "{}", `unsupported config file type "json"`},
+		"syntax error":     {"config.toml", "heartbeat-period = \n", "config.toml"},
+		"wrong type":       {"config.yaml", "heartbeat-period: often\n", "heartbeat-period"},
+		"invalid value":    {"config.yaml", "log-level: loud\nresources: [images, disks]\n", `unknown resource "disks"`},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			defer viper.Reset()
+			_, err := parseWithConfig(t, writeConfig(t, tt.name, tt.content))
+			assert.ErrorContains(t, err, tt.errMsg)
+		})
+	}
+
+	defer viper.Reset()
+	_, err := parseWithConfig(t, filepath.Join(t.TempDir(), "missing.toml"))
+	assert.Error(t, err, "an explicitly provided config file must exist")
+}
+
+func TestReloadSettings(t *testing.T) {
+	defer viper.Reset()
+	path := writeConfig(t, "config.toml", tomlConfig)
+	_, err := parseWithConfig(t, path)
+	assert.NoError(t, err)
+
+	assert.NoError(t, os.WriteFile(path, []byte("heartbeat-period = 15\nlog-level = \"info\"\n"), 0600))
+	set, err := ReloadSettings()
+	assert.NoError(t, err)
+	assert.Equal(t, 15, set.HeartbeatPeriod)
+	assert.Equal(t, "info", set.LogLevel)
+	assert.Equal(t, path, set.ConfigFile)
+
+	assert.NoError(t, os.WriteFile(path, []byte("heartbeat-period = -1\n"), 0600))
+	_, err = ReloadSettings()
+	assert.ErrorContains(t, err, "heartbeat-period")
+}
@@ -23,6 +23,9 @@ var OnError = func(err error, msg string) {
 //
 // The function does not return any value.
 func AddRunFlags(flags *pflag.FlagSet) {
+	// Configuration file
+	flags.String("config-file", "", "TOML or YAML configuration file. Flags and environment variables take precedence over it")
+
 	// Database location
 	flags.String("db-path", "", "NuvlaEdge Database path")
 	flags.String("rootfs", constants.DefaultRootFs, "Root filesystem")
@@ -36,7 +39,10 @@ func AddRunFlags(flags *pflag.FlagSet) {
 	// Agent configuration
 	flags.Int("heartbeat-period", 0, "Heartbeat period")
 	flags.Int("telemetry-period", 0, "Telemetry period")
-	flags.Int("remote-sync-period", 0, "Remote sync period")
+	flags.Int("remote-sync-period", 0, "Period of the search for jobs queued in Nuvla and not received")
+	flags.Int("shutdown-timeout", 0, "Maximum time in seconds to wait for running jobs on shutdown")
+	flags.String("admin-socket", "", "Unix socket of the local admin API. Empty disables the API")
+	flags.String("metrics-address", "", "Listen address (host:port) of the Prometheus metrics exporter. Disabled if empty")
 
 	// Resource cleanup
 	flags.Int("cleanup-period", 0, "COE (Docker/K8s) Cleanup period")
@@ -49,6 +55,10 @@ func AddRunFlags(flags *pflag.FlagSet) {
 	// Job Engine
 	flags.String("job-image", "", "Job Engine image")
 	flags.Bool("enable-legacy-job", false, "Enable legacy job support")
+	flags.Int("job-pool-size", 0, "Maximum number of jobs running at the same time")
+	flags.StringToInt("job-timeouts", nil, "Maximum execution time in seconds by job action, e.g. start_deployment=600")
+	flags.StringSlice("reboot-hooks", []string{}, "Commands run before rebooting, in the agent environment. "+
+		"A failing command cancels the reboot")
 
 	// Nuvla endpoint definition
 	flags.String("nuvla-endpoint", "", "Nuvla endpoint")
@@ -77,9 +87,12 @@ func setDefaultRunFlags() {
 	viper.SetDefault("heartbeat-period", constants.DefaultHeartbeatPeriod)
 	viper.SetDefault("telemetry-period", constants.DefaultTelemetryPeriod)
 	viper.SetDefault("remote-sync-period", constants.DefaultRemoteSyncPeriod)
+	viper.SetDefault("shutdown-timeout", constants.DefaultShutdownTimeout)
+	viper.SetDefault("admin-socket", constants.DefaultAdminSocket)
 	viper.SetDefault("vpn-enabled", constants.DefaultVPNEnabled)
 	viper.SetDefault("job-engine-image", constants.DefaultJobEngineImage)
 	viper.SetDefault("enable-legacy-job", constants.DefaultEnableLegacyJob)
+	viper.SetDefault("job-pool-size", constants.DefaultJobPoolSize)
 	viper.SetDefault("log-level", constants.DefaultLogLevel)
 	viper.SetDefault("debug", constants.DefaultDebug)
 	viper.SetDefault("cleanup-period", 86400)
@@ -101,6 +114,7 @@ func bindViperRunFlags(flags *pflag.FlagSet) {
 	// Bind viper run flags
 	errMsg := "Error binding flag to viper var"
 
+	OnError(viper.BindPFlag("config-file", flags.Lookup("config-file")), errMsg)
 	OnError(viper.BindPFlag("db-path", flags.Lookup("db-path")), errMsg)
 	OnError(viper.BindPFlag("rootfs", flags.Lookup("rootfs")), errMsg)
 	OnError(viper.BindPFlag("nuvla-endpoint", flags.Lookup("nuvla-endpoint")), errMsg)
@@ -111,12 +125,18 @@ func bindViperRunFlags(flags *pflag.FlagSet) {
 	OnError(viper.BindPFlag("heartbeat-period", flags.Lookup("heartbeat-period")), errMsg)
 	OnError(viper.BindPFlag("telemetry-period", flags.Lookup("telemetry-period")), errMsg)
 	OnError(viper.BindPFlag("remote-sync-period", flags.Lookup("remote-sync-period")), errMsg)
+	OnError(viper.BindPFlag("shutdown-timeout", flags.Lookup("shutdown-timeout")), errMsg)
+	OnError(viper.BindPFlag("admin-socket", flags.Lookup("admin-socket")), errMsg)
+	OnError(viper.BindPFlag("metrics-address", flags.Lookup("metrics-address")), errMsg)
 	OnError(viper.BindPFlag("cleanup-period", flags.Lookup("cleanup-period")), errMsg)
 	OnError(viper.BindPFlag("resources", flags.Lookup("resources")), errMsg)
 	OnError(viper.BindPFlag("vpn-enabled", flags.Lookup("vpn-enabled")), errMsg)
 	OnError(viper.BindPFlag("vpn-extra-config", flags.Lookup("vpn-extra-config")), errMsg)
 	OnError(viper.BindPFlag("job-engine-image", flags.Lookup("job-image")), errMsg)
 	OnError(viper.BindPFlag("enable-legacy-job", flags.Lookup("enable-legacy-job")), errMsg)
+	OnError(viper.BindPFlag("job-pool-size", flags.Lookup("job-pool-size")), errMsg)
+	OnError(viper.BindPFlag("job-timeouts", flags.Lookup("job-timeouts")), errMsg)
+	OnError(viper.BindPFlag("reboot-hooks", flags.Lookup("reboot-hooks")), errMsg)
 	OnError(viper.BindPFlag("log-level", flags.Lookup("log-level")), errMsg)
 	OnError(viper.BindPFlag("debug", flags.Lookup("debug")), errMsg)
 	OnError(viper.BindPFlag("irs", flags.Lookup("irs")), errMsg)
@@ -136,6 +156,7 @@ func setEnvBindings() {
 	// Env run flags
 	errMsg := "Error binding env var to viper var"
 
+	OnError(viper.BindEnv("config-file", "NUVLAEDGE_SETTINGS", "CONFIG_FILE"), errMsg)
 	OnError(viper.BindEnv("db-path", "DB_PATH", "DATA_LOCATION"), errMsg)
 	OnError(viper.BindEnv("rootfs", "ROOTFS"), errMsg)
 	OnError(viper.BindEnv("nuvla-endpoint", "NUVLA_ENDPOINT"), errMsg)
@@ -147,10 +168,15 @@ func setEnvBindings() {
 	OnError(viper.BindEnv("heartbeat-period", "HEARTBEAT_PERIOD"), errMsg)
 	OnError(viper.BindEnv("telemetry-period", "TELEMETRY_PERIOD"), errMsg)
 	OnError(viper.BindEnv("remote-sync-period", "REMOTE_SYNC_PERIOD"), errMsg)
+	OnError(viper.BindEnv("shutdown-timeout", "SHUTDOWN_TIMEOUT"), errMsg)
+	OnError(viper.BindEnv("admin-socket", "ADMIN_SOCKET"), errMsg)
+	OnError(viper.BindEnv("metrics-address", "METRICS_ADDRESS"), errMsg)
 	OnError(viper.BindEnv("cleanup-period", "CLEANUP_PERIOD"), errMsg)
 	OnError(viper.BindEnv("resources", "CLEAN_RESOURCES"), errMsg)
 	OnError(viper.BindEnv("job-engine-image", "NUVLAEDGE_JOB_ENGINE_LITE_IMAGE", "JOB_LEGACY_IMAGE"), errMsg)
 	OnError(viper.BindEnv("enable-legacy-job", "ENABLE_LEGACY_JOB", "JOB_LEGACY_ENABLE"), errMsg)
+	OnError(viper.BindEnv("job-pool-size", "JOB_POOL_SIZE"), errMsg)
+	OnError(viper.BindEnv("reboot-hooks", "REBOOT_HOOKS"), errMsg)
 	OnError(viper.BindEnv("vpn-enabled", "VPN_ENABLED"), errMsg)
 	OnError(viper.BindEnv("vpn-extra-config", "VPN_EXTRA_CONFIG"), errMsg)
 	OnError(viper.BindEnv("log-level", "NUVLAEDGE_LOG_LEVEL"), errMsg)
@@ -160,10 +186,11 @@ func setEnvBindings() {
 // ParseSettings initializes and applies configuration settings for the NuvlaEdge application.
 // This function binds command-line flags to their corresponding Viper configuration keys,
 // sets default values for various operational parameters, binds environment variables to
-// their corresponding Viper configuration keys, and finally unmarshals the configuration
-// into a NuvlaEdgeSettings struct. It ensures that all configurations are correctly applied
-// and accessible throughout the application. This function should be called after defining
-// command-line flags using AddRunFlags to ensure all flags are correctly initialized.
+// their corresponding Viper configuration keys, reads the configuration file if any, and finally
+// unmarshals and validates the configuration into a NuvlaEdgeSettings struct. The precedence is
+// flags, then environment variables, then configuration file and finally defaults. This function
+// should be called after defining command-line flags using AddRunFlags to ensure all flags are
+// correctly initialized.
 //
 // Parameters:
 // - flags (*pflag.FlagSet): A pointer to the flag set containing the command-line flags.
@@ -177,8 +204,12 @@ func ParseSettings(flags *pflag.FlagSet, opts *settings.NuvlaEdgeSettings) error
 	setEnvBindings()         // Bind environment variables to Viper keys.
 	viper.AutomaticEnv()     // Automatically load environment variables.
 
-	if err := viper.Unmarshal(opts); err != nil {
-		log.Infof("Error unmarshaling settings: %s", err)
+	if err := readConfigFile(); err != nil {
+		return err
+	}
+
+	if err := unmarshalSettings(opts); err != nil {
+		log.Infof("Error parsing settings: %s", err)
 		return err
 	}
 	return nil
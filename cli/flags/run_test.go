@@ -30,6 +30,9 @@ func TestAddRunFlags(t *testing.T) {
 		"--heartbeat-period", "1",
 		"--telemetry-period", "1",
 		"--remote-sync-period", "1",
+		"--shutdown-timeout", "30",
+		"--admin-socket", "/tmp/admin.sock",
+		"--metrics-address", ":9100",
 		"--vpn-enabled",
 		"--vpn-extra-config", "test",
 		"--job-image", "test",
@@ -48,6 +51,9 @@ func TestAddRunFlags(t *testing.T) {
 	assert.Equal(t, "1", flags.Lookup("heartbeat-period").Value.String())
 	assert.Equal(t, "1", flags.Lookup("telemetry-period").Value.String())
 	assert.Equal(t, "1", flags.Lookup("remote-sync-period").Value.String())
+	assert.Equal(t, "30", flags.Lookup("shutdown-timeout").Value.String())
+	assert.Equal(t, "/tmp/admin.sock", flags.Lookup("admin-socket").Value.String())
+	assert.Equal(t, ":9100", flags.Lookup("metrics-address").Value.String())
 	assert.Equal(t, "true", flags.Lookup("vpn-enabled").Value.String())
 	assert.Equal(t, "test", flags.Lookup("job-image").Value.String())
 	assert.Equal(t, "true", flags.Lookup("enable-legacy-job").Value.String())
@@ -64,6 +70,8 @@ func TestSetDefaultRunFlags(t *testing.T) {
 	assert.Equal(t, constants.DefaultHeartbeatPeriod, viper.GetInt("heartbeat-period"))
 	assert.Equal(t, constants.DefaultTelemetryPeriod, viper.GetInt("telemetry-period"))
 	assert.Equal(t, constants.DefaultRemoteSyncPeriod, viper.GetInt("remote-sync-period"))
+	assert.Equal(t, constants.DefaultShutdownTimeout, viper.GetInt("shutdown-timeout"))
+	assert.Equal(t, constants.DefaultAdminSocket, viper.GetString("admin-socket"))
 	assert.Equal(t, constants.DefaultVPNEnabled, viper.GetBool("vpn-enabled"))
 	assert.Equal(t, constants.DefaultJobEngineImage, viper.GetString("job-engine-image"))
 	assert.Equal(t, constants.DefaultEnableLegacyJob, viper.GetBool("enable-legacy-job"))
@@ -87,6 +95,9 @@ func TestBindViperRunFlags(t *testing.T) {
 		"--heartbeat-period", "1",
 		"--telemetry-period", "1",
 		"--remote-sync-period", "1",
+		"--shutdown-timeout", "30",
+		"--admin-socket", "/tmp/admin.sock",
+		"--metrics-address", ":9100",
 		"--vpn-enabled",
 		"--vpn-extra-config", "test",
 		"--job-image", "test",
@@ -105,6 +116,9 @@ func TestBindViperRunFlags(t *testing.T) {
 	assert.Equal(t, 1, viper.GetInt("heartbeat-period"))
 	assert.Equal(t, 1, viper.GetInt("telemetry-period"))
 	assert.Equal(t, 1, viper.GetInt("remote-sync-period"))
+	assert.Equal(t, 30, viper.GetInt("shutdown-timeout"))
+	assert.Equal(t, "/tmp/admin.sock", viper.GetString("admin-socket"))
+	assert.Equal(t, ":9100", viper.GetString("metrics-address"))
 	assert.Equal(t, true, viper.GetBool("vpn-enabled"))
 	assert.Equal(t, "test", viper.GetString("job-engine-image"))
 	assert.Equal(t, true, viper.GetBool("enable-legacy-job"))
@@ -122,6 +136,9 @@ var envs = map[string]string{
 	"HEARTBEAT_PERIOD":     "1",
 	"TELEMETRY_PERIOD":     "1",
 	"REMOTE_SYNC_PERIOD":   "1",
+	"SHUTDOWN_TIMEOUT":     "30",
+	"ADMIN_SOCKET":         "/tmp/admin.sock",
+	"METRICS_ADDRESS":      ":9100",
 	"VPN_ENABLED":          "true",
 	"VPN_EXTRA_CONFIG":     "test",
 	"JOB_LEGACY_IMAGE":     "test",
@@ -187,6 +204,9 @@ func TestParseSettings(t *testing.T) {
 	assert.Equal(t, 1, set.HeartbeatPeriod)
 	assert.Equal(t, 1, set.TelemetryPeriod)
 	assert.Equal(t, 1, set.RemoteSyncPeriod)
+	assert.Equal(t, 30, set.ShutdownTimeout)
+	assert.Equal(t, "/tmp/admin.sock", set.AdminSocket)
+	assert.Equal(t, ":9100", set.MetricsAddress)
 	assert.Equal(t, true, set.VpnEnabled)
 	assert.Equal(t, "test", set.VpnExtraConfig)
 	assert.Equal(t, "test", set.JobEngineImage)
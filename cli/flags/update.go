@@ -5,7 +5,10 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
+	"nuvlaedge-go/common/constants"
 	"nuvlaedge-go/types/options/command"
+	"nuvlaedge-go/updater/common"
+	"nuvlaedge-go/updater/release"
 )
 
 func AddUpdateFlags(cmd *cobra.Command) {
@@ -14,8 +17,9 @@ func AddUpdateFlags(cmd *cobra.Command) {
 	flags.SetInterspersed(false)
 
 	// Update flags
-	flags.Bool("force", false, "Force update")
+	flags.Bool("force", false, "Force update to the latest version without target version")
 	flags.Bool("quiet", false, "Quiet mode")
+	flags.Bool("allow-downgrade", false, "Allow updating to a version older than the current one")
 
 	flags.String("job-id", "", "Job ID")
 
@@ -23,26 +27,43 @@ func AddUpdateFlags(cmd *cobra.Command) {
 	flags.String("project", "", "Project")
 	flags.String("working-dir", "", "Working directory")
 
-	flags.String("target-version", "", "Target version")
+	flags.String("target-version", "", "Target version: a version, a semver constraint such as ~2.5 or latest")
 	flags.String("current-version", "", "Current version")
+	flags.String("channel", "", "Release channel of the target version: "+release.ChannelStable+" (default) or "+
+		release.ChannelPreRelease)
 
 	flags.StringSlice("compose-files", []string{}, "Compose files")
+	flags.String("bundle", "", "Update from a local bundle: tarball with a manifest, the compose files and the image "+
+		"archives, for devices without access to the releases and registries")
 
-	flags.String("on-update-failure", "", "On update failure")
-	flags.String("github-releases", "", "GitHub releases")
-	flags.Bool("hard-reset", false, "Hard reset")
+	flags.String("on-update-failure", "", "Behaviour on update failure: rollback (default) or keep")
+	flags.Bool("hard-reset", false, "Remove the containers of a failed update before rolling back")
+
+	flags.String("binary-path", "", "Host updates: NuvlaEdge binary to replace. Defaults to "+common.DefaultBinaryPath)
+	flags.String("service-name", "", "Host updates: systemd service running NuvlaEdge. Defaults to "+
+		common.DefaultServiceName)
+	flags.String("admin-socket", constants.DefaultAdminSocket, "Host updates: admin API socket used to check "+
+		"the health of NuvlaEdge. Empty relies on the service state only")
+
+	flags.String("nuvla-releases", "", "Nuvla releases endpoint. Defaults to "+release.NuvlaEndpoint)
+	flags.String("github-releases", "", "GitHub releases API endpoint. Defaults to "+release.GitHubReleasesAPIURL)
+	flags.String("public-key-file", "", "PEM ed25519 public key verifying the signature of the release checksums")
 }
 
 func setDefaultUpdateFlags() {
 	// Set default update flags
+	viper.SetDefault("nuvla-releases", "")
 	viper.SetDefault("github-releases", "")
+	viper.SetDefault("public-key-file", "")
 	viper.SetDefault("on-update-failure", "")
+	viper.SetDefault("channel", "")
 }
 
 func bindViperUpdateFlags(flags *pflag.FlagSet) {
 	errMsg := "Failed to bind update cmd flag to viper"
 	OnError(viper.BindPFlag("force", flags.Lookup("force")), errMsg)
 	OnError(viper.BindPFlag("quiet", flags.Lookup("quiet")), errMsg)
+	OnError(viper.BindPFlag("allow-downgrade", flags.Lookup("allow-downgrade")), errMsg)
 
 	OnError(viper.BindPFlag("job-id", flags.Lookup("job-id")), errMsg)
 
@@ -52,18 +73,28 @@ func bindViperUpdateFlags(flags *pflag.FlagSet) {
 
 	OnError(viper.BindPFlag("target-version", flags.Lookup("target-version")), errMsg)
 	OnError(viper.BindPFlag("current-version", flags.Lookup("current-version")), errMsg)
+	OnError(viper.BindPFlag("channel", flags.Lookup("channel")), errMsg)
 
 	OnError(viper.BindPFlag("compose-files", flags.Lookup("compose-files")), errMsg)
+	OnError(viper.BindPFlag("bundle", flags.Lookup("bundle")), errMsg)
 
 	OnError(viper.BindPFlag("on-update-failure", flags.Lookup("on-update-failure")), errMsg)
-	OnError(viper.BindPFlag("github-releases", flags.Lookup("github-releases")), errMsg)
 	OnError(viper.BindPFlag("hard-reset", flags.Lookup("hard-reset")), errMsg)
+
+	OnError(viper.BindPFlag("binary-path", flags.Lookup("binary-path")), errMsg)
+	OnError(viper.BindPFlag("service-name", flags.Lookup("service-name")), errMsg)
+	OnError(viper.BindPFlag("admin-socket", flags.Lookup("admin-socket")), errMsg)
+
+	OnError(viper.BindPFlag("nuvla-releases", flags.Lookup("nuvla-releases")), errMsg)
+	OnError(viper.BindPFlag("github-releases", flags.Lookup("github-releases")), errMsg)
+	OnError(viper.BindPFlag("public-key-file", flags.Lookup("public-key-file")), errMsg)
 }
 
 func setUpdateEnvBindings() {
 	errMsg := "Failed to set update env binding"
 	OnError(viper.BindEnv("force", "FORCE_UPDATE"), errMsg)
 	OnError(viper.BindEnv("quiet", "QUIET"), errMsg)
+	OnError(viper.BindEnv("allow-downgrade", "ALLOW_DOWNGRADE"), errMsg)
 
 	OnError(viper.BindEnv("job-id", "JOB_ID"), errMsg)
 
@@ -77,8 +108,13 @@ func setUpdateEnvBindings() {
 	OnError(viper.BindEnv("compose-files", "COMPOSE_FILES"), errMsg)
 
 	OnError(viper.BindEnv("on-update-failure", "ON_UPDATE_FAILURE"), errMsg)
-	OnError(viper.BindEnv("github-releases", "GITHUB_RELEASES"), errMsg)
 	OnError(viper.BindEnv("hard-reset", "HARD_RESET"), errMsg)
+
+	// Also set in the agent environment, which passes them to the updater
+	OnError(viper.BindEnv("channel", "UPDATE_CHANNEL"), errMsg)
+	OnError(viper.BindEnv("nuvla-releases", "UPDATE_NUVLA_RELEASES"), errMsg)
+	OnError(viper.BindEnv("github-releases", "UPDATE_GITHUB_RELEASES", "GITHUB_RELEASES"), errMsg)
+	OnError(viper.BindEnv("public-key-file", "UPDATE_PUBLIC_KEY_FILE"), errMsg)
 }
 
 func ParseUpdateFlags(flags *pflag.FlagSet, opts *command.UpdateCmdOptions) error {
@@ -95,3 +131,33 @@ func ParseUpdateFlags(flags *pflag.FlagSet, opts *command.UpdateCmdOptions) erro
 
 	return nil
 }
+
+func AddUpdateListFlags(cmd *cobra.Command) {
+	flags := cmd.Flags()
+
+	flags.String("channel", "", "Only list the published versions of the channel: "+release.ChannelStable+" or "+
+		release.ChannelPreRelease)
+	flags.Bool("notes", false, "Show the full release notes")
+
+	flags.String("nuvla-releases", "", "Nuvla releases endpoint. Defaults to "+release.NuvlaEndpoint)
+	flags.String("github-releases", "", "GitHub releases API endpoint. Defaults to "+release.GitHubReleasesAPIURL)
+}
+
+func ParseUpdateListFlags(flags *pflag.FlagSet, opts *command.UpdateListCmdOptions) error {
+	errMsg := "Failed to bind update list cmd flag to viper"
+	OnError(viper.BindPFlag("channel", flags.Lookup("channel")), errMsg)
+	OnError(viper.BindPFlag("notes", flags.Lookup("notes")), errMsg)
+	OnError(viper.BindPFlag("nuvla-releases", flags.Lookup("nuvla-releases")), errMsg)
+	OnError(viper.BindPFlag("github-releases", flags.Lookup("github-releases")), errMsg)
+
+	errMsg = "Failed to set update list env binding"
+	OnError(viper.BindEnv("nuvla-releases", "UPDATE_NUVLA_RELEASES"), errMsg)
+	OnError(viper.BindEnv("github-releases", "UPDATE_GITHUB_RELEASES", "GITHUB_RELEASES"), errMsg)
+
+	if err := viper.Unmarshal(opts); err != nil {
+		log.Info("Failed to unmarshal update list cmd flags")
+		return err
+	}
+
+	return nil
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"nuvlaedge-go/cli"
@@ -13,6 +14,11 @@ import (
 func main() {
 	onError := func(err error) {
 		log.Errorf("Error: %s", err)
+		// Commands can tell the outcome of a failure with the exit code, e.g. update
+		var exitErr interface{ ExitCode() int }
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.ExitCode())
+		}
 		os.Exit(1)
 	}
 
@@ -5,14 +5,24 @@ import "nuvlaedge-go/updater/release"
 func main() {
 	v := "2.15.1"
 
-	n, err := release.GetNuvlaRelease(v)
+	n, err := release.GetNuvlaRelease("", v)
+	if err != nil {
+		panic(err)
+	}
+
+	g, err := release.GetGitHubRelease("", v)
+	if err != nil {
+		panic(err)
+	}
+	verifier, _ := release.NewVerifier("")
+	sums, err := g.Checksums(verifier)
 	if err != nil {
 		panic(err)
 	}
 
 	files := []string{"docker-compose.yml"}
 
-	dFiles, err := n.GetComposeFiles(files, "/tmp/nuvlaedge/releases")
+	dFiles, err := n.GetComposeFiles(files, "/tmp/nuvlaedge/releases", sums)
 	if err != nil {
 		panic(err)
 	}
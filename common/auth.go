@@ -0,0 +1,34 @@
+package common
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrUnauthorized is returned when Nuvla rejects the NuvlaEdge credentials
+var ErrUnauthorized = errors.New("unauthorized by Nuvla")
+
+// unauthorizedMessages are the fragments of the api client errors caused by rejected credentials. The client doesn't
+// expose the status code of failed requests, so it can only be found in the error message.
+var unauthorizedMessages = []string{
+	"401 Unauthorized",
+	"403 Forbidden",
+	"status code: 401",
+	"status code: 403",
+}
+
+// IsUnauthorized asserts whether the error was caused by Nuvla rejecting the NuvlaEdge credentials
+func IsUnauthorized(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, ErrUnauthorized) {
+		return true
+	}
+	for _, m := range unauthorizedMessages {
+		if strings.Contains(err.Error(), m) {
+			return true
+		}
+	}
+	return false
+}
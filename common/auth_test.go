@@ -0,0 +1,19 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_IsUnauthorized(t *testing.T) {
+	assert.False(t, IsUnauthorized(nil))
+	assert.False(t, IsUnauthorized(errors.New("connection refused")))
+	assert.False(t, IsUnauthorized(errors.New("heartbeat failed with status code: 500")))
+
+	assert.True(t, IsUnauthorized(fmt.Errorf("%w: telemetry rejected", ErrUnauthorized)))
+	assert.True(t, IsUnauthorized(errors.New("error re-authenticating: error logging in: 401 Unauthorized")))
+	assert.True(t, IsUnauthorized(errors.New("error logging in: 403 Forbidden")))
+	assert.True(t, IsUnauthorized(errors.New("heartbeat failed with status code: 401")))
+}
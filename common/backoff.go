@@ -0,0 +1,55 @@
+package common
+
+import (
+	"math/rand"
+	"time"
+)
+
+// Backoff computes exponentially increasing waiting times between retries. A random jitter is applied to each value
+// so that several agents recovering from the same outage do not hit Nuvla at the same time.
+type Backoff struct {
+	Initial time.Duration
+	Max     time.Duration
+	Factor  float64
+	// Jitter is the fraction (0-1) of the computed delay that can be randomly added or removed
+	Jitter float64
+
+	attempt int
+}
+
+func NewBackoff(initial, maxDelay time.Duration) *Backoff {
+	return &Backoff{
+		Initial: initial,
+		Max:     maxDelay,
+		Factor:  2,
+		Jitter:  0.2,
+	}
+}
+
+// Next returns the time to wait before the next retry and increases the attempt counter
+func (b *Backoff) Next() time.Duration {
+	d := float64(b.Initial)
+	for i := 0; i < b.attempt && d < float64(b.Max); i++ {
+		d *= b.Factor
+	}
+	if d > float64(b.Max) {
+		d = float64(b.Max)
+	}
+	b.attempt++
+
+	if b.Jitter > 0 {
+		// #nosec
+		d += d * b.Jitter * (2*rand.Float64() - 1)
+	}
+
+	return time.Duration(d)
+}
+
+// Attempts returns the number of delays computed since the last reset
+func (b *Backoff) Attempts() int {
+	return b.attempt
+}
+
+func (b *Backoff) Reset() {
+	b.attempt = 0
+}
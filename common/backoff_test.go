@@ -0,0 +1,36 @@
+package common
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func Test_Backoff_Next(t *testing.T) {
+	b := NewBackoff(time.Second, 10*time.Second)
+	b.Jitter = 0
+
+	assert.Equal(t, time.Second, b.Next())
+	assert.Equal(t, 2*time.Second, b.Next())
+	assert.Equal(t, 4*time.Second, b.Next())
+	assert.Equal(t, 8*time.Second, b.Next())
+	assert.Equal(t, 10*time.Second, b.Next(), "delay should be capped to max")
+	assert.Equal(t, 10*time.Second, b.Next(), "delay should be capped to max")
+	assert.Equal(t, 6, b.Attempts())
+
+	b.Reset()
+	assert.Equal(t, 0, b.Attempts())
+	assert.Equal(t, time.Second, b.Next())
+}
+
+func Test_Backoff_Jitter(t *testing.T) {
+	b := NewBackoff(10*time.Second, time.Minute)
+	b.Jitter = 0.5
+
+	for i := 0; i < 20; i++ {
+		b.Reset()
+		d := b.Next()
+		assert.GreaterOrEqual(t, d, 5*time.Second)
+		assert.LessOrEqual(t, d, 15*time.Second)
+	}
+}
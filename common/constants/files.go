@@ -1,6 +1,8 @@
 package constants
 
 const (
-	NuvlaEdgeSessionFile = "nuvlaedge_session.json"
-	DefaultRootFs        = "/rootfs"
+	NuvlaEdgeSessionFile  = "nuvlaedge_session.json"
+	NuvlaEdgeResourceFile = "nuvlaedge_resource.json"
+	StoreDir              = "store" // Local state store, relative to the database path
+	DefaultRootFs         = "/rootfs"
 )
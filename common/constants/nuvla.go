@@ -3,4 +3,17 @@ package constants
 const (
 	DefaultVPNId  = "infrastructure-service/eb8e09c2-8387-4f6d-86a4-ff5ddf3d07d7"
 	DefaultPrefix = "self-registered-nuvlaedge"
+
+	// DeploymentLabel links the containers to the Nuvla deployment that created them
+	DeploymentLabel = "nuvla.deployment"
 )
+
+// NuvlaEdge operational status reported in telemetry
+const (
+	StatusOperational = "OPERATIONAL"
+	StatusDegraded    = "DEGRADED"
+)
+
+// RevocationThreshold is the number of consecutive authentication failures after which the NuvlaEdge credentials are
+// considered revoked by Nuvla
+const RevocationThreshold = 3
@@ -6,12 +6,17 @@ const (
 	DefaultInsecure = false
 
 	// Default NuvlaEdge configuration
+	DefaultConfigFile = "/etc/nuvlaedge/template.toml"
 	DefaultDBPath     = "/var/lib/nuvlaedge/"
 	DefaultVPNEnabled = false
 
+	// Local admin API
+	DefaultAdminSocket = "/var/run/nuvlaedge/admin.sock"
+
 	// Default Job Engine configuration
 	DefaultJobEngineImage  = "sixsq/nuvlaedge:latest"
 	DefaultEnableLegacyJob = true
+	DefaultJobPoolSize     = 4 // Jobs running at the same time
 
 	// Logging
 	DefaultLogLevel = "info"
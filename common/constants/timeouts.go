@@ -3,4 +3,18 @@ package constants
 const (
 	DefaultJobTimeout  = 300
 	DefaultPullTimeout = 1200
+
+	DefaultShutdownTimeout = 120
+	AdminShutdownTimeout   = 5
+	SettingsReloadTimeout  = 10
+	DecommissionTimeout    = 120
+
+	// Offline mode reconnection backoff, in seconds
+	ReconnectInitialDelay = 5
+	ReconnectMaxDelay     = 300
+
+	// Crashed workers restart backoff, in seconds. A worker running longer than WorkerStableRunTime resets the backoff
+	WorkerRestartInitialDelay = 1
+	WorkerRestartMaxDelay     = 300
+	WorkerStableRunTime       = 600
 )
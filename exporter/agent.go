@@ -0,0 +1,99 @@
+package exporter
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"nuvlaedge-go/common/version"
+)
+
+// Agent self-metrics. They are always recorded, but only exposed if the exporter is enabled.
+var (
+	TelemetrySendDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "telemetry",
+		Name:      "send_duration_seconds",
+		Help:      "Time taken to send telemetry to Nuvla.",
+		Buckets:   []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10},
+	})
+	TelemetrySendFailures = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "telemetry",
+		Name:      "send_failures_total",
+		Help:      "Number of failed telemetry sends.",
+	})
+
+	Heartbeats = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "heartbeat",
+		Name:      "sent_total",
+		Help:      "Number of heartbeats sent to Nuvla.",
+	})
+	HeartbeatErrors = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "heartbeat",
+		Name:      "errors_total",
+		Help:      "Number of heartbeats that failed or whose response could not be processed.",
+	})
+
+	JobDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "job",
+		Name:      "duration_seconds",
+		Help:      "Duration of the jobs by action and result.",
+		Buckets:   []float64{1, 5, 15, 30, 60, 120, 300, 600, 1200},
+	}, []string{"action", "result"})
+
+	JobsRunning = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "job",
+		Name:      "running",
+		Help:      "Number of jobs running.",
+	})
+	JobsQueued = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "job",
+		Name:      "queued",
+		Help:      "Number of jobs waiting for a free slot in the job pool.",
+	})
+
+	CommissionAttempts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "commission",
+		Name:      "attempts_total",
+		Help:      "Number of commissioning attempts by result.",
+	}, []string{"result"})
+
+	buildInfo = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "build_info",
+		Help:      "NuvlaEdge agent version.",
+	}, []string{"version"})
+)
+
+// Result label values
+const (
+	ResultSuccess = "success"
+	ResultFailure = "failure"
+)
+
+// Result returns the result label matching the given error
+func Result(err error) string {
+	if err != nil {
+		return ResultFailure
+	}
+	return ResultSuccess
+}
+
+func agentCollectors() []prometheus.Collector {
+	buildInfo.WithLabelValues(version.GetVersion()).Set(1)
+	return []prometheus.Collector{
+		TelemetrySendDuration,
+		TelemetrySendFailures,
+		Heartbeats,
+		HeartbeatErrors,
+		JobDuration,
+		JobsRunning,
+		JobsQueued,
+		CommissionAttempts,
+		buildInfo,
+	}
+}
@@ -0,0 +1,135 @@
+package exporter
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"nuvlaedge-go/types/metrics"
+)
+
+const (
+	namespace = "nuvlaedge"
+
+	mib = 1024 * 1024
+	gib = 1024 * 1024 * 1024
+)
+
+var containerLabels = []string{"container", "image", "deployment"}
+
+// StatusCollector exposes the device and container metrics gathered by the telemetry monitors. Metrics are read from
+// the latest local status on each scrape, so they are as fresh as what is sent to Nuvla.
+type StatusCollector struct {
+	status func() metrics.NuvlaEdgeStatus
+
+	cpuLoad15   *prometheus.Desc
+	cpuLoad1    *prometheus.Desc
+	cpuLoad5    *prometheus.Desc
+	cpuCapacity *prometheus.Desc
+	ramUsed     *prometheus.Desc
+	ramCapacity *prometheus.Desc
+	diskUsed    *prometheus.Desc
+	diskCap     *prometheus.Desc
+	netRx       *prometheus.Desc
+	netTx       *prometheus.Desc
+
+	containerUp       *prometheus.Desc
+	containerCpu      *prometheus.Desc
+	containerCpuLimit *prometheus.Desc
+	containerMem      *prometheus.Desc
+	containerMemLimit *prometheus.Desc
+	containerNetRx    *prometheus.Desc
+	containerNetTx    *prometheus.Desc
+	containerDiskIn   *prometheus.Desc
+	containerDiskOut  *prometheus.Desc
+	containerRestarts *prometheus.Desc
+}
+
+func NewStatusCollector(status func() metrics.NuvlaEdgeStatus) *StatusCollector {
+	name := func(subsystem, n string) string {
+		return prometheus.BuildFQName(namespace, subsystem, n)
+	}
+	return &StatusCollector{
+		status: status,
+
+		cpuLoad15:   prometheus.NewDesc(name("cpu", "load15"), "Average CPU load over the last 15 minutes.", nil, nil),
+		cpuLoad1:    prometheus.NewDesc(name("cpu", "load1"), "Average CPU load over the last minute.", nil, nil),
+		cpuLoad5:    prometheus.NewDesc(name("cpu", "load5"), "Average CPU load over the last 5 minutes.", nil, nil),
+		cpuCapacity: prometheus.NewDesc(name("cpu", "capacity"), "Number of CPUs.", nil, nil),
+		ramUsed:     prometheus.NewDesc(name("memory", "used_bytes"), "Used memory.", nil, nil),
+		ramCapacity: prometheus.NewDesc(name("memory", "capacity_bytes"), "Total memory.", nil, nil),
+		diskUsed:    prometheus.NewDesc(name("disk", "used_bytes"), "Used disk space, in GiB steps.", []string{"device"}, nil),
+		diskCap:     prometheus.NewDesc(name("disk", "capacity_bytes"), "Total disk space, in GiB steps.", []string{"device"}, nil),
+		netRx:       prometheus.NewDesc(name("network", "receive_bytes_total"), "Bytes received by the interface.", []string{"interface"}, nil),
+		netTx:       prometheus.NewDesc(name("network", "transmit_bytes_total"), "Bytes transmitted by the interface.", []string{"interface"}, nil),
+
+		containerUp:       prometheus.NewDesc(name("container", "up"), "Whether the container is running.", append(containerLabels, "state"), nil),
+		containerCpu:      prometheus.NewDesc(name("container", "cpu_usage_percent"), "Container CPU usage.", containerLabels, nil),
+		containerCpuLimit: prometheus.NewDesc(name("container", "cpu_limit_cores"), "Container CPU limit, 0 if unlimited.", containerLabels, nil),
+		containerMem:      prometheus.NewDesc(name("container", "memory_usage_bytes"), "Container memory usage.", containerLabels, nil),
+		containerMemLimit: prometheus.NewDesc(name("container", "memory_limit_bytes"), "Container memory limit.", containerLabels, nil),
+		containerNetRx:    prometheus.NewDesc(name("container", "network_receive_bytes_total"), "Bytes received by the container.", containerLabels, nil),
+		containerNetTx:    prometheus.NewDesc(name("container", "network_transmit_bytes_total"), "Bytes transmitted by the container.", containerLabels, nil),
+		containerDiskIn:   prometheus.NewDesc(name("container", "disk_read_bytes_total"), "Bytes read from disk by the container.", containerLabels, nil),
+		containerDiskOut:  prometheus.NewDesc(name("container", "disk_write_bytes_total"), "Bytes written to disk by the container.", containerLabels, nil),
+		containerRestarts: prometheus.NewDesc(name("container", "restarts_total"), "Number of restarts of the container.", containerLabels, nil),
+	}
+}
+
+func (c *StatusCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{
+		c.cpuLoad15, c.cpuLoad1, c.cpuLoad5, c.cpuCapacity, c.ramUsed, c.ramCapacity, c.diskUsed, c.diskCap, c.netRx,
+		c.netTx, c.containerUp, c.containerCpu, c.containerCpuLimit, c.containerMem, c.containerMemLimit,
+		c.containerNetRx, c.containerNetTx, c.containerDiskIn, c.containerDiskOut, c.containerRestarts,
+	} {
+		ch <- d
+	}
+}
+
+func (c *StatusCollector) Collect(ch chan<- prometheus.Metric) {
+	res := c.status().Resources
+
+	if cpu := res.CPUMetrics; cpu != nil {
+		ch <- prometheus.MustNewConstMetric(c.cpuLoad15, prometheus.GaugeValue, cpu.Load)
+		ch <- prometheus.MustNewConstMetric(c.cpuLoad1, prometheus.GaugeValue, cpu.Load1)
+		ch <- prometheus.MustNewConstMetric(c.cpuLoad5, prometheus.GaugeValue, cpu.Load5)
+		ch <- prometheus.MustNewConstMetric(c.cpuCapacity, prometheus.GaugeValue, float64(cpu.Capacity))
+	}
+
+	if ram := res.RamMetrics; ram != nil {
+		ch <- prometheus.MustNewConstMetric(c.ramUsed, prometheus.GaugeValue, float64(ram.Used*mib))
+		ch <- prometheus.MustNewConstMetric(c.ramCapacity, prometheus.GaugeValue, float64(ram.Capacity*mib))
+	}
+
+	for _, d := range res.DiskMetrics {
+		ch <- prometheus.MustNewConstMetric(c.diskUsed, prometheus.GaugeValue, float64(d.Used*gib), d.Device)
+		ch <- prometheus.MustNewConstMetric(c.diskCap, prometheus.GaugeValue, float64(d.Capacity*gib), d.Device)
+	}
+
+	for _, i := range res.NetStats {
+		ch <- prometheus.MustNewConstMetric(c.netRx, prometheus.CounterValue, float64(i.BytesReceived), i.Interface)
+		ch <- prometheus.MustNewConstMetric(c.netTx, prometheus.CounterValue, float64(i.BytesTransmitted), i.Interface)
+	}
+
+	for _, ct := range res.ContainerStats {
+		c.collectContainer(ch, ct)
+	}
+}
+
+func (c *StatusCollector) collectContainer(ch chan<- prometheus.Metric, ct metrics.ContainerData) {
+	labels := []string{ct.Name, ct.Image, ct.DeploymentId}
+
+	up := 0.0
+	if ct.State == "running" {
+		up = 1
+	}
+	ch <- prometheus.MustNewConstMetric(c.containerUp, prometheus.GaugeValue, up, append(labels, ct.State)...)
+	ch <- prometheus.MustNewConstMetric(c.containerCpu, prometheus.GaugeValue, ct.CpuUsage, labels...)
+	ch <- prometheus.MustNewConstMetric(c.containerCpuLimit, prometheus.GaugeValue, ct.CpuLimit, labels...)
+	ch <- prometheus.MustNewConstMetric(c.containerMem, prometheus.GaugeValue, float64(ct.MemUsage), labels...)
+	ch <- prometheus.MustNewConstMetric(c.containerMemLimit, prometheus.GaugeValue, float64(ct.MemLimit), labels...)
+	ch <- prometheus.MustNewConstMetric(c.containerNetRx, prometheus.CounterValue, float64(ct.NetIn), labels...)
+	ch <- prometheus.MustNewConstMetric(c.containerNetTx, prometheus.CounterValue, float64(ct.NetOut), labels...)
+	ch <- prometheus.MustNewConstMetric(c.containerDiskIn, prometheus.CounterValue, float64(ct.DiskIn), labels...)
+	ch <- prometheus.MustNewConstMetric(c.containerDiskOut, prometheus.CounterValue, float64(ct.DiskOut), labels...)
+	ch <- prometheus.MustNewConstMetric(c.containerRestarts, prometheus.CounterValue, float64(ct.RestartCount), labels...)
+}
+
+var _ prometheus.Collector = &StatusCollector{}
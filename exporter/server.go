@@ -0,0 +1,74 @@
+package exporter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	log "github.com/sirupsen/logrus"
+	"net"
+	"net/http"
+	"nuvlaedge-go/types/metrics"
+	"time"
+)
+
+// Server exposes the NuvlaEdge metrics in Prometheus/OpenMetrics format on /metrics
+type Server struct {
+	address  string
+	registry *prometheus.Registry
+	server   *http.Server
+}
+
+// NewServer creates the exporter for the given listen address. The status function provides the latest local
+// NuvlaEdge status from which the device and container metrics are read.
+func NewServer(address string, status func() metrics.NuvlaEdgeStatus) (*Server, error) {
+	reg := prometheus.NewRegistry()
+	if err := reg.Register(NewStatusCollector(status)); err != nil {
+		return nil, err
+	}
+	for _, c := range agentCollectors() {
+		if err := reg.Register(c); err != nil {
+			return nil, err
+		}
+	}
+
+	s := &Server{
+		address:  address,
+		registry: reg,
+	}
+	s.server = &http.Server{
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	return s, nil
+}
+
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", promhttp.HandlerFor(s.registry, promhttp.HandlerOpts{
+		EnableOpenMetrics: true,
+		ErrorLog:          log.StandardLogger(),
+	}))
+	return mux
+}
+
+// Start listens on the configured address and serves the metrics in the background
+func (s *Server) Start() error {
+	l, err := net.Listen("tcp", s.address)
+	if err != nil {
+		return fmt.Errorf("error listening on metrics address %s: %w", s.address, err)
+	}
+
+	go func() {
+		if err := s.server.Serve(l); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Errorf("Metrics exporter stopped with error: %s", err)
+		}
+	}()
+	log.Infof("Metrics exporter listening on %s", l.Addr())
+	return nil
+}
+
+func (s *Server) Stop(ctx context.Context) error {
+	return s.server.Shutdown(ctx)
+}
@@ -0,0 +1,90 @@
+package exporter
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"nuvlaedge-go/types/metrics"
+	"testing"
+)
+
+func testStatus() metrics.NuvlaEdgeStatus {
+	return metrics.NuvlaEdgeStatus{
+		Resources: metrics.Resources{
+			CPUMetrics:  &metrics.CPUMetrics{Load: 0.5, Load1: 1.5, Load5: 1, Capacity: 4},
+			RamMetrics:  &metrics.RamMetrics{Used: 512, Capacity: 1024},
+			DiskMetrics: metrics.DiskMetrics{{Device: "/dev/sda1", Used: 10, Capacity: 100}},
+			NetStats:    metrics.IfacesMetrics{{Interface: "eth0", BytesReceived: 1000, BytesTransmitted: 2000}},
+			ContainerStats: metrics.ContainerStats{
+				{Name: "app", Image: "nginx:latest", State: "running", CpuUsage: 12.5, MemUsage: 2048, NetIn: 10, RestartCount: 2, DeploymentId: "deployment/1234"},
+				{Name: "agent", Image: "nuvlaedge:latest", State: "exited"},
+			},
+		},
+	}
+}
+
+func scrape(t *testing.T, s *Server) string {
+	ts := httptest.NewServer(s.Handler())
+	defer ts.Close()
+
+	res, err := http.Get(ts.URL + "/metrics")
+	assert.NoError(t, err)
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	b, err := io.ReadAll(res.Body)
+	assert.NoError(t, err)
+	return string(b)
+}
+
+func Test_Server_DeviceMetrics(t *testing.T) {
+	s, err := NewServer(":0", testStatus)
+	assert.NoError(t, err)
+
+	out := scrape(t, s)
+	assert.Contains(t, out, "nuvlaedge_cpu_load1 1.5")
+	assert.Contains(t, out, "nuvlaedge_cpu_capacity 4")
+	assert.Contains(t, out, "nuvlaedge_memory_used_bytes 5.36870912e+08")
+	assert.Contains(t, out, `nuvlaedge_disk_capacity_bytes{device="/dev/sda1"} 1.073741824e+11`)
+	assert.Contains(t, out, "# TYPE nuvlaedge_network_receive_bytes_total counter")
+	assert.Contains(t, out, `nuvlaedge_network_receive_bytes_total{interface="eth0"} 1000`)
+}
+
+func Test_Server_ContainerMetrics(t *testing.T) {
+	s, err := NewServer(":0", testStatus)
+	assert.NoError(t, err)
+
+	out := scrape(t, s)
+	labels := `container="app",deployment="deployment/1234",image="nginx:latest"`
+	assert.Contains(t, out, `nuvlaedge_container_up{`+labels+`,state="running"} 1`)
+	assert.Contains(t, out, `nuvlaedge_container_cpu_usage_percent{`+labels+`} 12.5`)
+	assert.Contains(t, out, `nuvlaedge_container_memory_usage_bytes{`+labels+`} 2048`)
+	assert.Contains(t, out, `nuvlaedge_container_restarts_total{`+labels+`} 2`)
+	assert.Contains(t, out, `nuvlaedge_container_up{container="agent",deployment="",image="nuvlaedge:latest",state="exited"} 0`)
+}
+
+func Test_Server_EmptyStatus(t *testing.T) {
+	s, err := NewServer(":0", func() metrics.NuvlaEdgeStatus { return metrics.NuvlaEdgeStatus{} })
+	assert.NoError(t, err)
+
+	out := scrape(t, s)
+	assert.NotContains(t, out, "nuvlaedge_cpu_")
+	assert.NotContains(t, out, "nuvlaedge_container_")
+	assert.Contains(t, out, "nuvlaedge_build_info")
+}
+
+func Test_Server_AgentMetrics(t *testing.T) {
+	s, err := NewServer(":0", testStatus)
+	assert.NoError(t, err)
+
+	HeartbeatErrors.Inc()
+	JobDuration.WithLabelValues("reboot_nuvlabox", Result(errors.New("failed"))).Observe(3)
+	CommissionAttempts.WithLabelValues(Result(nil)).Inc()
+
+	out := scrape(t, s)
+	assert.Contains(t, out, "nuvlaedge_heartbeat_errors_total")
+	assert.Contains(t, out, `nuvlaedge_job_duration_seconds_count{action="reboot_nuvlabox",result="failure"} 1`)
+	assert.Contains(t, out, `nuvlaedge_commission_attempts_total{result="success"}`)
+}
@@ -0,0 +1,125 @@
+package nuvlaedge
+
+import (
+	"context"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"nuvlaedge-go/admin"
+	"nuvlaedge-go/common/constants"
+	"nuvlaedge-go/types/jobs"
+	"nuvlaedge-go/types/metrics"
+	"nuvlaedge-go/types/settings"
+	"nuvlaedge-go/types/worker"
+	"sort"
+	"time"
+)
+
+// statusProvider is implemented by the worker holding the local NuvlaEdge status, i.e. telemetry
+type statusProvider interface {
+	GetStatus() metrics.NuvlaEdgeStatus
+}
+
+// lastRunProvider is implemented by the workers that keep track of their last run
+type lastRunProvider interface {
+	GetLastRun() time.Time
+}
+
+// startAdminServer starts the local admin API if configured. The NuvlaEdge can run without it, so errors are only
+// logged.
+func (ne *NuvlaEdge) startAdminServer() {
+	if ne.conf.AdminSocket == "" {
+		log.Info("Admin API disabled")
+		return
+	}
+
+	s := admin.NewServer(ne.conf.AdminSocket, ne)
+	if err := s.Start(); err != nil {
+		log.Errorf("Error starting admin API: %s", err)
+		return
+	}
+	ne.adminServer = s
+}
+
+// stopAdminServer is called last on shutdown, so it gets its own timeout instead of whatever is left of the shutdown
+// deadline
+func (ne *NuvlaEdge) stopAdminServer() error {
+	if ne.adminServer == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), constants.AdminShutdownTimeout*time.Second)
+	defer cancel()
+	return ne.adminServer.Stop(ctx)
+}
+
+// Status returns the local NuvlaEdge status as gathered by telemetry
+func (ne *NuvlaEdge) Status() metrics.NuvlaEdgeStatus {
+	if p, ok := ne.workers[worker.Telemetry].(statusProvider); ok {
+		return p.GetStatus()
+	}
+	return metrics.NuvlaEdgeStatus{}
+}
+
+// Jobs returns the jobs currently running
+func (ne *NuvlaEdge) Jobs() []jobs.RunningJob {
+	if ne.workerOpts == nil || ne.workerOpts.Jobs == nil {
+		return []jobs.RunningJob{}
+	}
+	return ne.workerOpts.Jobs.List()
+}
+
+// WorkersInfo returns the status, period and last run of every worker, sorted by name
+func (ne *NuvlaEdge) WorkersInfo() []admin.WorkerInfo {
+	status := ne.WorkersStatus()
+
+	info := make([]admin.WorkerInfo, 0, len(ne.workers))
+	for t, w := range ne.workers {
+		wi := admin.WorkerInfo{Name: string(t), Status: status[t]}
+		if _, started := status[t]; !started {
+			wi.Status.State = worker.WorkerStopped
+		}
+		if p, ok := w.(worker.PeriodicWorker); ok {
+			wi.Period = p.GetPeriod()
+		}
+		if p, ok := w.(lastRunProvider); ok && !p.GetLastRun().IsZero() {
+			lastRun := p.GetLastRun()
+			wi.LastRun = &lastRun
+		}
+		info = append(info, wi)
+	}
+
+	sort.Slice(info, func(i, j int) bool {
+		return info[i].Name < info[j].Name
+	})
+	return info
+}
+
+// Settings returns the effective NuvlaEdge settings without secrets
+func (ne *NuvlaEdge) Settings() settings.NuvlaEdgeSettings {
+	ne.confMu.RLock()
+	defer ne.confMu.RUnlock()
+	return ne.conf.Redacted()
+}
+
+// TriggerWorker requests the given worker to run right away
+func (ne *NuvlaEdge) TriggerWorker(wType worker.WorkerType) error {
+	w, ok := ne.workers[wType]
+	if !ok {
+		return fmt.Errorf("%w: %s", admin.ErrWorkerNotFound, wType)
+	}
+
+	t, ok := w.(worker.Triggerable)
+	if !ok {
+		return fmt.Errorf("%w: %s", admin.ErrNotTriggerable, wType)
+	}
+
+	if s, started := ne.WorkersStatus()[wType]; !started || s.State == worker.WorkerStopped {
+		return fmt.Errorf("%w: %s", admin.ErrWorkerNotRunning, wType)
+	}
+
+	if !t.Trigger() {
+		log.Debugf("Worker %s already has a pending trigger", wType)
+	}
+	return nil
+}
+
+var _ admin.Agent = &NuvlaEdge{}
@@ -0,0 +1,38 @@
+package nuvlaedge
+
+import (
+	"context"
+	log "github.com/sirupsen/logrus"
+	"nuvlaedge-go/common/constants"
+	"nuvlaedge-go/exporter"
+	"time"
+)
+
+// startMetricsServer starts the Prometheus exporter if configured. Like the admin API, it is optional and errors are
+// only logged.
+func (ne *NuvlaEdge) startMetricsServer() {
+	if ne.conf.MetricsAddress == "" {
+		log.Info("Metrics exporter disabled")
+		return
+	}
+
+	s, err := exporter.NewServer(ne.conf.MetricsAddress, ne.Status)
+	if err != nil {
+		log.Errorf("Error creating metrics exporter: %s", err)
+		return
+	}
+	if err := s.Start(); err != nil {
+		log.Errorf("Error starting metrics exporter: %s", err)
+		return
+	}
+	ne.metricsServer = s
+}
+
+func (ne *NuvlaEdge) stopMetricsServer() error {
+	if ne.metricsServer == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), constants.AdminShutdownTimeout*time.Second)
+	defer cancel()
+	return ne.metricsServer.Stop(ctx)
+}
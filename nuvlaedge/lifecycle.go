@@ -0,0 +1,377 @@
+package nuvlaedge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/docker/cli/cli/command"
+	"github.com/docker/cli/cli/command/stack/options"
+	"github.com/docker/cli/cli/command/stack/swarm"
+	"github.com/docker/cli/cli/compose/convert"
+	"github.com/docker/cli/cli/flags"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/client"
+	"github.com/nuvla/api-client-go/clients/resources"
+	log "github.com/sirupsen/logrus"
+	"io"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"nuvlaedge-go/common"
+	"nuvlaedge-go/common/constants"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+var (
+	// errInvalidTransition is returned when the NuvlaEdge is requested to move to a state not reachable from the
+	// current one
+	errInvalidTransition = errors.New("invalid NuvlaEdge state transition")
+	// errDecommissioned is returned by the start-up process when Nuvla reports the NuvlaEdge as decommissioned
+	errDecommissioned = errors.New("NuvlaEdge decommissioned")
+	// errCredentialsRevoked is returned when Nuvla keeps rejecting the NuvlaEdge credentials
+	errCredentialsRevoked = errors.New("NuvlaEdge credentials revoked")
+)
+
+// lifecycleTransitions lists the states reachable from each NuvlaEdge state. Intermediate states can be skipped since
+// the agent might not be running while Nuvla moves the NuvlaEdge forward. DECOMMISSIONED is final.
+var lifecycleTransitions = map[resources.NuvlaEdgeState][]resources.NuvlaEdgeState{
+	resources.NuvlaEdgeStateNew: {
+		resources.NuvlaEdgeStateActivated, resources.NuvlaEdgeStateCommissioned,
+		resources.NuvlaEdgeStateDecommissioning, resources.NuvlaEdgeStateDecommissioned, resources.NuvlaEdgeStateError},
+	resources.NuvlaEdgeStateActivated: {
+		resources.NuvlaEdgeStateCommissioned,
+		resources.NuvlaEdgeStateDecommissioning, resources.NuvlaEdgeStateDecommissioned, resources.NuvlaEdgeStateError},
+	resources.NuvlaEdgeStateCommissioned: {
+		resources.NuvlaEdgeStateSuspended,
+		resources.NuvlaEdgeStateDecommissioning, resources.NuvlaEdgeStateDecommissioned, resources.NuvlaEdgeStateError},
+	resources.NuvlaEdgeStateSuspended: {
+		resources.NuvlaEdgeStateCommissioned,
+		resources.NuvlaEdgeStateDecommissioning, resources.NuvlaEdgeStateDecommissioned, resources.NuvlaEdgeStateError},
+	resources.NuvlaEdgeStateError: {
+		resources.NuvlaEdgeStateCommissioned,
+		resources.NuvlaEdgeStateDecommissioning, resources.NuvlaEdgeStateDecommissioned},
+	resources.NuvlaEdgeStateDecommissioning: {
+		resources.NuvlaEdgeStateDecommissioned, resources.NuvlaEdgeStateError},
+	resources.NuvlaEdgeStateDecommissioned: {},
+}
+
+// canTransition asserts whether the NuvlaEdge can move from one state to the other. Staying in the same state is
+// always allowed and so is any state when the current one is unknown.
+func canTransition(from, to resources.NuvlaEdgeState) bool {
+	if from == "" || from == to {
+		return true
+	}
+	return slices.Contains(lifecycleTransitions[from], to)
+}
+
+func isDecommissioned(state resources.NuvlaEdgeState) bool {
+	return state == resources.NuvlaEdgeStateDecommissioning || state == resources.NuvlaEdgeStateDecommissioned
+}
+
+// lifecycle keeps track of the NuvlaEdge state and persists it so that it survives restarts
+type lifecycle struct {
+	mu     sync.Mutex
+	dbPath string
+	id     string
+	res    resources.NuvlaEdgeResource
+}
+
+// newLifecycle loads the persisted state of the NuvlaEdge. A state persisted by another NuvlaEdge, e.g. after the
+// device is re-installed with a new id, is ignored.
+func newLifecycle(dbPath, id string) *lifecycle {
+	l := &lifecycle{dbPath: dbPath, id: id}
+
+	res, err := loadResourceState(dbPath)
+	switch {
+	case err != nil:
+		log.Debugf("No persisted NuvlaEdge state: %s", err)
+	case res.Id != "" && res.Id != id:
+		log.Warnf("Ignoring persisted state of NuvlaEdge %s", res.Id)
+	default:
+		l.res = *res
+	}
+	return l
+}
+
+// State returns the current NuvlaEdge state, empty if unknown
+func (l *lifecycle) State() resources.NuvlaEdgeState {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.res.State
+}
+
+// Last returns the last known NuvlaEdge resource, or nil if unknown
+func (l *lifecycle) Last() *resources.NuvlaEdgeResource {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.res.State == "" {
+		return nil
+	}
+	res := l.res
+	return &res
+}
+
+// Update moves the NuvlaEdge to the state of the given resource and persists it. The state is updated even if it
+// can't be persisted.
+func (l *lifecycle) Update(res resources.NuvlaEdgeResource) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.update(res)
+}
+
+// Transition moves the NuvlaEdge to the given state and persists it
+func (l *lifecycle) Transition(to resources.NuvlaEdgeState) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	res := l.res
+	res.State = to
+	return l.update(res)
+}
+
+func (l *lifecycle) update(res resources.NuvlaEdgeResource) error {
+	if !canTransition(l.res.State, res.State) {
+		return fmt.Errorf("%w: from %s to %s", errInvalidTransition, l.res.State, res.State)
+	}
+	if l.res.State != res.State {
+		log.Infof("NuvlaEdge state changed from %s to %s", l.res.State, res.State)
+	}
+
+	if res.Id == "" {
+		res.Id = l.id
+	}
+	l.res = res
+	if err := saveResourceState(l.dbPath, res); err != nil {
+		return fmt.Errorf("error persisting NuvlaEdge state: %w", err)
+	}
+	return nil
+}
+
+// updateLifecycle records the state received from Nuvla during the start-up process
+func (ne *NuvlaEdge) updateLifecycle(res resources.NuvlaEdgeResource) error {
+	if err := ne.lifecycle.Update(res); err != nil {
+		if errors.Is(err, errInvalidTransition) {
+			return fmt.Errorf("%w: %s", errStartUpState, err)
+		}
+		log.Warn(err)
+	}
+
+	if isDecommissioned(res.State) {
+		return fmt.Errorf("%w: state %s", errDecommissioned, res.State)
+	}
+	return nil
+}
+
+// watchLifecycle handles the state changes and the credential revocations reported by the workers until the context
+// is done or the NuvlaEdge exits
+func (ne *NuvlaEdge) watchLifecycle(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ne.exitCh:
+			return
+		case state := <-ne.stateCh:
+			ne.onStateChange(state)
+		case err := <-ne.revocationCh:
+			ne.onRevocation(err)
+		}
+	}
+}
+
+func (ne *NuvlaEdge) onStateChange(state resources.NuvlaEdgeState) {
+	if err := ne.lifecycle.Transition(state); err != nil {
+		log.Warnf("Error updating NuvlaEdge state: %s", err)
+	}
+
+	if isDecommissioned(state) {
+		ne.decommission()
+	}
+}
+
+// onRevocation stops the NuvlaEdge when Nuvla keeps rejecting its credentials. Nuvla revokes them when the
+// NuvlaEdge is decommissioned, so a pending decommission is completed. Otherwise, the local data is left untouched
+// and the NuvlaEdge exits with an error.
+func (ne *NuvlaEdge) onRevocation(err error) {
+	if isDecommissioned(ne.lifecycle.State()) {
+		ne.decommission()
+		return
+	}
+
+	log.Errorf("Nuvla keeps rejecting the NuvlaEdge credentials, stopping: %s", err)
+	stopErr := ne.Stop()
+	ne.exit(errors.Join(fmt.Errorf("%w: %s", errCredentialsRevoked, err), stopErr))
+}
+
+// decommission stops the workers and the deployments managed by Nuvla, removes the NuvlaEdge credentials and exits.
+// It only runs once, no matter how many times the decommission is detected.
+func (ne *NuvlaEdge) decommission() {
+	ne.decommissionOnce.Do(func() {
+		log.Warn("NuvlaEdge decommissioned, stopping deployments and removing credentials")
+
+		var errList []error
+		if err := ne.Stop(); err != nil {
+			errList = append(errList, err)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), constants.DecommissionTimeout*time.Second)
+		defer cancel()
+		if err := ne.stopDeployments(ctx); err != nil {
+			log.Errorf("Error stopping Nuvla deployments: %s", err)
+			errList = append(errList, err)
+		}
+
+		if err := ne.wipeCredentials(); err != nil {
+			log.Errorf("Error removing NuvlaEdge credentials: %s", err)
+			errList = append(errList, err)
+		}
+
+		// The local state belongs to this NuvlaEdge, the device might be installed again as a new one
+		if ne.workerOpts != nil {
+			if err := ne.workerOpts.Store.Clear(); err != nil {
+				log.Errorf("Error clearing local store: %s", err)
+				errList = append(errList, err)
+			}
+		}
+
+		if err := ne.lifecycle.Transition(resources.NuvlaEdgeStateDecommissioned); err != nil {
+			log.Errorf("Error updating NuvlaEdge state: %s", err)
+			errList = append(errList, err)
+		}
+
+		log.Info("NuvlaEdge decommission completed")
+		ne.exit(errors.Join(errList...))
+	})
+}
+
+// wipeCredentials removes the session file, which holds the IRS, and forgets the credentials in memory
+func (ne *NuvlaEdge) wipeCredentials() error {
+	ne.confMu.Lock()
+	ne.conf.Irs = ""
+	ne.conf.ApiKey = ""
+	ne.conf.ApiSecret = ""
+	sessionFile := filepath.Join(ne.conf.DBPPath, constants.NuvlaEdgeSessionFile)
+	ne.confMu.Unlock()
+
+	if ne.nuvla != nil {
+		ne.nuvla.Irs = ""
+	}
+
+	if err := os.Remove(sessionFile); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// stopDeployments stops the deployments managed by Nuvla in the container orchestration engine the NuvlaEdge runs in.
+// In Kubernetes, the namespaces of the deployments are removed. In Docker, the stacks are removed before stopping the
+// containers, which swarm would otherwise restart.
+func (ne *NuvlaEdge) stopDeployments(ctx context.Context) error {
+	if common.IsRunningInKubernetes() {
+		config, err := common.NewKubernetesConfig()
+		if err != nil {
+			return fmt.Errorf("error loading kubernetes configuration: %w", err)
+		}
+		kCli, err := kubernetes.NewForConfig(config)
+		if err != nil {
+			return err
+		}
+		return removeNamespaces(ctx, kCli)
+	}
+
+	if ne.dockerClient == nil {
+		return errors.New("docker client not available")
+	}
+	return errors.Join(removeStacks(ctx, ne.dockerClient), stopContainers(ctx, ne.dockerClient))
+}
+
+// stopContainers stops the containers created by Nuvla deployments
+func stopContainers(ctx context.Context, cli client.APIClient) error {
+	containers, err := cli.ContainerList(ctx, container.ListOptions{
+		Filters: filters.NewArgs(filters.Arg("label", constants.DeploymentLabel)),
+	})
+	if err != nil {
+		return err
+	}
+
+	var errList []error
+	for _, c := range containers {
+		log.Infof("Stopping container %s of deployment %s", c.ID, c.Labels[constants.DeploymentLabel])
+		if err := cli.ContainerStop(ctx, c.ID, container.StopOptions{}); err != nil {
+			errList = append(errList, fmt.Errorf("error stopping container %s: %w", c.ID, err))
+		}
+	}
+	return errors.Join(errList...)
+}
+
+// removeStacks removes the stacks of the services created by Nuvla deployments. Only the managers of the swarm can
+// remove them, they are left running when the NuvlaEdge is a worker node or swarm is not enabled.
+func removeStacks(ctx context.Context, cli client.APIClient) error {
+	info, err := cli.Info(ctx)
+	if err != nil {
+		return err
+	}
+	if !info.Swarm.ControlAvailable {
+		log.Debug("Not a swarm manager, no stacks to remove")
+		return nil
+	}
+
+	services, err := cli.ServiceList(ctx, types.ServiceListOptions{
+		Filters: filters.NewArgs(filters.Arg("label", constants.DeploymentLabel)),
+	})
+	if err != nil {
+		return err
+	}
+
+	var namespaces []string
+	for _, svc := range services {
+		ns := svc.Spec.Labels[convert.LabelNamespace]
+		if ns != "" && !slices.Contains(namespaces, ns) {
+			log.Infof("Removing stack %s of deployment %s", ns, svc.Spec.Labels[constants.DeploymentLabel])
+			namespaces = append(namespaces, ns)
+		}
+	}
+	if len(namespaces) == 0 {
+		return nil
+	}
+
+	dCli, err := command.NewDockerCli(command.WithCombinedStreams(io.Discard))
+	if err != nil {
+		return err
+	}
+	if err := dCli.Initialize(&flags.ClientOptions{Context: "default"}, command.WithAPIClient(cli)); err != nil {
+		return err
+	}
+	return swarm.RunRemove(ctx, dCli, options.Remove{Namespaces: namespaces, Detach: true})
+}
+
+// removeNamespaces removes the namespaces of the Nuvla deployments, along with everything deployed in them
+func removeNamespaces(ctx context.Context, kCli kubernetes.Interface) error {
+	namespaces, err := kCli.CoreV1().Namespaces().List(ctx, metav1.ListOptions{LabelSelector: constants.DeploymentLabel})
+	if err != nil {
+		return err
+	}
+
+	var errList []error
+	for _, ns := range namespaces.Items {
+		log.Infof("Removing namespace %s of deployment %s", ns.Name, ns.Labels[constants.DeploymentLabel])
+		err := kCli.CoreV1().Namespaces().Delete(ctx, ns.Name, metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			errList = append(errList, fmt.Errorf("error removing namespace %s: %w", ns.Name, err))
+		}
+	}
+	return errors.Join(errList...)
+}
+
+// exit makes Run return with the given error
+func (ne *NuvlaEdge) exit(err error) {
+	ne.exitOnce.Do(func() {
+		ne.exitErr = err
+		close(ne.exitCh)
+	})
+}
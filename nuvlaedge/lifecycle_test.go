@@ -0,0 +1,218 @@
+package nuvlaedge
+
+import (
+	"context"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/docker/docker/api/types/system"
+	"github.com/docker/docker/client"
+	"github.com/nuvla/api-client-go/clients/resources"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"nuvlaedge-go/common/constants"
+	"nuvlaedge-go/testutils"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// dockerClientMock lists the given containers and records the ones stopped
+type dockerClientMock struct {
+	client.APIClient
+
+	containers []types.Container
+	stopped    []string
+
+	manager  bool
+	services []swarm.Service
+	removed  []string
+}
+
+func (d *dockerClientMock) ContainerList(_ context.Context, opts container.ListOptions) ([]types.Container, error) {
+	var res []types.Container
+	for _, c := range d.containers {
+		if _, ok := c.Labels[constants.DeploymentLabel]; ok || !opts.Filters.Contains("label") {
+			res = append(res, c)
+		}
+	}
+	return res, nil
+}
+
+func (d *dockerClientMock) ContainerStop(_ context.Context, id string, _ container.StopOptions) error {
+	d.stopped = append(d.stopped, id)
+	return nil
+}
+
+func (d *dockerClientMock) Info(_ context.Context) (system.Info, error) {
+	return system.Info{Swarm: swarm.Info{ControlAvailable: d.manager}}, nil
+}
+
+func (d *dockerClientMock) Ping(_ context.Context) (types.Ping, error) {
+	return types.Ping{}, nil
+}
+
+func (d *dockerClientMock) NegotiateAPIVersionPing(_ types.Ping) {}
+
+func (d *dockerClientMock) ClientVersion() string {
+	return "1.24"
+}
+
+func (d *dockerClientMock) ServiceList(_ context.Context, opts types.ServiceListOptions) ([]swarm.Service, error) {
+	var res []swarm.Service
+	for _, svc := range d.services {
+		if opts.Filters.Contains("label") && !opts.Filters.MatchKVList("label", svc.Spec.Labels) {
+			continue
+		}
+		res = append(res, svc)
+	}
+	return res, nil
+}
+
+func (d *dockerClientMock) ServiceRemove(_ context.Context, id string) error {
+	d.removed = append(d.removed, id)
+	return nil
+}
+
+func (d *dockerClientMock) NetworkList(_ context.Context, _ network.ListOptions) ([]network.Summary, error) {
+	return nil, nil
+}
+
+func Test_canTransition(t *testing.T) {
+	assert.True(t, canTransition("", resources.NuvlaEdgeStateCommissioned))
+	assert.True(t, canTransition(resources.NuvlaEdgeStateCommissioned, resources.NuvlaEdgeStateCommissioned))
+	assert.True(t, canTransition(resources.NuvlaEdgeStateNew, resources.NuvlaEdgeStateActivated))
+	assert.True(t, canTransition(resources.NuvlaEdgeStateActivated, resources.NuvlaEdgeStateCommissioned))
+	assert.True(t, canTransition(resources.NuvlaEdgeStateCommissioned, resources.NuvlaEdgeStateDecommissioning))
+	assert.True(t, canTransition(resources.NuvlaEdgeStateDecommissioning, resources.NuvlaEdgeStateDecommissioned))
+
+	assert.False(t, canTransition(resources.NuvlaEdgeStateCommissioned, resources.NuvlaEdgeStateNew))
+	assert.False(t, canTransition(resources.NuvlaEdgeStateCommissioned, resources.NuvlaEdgeStateActivated))
+	assert.False(t, canTransition(resources.NuvlaEdgeStateDecommissioning, resources.NuvlaEdgeStateCommissioned))
+	assert.False(t, canTransition(resources.NuvlaEdgeStateDecommissioned, resources.NuvlaEdgeStateCommissioned),
+		"decommissioned should be final")
+}
+
+func Test_lifecycle_Persistence(t *testing.T) {
+	d := NewTempDir()
+	defer RemoveTempDir()
+
+	l := newLifecycle(d, "nuvlabox/1")
+	assert.Empty(t, l.State())
+	assert.Nil(t, l.Last())
+
+	assert.NoError(t, l.Transition(resources.NuvlaEdgeStateActivated))
+	assert.NoError(t, l.Update(resources.NuvlaEdgeResource{State: resources.NuvlaEdgeStateCommissioned, RefreshInterval: 60}))
+	assert.ErrorIs(t, l.Transition(resources.NuvlaEdgeStateNew), errInvalidTransition)
+	assert.Equal(t, resources.NuvlaEdgeStateCommissioned, l.State())
+
+	reloaded := newLifecycle(d, "nuvlabox/1")
+	assert.Equal(t, resources.NuvlaEdgeStateCommissioned, reloaded.State())
+	assert.Equal(t, 60, reloaded.Last().RefreshInterval)
+
+	other := newLifecycle(d, "nuvlabox/2")
+	assert.Empty(t, other.State(), "the state of another NuvlaEdge should be ignored")
+}
+
+func Test_NuvlaEdge_updateLifecycle(t *testing.T) {
+	d := NewTempDir()
+	defer RemoveTempDir()
+
+	ne, _ := newNuvlaEdgeWithMocks(nil)
+	ne.lifecycle = newLifecycle(d, "nuvlabox/1")
+
+	assert.NoError(t, ne.updateLifecycle(resources.NuvlaEdgeResource{State: resources.NuvlaEdgeStateCommissioned}))
+	assert.ErrorIs(t, ne.updateLifecycle(resources.NuvlaEdgeResource{State: resources.NuvlaEdgeStateNew}), errStartUpState)
+	assert.ErrorIs(t, ne.updateLifecycle(resources.NuvlaEdgeResource{State: resources.NuvlaEdgeStateDecommissioning}), errDecommissioned)
+}
+
+func Test_NuvlaEdge_Decommission(t *testing.T) {
+	d := NewTempDir()
+	defer RemoveTempDir()
+
+	sessionFile := filepath.Join(d, constants.NuvlaEdgeSessionFile)
+	assert.NoError(t, os.WriteFile(sessionFile, []byte("{}"), 0600))
+
+	ne, stopped := newNuvlaEdgeWithMocks(shutdownOrder)
+	ne.conf.DBPPath = d
+	ne.conf.Irs = "irs"
+	ne.lifecycle = newLifecycle(d, "nuvlabox/1")
+	assert.NoError(t, ne.lifecycle.Transition(resources.NuvlaEdgeStateCommissioned))
+	dockerMock := &dockerClientMock{containers: []types.Container{
+		{ID: "deployment-container", Labels: map[string]string{constants.DeploymentLabel: "deployment/1"}},
+		{ID: "other-container", Labels: map[string]string{}},
+	}}
+	ne.dockerClient = dockerMock
+	assert.NoError(t, ne.startWorkers(localWorkers))
+
+	ne.onStateChange(resources.NuvlaEdgeStateDecommissioning)
+
+	assert.Len(t, *stopped, len(localWorkers))
+	assert.Equal(t, []string{"deployment-container"}, dockerMock.stopped)
+	assert.NoFileExists(t, sessionFile)
+	assert.Empty(t, ne.conf.Irs)
+	assert.Equal(t, resources.NuvlaEdgeStateDecommissioned, newLifecycle(d, "nuvlabox/1").State())
+	assert.NoError(t, ne.Run(context.Background()), "decommission should exit cleanly")
+
+	ne.decommission()
+	assert.Len(t, dockerMock.stopped, 1, "decommission should only run once")
+}
+
+func Test_NuvlaEdge_onRevocation(t *testing.T) {
+	d := NewTempDir()
+	defer RemoveTempDir()
+
+	sessionFile := filepath.Join(d, constants.NuvlaEdgeSessionFile)
+	assert.NoError(t, os.WriteFile(sessionFile, []byte("{}"), 0600))
+
+	ne, stopped := newNuvlaEdgeWithMocks(shutdownOrder)
+	ne.conf.DBPPath = d
+	assert.NoError(t, ne.startWorkers(localWorkers))
+
+	ne.onRevocation(assert.AnError)
+
+	assert.Len(t, *stopped, len(localWorkers))
+	assert.FileExists(t, sessionFile, "a revocation alone should not remove the credentials")
+	assert.ErrorIs(t, ne.Run(context.Background()), errCredentialsRevoked)
+}
+
+func Test_removeStacks(t *testing.T) {
+	stackService := func(id, namespace string, labels map[string]string) swarm.Service {
+		svc := swarm.Service{ID: id}
+		svc.Spec.Labels = map[string]string{"com.docker.stack.namespace": namespace}
+		for k, v := range labels {
+			svc.Spec.Labels[k] = v
+		}
+		return svc
+	}
+	services := []swarm.Service{
+		stackService("deployment-service", "1", map[string]string{constants.DeploymentLabel: "deployment/1"}),
+		stackService("other-service", "other", nil),
+	}
+
+	dockerMock := &dockerClientMock{services: services}
+	assert.NoError(t, removeStacks(context.Background(), dockerMock))
+	assert.Empty(t, dockerMock.removed, "only swarm managers should remove the stacks")
+
+	dockerMock = &dockerClientMock{services: services, manager: true}
+	assert.NoError(t, removeStacks(context.Background(), dockerMock))
+	assert.Equal(t, []string{"deployment-service"}, dockerMock.removed)
+}
+
+func Test_removeNamespaces(t *testing.T) {
+	api := testutils.NewFakeKubeAPI()
+	defer api.Close()
+	api.Set("/api/v1/namespaces", corev1.NamespaceList{Items: []corev1.Namespace{
+		{ObjectMeta: metav1.ObjectMeta{Name: "1", Labels: map[string]string{constants.DeploymentLabel: "1"}}},
+	}})
+	api.Set("/api/v1/namespaces/1", corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "1"}})
+	kCli, err := kubernetes.NewForConfig(api.Config())
+	assert.NoError(t, err)
+
+	assert.NoError(t, removeNamespaces(context.Background(), kCli))
+	_, ok := api.Get("/api/v1/namespaces/1")
+	assert.False(t, ok, "the namespace of the deployment should be removed")
+}
@@ -10,27 +10,42 @@ import (
 	"github.com/nuvla/api-client-go/clients/resources"
 	types2 "github.com/nuvla/api-client-go/types"
 	log "github.com/sirupsen/logrus"
+	"nuvlaedge-go/admin"
 	"nuvlaedge-go/common"
 	"nuvlaedge-go/common/constants"
+	"nuvlaedge-go/exporter"
+	"nuvlaedge-go/store"
 	"nuvlaedge-go/types"
 	"nuvlaedge-go/types/jobs"
 	"nuvlaedge-go/types/settings"
 	"nuvlaedge-go/types/worker"
 	"nuvlaedge-go/workers"
 	"path"
+	"path/filepath"
+	"slices"
+	"sync"
+	"sync/atomic"
 )
 
+// commissionerChSize holds the updates of the engine monitors while the commissioner is busy. The monitors drop them
+// once it is full, e.g. offline, where the commissioner is not running.
+const commissionerChSize = 4
+
 type Workers map[worker.WorkerType]worker.Worker
 
 type NuvlaEdge struct {
-	ctx  context.Context             // Parent context
-	conf *settings.NuvlaEdgeSettings // NuvlaEdge settings
+	ctx    context.Context             // Parent context
+	conf   *settings.NuvlaEdgeSettings // NuvlaEdge settings
+	confMu sync.RWMutex                // Guards the settings that can be reloaded at runtime
 
 	// Channels
-	commissionerCh   chan types.CommissionData // Connects Telemetry/EngineMonitor with Commissioner
-	jobCh            chan string               // Connects Agent and Telemetry with Job Processor
-	deploymentCh     chan jobs.Job             // Connects Job Processor with Deployment handler
-	confLastUpdateCh chan string               // Connects Heartbeat and Telemetry responses with Configuration handler
+	commissionerCh   chan types.CommissionData        // Connects Telemetry/EngineMonitor with Commissioner
+	jobCh            chan string                      // Connects Agent and Telemetry with Job Processor
+	deploymentCh     chan jobs.Job                    // Connects Job Processor with Deployment handler
+	confLastUpdateCh chan string                      // Connects Heartbeat and Telemetry responses with Configuration handler
+	settingsCh       chan *settings.NuvlaEdgeSettings // Connects settings reloads with Configuration handler
+	stateCh          chan resources.NuvlaEdgeState    // Connects Configuration handler with the lifecycle
+	revocationCh     chan error                       // Connects Heartbeat with the lifecycle
 
 	nuvla        *clients.NuvlaEdgeClient
 	dockerClient client.APIClient
@@ -39,6 +54,27 @@ type NuvlaEdge struct {
 	workerConf *worker.WorkerConfig
 
 	workers Workers
+
+	// offline is set while Nuvla is not reachable and only the local workers are running
+	offline atomic.Bool
+
+	lifecycle        *lifecycle
+	decommissionOnce sync.Once
+
+	// exitCh is closed when the NuvlaEdge stops by itself, e.g. on decommission. exitErr is then returned by Run
+	exitCh   chan struct{}
+	exitErr  error
+	exitOnce sync.Once
+
+	// Workers don't run on the parent context so that they can be stopped in order on shutdown
+	routinesMu sync.Mutex
+	routines   map[worker.WorkerType]*workerRoutine
+	stopping   bool
+	stopOnce   sync.Once
+	stopErr    error
+
+	adminServer   *admin.Server
+	metricsServer *exporter.Server
 }
 
 func NewNuvlaEdge(ctx context.Context, conf *settings.NuvlaEdgeSettings) (*NuvlaEdge, error) {
@@ -47,7 +83,7 @@ func NewNuvlaEdge(ctx context.Context, conf *settings.NuvlaEdgeSettings) (*Nuvla
 	if err != nil {
 		return nil, err
 	}
-	b, _ := json.MarshalIndent(conf, "", "  ")
+	b, _ := json.MarshalIndent(conf.Redacted(), "", "  ")
 	log.Infof("Starting NuvlaEdge with settings: %s", string(b))
 
 	// To add K8s, this will need to be converted into an interface
@@ -56,11 +92,20 @@ func NewNuvlaEdge(ctx context.Context, conf *settings.NuvlaEdgeSettings) (*Nuvla
 		return nil, err
 	}
 
+	st, err := store.Open(filepath.Join(conf.DBPPath, constants.StoreDir))
+	if err != nil {
+		// The NuvlaEdge can run without it, it just won't resume from its previous state after a restart
+		log.Errorf("Error opening local store: %s", err)
+	}
+
 	wConf := worker.NewDefaultWorkersConfig()
-	wConf.EnableJobLegacy = conf.EnableJobLegacySupport
-	wConf.LegacyJobImage = conf.JobEngineImage
-	wConf.CleanUpPeriod = conf.CleanUpPeriod
-	wConf.RemoveObjects = conf.Resources
+	wConf.UpdateFromSettings(conf)
+	// The configuration received from Nuvla prevails over the local settings, as it does while running
+	workers.RestoreRemoteConfig(st, wConf)
+
+	if conf.ShutdownTimeout <= 0 {
+		conf.ShutdownTimeout = constants.DefaultShutdownTimeout
+	}
 
 	ne := &NuvlaEdge{
 		ctx:          ctx,
@@ -70,10 +115,17 @@ func NewNuvlaEdge(ctx context.Context, conf *settings.NuvlaEdgeSettings) (*Nuvla
 		workerConf:   wConf,
 
 		// Channels
-		commissionerCh:   make(chan types.CommissionData),
+		commissionerCh:   make(chan types.CommissionData, commissionerChSize),
 		jobCh:            make(chan string),
 		deploymentCh:     make(chan jobs.Job),
 		confLastUpdateCh: make(chan string),
+		settingsCh:       make(chan *settings.NuvlaEdgeSettings),
+		stateCh:          make(chan resources.NuvlaEdgeState, 1),
+		revocationCh:     make(chan error, 1),
+
+		lifecycle: newLifecycle(conf.DBPPath, nuvla.NuvlaEdgeId.String()),
+		exitCh:    make(chan struct{}),
+		routines:  make(map[worker.WorkerType]*workerRoutine),
 	}
 
 	jobRegistry := jobs.NewRunningJobs()
@@ -84,7 +136,12 @@ func NewNuvlaEdge(ctx context.Context, conf *settings.NuvlaEdgeSettings) (*Nuvla
 		JobCh:            ne.jobCh,
 		DeploymentCh:     ne.deploymentCh,
 		ConfLastUpdateCh: ne.confLastUpdateCh,
+		SettingsCh:       ne.settingsCh,
+		StateCh:          ne.stateCh,
+		RevocationCh:     ne.revocationCh,
 		Jobs:             &jobRegistry,
+		WorkersStatus:    ne,
+		Store:            st,
 	}
 
 	ne.workers, err = WorkerGenerator(ne.workerOpts, ne.workerConf)
@@ -96,33 +153,55 @@ func NewNuvlaEdge(ctx context.Context, conf *settings.NuvlaEdgeSettings) (*Nuvla
 }
 
 func (ne *NuvlaEdge) Start(ctx context.Context) error {
+	switch ne.lifecycle.State() {
+	case resources.NuvlaEdgeStateDecommissioned:
+		return fmt.Errorf("%w: NuvlaEdge was decommissioned, remove %s to reuse the device",
+			errStartUpState, path.Join(ne.conf.DBPPath, constants.NuvlaEdgeResourceFile))
+	case resources.NuvlaEdgeStateDecommissioning:
+		log.Warn("NuvlaEdge was stopped while decommissioning, resuming decommission")
+		ne.decommission()
+		return nil
+	}
+
+	ne.startAdminServer()
+	ne.startMetricsServer()
 
 	// NuvlaEdge startup process...
+	err := ne.startUpProcess(ctx)
+	if err == nil {
+		go ne.watchLifecycle(ctx)
+		return ne.startWorkers(slices.Concat(localWorkers, remoteWorkers))
+	}
+
+	if errors.Is(err, errDecommissioned) {
+		ne.decommission()
+		return nil
+	}
 
-	if err := ne.startUpProcess(ctx); err != nil {
+	if !isRecoverableStartUpError(err) {
 		return err
 	}
 
-	if err := ne.startWorkers(); err != nil {
+	log.Warnf("Start up process failed, starting NuvlaEdge in offline mode: %s", err)
+	if err := ne.startOffline(); err != nil {
 		return err
 	}
 
+	go ne.watchLifecycle(ctx)
+	go ne.reconnect(ctx)
 	return nil
 }
 
+// Run blocks until the context is done and then gracefully shuts down the NuvlaEdge. It returns earlier if the
+// NuvlaEdge stops by itself, i.e. when decommissioned or when its credentials are revoked.
 func (ne *NuvlaEdge) Run(ctx context.Context) error {
-	<-ctx.Done()
-	return nil
-}
-
-func (ne *NuvlaEdge) startWorkers() error {
-	var errList []error
-	for _, w := range ne.workers {
-		if err := w.Start(ne.ctx); err != nil {
-			errList = append(errList, err)
-		}
+	select {
+	case <-ctx.Done():
+		log.Info("Termination signal received, shutting down NuvlaEdge...")
+		return ne.Stop()
+	case <-ne.exitCh:
+		return ne.exitErr
 	}
-	return errors.Join(errList...)
 }
 
 func (ne *NuvlaEdge) startUpProcess(ctx context.Context) error {
@@ -159,7 +238,7 @@ func (ne *NuvlaEdge) startUpProcess(ctx context.Context) error {
 		c, err = common.FromIrsV2(ne.conf.Irs, ne.nuvla.NuvlaEdgeId.String())
 
 		if err != nil {
-			return fmt.Errorf("error decrypting credentials: %s", err)
+			return fmt.Errorf("%w: error decrypting credentials: %s", errStartUpCredentials, err)
 		}
 
 	} else {
@@ -167,6 +246,9 @@ func (ne *NuvlaEdge) startUpProcess(ctx context.Context) error {
 	}
 
 	if err := ne.nuvla.LogIn(c); err != nil {
+		if common.IsUnauthorized(err) {
+			return fmt.Errorf("%w: %s", errStartUpCredentials, err)
+		}
 		return err
 	}
 
@@ -180,6 +262,12 @@ func (ne *NuvlaEdge) startUpProcess(ctx context.Context) error {
 	}
 
 	res := ne.nuvla.GetNuvlaEdgeResource()
+	if res.State == "" {
+		return errNuvlaUnreachable
+	}
+	if err := ne.updateLifecycle(res); err != nil {
+		return err
+	}
 
 	if res.State == resources.NuvlaEdgeStateActivated {
 		// Trigger commission once
@@ -196,9 +284,15 @@ func (ne *NuvlaEdge) startUpProcess(ctx context.Context) error {
 	}
 
 	res = ne.nuvla.GetNuvlaEdgeResource()
+	if res.State == "" {
+		return errNuvlaUnreachable
+	}
+	if err := ne.updateLifecycle(res); err != nil {
+		return err
+	}
 
 	if res.State != resources.NuvlaEdgeStateCommissioned {
-		return fmt.Errorf("can't start a NuvlaEDge from state: %s", res.State)
+		return fmt.Errorf("%w: can't start a NuvlaEDge from state: %s", errStartUpState, res.State)
 	}
 
 	log.Info("Start Up process completed, NuvlaEdge is ready")
@@ -1 +1,110 @@
 package nuvlaedge
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"nuvlaedge-go/admin"
+	"nuvlaedge-go/types/settings"
+	"nuvlaedge-go/types/worker"
+	"nuvlaedge-go/workers"
+	"sync"
+	"testing"
+)
+
+// workerMock records the order in which workers are stopped
+type workerMock struct {
+	worker.WorkerBase
+
+	mu      *sync.Mutex
+	stopped *[]worker.WorkerType
+	running bool
+}
+
+func (w *workerMock) Init(_ *worker.WorkerOpts, _ *worker.WorkerConfig) error { return nil }
+func (w *workerMock) Start(_ context.Context) error                           { return nil }
+func (w *workerMock) Reconfigure(_ *worker.WorkerConfig) error                { return nil }
+func (w *workerMock) Run(ctx context.Context) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+func (w *workerMock) Stop(_ context.Context) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	*w.stopped = append(*w.stopped, worker.WorkerType(w.GetName()))
+	return nil
+}
+
+func newNuvlaEdgeWithMocks(wTypes []worker.WorkerType) (*NuvlaEdge, *[]worker.WorkerType) {
+	mu := &sync.Mutex{}
+	stopped := make([]worker.WorkerType, 0)
+	ws := make(Workers)
+	for _, t := range wTypes {
+		ws[t] = &workerMock{WorkerBase: worker.NewWorkerBase(t), mu: mu, stopped: &stopped}
+	}
+	return &NuvlaEdge{
+		conf:      &settings.NuvlaEdgeSettings{ShutdownTimeout: 1},
+		workers:   ws,
+		routines:  make(map[worker.WorkerType]*workerRoutine),
+		lifecycle: &lifecycle{},
+		exitCh:    make(chan struct{}),
+	}, &stopped
+}
+
+func Test_NuvlaEdge_Stop_Order(t *testing.T) {
+	ne, stopped := newNuvlaEdgeWithMocks(shutdownOrder)
+
+	assert.NoError(t, ne.startWorkers([]worker.WorkerType{worker.JobProcessor, worker.Heartbeat, worker.Telemetry}))
+	assert.Len(t, ne.routines, 3)
+
+	assert.NoError(t, ne.Stop())
+	assert.Equal(t, []worker.WorkerType{worker.Telemetry, worker.Heartbeat, worker.JobProcessor}, *stopped)
+	for _, r := range ne.routines {
+		_, open := <-r.done
+		assert.False(t, open, "worker routine should be done after stop")
+	}
+}
+
+func Test_NuvlaEdge_StartWorkers_AfterStop(t *testing.T) {
+	ne, _ := newNuvlaEdgeWithMocks(localWorkers)
+	assert.NoError(t, ne.Stop())
+	assert.Error(t, ne.startWorkers(localWorkers), "workers should not start while shutting down")
+	assert.Empty(t, ne.routines)
+}
+
+func Test_NuvlaEdge_TriggerWorker(t *testing.T) {
+	ne, _ := newNuvlaEdgeWithMocks([]worker.WorkerType{worker.JobProcessor})
+	hb := &workers.Heartbeat{}
+	assert.NoError(t, hb.Init(&worker.WorkerOpts{}, &worker.WorkerConfig{HeartBeatPeriod: 60}))
+	ne.workers[worker.Heartbeat] = hb
+
+	assert.ErrorIs(t, ne.TriggerWorker(worker.Telemetry), admin.ErrWorkerNotFound)
+	assert.ErrorIs(t, ne.TriggerWorker(worker.Heartbeat), admin.ErrWorkerNotRunning)
+
+	ne.routines[worker.Heartbeat] = newWorkerRoutine(func() {})
+	ne.routines[worker.JobProcessor] = newWorkerRoutine(func() {})
+	assert.ErrorIs(t, ne.TriggerWorker(worker.JobProcessor), admin.ErrNotTriggerable)
+	assert.NoError(t, ne.TriggerWorker(worker.Heartbeat))
+	assert.Len(t, hb.TriggerCh, 1)
+	assert.NoError(t, ne.TriggerWorker(worker.Heartbeat), "pending trigger should not fail")
+}
+
+func Test_NuvlaEdge_WorkersInfo(t *testing.T) {
+	ne, _ := newNuvlaEdgeWithMocks([]worker.WorkerType{worker.JobProcessor})
+	hb := &workers.Heartbeat{}
+	assert.NoError(t, hb.Init(&worker.WorkerOpts{}, &worker.WorkerConfig{HeartBeatPeriod: 60}))
+	hb.MarkRun()
+	ne.workers[worker.Heartbeat] = hb
+	ne.routines[worker.Heartbeat] = newWorkerRoutine(func() {})
+
+	info := ne.WorkersInfo()
+	assert.Len(t, info, 2)
+	assert.Equal(t, string(worker.Heartbeat), info[0].Name)
+	assert.Equal(t, 60, info[0].Period)
+	assert.NotNil(t, info[0].LastRun)
+	assert.Equal(t, worker.WorkerStarting, info[0].Status.State)
+
+	assert.Equal(t, string(worker.JobProcessor), info[1].Name)
+	assert.Equal(t, 0, info[1].Period)
+	assert.Nil(t, info[1].LastRun)
+	assert.Equal(t, worker.WorkerStopped, info[1].Status.State)
+}
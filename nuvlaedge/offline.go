@@ -0,0 +1,136 @@
+package nuvlaedge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/nuvla/api-client-go/clients/resources"
+	log "github.com/sirupsen/logrus"
+	"nuvlaedge-go/common"
+	"nuvlaedge-go/common/constants"
+	"nuvlaedge-go/store"
+	"nuvlaedge-go/types/worker"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+var (
+	// errStartUpState is returned when the remote NuvlaEdge state does not allow the agent to start. Retrying won't help.
+	errStartUpState = errors.New("invalid NuvlaEdge state")
+	// errStartUpCredentials is returned when the local credentials cannot be decoded. Retrying won't help either.
+	errStartUpCredentials = errors.New("invalid NuvlaEdge credentials")
+	// errNuvlaUnreachable is returned when Nuvla didn't provide the NuvlaEdge resource
+	errNuvlaUnreachable = errors.New("NuvlaEdge resource not available from Nuvla")
+)
+
+// isRecoverableStartUpError asserts whether the NuvlaEdge can run in offline mode and retry the start-up process
+// later on. Anything but an invalid state, invalid credentials or a decommission is considered a connectivity issue.
+func isRecoverableStartUpError(err error) bool {
+	return !errors.Is(err, errStartUpState) && !errors.Is(err, errStartUpCredentials) && !errors.Is(err, errDecommissioned)
+}
+
+// saveResourceState persists the last known NuvlaEdge resource to the database path
+func saveResourceState(dbPath string, res resources.NuvlaEdgeResource) error {
+	b, err := json.MarshalIndent(res, "", "  ")
+	if err != nil {
+		return err
+	}
+	return store.WriteFile(filepath.Join(dbPath, constants.NuvlaEdgeResourceFile), b, 0600)
+}
+
+// loadResourceState reads the last known NuvlaEdge resource from the database path
+func loadResourceState(dbPath string) (*resources.NuvlaEdgeResource, error) {
+	b, err := os.ReadFile(filepath.Join(dbPath, constants.NuvlaEdgeResourceFile))
+	if err != nil {
+		return nil, err
+	}
+
+	res := &resources.NuvlaEdgeResource{}
+	if err := json.Unmarshal(b, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// canRunOffline asserts, from the last known state, whether the NuvlaEdge is allowed to start in offline mode.
+// If there is no known state, the NuvlaEdge is assumed to be operative.
+func canRunOffline(res *resources.NuvlaEdgeResource) bool {
+	if res == nil {
+		return true
+	}
+	return res.State != resources.NuvlaEdgeStateDecommissioning && res.State != resources.NuvlaEdgeStateDecommissioned
+}
+
+// IsOffline returns true while the NuvlaEdge runs without connection to Nuvla
+func (ne *NuvlaEdge) IsOffline() bool {
+	return ne.offline.Load()
+}
+
+// startOffline starts the workers that do not depend on Nuvla
+func (ne *NuvlaEdge) startOffline() error {
+	last := ne.lifecycle.Last()
+	if last == nil {
+		log.Warn("No last known NuvlaEdge state available")
+	} else {
+		log.Infof("Last known NuvlaEdge state: %s", last.State)
+	}
+
+	if !canRunOffline(last) {
+		return fmt.Errorf("%w: cannot run in offline mode from state %s", errStartUpState, last.State)
+	}
+
+	ne.offline.Store(true)
+	return ne.startWorkers(localWorkers)
+}
+
+// reconnect retries the start-up process with exponential backoff until Nuvla is reachable. Then, it starts the
+// remaining workers and switches the NuvlaEdge to full operation.
+func (ne *NuvlaEdge) reconnect(ctx context.Context) {
+	b := common.NewBackoff(constants.ReconnectInitialDelay*time.Second, constants.ReconnectMaxDelay*time.Second)
+
+	for {
+		wait := b.Next()
+		log.Infof("NuvlaEdge offline, retrying connection to Nuvla in %s (attempt %d)", wait.Round(time.Second), b.Attempts())
+
+		select {
+		case <-ctx.Done():
+			log.Info("Context done, stop reconnecting to Nuvla")
+			return
+		case <-time.After(wait):
+		}
+
+		err := ne.startUpProcess(ctx)
+		if err == nil {
+			break
+		}
+
+		if errors.Is(err, errDecommissioned) {
+			ne.decommission()
+			return
+		}
+		if common.IsUnauthorized(err) {
+			ne.onRevocation(err)
+			return
+		}
+		if !isRecoverableStartUpError(err) {
+			// Retrying won't help, exit so that the service manager restarts the NuvlaEdge
+			log.Errorf("Cannot switch NuvlaEdge to online mode, stopping: %s", err)
+			ne.exit(errors.Join(err, ne.Stop()))
+			return
+		}
+		log.Warnf("Nuvla still not reachable: %s", err)
+	}
+
+	log.Info("Connection to Nuvla recovered, switching NuvlaEdge to online mode")
+	ne.offline.Store(false)
+	if err := ne.startWorkers(remoteWorkers); err != nil {
+		log.Errorf("Error starting online workers: %s", err)
+	}
+
+	// Jobs created while offline never reached the NuvlaEdge through the heartbeat or telemetry responses
+	if err := ne.TriggerWorker(worker.JobProcessor); err != nil {
+		log.Warnf("Cannot search jobs queued while offline: %s", err)
+	}
+}
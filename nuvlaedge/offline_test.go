@@ -0,0 +1,45 @@
+package nuvlaedge
+
+import (
+	"errors"
+	"fmt"
+	"github.com/nuvla/api-client-go/clients/resources"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_isRecoverableStartUpError(t *testing.T) {
+	assert.True(t, isRecoverableStartUpError(errors.New("connection refused")))
+	assert.True(t, isRecoverableStartUpError(errNuvlaUnreachable))
+	assert.False(t, isRecoverableStartUpError(fmt.Errorf("%w: DECOMMISSIONED", errStartUpState)))
+	assert.False(t, isRecoverableStartUpError(fmt.Errorf("%w: bad irs", errStartUpCredentials)))
+	assert.False(t, isRecoverableStartUpError(fmt.Errorf("%w: state DECOMMISSIONED", errDecommissioned)))
+}
+
+func Test_ResourceState_SaveLoad(t *testing.T) {
+	d := NewTempDir()
+	defer RemoveTempDir()
+
+	_, err := loadResourceState(d)
+	assert.Error(t, err, "loading a non existing state should fail")
+
+	res := resources.NuvlaEdgeResource{
+		State:             resources.NuvlaEdgeStateCommissioned,
+		RefreshInterval:   60,
+		HeartbeatInterval: 20,
+	}
+	assert.NoError(t, saveResourceState(d, res))
+
+	loaded, err := loadResourceState(d)
+	assert.NoError(t, err)
+	assert.Equal(t, res.State, loaded.State)
+	assert.Equal(t, res.RefreshInterval, loaded.RefreshInterval)
+	assert.Equal(t, res.HeartbeatInterval, loaded.HeartbeatInterval)
+}
+
+func Test_canRunOffline(t *testing.T) {
+	assert.True(t, canRunOffline(nil), "unknown state should allow offline mode")
+	assert.True(t, canRunOffline(&resources.NuvlaEdgeResource{State: resources.NuvlaEdgeStateCommissioned}))
+	assert.False(t, canRunOffline(&resources.NuvlaEdgeResource{State: resources.NuvlaEdgeStateDecommissioned}))
+	assert.False(t, canRunOffline(&resources.NuvlaEdgeResource{State: resources.NuvlaEdgeStateDecommissioning}))
+}
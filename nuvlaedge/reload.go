@@ -0,0 +1,71 @@
+package nuvlaedge
+
+import (
+	"errors"
+	log "github.com/sirupsen/logrus"
+	"nuvlaedge-go/common"
+	"nuvlaedge-go/common/constants"
+	"nuvlaedge-go/types/settings"
+	"time"
+)
+
+// restartRequired returns the keys of the settings that changed but can only be applied by restarting the NuvlaEdge
+func restartRequired(current, reloaded *settings.NuvlaEdgeSettings) []string {
+	var keys []string
+	check := func(key string, changed bool) {
+		if changed {
+			keys = append(keys, key)
+		}
+	}
+
+	check("db-path", current.DBPPath != reloaded.DBPPath)
+	check("nuvla-endpoint", current.NuvlaEndpoint != reloaded.NuvlaEndpoint)
+	check("nuvla-insecure", current.NuvlaInsecure != reloaded.NuvlaInsecure)
+	check("nuvlaedge-uuid", reloaded.NuvlaEdgeUUID != "" &&
+		SanitiseUUID(current.NuvlaEdgeUUID, "nuvlabox") != SanitiseUUID(reloaded.NuvlaEdgeUUID, "nuvlabox"))
+	check("admin-socket", current.AdminSocket != reloaded.AdminSocket)
+	check("metrics-address", current.MetricsAddress != reloaded.MetricsAddress)
+	check("vpn-enabled", current.VpnEnabled != reloaded.VpnEnabled)
+	check("vpn-extra-config", current.VpnExtraConfig != reloaded.VpnExtraConfig)
+	return keys
+}
+
+// Reload applies the non-identity settings to the running NuvlaEdge: log level, periods, clean up resources and
+// job engine configuration. The worker configuration is pushed to the workers through the ConfUpdater. Changes to
+// any other setting are ignored until the next restart.
+func (ne *NuvlaEdge) Reload(s *settings.NuvlaEdgeSettings) error {
+	if keys := restartRequired(ne.conf, s); len(keys) > 0 {
+		log.Warnf("Settings %v changed but require a restart to be applied", keys)
+	}
+
+	if s.LogLevel != ne.conf.LogLevel || s.Debug != ne.conf.Debug {
+		log.Infof("Setting log level to %s (debug: %t)", s.LogLevel, s.Debug)
+		common.InitLogging(s.LogLevel, s.Debug)
+	}
+
+	ne.confMu.Lock()
+	ne.conf.LogLevel = s.LogLevel
+	ne.conf.Debug = s.Debug
+	ne.conf.HeartbeatPeriod = s.HeartbeatPeriod
+	ne.conf.TelemetryPeriod = s.TelemetryPeriod
+	ne.conf.RemoteSyncPeriod = s.RemoteSyncPeriod
+	ne.conf.CleanUpPeriod = s.CleanUpPeriod
+	ne.conf.Resources = s.Resources
+	ne.conf.JobEngineImage = s.JobEngineImage
+	ne.conf.EnableJobLegacySupport = s.EnableJobLegacySupport
+	ne.conf.JobPoolSize = s.JobPoolSize
+	ne.conf.JobTimeouts = s.JobTimeouts
+	ne.conf.RebootHooks = s.RebootHooks
+	if s.ShutdownTimeout > 0 {
+		ne.conf.ShutdownTimeout = s.ShutdownTimeout
+	}
+	ne.confMu.Unlock()
+
+	select {
+	case ne.settingsCh <- s:
+		log.Info("NuvlaEdge settings reloaded")
+		return nil
+	case <-time.After(constants.SettingsReloadTimeout * time.Second):
+		return errors.New("timeout pushing the reloaded settings to the workers")
+	}
+}
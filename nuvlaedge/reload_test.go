@@ -0,0 +1,52 @@
+package nuvlaedge
+
+import (
+	"github.com/stretchr/testify/assert"
+	"nuvlaedge-go/types/settings"
+	"testing"
+)
+
+func Test_restartRequired(t *testing.T) {
+	current := &settings.NuvlaEdgeSettings{NuvlaEndpoint: "https://nuvla.io", NuvlaEdgeUUID: "nuvlabox/1234", LogLevel: "info"}
+
+	reloaded := *current
+	reloaded.LogLevel = "debug"
+	reloaded.HeartbeatPeriod = 10
+	assert.Empty(t, restartRequired(current, &reloaded))
+
+	reloaded.NuvlaEdgeUUID = "1234"
+	assert.Empty(t, restartRequired(current, &reloaded), "same UUID in short form doesn't require restart")
+
+	reloaded.NuvlaEndpoint = "https://other.nuvla.io"
+	reloaded.NuvlaEdgeUUID = "nuvlabox/5678"
+	assert.Equal(t, []string{"nuvla-endpoint", "nuvlaedge-uuid"}, restartRequired(current, &reloaded))
+}
+
+func Test_NuvlaEdge_Reload(t *testing.T) {
+	ne := &NuvlaEdge{
+		conf: &settings.NuvlaEdgeSettings{
+			NuvlaEndpoint:   "https://nuvla.io",
+			LogLevel:        "panic",
+			HeartbeatPeriod: 20,
+			ShutdownTimeout: 120,
+			Resources:       []string{"images"},
+		},
+		settingsCh: make(chan *settings.NuvlaEdgeSettings, 1),
+	}
+
+	reloaded := &settings.NuvlaEdgeSettings{
+		NuvlaEndpoint:   "https://other.nuvla.io",
+		LogLevel:        "panic",
+		HeartbeatPeriod: 10,
+		Resources:       []string{"images", "volumes"},
+		JobEngineImage:  "nuvladev/nuvlaedge:main",
+	}
+	assert.NoError(t, ne.Reload(reloaded))
+
+	assert.Equal(t, 10, ne.conf.HeartbeatPeriod)
+	assert.Equal(t, []string{"images", "volumes"}, ne.conf.Resources)
+	assert.Equal(t, "nuvladev/nuvlaedge:main", ne.conf.JobEngineImage)
+	assert.Equal(t, 120, ne.conf.ShutdownTimeout, "undefined shutdown timeout should keep the current one")
+	assert.Equal(t, "https://nuvla.io", ne.conf.NuvlaEndpoint, "identity settings should not be reloaded")
+	assert.Equal(t, reloaded, <-ne.settingsCh, "reloaded settings should be pushed to the workers")
+}
@@ -0,0 +1,128 @@
+package nuvlaedge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"nuvlaedge-go/common"
+	"nuvlaedge-go/common/constants"
+	"nuvlaedge-go/types/worker"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// newRestartBackoff provides the delays between restarts of a crashed worker
+var newRestartBackoff = func() *common.Backoff {
+	return common.NewBackoff(constants.WorkerRestartInitialDelay*time.Second, constants.WorkerRestartMaxDelay*time.Second)
+}
+
+// workerRoutine keeps track of the supervised Run loop of a started worker
+type workerRoutine struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mu     sync.Mutex
+	status worker.WorkerStatus
+}
+
+func newWorkerRoutine(cancel context.CancelFunc) *workerRoutine {
+	return &workerRoutine{
+		cancel: cancel,
+		done:   make(chan struct{}),
+		status: worker.WorkerStatus{State: worker.WorkerStarting, Since: time.Now()},
+	}
+}
+
+func (r *workerRoutine) setState(state worker.WorkerState) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.status.State = state
+	r.status.Since = time.Now()
+}
+
+func (r *workerRoutine) setFailed(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.status.State = worker.WorkerFailed
+	r.status.Since = time.Now()
+	r.status.LastError = err.Error()
+}
+
+func (r *workerRoutine) setRestarting() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.status.State = worker.WorkerRestarting
+	r.status.Since = time.Now()
+	r.status.Restarts++
+}
+
+func (r *workerRoutine) getStatus() worker.WorkerStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.status
+}
+
+// safeRun runs the worker loop and converts a panic into an error
+func safeRun(ctx context.Context, w worker.Worker) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			log.Debugf("Worker %s panic stack trace: %s", w.GetName(), debug.Stack())
+			err = fmt.Errorf("panic: %v", p)
+		}
+	}()
+
+	if err = w.Run(ctx); err == nil && ctx.Err() == nil {
+		err = errors.New("run loop exited unexpectedly")
+	}
+	return err
+}
+
+// supervise runs the worker until the context is cancelled. Crashed workers are restarted with exponential backoff,
+// which is reset once the worker has been running for WorkerStableRunTime.
+func (ne *NuvlaEdge) supervise(ctx context.Context, t worker.WorkerType, w worker.Worker, r *workerRoutine) {
+	defer close(r.done)
+	defer r.setState(worker.WorkerStopped)
+
+	b := newRestartBackoff()
+	for {
+		r.setState(worker.WorkerRunning)
+		started := time.Now()
+
+		err := safeRun(ctx, w)
+		if ctx.Err() != nil {
+			return
+		}
+
+		if time.Since(started) > constants.WorkerStableRunTime*time.Second {
+			b.Reset()
+		}
+		r.setFailed(err)
+		wait := b.Next()
+		log.Errorf("Worker %s crashed, restarting in %s: %s", t, wait.Round(time.Millisecond), err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+
+		r.setRestarting()
+		log.Infof("Restarting worker %s (attempt %d)", t, b.Attempts())
+	}
+}
+
+// WorkersStatus returns the status of the started workers
+func (ne *NuvlaEdge) WorkersStatus() map[worker.WorkerType]worker.WorkerStatus {
+	ne.routinesMu.Lock()
+	defer ne.routinesMu.Unlock()
+
+	status := make(map[worker.WorkerType]worker.WorkerStatus, len(ne.routines))
+	for t, r := range ne.routines {
+		status[t] = r.getStatus()
+	}
+	return status
+}
+
+var _ worker.StatusProvider = &NuvlaEdge{}
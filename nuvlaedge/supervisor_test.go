@@ -0,0 +1,102 @@
+package nuvlaedge
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"nuvlaedge-go/common"
+	"nuvlaedge-go/types/settings"
+	"nuvlaedge-go/types/worker"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// crashingWorker fails its first runs, either returning an error or panicking, and then runs until cancelled
+type crashingWorker struct {
+	worker.WorkerBase
+
+	crashes int32
+	panics  bool
+	runs    atomic.Int32
+}
+
+func (w *crashingWorker) Init(_ *worker.WorkerOpts, _ *worker.WorkerConfig) error { return nil }
+func (w *crashingWorker) Start(_ context.Context) error                           { return nil }
+func (w *crashingWorker) Reconfigure(_ *worker.WorkerConfig) error                { return nil }
+func (w *crashingWorker) Stop(_ context.Context) error                            { return nil }
+func (w *crashingWorker) Run(ctx context.Context) error {
+	if w.runs.Add(1) <= w.crashes {
+		if w.panics {
+			panic("worker panic")
+		}
+		return errors.New("worker error")
+	}
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func init() {
+	newRestartBackoff = func() *common.Backoff {
+		b := common.NewBackoff(10*time.Millisecond, 50*time.Millisecond)
+		b.Jitter = 0
+		return b
+	}
+}
+
+func newSupervisedNuvlaEdge(w worker.Worker) *NuvlaEdge {
+	return &NuvlaEdge{
+		conf:     &settings.NuvlaEdgeSettings{ShutdownTimeout: 1},
+		workers:  Workers{worker.WorkerType(w.GetName()): w},
+		routines: make(map[worker.WorkerType]*workerRoutine),
+	}
+}
+
+func Test_Supervisor_RestartsCrashedWorker(t *testing.T) {
+	tests := map[string]bool{"error": false, "panic": true}
+	for name, panics := range tests {
+		t.Run(name, func(t *testing.T) {
+			w := &crashingWorker{WorkerBase: worker.NewWorkerBase(worker.Heartbeat), crashes: 2, panics: panics}
+			ne := newSupervisedNuvlaEdge(w)
+
+			assert.NoError(t, ne.startWorkers([]worker.WorkerType{worker.Heartbeat}))
+			assert.Eventually(t, func() bool {
+				return w.runs.Load() == 3 && ne.WorkersStatus()[worker.Heartbeat].State == worker.WorkerRunning
+			}, time.Second, 5*time.Millisecond)
+
+			s := ne.WorkersStatus()[worker.Heartbeat]
+			assert.Equal(t, 2, s.Restarts)
+			assert.True(t, s.Healthy())
+			assert.Contains(t, s.LastError, "worker")
+
+			assert.NoError(t, ne.Stop())
+			assert.Equal(t, worker.WorkerStopped, ne.WorkersStatus()[worker.Heartbeat].State)
+		})
+	}
+}
+
+func Test_Supervisor_FailedWorkerState(t *testing.T) {
+	newRestartBackoffOrig := newRestartBackoff
+	defer func() { newRestartBackoff = newRestartBackoffOrig }()
+	newRestartBackoff = func() *common.Backoff { return common.NewBackoff(time.Hour, time.Hour) }
+
+	w := &crashingWorker{WorkerBase: worker.NewWorkerBase(worker.Telemetry), crashes: 1}
+	ne := newSupervisedNuvlaEdge(w)
+
+	assert.NoError(t, ne.startWorkers([]worker.WorkerType{worker.Telemetry}))
+	assert.Eventually(t, func() bool {
+		return ne.WorkersStatus()[worker.Telemetry].State == worker.WorkerFailed
+	}, time.Second, 5*time.Millisecond)
+	assert.Equal(t, "worker error", ne.WorkersStatus()[worker.Telemetry].LastError)
+
+	// A worker waiting to be restarted must not block the shutdown
+	assert.NoError(t, ne.Stop())
+	assert.Equal(t, int32(1), w.runs.Load())
+}
+
+func Test_safeRun_Cancelled(t *testing.T) {
+	w := &workerMock{WorkerBase: worker.NewWorkerBase(worker.Heartbeat)}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, safeRun(ctx, w), context.Canceled)
+}
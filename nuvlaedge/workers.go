@@ -1,12 +1,15 @@
 package nuvlaedge
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	log "github.com/sirupsen/logrus"
 	"nuvlaedge-go/types/worker"
 	"nuvlaedge-go/workers"
 	"nuvlaedge-go/workers/job_processor"
 	"nuvlaedge-go/workers/telemetry"
+	"time"
 )
 
 // A worker is a module of NuvlaEdge that executes a periodic task (or periodically triggered task).
@@ -34,6 +37,31 @@ import (
 // - Deployment
 //.  	- JobProcessor
 
+// localWorkers don't need Nuvla to do their job and are started even if the NuvlaEdge is offline
+var localWorkers = []worker.WorkerType{
+	worker.Telemetry,
+	worker.ResourceCleaner,
+	worker.JobProcessor,
+	worker.ConfUpdater,
+}
+
+// remoteWorkers require a connection to Nuvla and are only started once the start-up process succeeds
+var remoteWorkers = []worker.WorkerType{
+	worker.Heartbeat,
+	worker.Commissioner,
+}
+
+// shutdownOrder defines the order in which the workers are stopped. The job producers go first so that no new jobs
+// reach the job processor, which is stopped last to let the running jobs finish.
+var shutdownOrder = []worker.WorkerType{
+	worker.Telemetry,
+	worker.Heartbeat,
+	worker.ResourceCleaner,
+	worker.Commissioner,
+	worker.ConfUpdater,
+	worker.JobProcessor,
+}
+
 func generateWorkers() Workers {
 	return Workers{
 		// Timed
@@ -76,3 +104,99 @@ func WorkerGenerator(opts *worker.WorkerOpts, conf *worker.WorkerConfig) (Worker
 	}
 	return workerMap, errors.Join(errList...)
 }
+
+// startWorkers starts the given workers and runs them in their own supervised routine. Workers already running are
+// skipped.
+func (ne *NuvlaEdge) startWorkers(wTypes []worker.WorkerType) error {
+	ne.routinesMu.Lock()
+	defer ne.routinesMu.Unlock()
+
+	if ne.stopping {
+		return errors.New("NuvlaEdge is shutting down, cannot start workers")
+	}
+
+	var errList []error
+	for _, t := range wTypes {
+		w, ok := ne.workers[t]
+		if !ok {
+			continue
+		}
+		if _, running := ne.routines[t]; running {
+			continue
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		if err := w.Start(ctx); err != nil {
+			cancel()
+			errList = append(errList, err)
+			continue
+		}
+
+		r := newWorkerRoutine(cancel)
+		ne.routines[t] = r
+		go ne.supervise(ctx, t, w, r)
+	}
+	return errors.Join(errList...)
+}
+
+// Stop gracefully shuts down the NuvlaEdge following the shutdownOrder. The job processor is given until the shutdown
+// timeout to drain the running jobs. It only returns once every worker has confirmed it stopped. Later calls return
+// the result of the first one.
+func (ne *NuvlaEdge) Stop() error {
+	ne.stopOnce.Do(func() {
+		ne.stopErr = ne.stop()
+	})
+	return ne.stopErr
+}
+
+func (ne *NuvlaEdge) stop() error {
+	ne.routinesMu.Lock()
+	ne.stopping = true
+	ne.routinesMu.Unlock()
+
+	ne.confMu.RLock()
+	timeout := time.Duration(ne.conf.ShutdownTimeout) * time.Second
+	ne.confMu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var errList []error
+	for _, t := range shutdownOrder {
+		if err := ne.stopWorker(ctx, t); err != nil {
+			log.Errorf("Error stopping worker %s: %s", t, err)
+			errList = append(errList, err)
+		}
+	}
+
+	if err := ne.stopMetricsServer(); err != nil {
+		log.Errorf("Error stopping metrics exporter: %s", err)
+		errList = append(errList, err)
+	}
+	if err := ne.stopAdminServer(); err != nil {
+		log.Errorf("Error stopping admin API: %s", err)
+		errList = append(errList, err)
+	}
+
+	log.Info("NuvlaEdge stopped")
+	return errors.Join(errList...)
+}
+
+func (ne *NuvlaEdge) stopWorker(ctx context.Context, t worker.WorkerType) error {
+	ne.routinesMu.Lock()
+	r, ok := ne.routines[t]
+	ne.routinesMu.Unlock()
+	if !ok {
+		return nil
+	}
+
+	log.Infof("Stopping worker %s", t)
+	r.cancel()
+	<-r.done
+
+	if err := ne.workers[t].Stop(ctx); err != nil {
+		return fmt.Errorf("worker %s didn't stop cleanly: %w", t, err)
+	}
+	log.Infof("Worker %s stopped", t)
+	return nil
+}
@@ -51,13 +51,14 @@ func NewComposeOrchestrator(dClient client.APIClient) (*Compose, error) {
 
 func (c *Compose) Start(ctx context.Context, opts *types.StartOpts) error {
 	// Validate opts here for Compose...
-	return c.start(ctx, opts.CFiles, opts.Env, opts.ProjectName, opts.WorkingDir)
+	return c.start(ctx, opts.CFiles, opts.Env, opts.ProjectName, opts.WorkingDir, !opts.NoPull)
 }
 
 func (c *Compose) start(ctx context.Context, files []string,
 	env []string,
 	projectName string,
-	workingDir string) error {
+	workingDir string,
+	pull bool) error {
 
 	pOptions, err := cli.NewProjectOptions(
 		files,
@@ -90,8 +91,10 @@ func (c *Compose) start(ctx context.Context, files []string,
 		project.Services[i] = s
 	}
 
-	if err := c.service.Pull(ctx, project, api.PullOptions{}); err != nil {
-		return err
+	if pull {
+		if err := c.service.Pull(ctx, project, api.PullOptions{}); err != nil {
+			return err
+		}
 	}
 
 	if err = c.service.Up(ctx, project, api.UpOptions{}); err != nil {
@@ -42,6 +42,9 @@ func Test_Compose_Start(t *testing.T) {
 	assert.NotNil(t, err, "Error should not be nil")
 	assert.Contains(t, err.Error(), "pull error", "Error should contain pull error")
 
+	err = co.Start(ctx, &types.StartOpts{NoPull: true})
+	assert.Nil(t, err, "Images should not be pulled")
+
 	mockService.PullErr = nil
 	mockService.UpErr = errors.New("up error")
 	err = co.Start(ctx, startOpts)
@@ -0,0 +1,286 @@
+package store
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// SchemaVersion is the version of the records written by this agent. Records written with an older version are
+// migrated when read.
+const SchemaVersion = 1
+
+// Keys of the records kept by the NuvlaEdge
+const (
+	JobsKey      = "jobs"      // Jobs in-flight, recorded by the job processor
+	TelemetryKey = "telemetry" // Last status sent to Nuvla, recorded by telemetry
+	ConfigKey    = "config"    // Last configuration received from Nuvla, recorded by the conf updater
+
+	StoppedContainersKey = "stopped-containers" // Deployment containers stopped before a reboot, recorded by the executors
+
+	DeploymentKeyPrefix = "deployment-" // Version of a deployment running, by deployment UUID, recorded by the executors
+)
+
+var (
+	// ErrNotFound is returned when there is no valid record for a key
+	ErrNotFound = errors.New("record not found")
+	// ErrNewerSchema is returned when a record was written by a newer agent, i.e. after a downgrade
+	ErrNewerSchema = errors.New("record written with a newer schema version")
+)
+
+// migrations upgrade the data of a record from the version they are indexed by to the next one
+var migrations = map[int]func(key string, data json.RawMessage) (json.RawMessage, error){}
+
+// record is the envelope of every value written to the store
+type record struct {
+	Version   int             `json:"version"`
+	UpdatedAt time.Time       `json:"updated"`
+	Checksum  string          `json:"checksum"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// Store is a crash-safe key-value store of JSON records, one file per key. Records are replaced atomically and the
+// previous version is kept as backup to recover from corrupted files. A nil Store doesn't persist anything.
+type Store struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// Open creates the store directory if needed and returns the store
+func Open(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("error creating store directory %s: %w", dir, err)
+	}
+	return &Store{dir: dir}, nil
+}
+
+func (s *Store) path(key string) string {
+	return filepath.Join(s.dir, key+".json")
+}
+
+// Put stores the JSON representation of v under the given key
+func (s *Store) Put(key string, v any) error {
+	if s == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("error encoding record %s: %w", key, err)
+	}
+	b, err := json.MarshalIndent(record{
+		Version:   SchemaVersion,
+		UpdatedAt: time.Now().UTC(),
+		Checksum:  checksum(data),
+		Data:      data,
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding record %s: %w", key, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p := s.path(key)
+	if _, err := os.Stat(p); err == nil {
+		// Keep the current record as backup in case the new one gets corrupted
+		if err := copyFile(p, p+".bak"); err != nil {
+			log.Warnf("Error backing up record %s: %s", key, err)
+		}
+	}
+	return WriteFile(p, b, 0600)
+}
+
+// Get decodes the record stored under the given key into v. If the record is corrupted, it is quarantined and the
+// backup is used instead. ErrNotFound is returned if there is no valid record.
+func (s *Store) Get(key string, v any) error {
+	if s == nil {
+		return ErrNotFound
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p := s.path(key)
+	data, err := readRecord(key, p)
+	if err == nil {
+		return decode(key, data, v)
+	}
+	if errors.Is(err, ErrNewerSchema) {
+		return err
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		log.Warnf("Record %s is corrupted, recovering from backup: %s", key, err)
+		quarantine(p)
+	}
+
+	data, bakErr := readRecord(key, p+".bak")
+	if bakErr != nil {
+		if !errors.Is(bakErr, os.ErrNotExist) {
+			log.Warnf("Backup of record %s is not usable: %s", key, bakErr)
+			quarantine(p + ".bak")
+		}
+		return fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+
+	if err := copyFile(p+".bak", p); err != nil {
+		log.Warnf("Error restoring record %s from backup: %s", key, err)
+	}
+	return decode(key, data, v)
+}
+
+// Delete removes the record and its backup
+func (s *Store) Delete(key string) error {
+	if s == nil {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p := s.path(key)
+	var errList []error
+	for _, f := range []string{p, p + ".bak"} {
+		if err := os.Remove(f); err != nil && !errors.Is(err, os.ErrNotExist) {
+			errList = append(errList, err)
+		}
+	}
+	return errors.Join(errList...)
+}
+
+// Clear removes every record from the store
+func (s *Store) Clear() error {
+	if s == nil {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return err
+	}
+	var errList []error
+	for _, e := range entries {
+		if err := os.RemoveAll(filepath.Join(s.dir, e.Name())); err != nil {
+			errList = append(errList, err)
+		}
+	}
+	return errors.Join(errList...)
+}
+
+// readRecord reads and validates a record file, migrating its data to the current schema version
+func readRecord(key, p string) (json.RawMessage, error) {
+	b, err := os.ReadFile(p)
+	if err != nil {
+		return nil, err
+	}
+
+	var r record
+	if err := json.Unmarshal(b, &r); err != nil {
+		return nil, fmt.Errorf("error decoding record: %w", err)
+	}
+	if r.Checksum != checksum(r.Data) {
+		return nil, errors.New("checksum mismatch")
+	}
+	if r.Version > SchemaVersion {
+		return nil, fmt.Errorf("%w: %s has version %d, supported %d", ErrNewerSchema, key, r.Version, SchemaVersion)
+	}
+
+	data := r.Data
+	for v := r.Version; v < SchemaVersion; v++ {
+		m, ok := migrations[v]
+		if !ok {
+			return nil, fmt.Errorf("no migration of record %s from version %d", key, v)
+		}
+		if data, err = m(key, data); err != nil {
+			return nil, fmt.Errorf("error migrating record %s from version %d: %w", key, v, err)
+		}
+	}
+	return data, nil
+}
+
+func decode(key string, data json.RawMessage, v any) error {
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("error decoding record %s: %w", key, err)
+	}
+	return nil
+}
+
+// checksum hashes the compact form of the data, records are indented when written
+func checksum(data []byte) string {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, data); err == nil {
+		data = buf.Bytes()
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// quarantine moves a corrupted file aside so that it can be inspected without being read again
+func quarantine(p string) {
+	dest := fmt.Sprintf("%s.corrupted-%d", p, time.Now().Unix())
+	if err := os.Rename(p, dest); err != nil {
+		log.Errorf("Error moving corrupted file %s aside: %s", p, err)
+		return
+	}
+	log.Warnf("Corrupted file moved to %s", dest)
+}
+
+func copyFile(src, dst string) error {
+	b, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	return WriteFile(dst, b, 0600)
+}
+
+// WriteFile writes the data to a temporary file and renames it to the given path once flushed to disk, so that the
+// file is never left half written.
+func WriteFile(p string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(p)
+	f, err := os.CreateTemp(dir, "."+filepath.Base(p)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	defer func() {
+		// No-op once renamed
+		_ = os.Remove(tmp)
+	}()
+
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp, perm); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, p); err != nil {
+		return err
+	}
+
+	// Persist the rename itself
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
@@ -0,0 +1,127 @@
+package store
+
+import (
+	"encoding/json"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func init() {
+	log.SetLevel(log.PanicLevel)
+}
+
+type testRecord struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+func Test_Store_PutGet(t *testing.T) {
+	s, err := Open(filepath.Join(t.TempDir(), "store"))
+	assert.NoError(t, err)
+
+	var r testRecord
+	assert.ErrorIs(t, s.Get("missing", &r), ErrNotFound)
+
+	assert.NoError(t, s.Put("test", testRecord{Name: "first", Count: 1}))
+	assert.NoError(t, s.Put("test", testRecord{Name: "second", Count: 2}))
+	assert.NoError(t, s.Get("test", &r))
+	assert.Equal(t, testRecord{Name: "second", Count: 2}, r)
+
+	assert.NoError(t, s.Delete("test"))
+	assert.ErrorIs(t, s.Get("test", &r), ErrNotFound)
+	assert.NoError(t, s.Delete("test"), "deleting a missing record should not fail")
+
+	assert.NoError(t, s.Put("first", testRecord{}))
+	assert.NoError(t, s.Put("second", testRecord{}))
+	assert.NoError(t, s.Clear())
+	assert.ErrorIs(t, s.Get("first", &r), ErrNotFound)
+	assert.ErrorIs(t, s.Get("second", &r), ErrNotFound)
+}
+
+func Test_Store_RecoverFromBackup(t *testing.T) {
+	dir := t.TempDir()
+	s, _ := Open(dir)
+
+	assert.NoError(t, s.Put("test", testRecord{Name: "first", Count: 1}))
+	assert.NoError(t, s.Put("test", testRecord{Name: "second", Count: 2}))
+
+	// Simulate a torn write of the current record
+	assert.NoError(t, os.WriteFile(s.path("test"), []byte(`{"version": 1, "data": {"na`), 0600))
+
+	var r testRecord
+	assert.NoError(t, s.Get("test", &r))
+	assert.Equal(t, testRecord{Name: "first", Count: 1}, r, "the backup should be used")
+
+	corrupted, _ := filepath.Glob(filepath.Join(dir, "test.json.corrupted-*"))
+	assert.Len(t, corrupted, 1, "the corrupted record should be quarantined")
+
+	r = testRecord{}
+	assert.NoError(t, s.Get("test", &r), "the record should be restored from the backup")
+	assert.Equal(t, "first", r.Name)
+}
+
+func Test_Store_ChecksumMismatch(t *testing.T) {
+	s, _ := Open(t.TempDir())
+	assert.NoError(t, s.Put("test", testRecord{Name: "first", Count: 1}))
+
+	b, _ := os.ReadFile(s.path("test"))
+	var rec record
+	assert.NoError(t, json.Unmarshal(b, &rec))
+	rec.Data = json.RawMessage(`{"name":"tampered","count":1}`)
+	b, _ = json.Marshal(rec)
+	assert.NoError(t, os.WriteFile(s.path("test"), b, 0600))
+
+	var r testRecord
+	assert.ErrorIs(t, s.Get("test", &r), ErrNotFound, "a record without valid backup should not be found")
+}
+
+func Test_Store_SchemaVersion(t *testing.T) {
+	s, _ := Open(t.TempDir())
+
+	data := json.RawMessage(`{"name":"future"}`)
+	b, _ := json.Marshal(record{Version: SchemaVersion + 1, Checksum: checksum(data), Data: data})
+	assert.NoError(t, os.WriteFile(s.path("test"), b, 0600))
+
+	var r testRecord
+	assert.ErrorIs(t, s.Get("test", &r), ErrNewerSchema)
+	assert.FileExists(t, s.path("test"), "records from newer agents should not be quarantined")
+
+	migrations[0] = func(_ string, data json.RawMessage) (json.RawMessage, error) {
+		return json.RawMessage(`{"name":"migrated"}`), nil
+	}
+	defer delete(migrations, 0)
+	data = json.RawMessage(`{"old-name":"legacy"}`)
+	b, _ = json.Marshal(record{Version: 0, Checksum: checksum(data), Data: data})
+	assert.NoError(t, os.WriteFile(s.path("legacy"), b, 0600))
+
+	assert.NoError(t, s.Get("legacy", &r))
+	assert.Equal(t, "migrated", r.Name)
+}
+
+func Test_Store_Nil(t *testing.T) {
+	var s *Store
+	var r testRecord
+	assert.NoError(t, s.Put("test", testRecord{}))
+	assert.ErrorIs(t, s.Get("test", &r), ErrNotFound)
+	assert.NoError(t, s.Delete("test"))
+	assert.NoError(t, s.Clear())
+}
+
+func Test_WriteFile(t *testing.T) {
+	dir := t.TempDir()
+	p := filepath.Join(dir, "file")
+
+	assert.NoError(t, WriteFile(p, []byte("content"), 0640))
+	b, err := os.ReadFile(p)
+	assert.NoError(t, err)
+	assert.Equal(t, "content", string(b))
+
+	info, _ := os.Stat(p)
+	assert.Equal(t, os.FileMode(0640), info.Mode().Perm())
+
+	entries, _ := os.ReadDir(dir)
+	assert.Len(t, entries, 1, "no temporary file should be left behind")
+}
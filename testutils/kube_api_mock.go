@@ -0,0 +1,168 @@
+package testutils
+
+import (
+	"encoding/json"
+	"io"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// FakeKubeAPI is a minimal Kubernetes API server. It serves the objects set by path, stores the objects applied with
+// server-side apply, merges the other patches into the objects and removes the deleted ones. Like in the actual API
+// server, the status of the objects is kept when applying them. The collections not set are listed from the objects
+// under their path. Any other request is answered with not found.
+type FakeKubeAPI struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	server  *httptest.Server
+}
+
+func NewFakeKubeAPI() *FakeKubeAPI {
+	f := &FakeKubeAPI{objects: make(map[string][]byte)}
+	f.server = httptest.NewServer(f)
+	return f
+}
+
+// Config returns the configuration of the clients of the server
+func (f *FakeKubeAPI) Config() *rest.Config {
+	return &rest.Config{Host: f.server.URL}
+}
+
+func (f *FakeKubeAPI) Close() {
+	f.server.Close()
+}
+
+// Set serves obj, encoded as JSON, at path
+func (f *FakeKubeAPI) Set(path string, obj interface{}) {
+	b, _ := json.Marshal(obj)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.objects[path] = b
+}
+
+// Get returns the object at path, decoded as a map
+func (f *FakeKubeAPI) Get(path string) (map[string]interface{}, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	b, ok := f.objects[path]
+	if !ok {
+		return nil, false
+	}
+	var obj map[string]interface{}
+	_ = json.Unmarshal(b, &obj)
+	return obj, true
+}
+
+func (f *FakeKubeAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case http.MethodGet:
+		if b, ok := f.objects[r.URL.Path]; ok {
+			_, _ = w.Write(b)
+			return
+		}
+		if b, ok := f.list(r.URL.Path); ok {
+			_, _ = w.Write(b)
+			return
+		}
+	case http.MethodPatch:
+		b, ok := f.patch(r)
+		if !ok {
+			break
+		}
+		f.objects[r.URL.Path] = b
+		_, _ = w.Write(b)
+		return
+	case http.MethodDelete:
+		if _, ok := f.objects[r.URL.Path]; ok {
+			delete(f.objects, r.URL.Path)
+			_ = json.NewEncoder(w).Encode(metav1.Status{Status: metav1.StatusSuccess})
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusNotFound)
+	_ = json.NewEncoder(w).Encode(metav1.Status{
+		TypeMeta: metav1.TypeMeta{Kind: "Status", APIVersion: "v1"},
+		Status:   metav1.StatusFailure,
+		Reason:   metav1.StatusReasonNotFound,
+		Code:     http.StatusNotFound,
+	})
+}
+
+// list returns the objects directly under path, sorted by name, false if there are none. Selectors are ignored.
+func (f *FakeKubeAPI) list(path string) ([]byte, bool) {
+	var paths []string
+	for p, b := range f.objects {
+		name, ok := strings.CutPrefix(p, path+"/")
+		if !ok || strings.Contains(name, "/") {
+			continue
+		}
+		var obj metav1.PartialObjectMetadata
+		if json.Unmarshal(b, &obj) == nil && obj.Name == name {
+			paths = append(paths, p)
+		}
+	}
+	sort.Strings(paths)
+
+	items := make([]json.RawMessage, 0, len(paths))
+	for _, p := range paths {
+		items = append(items, f.objects[p])
+	}
+	if len(paths) == 0 {
+		return nil, false
+	}
+	b, _ := json.Marshal(map[string]interface{}{"metadata": map[string]interface{}{}, "items": items})
+	return b, true
+}
+
+// patch returns the object at the path of the request once patched
+func (f *FakeKubeAPI) patch(r *http.Request) ([]byte, bool) {
+	var obj, existing map[string]interface{}
+	body, _ := io.ReadAll(r.Body)
+	if err := json.Unmarshal(body, &obj); err != nil {
+		return nil, false
+	}
+	b, found := f.objects[r.URL.Path]
+	if found {
+		_ = json.Unmarshal(b, &existing)
+	}
+
+	if r.Header.Get("Content-Type") == "application/apply-patch+yaml" {
+		if r.URL.Query().Get("fieldManager") == "" {
+			return nil, false
+		}
+		if status, ok := existing["status"]; ok {
+			obj["status"] = status
+		}
+	} else {
+		if !found {
+			return nil, false
+		}
+		obj = mergeObjects(existing, obj)
+	}
+
+	b, _ = json.Marshal(obj)
+	return b, true
+}
+
+func mergeObjects(dst, src map[string]interface{}) map[string]interface{} {
+	for k, v := range src {
+		srcMap, srcOk := v.(map[string]interface{})
+		dstMap, dstOk := dst[k].(map[string]interface{})
+		if srcOk && dstOk {
+			dst[k] = mergeObjects(dstMap, srcMap)
+		} else {
+			dst[k] = v
+		}
+	}
+	return dst
+}
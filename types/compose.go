@@ -22,6 +22,8 @@ type StartOpts struct {
 	Env         []string
 	ProjectName string
 	WorkingDir  string
+	// NoPull starts with the images available locally, e.g. to restore previous images
+	NoPull bool
 }
 
 // StopOpts are shared options for any orchestrator stop operation, thus, each stop will access its required fields
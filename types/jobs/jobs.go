@@ -2,8 +2,10 @@ package jobs
 
 import (
 	"slices"
+	"sort"
 	"strings"
 	"sync"
+	"time"
 )
 
 type Job interface {
@@ -69,9 +71,52 @@ func (r *JobRegistry) String() string {
 	return jobSummary
 }
 
+// List returns a snapshot of the running jobs sorted by start time
+func (r *JobRegistry) List() []RunningJob {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	list := make([]RunningJob, 0, len(r.jobs))
+	for _, job := range r.jobs {
+		list = append(list, *job)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].StartedAt.Before(list[j].StartedAt)
+	})
+	return list
+}
+
+// SetRunning marks a queued job as running
+func (r *JobRegistry) SetRunning(jobId string) bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	job, ok := r.jobs[jobId]
+	if !ok {
+		return false
+	}
+	job.Queued = false
+	job.StartedAt = time.Now()
+	return true
+}
+
+// Count returns the number of jobs running and waiting to run
+func (r *JobRegistry) Count() (running, queued int) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	for _, job := range r.jobs {
+		if job.Queued {
+			queued++
+		} else {
+			running++
+		}
+	}
+	return running, queued
+}
+
 type RunningJob struct {
-	JobId   string
-	JobType string
+	JobId     string    `json:"job-id"`
+	JobType   string    `json:"job-type"`
+	StartedAt time.Time `json:"started-at"`       // Time the job started running, or was queued if still waiting
+	Queued    bool      `json:"queued,omitempty"` // Waiting for a free slot in the job pool
 }
 
 const (
@@ -3,6 +3,7 @@ package jobs
 import (
 	"github.com/stretchr/testify/assert"
 	"testing"
+	"time"
 )
 
 func Test_NewRunningJobs(t *testing.T) {
@@ -70,3 +71,38 @@ func Test_JobRegistry_String(t *testing.T) {
 
 	assert.NotEmpty(t, js.String(), "String should not be empty")
 }
+
+func Test_JobRegistry_List(t *testing.T) {
+	js := NewRunningJobs()
+	assert.Empty(t, js.List(), "List should be empty")
+
+	now := time.Now()
+	js.Add(&RunningJob{JobId: "2", StartedAt: now})
+	js.Add(&RunningJob{JobId: "1", StartedAt: now.Add(-time.Minute)})
+
+	l := js.List()
+	assert.Len(t, l, 2)
+	assert.Equal(t, "1", l[0].JobId, "Jobs should be sorted by start time")
+	assert.Equal(t, "2", l[1].JobId, "Jobs should be sorted by start time")
+}
+
+func Test_JobRegistry_SetRunningCount(t *testing.T) {
+	js := NewRunningJobs()
+	js.Add(&RunningJob{JobId: "1"})
+	js.Add(&RunningJob{JobId: "2", Queued: true})
+	js.Add(&RunningJob{JobId: "3", Queued: true})
+
+	running, queued := js.Count()
+	assert.Equal(t, 1, running)
+	assert.Equal(t, 2, queued)
+
+	assert.True(t, js.SetRunning("2"))
+	assert.False(t, js.SetRunning("4"), "Unknown job should not be set running")
+	j, _ := js.Get("2")
+	assert.False(t, j.Queued)
+	assert.False(t, j.StartedAt.IsZero(), "Start time should be set")
+
+	running, queued = js.Count()
+	assert.Equal(t, 2, running)
+	assert.Equal(t, 1, queued)
+}
@@ -25,5 +25,12 @@ type DockerResources struct {
 	Secrets  []map[string]interface{} `json:"secrets"`
 }
 
+// KubernetesResources are omitted when empty, the installations in Docker report none
 type KubernetesResources struct {
+	Namespaces             []map[string]interface{} `json:"namespaces,omitempty"`
+	Nodes                  []map[string]interface{} `json:"nodes,omitempty"`
+	Pods                   []map[string]interface{} `json:"pods,omitempty"`
+	Deployments            []map[string]interface{} `json:"deployments,omitempty"`
+	Services               []map[string]interface{} `json:"services,omitempty"`
+	PersistentVolumeClaims []map[string]interface{} `json:"persistentvolumeclaims,omitempty"`
 }
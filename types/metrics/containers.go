@@ -91,4 +91,7 @@ type ContainerData struct {
 	State           string  `json:"state,omitempty"`
 	CreatedAt       string  `json:"created-at,omitempty"`
 	Image           string  `json:"image,omitempty"`
+
+	// Nuvla deployment the container belongs to, if any. Only used locally, not part of the telemetry schema
+	DeploymentId string `json:"-"`
 }
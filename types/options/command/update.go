@@ -1,8 +1,9 @@
 package command
 
 type UpdateCmdOptions struct {
-	Force bool
-	Quiet bool
+	Force          bool
+	Quiet          bool
+	AllowDowngrade bool `mapstructure:"allow-downgrade"`
 
 	// Job tracking
 	JobId string `mapstructure:"job-id"`
@@ -12,15 +13,37 @@ type UpdateCmdOptions struct {
 	Project     string   `mapstructure:"project"`
 	WorkingDir  string   `mapstructure:"working-dir"`
 
-	// Version tracking
+	// Version tracking. The target version can be a semver constraint, resolved in the release channel
 	TargetVersion  string `mapstructure:"target-version"`
 	CurrentVersion string `mapstructure:"current-version"`
+	Channel        string `mapstructure:"channel"`
 
 	// Compose Update
 	ComposeFiles []string `mapstructure:"compose-files"`
 
+	// Air-gapped update from a local bundle, see updater.Bundle
+	Bundle string `mapstructure:"bundle"`
+
 	// Update failure handling
 	OnUpdateFailure string `mapstructure:"on-update-failure"`
-	GitHubReleases  string `mapstructure:"github-releases"`
 	HardReset       bool   `mapstructure:"hard-reset"`
+
+	// Host update
+	BinaryPath  string `mapstructure:"binary-path"`
+	ServiceName string `mapstructure:"service-name"`
+	AdminSocket string `mapstructure:"admin-socket"`
+
+	// Release sources and verification
+	NuvlaReleases  string `mapstructure:"nuvla-releases"`
+	GitHubReleases string `mapstructure:"github-releases"`
+	PublicKeyFile  string `mapstructure:"public-key-file"`
+}
+
+type UpdateListCmdOptions struct {
+	// Channel filters the listed versions. Empty lists all of them, including the unpublished ones
+	Channel string `mapstructure:"channel"`
+	Notes   bool   `mapstructure:"notes"`
+
+	NuvlaReleases  string `mapstructure:"nuvla-releases"`
+	GitHubReleases string `mapstructure:"github-releases"`
 }
@@ -1,5 +1,18 @@
 package settings
 
+import (
+	"errors"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"net"
+	"slices"
+)
+
+const redacted = "<redacted>"
+
+// cleanUpResources are the resource types the resource cleaner knows how to prune
+var cleanUpResources = []string{"containers", "images", "volumes", "networks", "system"}
+
 type NuvlaEdgeSettings struct {
 	// NuvlaEdge Database Location
 	DBPPath    string `toml:"db-path" json:"db-path,omitempty" mapstructure:"db-path"`
@@ -21,6 +34,14 @@ type NuvlaEdgeSettings struct {
 	RemoteSyncPeriod int `mapstructure:"remote-sync-period" toml:"remote-sync-period" json:"remote-sync-period,omitempty"`
 	CleanUpPeriod    int `mapstructure:"cleanup-period" toml:"cleanup-period" json:"cleanup-period,omitempty"`
 
+	// Maximum time, in seconds, to wait for the running jobs to finish on shutdown
+	ShutdownTimeout int `mapstructure:"shutdown-timeout" toml:"shutdown-timeout" json:"shutdown-timeout,omitempty"`
+
+	// Unix socket of the local admin API. Empty disables the API
+	AdminSocket string `mapstructure:"admin-socket" toml:"admin-socket" json:"admin-socket,omitempty"`
+	// Listen address (host:port) of the Prometheus metrics exporter. Empty disables the exporter
+	MetricsAddress string `mapstructure:"metrics-address" toml:"metrics-address" json:"metrics-address,omitempty"`
+
 	// Resource cleanup
 	Resources []string `mapstructure:"resources" toml:"resources" json:"resources,omitempty"`
 
@@ -31,6 +52,12 @@ type NuvlaEdgeSettings struct {
 	// Job Engine
 	JobEngineImage         string `mapstructure:"job-engine-image" toml:"job-engine-image" json:"job-engine-image,omitempty"`
 	EnableJobLegacySupport bool   `mapstructure:"enable-legacy-job" toml:"enable-legacy-job" json:"enable-legacy-job,omitempty"`
+	// Maximum number of jobs running at the same time, the others wait in a queue
+	JobPoolSize int `mapstructure:"job-pool-size" toml:"job-pool-size" json:"job-pool-size,omitempty"`
+	// Maximum execution time in seconds by job action, e.g. start_deployment or deployment_state_10
+	JobTimeouts map[string]int `mapstructure:"job-timeouts" toml:"job-timeouts" json:"job-timeouts,omitempty"`
+	// Commands run, in order, before rebooting. A failing command cancels the reboot
+	RebootHooks []string `mapstructure:"reboot-hooks" toml:"reboot-hooks" json:"reboot-hooks,omitempty"`
 
 	// Logging
 	LogLevel string `mapstructure:"log-level" toml:"log-level" json:"log-level,omitempty"`
@@ -39,3 +66,68 @@ type NuvlaEdgeSettings struct {
 	// Irs
 	Irs string `mapstructure:"irs" toml:"irs" json:"irs,omitempty"`
 }
+
+// Redacted returns a copy of the settings safe to be logged or exposed, with the secrets hidden
+func (s NuvlaEdgeSettings) Redacted() NuvlaEdgeSettings {
+	if s.ApiKey != "" {
+		s.ApiKey = redacted
+	}
+	if s.ApiSecret != "" {
+		s.ApiSecret = redacted
+	}
+	if s.Irs != "" {
+		s.Irs = redacted
+	}
+	return s
+}
+
+// Validate checks the values of the settings. All the invalid settings are reported at once, named after their
+// configuration key.
+func (s *NuvlaEdgeSettings) Validate() error {
+	var errList []error
+
+	periods := []struct {
+		key   string
+		value int
+	}{
+		{"heartbeat-period", s.HeartbeatPeriod},
+		{"telemetry-period", s.TelemetryPeriod},
+		{"remote-sync-period", s.RemoteSyncPeriod},
+		{"cleanup-period", s.CleanUpPeriod},
+		{"shutdown-timeout", s.ShutdownTimeout},
+	}
+	for _, p := range periods {
+		if p.value < 0 {
+			errList = append(errList, fmt.Errorf("%s: must be a positive number of seconds, got %d", p.key, p.value))
+		}
+	}
+
+	if s.JobPoolSize < 0 {
+		errList = append(errList, fmt.Errorf("job-pool-size: must be a positive number of jobs, got %d", s.JobPoolSize))
+	}
+	for action, timeout := range s.JobTimeouts {
+		if timeout < 0 {
+			errList = append(errList, fmt.Errorf("job-timeouts: %s must be a positive number of seconds, got %d", action, timeout))
+		}
+	}
+
+	if s.LogLevel != "" {
+		if _, err := log.ParseLevel(s.LogLevel); err != nil {
+			errList = append(errList, fmt.Errorf("log-level: %w", err))
+		}
+	}
+
+	for _, r := range s.Resources {
+		if !slices.Contains(cleanUpResources, r) {
+			errList = append(errList, fmt.Errorf("resources: unknown resource %q, expected any of %v", r, cleanUpResources))
+		}
+	}
+
+	if s.MetricsAddress != "" {
+		if _, _, err := net.SplitHostPort(s.MetricsAddress); err != nil {
+			errList = append(errList, fmt.Errorf("metrics-address: %w", err))
+		}
+	}
+
+	return errors.Join(errList...)
+}
@@ -0,0 +1,49 @@
+package settings
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_NuvlaEdgeSettings_Redacted(t *testing.T) {
+	s := NuvlaEdgeSettings{ApiKey: "key", ApiSecret: "secret", Irs: "irs", NuvlaEndpoint: "https://nuvla.io"}
+
+	r := s.Redacted()
+	assert.Equal(t, redacted, r.ApiKey)
+	assert.Equal(t, redacted, r.ApiSecret)
+	assert.Equal(t, redacted, r.Irs)
+	assert.Equal(t, "https://nuvla.io", r.NuvlaEndpoint)
+	assert.Equal(t, "irs", s.Irs, "original settings should not be modified")
+
+	assert.Empty(t, NuvlaEdgeSettings{}.Redacted().Irs, "empty secrets should stay empty")
+}
+
+func Test_NuvlaEdgeSettings_Validate(t *testing.T) {
+	s := NuvlaEdgeSettings{
+		HeartbeatPeriod: 20,
+		LogLevel:        "debug",
+		Resources:       []string{"images", "system"},
+		MetricsAddress:  ":9100",
+	}
+	assert.NoError(t, s.Validate())
+	assert.NoError(t, (&NuvlaEdgeSettings{}).Validate(), "empty settings should be valid")
+
+	s = NuvlaEdgeSettings{
+		HeartbeatPeriod: -1,
+		CleanUpPeriod:   -10,
+		LogLevel:        "loud",
+		Resources:       []string{"disks"},
+		MetricsAddress:  "9100",
+		JobPoolSize:     -1,
+		JobTimeouts:     map[string]int{"start_deployment": -1},
+	}
+	err := s.Validate()
+	assert.ErrorContains(t, err, "heartbeat-period")
+	assert.ErrorContains(t, err, "cleanup-period")
+	assert.ErrorContains(t, err, "log-level")
+	assert.ErrorContains(t, err, `unknown resource "disks"`)
+	assert.ErrorContains(t, err, "metrics-address")
+	assert.ErrorContains(t, err, "job-pool-size")
+	assert.ErrorContains(t, err, "job-timeouts: start_deployment")
+	assert.NotContains(t, err.Error(), "telemetry-period")
+}
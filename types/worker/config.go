@@ -5,8 +5,10 @@ import (
 	"github.com/nuvla/api-client-go/clients"
 	"github.com/nuvla/api-client-go/clients/resources"
 	"nuvlaedge-go/common/constants"
+	"nuvlaedge-go/store"
 	"nuvlaedge-go/types"
 	"nuvlaedge-go/types/jobs"
+	"nuvlaedge-go/types/settings"
 )
 
 type WorkerConfig struct {
@@ -20,8 +22,12 @@ type WorkerConfig struct {
 	CommissionPeriod int
 
 	// Job Processor
-	EnableJobLegacy bool
-	LegacyJobImage  string
+	EnableJobLegacy  bool
+	LegacyJobImage   string
+	RemoteSyncPeriod int            // Period of the search for jobs queued in Nuvla that were not received
+	JobPoolSize      int            // Maximum number of jobs running at the same time
+	JobTimeouts      map[string]int // Maximum execution time in seconds by job action
+	RebootHooks      []string       // Commands run before rebooting
 }
 
 func NewDefaultWorkersConfig() *WorkerConfig {
@@ -31,6 +37,8 @@ func NewDefaultWorkersConfig() *WorkerConfig {
 		CleanUpPeriod:    constants.DefaultCleanUpPeriod,
 		CommissionPeriod: constants.MinCommissioningPeriod,
 		EnableJobLegacy:  false,
+		RemoteSyncPeriod: constants.DefaultRemoteSyncPeriod,
+		JobPoolSize:      constants.DefaultJobPoolSize,
 	}
 }
 
@@ -40,9 +48,31 @@ func (wc *WorkerConfig) UpdateFromResource(res *resources.NuvlaEdgeResource) {
 	//wc.CleanUpPeriod = res.CleanUpPeriod
 	//wc.CommissionPeriod = res.CommissionPeriod
 
-	// TODO: Update when nuvla has the new fields
-	wc.CleanUpPeriod = constants.DefaultCleanUpPeriod
-	wc.RemoveObjects = []string{"images"}
+	// TODO: Update clean up when nuvla has the new fields. Until then, they are only defined by the local settings
+}
+
+// UpdateFromSettings applies the reloadable local settings. Periods are only updated if defined.
+func (wc *WorkerConfig) UpdateFromSettings(s *settings.NuvlaEdgeSettings) {
+	if s.TelemetryPeriod > 0 {
+		wc.TelemetryPeriod = s.TelemetryPeriod
+	}
+	if s.HeartbeatPeriod > 0 {
+		wc.HeartBeatPeriod = s.HeartbeatPeriod
+	}
+	if s.CleanUpPeriod > 0 {
+		wc.CleanUpPeriod = s.CleanUpPeriod
+	}
+	if s.RemoteSyncPeriod > 0 {
+		wc.RemoteSyncPeriod = s.RemoteSyncPeriod
+	}
+	if s.JobPoolSize > 0 {
+		wc.JobPoolSize = s.JobPoolSize
+	}
+	wc.JobTimeouts = s.JobTimeouts
+	wc.RebootHooks = s.RebootHooks
+	wc.RemoveObjects = s.Resources
+	wc.EnableJobLegacy = s.EnableJobLegacySupport
+	wc.LegacyJobImage = s.JobEngineImage
 }
 
 type WorkerOpts struct {
@@ -53,8 +83,17 @@ type WorkerOpts struct {
 	JobCh            chan string
 	DeploymentCh     chan jobs.Job
 	ConfLastUpdateCh chan string
+	SettingsCh       chan *settings.NuvlaEdgeSettings // Reloaded local settings, consumed by the ConfUpdater
+	StateCh          chan resources.NuvlaEdgeState    // NuvlaEdge states received from Nuvla, consumed by the lifecycle
+	RevocationCh     chan error                       // Repeated authentication failures, consumed by the lifecycle
 	ConfigChannels   []chan *WorkerConfig
 
 	// Thread safe job registry. Shared between JobProcessor and DeploymentHandler
 	Jobs *jobs.JobRegistry
+
+	// Health of the workers, reported by telemetry
+	WorkersStatus StatusProvider
+
+	// Local state store, nil if not available
+	Store *store.Store
 }
@@ -0,0 +1,33 @@
+package worker
+
+import (
+	"time"
+)
+
+type WorkerState string
+
+const (
+	WorkerStarting   WorkerState = "STARTING"
+	WorkerRunning    WorkerState = "RUNNING"
+	WorkerFailed     WorkerState = "FAILED"
+	WorkerRestarting WorkerState = "RESTARTING"
+	WorkerStopped    WorkerState = "STOPPED"
+)
+
+// WorkerStatus is the health of a worker as tracked by the NuvlaEdge supervisor
+type WorkerStatus struct {
+	State     WorkerState `json:"state"`
+	Since     time.Time   `json:"since"`
+	Restarts  int         `json:"restarts"`
+	LastError string      `json:"last-error,omitempty"`
+}
+
+// Healthy returns false if the worker crashed and is waiting to be restarted
+func (s WorkerStatus) Healthy() bool {
+	return s.State != WorkerFailed && s.State != WorkerRestarting
+}
+
+// StatusProvider exposes the status of the supervised workers
+type StatusProvider interface {
+	WorkersStatus() map[WorkerType]WorkerStatus
+}
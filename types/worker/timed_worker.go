@@ -10,7 +10,9 @@ type TimedWorker struct {
 
 	period     int
 	BaseTicker *time.Ticker
-	mu         sync.Mutex
+	// TriggerCh requests the worker to do its job right away, without waiting for the next tick
+	TriggerCh chan struct{}
+	mu        sync.Mutex
 }
 
 func NewTimedWorker(period int, wType WorkerType) TimedWorker {
@@ -18,6 +20,7 @@ func NewTimedWorker(period int, wType WorkerType) TimedWorker {
 		WorkerBase: NewWorkerBase(wType),
 		period:     period,
 		BaseTicker: time.NewTicker(time.Duration(period) * time.Second),
+		TriggerCh:  make(chan struct{}, 1),
 		mu:         sync.Mutex{},
 	}
 }
@@ -34,3 +37,13 @@ func (w *TimedWorker) SetPeriod(period int) {
 	w.period = period
 	w.BaseTicker.Reset(time.Duration(w.period) * time.Second)
 }
+
+// Trigger requests an immediate run of the worker. Returns false if a run was already requested and is still pending.
+func (w *TimedWorker) Trigger() bool {
+	select {
+	case w.TriggerCh <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
@@ -2,6 +2,8 @@ package worker
 
 import (
 	"context"
+	"sync/atomic"
+	"time"
 )
 
 type WorkerType string
@@ -18,23 +20,52 @@ const (
 
 type Worker interface {
 	Init(opts *WorkerOpts, conf *WorkerConfig) error
+	// Start prepares the worker to run. It must not block, the Run loop is launched and tracked by the NuvlaEdge
 	Start(ctx context.Context) error
 	Reconfigure(conf *WorkerConfig) error
+	// Run executes the worker loop until the context is cancelled
 	Run(ctx context.Context) error
+	// Stop releases the worker resources once Run has returned. The context carries the shutdown deadline
 	Stop(ctx context.Context) error
 	GetName() string
 	GetConfChannel() chan *WorkerConfig
 }
 
+// Triggerable is implemented by the workers that can be requested to run right away
+type Triggerable interface {
+	Trigger() bool
+}
+
+// PeriodicWorker is implemented by the workers running on a fixed period, in seconds
+type PeriodicWorker interface {
+	GetPeriod() int
+}
+
 type WorkerBase struct {
 	ConfChan   chan *WorkerConfig
 	workerType WorkerType
+	lastRun    *atomic.Int64 // Unix nanoseconds of the last time the worker did its job
 }
 
 func (wb *WorkerBase) GetName() string {
 	return string(wb.workerType)
 }
 
+// MarkRun records that the worker just did its job
+func (wb *WorkerBase) MarkRun() {
+	if wb.lastRun != nil {
+		wb.lastRun.Store(time.Now().UnixNano())
+	}
+}
+
+// GetLastRun returns the last time the worker did its job, or the zero time if it never did
+func (wb *WorkerBase) GetLastRun() time.Time {
+	if wb.lastRun == nil || wb.lastRun.Load() == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, wb.lastRun.Load())
+}
+
 func (wb *WorkerBase) GetConfChannel() chan *WorkerConfig {
 	return wb.ConfChan
 }
@@ -43,5 +74,6 @@ func NewWorkerBase(wType WorkerType) WorkerBase {
 	return WorkerBase{
 		workerType: wType,
 		ConfChan:   make(chan *WorkerConfig),
+		lastRun:    &atomic.Int64{},
 	}
 }
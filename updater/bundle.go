@@ -0,0 +1,244 @@
+package updater
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/docker/docker/pkg/jsonmessage"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"nuvlaedge-go/updater/common"
+	"nuvlaedge-go/updater/release"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+)
+
+const (
+	// BundleManifestFile describes the content of an update bundle
+	BundleManifestFile = "manifest.json"
+	// bundleDirPattern names the directories, in the working directory, where the bundles are extracted
+	bundleDirPattern = ".nuvlaedge-bundle-*"
+)
+
+// BundleManifest describes an update bundle. The bundle also holds the checksums of every file it lists, in
+// release.ChecksumsAsset, signed in release.SignatureAsset when a public key is configured.
+type BundleManifest struct {
+	Version string `json:"version"`
+	// Compose files, at the root of the bundle
+	ComposeFiles []string `json:"compose-files"`
+	// Image archives, as written by docker save, relative to the root of the bundle
+	Images []string `json:"images"`
+}
+
+// Bundle is an update bundle extracted in Dir, allowing updates without access to the releases or the registries
+type Bundle struct {
+	Dir      string
+	Manifest BundleManifest
+}
+
+// OpenBundle extracts the bundle archive, a tarball optionally gzipped, in a new directory of dir and validates it
+func OpenBundle(archive, dir string, verifier *release.Verifier) (*Bundle, error) {
+	bundleDir, err := os.MkdirTemp(dir, bundleDirPattern)
+	if err != nil {
+		return nil, err
+	}
+	b := &Bundle{Dir: bundleDir}
+
+	if err := extractTar(archive, bundleDir); err != nil {
+		_ = b.Close()
+		return nil, fmt.Errorf("error extracting bundle %s: %w", archive, err)
+	}
+	if err := b.validate(verifier); err != nil {
+		_ = b.Close()
+		return nil, fmt.Errorf("invalid bundle %s: %w", archive, err)
+	}
+	return b, nil
+}
+
+// Close removes the extracted bundle
+func (b *Bundle) Close() error {
+	return os.RemoveAll(b.Dir)
+}
+
+// validate reads the manifest and verifies every file it lists against the checksums of the bundle
+func (b *Bundle) validate(verifier *release.Verifier) error {
+	// #nosec
+	data, err := os.ReadFile(filepath.Join(b.Dir, BundleManifestFile))
+	if err != nil {
+		return fmt.Errorf("error reading manifest: %w", err)
+	}
+	if err := json.Unmarshal(data, &b.Manifest); err != nil {
+		return fmt.Errorf("error parsing manifest: %w", err)
+	}
+	if b.Manifest.Version == "" {
+		return errors.New("manifest has no version")
+	}
+	if len(b.Manifest.ComposeFiles) == 0 {
+		return errors.New("manifest has no compose files")
+	}
+	for _, name := range b.Manifest.ComposeFiles {
+		if name != filepath.Base(name) {
+			return fmt.Errorf("compose file %s is not at the root of the bundle", name)
+		}
+	}
+
+	sums, err := b.checksums(verifier)
+	if err != nil {
+		return err
+	}
+	for _, name := range append(slices.Clone(b.Manifest.ComposeFiles), b.Manifest.Images...) {
+		if err := b.verifyFile(name, sums); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *Bundle) checksums(verifier *release.Verifier) (release.Checksums, error) {
+	// #nosec
+	data, err := os.ReadFile(filepath.Join(b.Dir, release.ChecksumsAsset))
+	if err != nil {
+		return nil, fmt.Errorf("%w: error reading %s: %w", release.ErrVerification, release.ChecksumsAsset, err)
+	}
+	// #nosec
+	sig, err := os.ReadFile(filepath.Join(b.Dir, release.SignatureAsset))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	return verifier.Checksums(data, sig)
+}
+
+func (b *Bundle) verifyFile(name string, sums release.Checksums) error {
+	if !filepath.IsLocal(name) {
+		return fmt.Errorf("file %s is outside the bundle", name)
+	}
+	// #nosec
+	f, err := os.Open(filepath.Join(b.Dir, name))
+	if err != nil {
+		return fmt.Errorf("error opening %s: %w", name, err)
+	}
+	defer f.Close()
+	return sums.VerifyReader(name, f)
+}
+
+// installComposeFiles copies the compose files of the bundle to the working directory and returns their paths
+func (b *Bundle) installComposeFiles(names []string, workDir string) ([]string, error) {
+	var files []string
+	for _, name := range names {
+		if !slices.Contains(b.Manifest.ComposeFiles, name) {
+			return nil, fmt.Errorf("compose file %s not found in bundle", name)
+		}
+		// #nosec
+		content, err := os.ReadFile(filepath.Join(b.Dir, name))
+		if err != nil {
+			return nil, err
+		}
+		if err := common.SaveFile(name, workDir, string(content)); err != nil {
+			return nil, err
+		}
+		files = append(files, filepath.Join(workDir, name))
+	}
+	return files, nil
+}
+
+// loadImages loads the image archives of the bundle into Docker
+func (du *DockerUpdater) loadImages(ctx context.Context, b *Bundle) error {
+	for _, name := range b.Manifest.Images {
+		log.Infof("Loading images from %s", name)
+		if err := du.loadImage(ctx, filepath.Join(b.Dir, name)); err != nil {
+			return fmt.Errorf("error loading images from %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func (du *DockerUpdater) loadImage(ctx context.Context, archive string) error {
+	// #nosec
+	f, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	res, err := du.dCli.ImageLoad(ctx, f, true)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	// Errors of the load are only reported in the response stream
+	return jsonmessage.DisplayJSONMessagesStream(res.Body, io.Discard, 0, false, nil)
+}
+
+// extractTar extracts the regular files and directories of the archive in dir. Other entries are ignored.
+func extractTar(archive, dir string) error {
+	// #nosec
+	f, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var r io.Reader = bufio.NewReader(f)
+	if magic, _ := r.(*bufio.Reader).Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		name := path.Clean(hdr.Name)
+		if !filepath.IsLocal(name) {
+			return fmt.Errorf("entry %s is outside the bundle", hdr.Name)
+		}
+		target := filepath.Join(dir, name)
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0700); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := extractFile(tr, target); err != nil {
+				return err
+			}
+		default:
+			log.Debugf("Ignoring bundle entry %s", hdr.Name)
+		}
+	}
+}
+
+func extractFile(r io.Reader, target string) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0700); err != nil {
+		return err
+	}
+	// #nosec
+	f, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	// #nosec G110 -- bundles are provided by the administrator of the device
+	if _, err := io.Copy(f, r); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
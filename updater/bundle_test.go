@@ -0,0 +1,109 @@
+package updater
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"nuvlaedge-go/types/options/command"
+	"nuvlaedge-go/updater/release"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const bundleManifest = `{"version": "2.6.0", "compose-files": ["docker-compose.yml"], "images": ["images/agent.tar"]}`
+
+func sha256Hex(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// writeBundle writes a gzipped tarball with the given files, by name
+func writeBundle(t *testing.T, files map[string]string) string {
+	archive := filepath.Join(t.TempDir(), "bundle.tar.gz")
+	f, err := os.Create(archive)
+	assert.NoError(t, err)
+	defer f.Close()
+
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		assert.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0600, Size: int64(len(content))}))
+		_, err := tw.Write([]byte(content))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, tw.Close())
+	assert.NoError(t, gz.Close())
+	return archive
+}
+
+func validBundleFiles() map[string]string {
+	return map[string]string{
+		BundleManifestFile:   bundleManifest,
+		"docker-compose.yml": "compose",
+		"images/agent.tar":   "image",
+		release.ChecksumsAsset: fmt.Sprintf("%s  docker-compose.yml\n%s  images/agent.tar\n",
+			sha256Hex("compose"), sha256Hex("image")),
+	}
+}
+
+func Test_OpenBundle(t *testing.T) {
+	verifier, _ := release.NewVerifier("")
+	workDir := t.TempDir()
+
+	b, err := OpenBundle(writeBundle(t, validBundleFiles()), workDir, verifier)
+	assert.NoError(t, err)
+	assert.Equal(t, "2.6.0", b.Manifest.Version)
+	assert.Equal(t, []string{"images/agent.tar"}, b.Manifest.Images)
+
+	files, err := b.installComposeFiles([]string{"docker-compose.yml"}, workDir)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(workDir, "docker-compose.yml")}, files)
+	content, _ := os.ReadFile(files[0])
+	assert.Equal(t, "compose", string(content))
+
+	_, err = b.installComposeFiles([]string{"docker-compose.gpu.yml"}, workDir)
+	assert.Error(t, err, "compose files missing from the bundle should be rejected")
+
+	assert.NoError(t, b.Close())
+	_, err = os.Stat(b.Dir)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func Test_OpenBundle_Invalid(t *testing.T) {
+	verifier, _ := release.NewVerifier("")
+
+	tests := map[string]func(files map[string]string){
+		"tampered image":    func(files map[string]string) { files["images/agent.tar"] = "tampered" },
+		"missing image":     func(files map[string]string) { delete(files, "images/agent.tar") },
+		"missing checksums": func(files map[string]string) { delete(files, release.ChecksumsAsset) },
+		"missing manifest":  func(files map[string]string) { delete(files, BundleManifestFile) },
+		"missing version": func(files map[string]string) {
+			files[BundleManifestFile] = `{"compose-files": ["docker-compose.yml"]}`
+		},
+		"nested compose file": func(files map[string]string) {
+			files[BundleManifestFile] = `{"version": "2.6.0", "compose-files": ["compose/docker-compose.yml"]}`
+		},
+		"entry outside bundle": func(files map[string]string) { files["../docker-compose.yml"] = "compose" },
+	}
+	for name, modify := range tests {
+		t.Run(name, func(t *testing.T) {
+			files := validBundleFiles()
+			modify(files)
+			workDir := t.TempDir()
+
+			_, err := OpenBundle(writeBundle(t, files), workDir, verifier)
+			assert.Error(t, err)
+			entries, _ := os.ReadDir(workDir)
+			assert.Empty(t, entries, "invalid bundles should be removed")
+		})
+	}
+}
+
+func Test_DockerUpdater_ValidateOpts_Bundle(t *testing.T) {
+	du := &DockerUpdater{opts: &command.UpdateCmdOptions{Project: "nuvlaedge", Bundle: "bundle.tar.gz"}}
+	assert.NoError(t, du.ValidateOpts(), "the target version of bundles is in their manifest")
+}
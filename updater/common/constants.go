@@ -1,6 +1,35 @@
 package common
 
+import "time"
+
 const (
 	DockerGitHubRelease = "https://github.com/nuvlaedge/deployment/releases"
 	DockerNuvlaRelease  = "https://nuvla.io/releases"
 )
+
+const (
+	// HealthCheckDuration is the time NuvlaEdge has to keep running after an update to be considered healthy
+	HealthCheckDuration = 2 * time.Minute
+	HealthCheckPeriod   = 10 * time.Second
+)
+
+// Behaviours on update failure
+const (
+	OnFailureRollback = "rollback" // Default, brings the previous version back up
+	OnFailureKeep     = "keep"     // Leaves the failed update in place, e.g. to debug it
+)
+
+// Exit codes of the update command, used by the agent to report the outcome of an update job
+const (
+	UpdateFailedExitCode     = 1 // The installation was not changed
+	UpdateRolledBackExitCode = 3
+	RollbackFailedExitCode   = 4
+)
+
+// Host installations, as set up by the installer
+const (
+	DefaultBinaryPath  = "/usr/local/bin/nuvlaedge"
+	DefaultServiceName = "nuvlaedge"
+	// BinaryBackupSuffix is appended to the binary path to keep the previous binary during an update
+	BinaryBackupSuffix = ".backup"
+)
@@ -1,11 +1,13 @@
 package common
 
 import (
+	"fmt"
 	log "github.com/sirupsen/logrus"
 	"io"
 	"net/http"
 	"os"
 	"path/filepath"
+	"time"
 )
 
 func SaveFile(fileName, workDir, content string) error {
@@ -30,15 +32,25 @@ func SaveFile(fileName, workDir, content string) error {
 	return nil
 }
 
-func DownloadFile(url string, dest string) error {
-	// #nosec
-	resp, err := http.Get(url)
+// httpClient is used for all the release downloads
+var httpClient = &http.Client{Timeout: 2 * time.Minute}
+
+// Download returns the content found at the url, failing on non-2xx responses
+func Download(url string) ([]byte, error) {
+	resp, err := httpClient.Get(url)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer resp.Body.Close()
 
-	b, err := io.ReadAll(resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("downloading %s returned status code %d", url, resp.StatusCode)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func DownloadFile(url string, dest string) error {
+	b, err := Download(url)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,18 @@
+package common
+
+import (
+	"bufio"
+	"strings"
+)
+
+// ParseUnitProperties parses the output of systemctl show: one Key=Value per line
+func ParseUnitProperties(out string) map[string]string {
+	props := make(map[string]string)
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	for scanner.Scan() {
+		if k, v, ok := strings.Cut(scanner.Text(), "="); ok {
+			props[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		}
+	}
+	return props
+}
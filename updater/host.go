@@ -0,0 +1,293 @@
+package updater
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"net"
+	"net/http"
+	"nuvlaedge-go/common/version"
+	"nuvlaedge-go/types/options/command"
+	"nuvlaedge-go/updater/common"
+	"nuvlaedge-go/updater/release"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HostUpdater updates a NuvlaEdge installed as a binary run by a systemd service. The binary is swapped atomically,
+// the previous one being kept as a backup, which is restored if the new binary doesn't report healthy.
+type HostUpdater struct {
+	opts *command.UpdateCmdOptions
+
+	// systemctl runs systemctl with the given arguments and returns its output
+	systemctl func(ctx context.Context, args ...string) (string, error)
+}
+
+// UpdateHost updates a host installation, restoring the previous binary if the update fails
+func UpdateHost(ctx context.Context, opts *command.UpdateCmdOptions) error {
+	return NewHostUpdater(opts).Update(ctx)
+}
+
+func NewHostUpdater(opts *command.UpdateCmdOptions) *HostUpdater {
+	return &HostUpdater{
+		opts:      opts,
+		systemctl: runSystemctl,
+	}
+}
+
+func (hu *HostUpdater) ValidateOpts() error {
+	if hu.opts == nil {
+		return errors.New("update options are nil")
+	}
+	if hu.opts.BinaryPath == "" {
+		hu.opts.BinaryPath = common.DefaultBinaryPath
+	}
+	if hu.opts.ServiceName == "" {
+		hu.opts.ServiceName = common.DefaultServiceName
+	}
+	// The update runs the installed binary
+	if hu.opts.CurrentVersion == "" {
+		hu.opts.CurrentVersion = version.Version
+	}
+	if hu.opts.TargetVersion == "" && !hu.opts.Force {
+		return errors.New("target version is required. Use --force to update without a target " +
+			"version to the latest available")
+	}
+	return nil
+}
+
+// Update downloads and verifies the binary of the target release, swaps it with the installed one and restarts the
+// service
+func (hu *HostUpdater) Update(ctx context.Context) error {
+	if err := hu.ValidateOpts(); err != nil {
+		return &UpdateError{Outcome: OutcomeFailed, Err: err}
+	}
+	if err := resolveTargetVersion(hu.opts); err != nil {
+		return &UpdateError{Outcome: OutcomeFailed, Err: err}
+	}
+
+	binary, err := hu.downloadBinary()
+	if err != nil {
+		return &UpdateError{Outcome: OutcomeFailed, Err: err}
+	}
+
+	backup := hu.opts.BinaryPath + common.BinaryBackupSuffix
+	if err := swapBinary(hu.opts.BinaryPath, backup, binary); err != nil {
+		return &UpdateError{Outcome: OutcomeFailed, Err: fmt.Errorf("error installing the new binary: %w", err)}
+	}
+	log.Infof("Binary %s updated, previous one kept in %s", hu.opts.BinaryPath, backup)
+
+	err = hu.restartAndCheck(ctx)
+	if err == nil {
+		log.Info("NuvlaEdge is healthy")
+		return nil
+	}
+
+	log.Warnf("Update failed: %s", err)
+	if hu.opts.OnUpdateFailure == common.OnFailureKeep {
+		log.Warn("Keeping the failed update in place")
+		return &UpdateError{Outcome: OutcomeFailed, Err: err}
+	}
+
+	log.Warnf("Restoring the previous binary from %s", backup)
+	if rbErr := hu.rollback(ctx, backup); rbErr != nil {
+		log.Errorf("Rollback failed: %s", rbErr)
+		return &UpdateError{Outcome: OutcomeRollbackFailed, Err: err, RollbackErr: rbErr}
+	}
+	log.Info("NuvlaEdge rolled back and healthy")
+	return &UpdateError{Outcome: OutcomeRolledBack, Err: err}
+}
+
+func (hu *HostUpdater) rollback(ctx context.Context, backup string) error {
+	// #nosec
+	previous, err := os.ReadFile(backup)
+	if err != nil {
+		return fmt.Errorf("error reading the previous binary: %w", err)
+	}
+	if err := swapBinary(hu.opts.BinaryPath, "", previous); err != nil {
+		return fmt.Errorf("error restoring the previous binary: %w", err)
+	}
+	return hu.restartAndCheck(ctx)
+}
+
+func (hu *HostUpdater) restartAndCheck(ctx context.Context) error {
+	if _, err := hu.systemctl(ctx, "restart", hu.opts.ServiceName); err != nil {
+		return fmt.Errorf("error restarting service %s: %w", hu.opts.ServiceName, err)
+	}
+	return hu.checkHealth(ctx, common.HealthCheckPeriod)
+}
+
+// downloadBinary returns the binary of the target release for this platform, verified against the release checksums
+func (hu *HostUpdater) downloadBinary() ([]byte, error) {
+	verifier, err := release.NewVerifier(hu.opts.PublicKeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	ghRelease, err := release.GetGitHubRelease(hu.opts.GitHubReleases, hu.opts.TargetVersion)
+	if err != nil {
+		return nil, fmt.Errorf("error getting GitHub release %s: %w", hu.opts.TargetVersion, err)
+	}
+	sums, err := ghRelease.Checksums(verifier)
+	if err != nil {
+		return nil, err
+	}
+
+	asset, err := findBinaryAsset(ghRelease, runtime.GOOS, runtime.GOARCH)
+	if err != nil {
+		return nil, err
+	}
+	log.Infof("Downloading %s", asset.Name)
+	binary, err := common.Download(asset.BrowserDownloadUrl)
+	if err != nil {
+		return nil, fmt.Errorf("error downloading %s: %w", asset.Name, err)
+	}
+	if err := sums.Verify(asset.Name, binary); err != nil {
+		return nil, err
+	}
+	return binary, nil
+}
+
+// findBinaryAsset returns the binary of the release for the platform, e.g. nuvlaedge-linux-arm64-2.6.0
+func findBinaryAsset(r *release.GitHubRelease, goos, goarch string) (*release.GitHubAsset, error) {
+	base := fmt.Sprintf("nuvlaedge-%s-%s", goos, goarch)
+	for _, name := range []string{base + "-" + r.TagName, base + "-v" + r.TagName, base} {
+		for i := range r.Assets {
+			if r.Assets[i].Name == name {
+				return &r.Assets[i], nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("release %s has no binary for %s/%s", r.TagName, goos, goarch)
+}
+
+// swapBinary atomically replaces the binary with the new content. The replaced binary is moved to backup, unless
+// backup is empty.
+func swapBinary(path, backup string, content []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(content); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	// #nosec
+	if err := os.Chmod(tmp.Name(), 0755); err != nil {
+		return err
+	}
+
+	if backup != "" {
+		_ = os.Remove(backup + ".tmp")
+		if err := os.Link(path, backup+".tmp"); err != nil {
+			return fmt.Errorf("error backing up %s: %w", path, err)
+		}
+		if err := os.Rename(backup+".tmp", backup); err != nil {
+			return fmt.Errorf("error backing up %s: %w", path, err)
+		}
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// checkHealth monitors the service during common.HealthCheckDuration. It is unhealthy if it stops or restarts, or
+// if it never reports healthy through the admin API, when enabled.
+func (hu *HostUpdater) checkHealth(ctx context.Context, period time.Duration) error {
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+
+	ctxCheck, cancel := context.WithTimeout(ctx, common.HealthCheckDuration)
+	defer cancel()
+
+	initialRestarts := -1
+	reported := hu.opts.AdminSocket == ""
+
+	for {
+		select {
+		case <-ctxCheck.Done():
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if !reported {
+				return fmt.Errorf("NuvlaEdge did not report healthy within %s", common.HealthCheckDuration)
+			}
+			log.Info("Health check finished, NuvlaEdge is healthy")
+			return nil
+		case <-ticker.C:
+			state, restarts, err := hu.serviceState(ctx)
+			if err != nil {
+				return fmt.Errorf("error monitoring service %s: %w", hu.opts.ServiceName, err)
+			}
+			if initialRestarts == -1 {
+				initialRestarts = restarts
+			}
+			if restarts > initialRestarts {
+				return errors.New("NuvlaEdge restarted, not healthy")
+			}
+			if state != "active" {
+				return fmt.Errorf("NuvlaEdge service is %s, not healthy", state)
+			}
+			if !reported {
+				reported = reportsHealthy(ctx, hu.opts.AdminSocket)
+			}
+		}
+	}
+}
+
+// serviceState returns the systemd state of the service and the number of times systemd restarted it
+func (hu *HostUpdater) serviceState(ctx context.Context) (string, int, error) {
+	out, err := hu.systemctl(ctx, "show", hu.opts.ServiceName, "--property=ActiveState,NRestarts")
+	if err != nil {
+		return "", 0, err
+	}
+	props := common.ParseUnitProperties(out)
+	restarts, _ := strconv.Atoi(props["NRestarts"])
+	return props["ActiveState"], restarts, nil
+}
+
+// reportsHealthy asserts whether the health endpoint of the admin API answers OK
+func reportsHealthy(ctx context.Context, socket string) bool {
+	client := &http.Client{
+		Timeout: 5 * time.Second,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", socket)
+			},
+		},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://admin/health", nil)
+	if err != nil {
+		return false
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		log.Debugf("NuvlaEdge admin API not available yet: %s", err)
+		return false
+	}
+	defer res.Body.Close()
+	return res.StatusCode == http.StatusOK
+}
+
+func runSystemctl(ctx context.Context, args ...string) (string, error) {
+	// #nosec
+	out, err := exec.CommandContext(ctx, "systemctl", args...).CombinedOutput()
+	if err != nil {
+		return string(out), fmt.Errorf("systemctl %s: %w: %s", strings.Join(args, " "), err, out)
+	}
+	return string(out), nil
+}
@@ -0,0 +1,103 @@
+package updater
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"net/http"
+	"nuvlaedge-go/types/options/command"
+	"nuvlaedge-go/updater/common"
+	"nuvlaedge-go/updater/release"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_FindBinaryAsset(t *testing.T) {
+	r := &release.GitHubRelease{TagName: "2.6.0", Assets: []release.GitHubAsset{
+		{Name: "nuvlaedge-linux-arm64-2.6.0"},
+		{Name: "nuvlaedge-linux-arm-2.6.0"},
+		{Name: "nuvlaedge-darwin-amd64"},
+	}}
+
+	a, err := findBinaryAsset(r, "linux", "arm")
+	assert.NoError(t, err)
+	assert.Equal(t, "nuvlaedge-linux-arm-2.6.0", a.Name)
+
+	a, err = findBinaryAsset(r, "darwin", "amd64")
+	assert.NoError(t, err)
+	assert.Equal(t, "nuvlaedge-darwin-amd64", a.Name)
+
+	_, err = findBinaryAsset(r, "linux", "amd64")
+	assert.Error(t, err)
+}
+
+func Test_SwapBinary(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nuvlaedge")
+	backup := path + common.BinaryBackupSuffix
+	assert.NoError(t, os.WriteFile(path, []byte("v1"), 0755))
+
+	assert.NoError(t, swapBinary(path, backup, []byte("v2")))
+	content, _ := os.ReadFile(path)
+	assert.Equal(t, "v2", string(content))
+	content, _ = os.ReadFile(backup)
+	assert.Equal(t, "v1", string(content))
+	info, _ := os.Stat(path)
+	assert.Equal(t, os.FileMode(0755), info.Mode().Perm())
+
+	// Restoring doesn't touch the backup
+	assert.NoError(t, swapBinary(path, "", content))
+	content, _ = os.ReadFile(path)
+	assert.Equal(t, "v1", string(content))
+	_, err := os.Stat(backup)
+	assert.NoError(t, err)
+
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	assert.Len(t, entries, 2, "no temporary file should be left")
+}
+
+func Test_HostUpdater_ServiceState(t *testing.T) {
+	var called []string
+	hu := &HostUpdater{
+		opts: &command.UpdateCmdOptions{ServiceName: "nuvlaedge"},
+		systemctl: func(_ context.Context, args ...string) (string, error) {
+			called = args
+			return "ActiveState=active\nNRestarts=2\n", nil
+		},
+	}
+	state, restarts, err := hu.serviceState(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "active", state)
+	assert.Equal(t, 2, restarts)
+	assert.Equal(t, "nuvlaedge", called[1])
+}
+
+func Test_ReportsHealthy(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "admin.sock")
+	assert.False(t, reportsHealthy(context.Background(), socket), "missing socket should not be healthy")
+
+	l, err := net.Listen("unix", socket)
+	assert.NoError(t, err)
+	healthy := true
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !healthy {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	})}
+	go func() { _ = srv.Serve(l) }()
+	defer srv.Close()
+
+	assert.True(t, reportsHealthy(context.Background(), socket))
+	healthy = false
+	assert.False(t, reportsHealthy(context.Background(), socket))
+}
+
+func Test_HostUpdater_ValidateOpts(t *testing.T) {
+	hu := NewHostUpdater(&command.UpdateCmdOptions{TargetVersion: "2.6.0"})
+	assert.NoError(t, hu.ValidateOpts())
+	assert.Equal(t, common.DefaultBinaryPath, hu.opts.BinaryPath)
+	assert.Equal(t, common.DefaultServiceName, hu.opts.ServiceName)
+
+	hu = NewHostUpdater(&command.UpdateCmdOptions{})
+	assert.Error(t, hu.ValidateOpts())
+}
@@ -5,7 +5,6 @@ import (
 	"errors"
 	"fmt"
 	log "github.com/sirupsen/logrus"
-	"net/http"
 	"nuvlaedge-go/updater/common"
 	"path/filepath"
 	"slices"
@@ -19,7 +18,7 @@ const (
 
 type GitHubAsset struct {
 	Url                string `json:"url"`
-	Id                 string `json:"id"`
+	Id                 int64  `json:"id"`
 	Name               string `json:"name"`
 	Label              string `json:"label"`
 	BrowserDownloadUrl string `json:"browser_download_url"`
@@ -31,29 +30,68 @@ type GitHubRelease struct {
 	UploadURL string `json:"upload_url"`
 	HTMLURL   string `json:"html_url"`
 
-	TagName    string        `json:"tag_name"`
-	Name       string        `json:"name"`
-	Assets     []GitHubAsset `json:"assets"`
-	PreRelease bool          `json:"prerelease"`
+	TagName     string        `json:"tag_name"`
+	Name        string        `json:"name"`
+	Body        string        `json:"body"`
+	Assets      []GitHubAsset `json:"assets"`
+	Draft       bool          `json:"draft"`
+	PreRelease  bool          `json:"prerelease"`
+	PublishedAt string        `json:"published_at"`
 }
 
-func (gt *GitHubRelease) GetComposeFiles(fileNames []string, workDir string) ([]string, error) {
+// GetComposeFiles downloads the requested compose files of the release, verifies them and saves them in the working
+// directory
+func (gt *GitHubRelease) GetComposeFiles(fileNames []string, workDir string, sums Checksums) ([]string, error) {
 	var files []string
 	for _, asset := range gt.Assets {
-		if slices.Contains(fileNames, asset.Name) {
-			err := common.DownloadFile(asset.Url, filepath.Join(workDir, asset.Name))
-			if err != nil {
-				log.Errorf("Error downloading asset %s: %s", asset.Name, err)
-				return nil, err
-			}
+		if !slices.Contains(fileNames, asset.Name) {
+			continue
+		}
+		content, err := common.Download(asset.BrowserDownloadUrl)
+		if err != nil {
+			log.Errorf("Error downloading asset %s: %s", asset.Name, err)
+			return nil, err
+		}
+		if err := sums.Verify(asset.Name, content); err != nil {
+			return nil, err
+		}
+		if err := common.SaveFile(asset.Name, workDir, string(content)); err != nil {
+			return nil, err
 		}
+		files = append(files, filepath.Join(workDir, asset.Name))
 	}
 
+	if len(files) == 0 {
+		return nil, errors.New("no compose files found")
+	}
 	return files, nil
 }
 
-func GetGitHubRelease(version string) (*GitHubRelease, error) {
-	releases, err := ListGitHubReleases()
+// Checksums downloads the checksums of the release files and verifies their signature
+func (gt *GitHubRelease) Checksums(v *Verifier) (Checksums, error) {
+	var checksums, signature []byte
+	for _, asset := range gt.Assets {
+		var err error
+		switch asset.Name {
+		case ChecksumsAsset:
+			checksums, err = common.Download(asset.BrowserDownloadUrl)
+		case SignatureAsset:
+			signature, err = common.Download(asset.BrowserDownloadUrl)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error downloading %s: %w", asset.Name, err)
+		}
+	}
+	if checksums == nil {
+		return nil, fmt.Errorf("%w: release %s doesn't publish %s", ErrVerification, gt.TagName, ChecksumsAsset)
+	}
+	return v.Checksums(checksums, signature)
+}
+
+// GetGitHubRelease returns the release with the given tag, or the latest stable one if the version is empty or
+// latest. The endpoint defaults to GitHubReleasesAPIURL.
+func GetGitHubRelease(endpoint, version string) (*GitHubRelease, error) {
+	releases, err := ListGitHubReleases(endpoint)
 	if err != nil {
 		return nil, err
 	}
@@ -63,7 +101,11 @@ func GetGitHubRelease(version string) (*GitHubRelease, error) {
 	}
 
 	for _, release := range releases {
-		if release.TagName == version {
+		if version == "" || version == "latest" {
+			if !release.PreRelease && !release.Draft {
+				return &release, nil
+			}
+		} else if release.TagName == version {
 			return &release, nil
 		}
 	}
@@ -72,15 +114,17 @@ func GetGitHubRelease(version string) (*GitHubRelease, error) {
 	return &releases[0], errors.New("no release found")
 }
 
-func ListGitHubReleases() ([]GitHubRelease, error) {
-	resp, err := http.Get(GitHubReleasesAPIURL)
+func ListGitHubReleases(endpoint string) ([]GitHubRelease, error) {
+	if endpoint == "" {
+		endpoint = GitHubReleasesAPIURL
+	}
+	b, err := common.Download(endpoint)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch releases: %w", err)
 	}
-	defer resp.Body.Close()
 
 	var r []GitHubRelease
-	err = json.NewDecoder(resp.Body).Decode(&r)
+	err = json.Unmarshal(b, &r)
 	if err != nil {
 		return nil, err
 	}
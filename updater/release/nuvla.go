@@ -5,7 +5,6 @@ import (
 	"errors"
 	"fmt"
 	log "github.com/sirupsen/logrus"
-	"net/http"
 	"nuvlaedge-go/updater/common"
 	"path/filepath"
 	"slices"
@@ -19,7 +18,8 @@ type NuvlaComposeFile struct {
 
 type NuvlaReleaseResource struct {
 	ReleaseDate  string             `json:"release-date"`
-	PreRelease   bool               `json:"prerelease"`
+	ReleaseNotes string             `json:"release-notes"`
+	PreRelease   bool               `json:"pre-release"`
 	ComposeFiles []NuvlaComposeFile `json:"compose-files"`
 	Id           string             `json:"id"`
 	Url          string             `json:"url"`
@@ -27,10 +27,15 @@ type NuvlaReleaseResource struct {
 	Published    bool               `json:"published"`
 }
 
-func (nr *NuvlaReleaseResource) GetComposeFiles(fileNames []string, workDir string) ([]string, error) {
+// GetComposeFiles verifies the requested compose files of the release and saves them in the working directory
+func (nr *NuvlaReleaseResource) GetComposeFiles(fileNames []string, workDir string, sums Checksums) ([]string, error) {
 	var composeFiles []string
 	for _, composeFile := range nr.ComposeFiles {
 		if slices.Contains(fileNames, composeFile.Name) {
+			if err := sums.Verify(composeFile.Name, []byte(composeFile.FileContent)); err != nil {
+				return nil, err
+			}
+
 			// Save file to disk
 			err := common.SaveFile(composeFile.Name, workDir, composeFile.FileContent)
 			if err != nil {
@@ -53,25 +58,14 @@ const (
 	NuvlaEndpoint = "https://nuvla.io/api/nuvlabox-release"
 )
 
-func GetNuvlaRelease(version string) (*NuvlaReleaseResource, error) {
-	ret, err := http.Get(NuvlaEndpoint)
-	if err != nil {
-		return nil, err
-	}
-	var res struct {
-		Releases []NuvlaReleaseResource `json:"resources"`
-		Id       string                 `json:"id"`
-	}
-
-	defer ret.Body.Close()
-
-	err = json.NewDecoder(ret.Body).Decode(&res)
+// GetNuvlaRelease returns the release with the given version, or the latest published stable one if the version is
+// empty or latest. The endpoint defaults to NuvlaEndpoint.
+func GetNuvlaRelease(endpoint, version string) (*NuvlaReleaseResource, error) {
+	rel, err := ListNuvlaReleases(endpoint)
 	if err != nil {
 		return nil, err
 	}
 
-	rel := res.Releases
-
 	if len(rel) == 0 {
 		log.Warnf("No nuvla releases found")
 		return nil, errors.New("no nuvla releases found")
@@ -79,7 +73,12 @@ func GetNuvlaRelease(version string) (*NuvlaReleaseResource, error) {
 
 	log.Infof("Found %d nuvla releases", len(rel))
 	if version == "" || version == "latest" {
-		return &rel[len(rel)-1], nil
+		for i := len(rel) - 1; i >= 0; i-- {
+			if rel[i].Published && !rel[i].PreRelease {
+				return &rel[i], nil
+			}
+		}
+		return nil, errors.New("no published stable nuvla release found")
 	}
 
 	for _, release := range rel {
@@ -92,3 +91,23 @@ func GetNuvlaRelease(version string) (*NuvlaReleaseResource, error) {
 	log.Info("Version not found, returning the latest release")
 	return &rel[len(rel)-1], fmt.Errorf("requested version not found")
 }
+
+// ListNuvlaReleases returns all the releases of the endpoint, which defaults to NuvlaEndpoint
+func ListNuvlaReleases(endpoint string) ([]NuvlaReleaseResource, error) {
+	if endpoint == "" {
+		endpoint = NuvlaEndpoint
+	}
+	b, err := common.Download(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	var res struct {
+		Releases []NuvlaReleaseResource `json:"resources"`
+		Id       string                 `json:"id"`
+	}
+
+	if err := json.Unmarshal(b, &res); err != nil {
+		return nil, err
+	}
+	return res.Releases, nil
+}
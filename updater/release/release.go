@@ -1,5 +1,7 @@
 package release
 
 type Release interface {
-	GetComposeFiles(fileNames []string, workDir string) ([]string, error)
+	// GetComposeFiles saves the requested compose files of the release in the working directory, once verified
+	// against the checksums, and returns their paths
+	GetComposeFiles(fileNames []string, workDir string, sums Checksums) ([]string, error)
 }
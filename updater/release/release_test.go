@@ -0,0 +1,88 @@
+package release
+
+import (
+	"encoding/json"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func init() {
+	log.SetLevel(log.PanicLevel)
+}
+
+// newReleasesServer serves a GitHub release 2.6.0 with a compose file and its checksums, and the matching Nuvla
+// release with the given compose file content
+func newReleasesServer(t *testing.T, nuvlaContent string) *httptest.Server {
+	mux := http.NewServeMux()
+	var srv *httptest.Server
+	mux.HandleFunc("/github/releases", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode([]GitHubRelease{
+			{TagName: "2.7.0-rc1", PreRelease: true},
+			{TagName: "2.6.0", Assets: []GitHubAsset{
+				{Name: "docker-compose.yml", BrowserDownloadUrl: srv.URL + "/download/docker-compose.yml"},
+				{Name: ChecksumsAsset, BrowserDownloadUrl: srv.URL + "/download/" + ChecksumsAsset},
+			}},
+		})
+	})
+	mux.HandleFunc("/download/docker-compose.yml", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("compose"))
+	})
+	mux.HandleFunc("/download/"+ChecksumsAsset, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(sha256Hex("compose") + "  docker-compose.yml\n"))
+	})
+	mux.HandleFunc("/nuvla/releases", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"resources": []NuvlaReleaseResource{
+				{Release: "2.6.0", ComposeFiles: []NuvlaComposeFile{{Name: "docker-compose.yml", FileContent: nuvlaContent}}},
+			},
+		})
+	})
+	srv = httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func Test_GitHubRelease(t *testing.T) {
+	srv := newReleasesServer(t, "compose")
+
+	r, err := GetGitHubRelease(srv.URL+"/github/releases", "")
+	assert.NoError(t, err)
+	assert.Equal(t, "2.6.0", r.TagName, "latest should skip pre-releases")
+
+	v, _ := NewVerifier("")
+	sums, err := r.Checksums(v)
+	assert.NoError(t, err)
+
+	workDir := t.TempDir()
+	files, err := r.GetComposeFiles([]string{"docker-compose.yml"}, workDir, sums)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(workDir, "docker-compose.yml")}, files)
+
+	_, err = r.GetComposeFiles([]string{"docker-compose.yml"}, t.TempDir(), Checksums{"docker-compose.yml": sha256Hex("other")})
+	assert.ErrorIs(t, err, ErrVerification)
+
+	_, err = (&GitHubRelease{TagName: "2.5.0"}).Checksums(v)
+	assert.ErrorIs(t, err, ErrVerification, "releases without checksums should be rejected")
+}
+
+func Test_NuvlaRelease(t *testing.T) {
+	srv := newReleasesServer(t, "tampered")
+	sums := Checksums{"docker-compose.yml": sha256Hex("compose")}
+
+	r, err := GetNuvlaRelease(srv.URL+"/nuvla/releases", "2.6.0")
+	assert.NoError(t, err)
+
+	workDir := t.TempDir()
+	_, err = r.GetComposeFiles([]string{"docker-compose.yml"}, workDir, sums)
+	assert.ErrorIs(t, err, ErrVerification)
+	_, err = os.Stat(filepath.Join(workDir, "docker-compose.yml"))
+	assert.True(t, os.IsNotExist(err), "files failing verification should not be saved")
+
+	_, err = GetNuvlaRelease(srv.URL+"/missing", "2.6.0")
+	assert.Error(t, err)
+}
@@ -0,0 +1,137 @@
+package release
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+const (
+	// ChecksumsAsset is the release asset listing the SHA-256 checksum of every file, in sha256sum format
+	ChecksumsAsset = "SHA256SUMS"
+	// SignatureAsset is the detached ed25519 signature of ChecksumsAsset, raw or base64 encoded
+	SignatureAsset = "SHA256SUMS.sig"
+)
+
+// ErrVerification is wrapped by all the errors of release files failing verification
+var ErrVerification = errors.New("release verification failed")
+
+// Checksums are the expected SHA-256 checksums of the release files, by file name
+type Checksums map[string]string
+
+// Verify asserts the content of the file matches its checksum. Files without checksum are rejected.
+func (c Checksums) Verify(name string, content []byte) error {
+	return c.VerifyReader(name, bytes.NewReader(content))
+}
+
+// VerifyReader is Verify for content read from r, e.g. files too large to be loaded in memory
+func (c Checksums) VerifyReader(name string, r io.Reader) error {
+	expected, ok := c[name]
+	if !ok {
+		return fmt.Errorf("%w: no checksum for %s", ErrVerification, name)
+	}
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return fmt.Errorf("error reading %s: %w", name, err)
+	}
+	if hex.EncodeToString(h.Sum(nil)) != expected {
+		return fmt.Errorf("%w: checksum mismatch for %s", ErrVerification, name)
+	}
+	return nil
+}
+
+// ParseChecksums reads a checksums file in sha256sum format: "<hex checksum>  <file name>" per line
+func ParseChecksums(data []byte) (Checksums, error) {
+	sums := make(Checksums)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%w: invalid checksums line %q", ErrVerification, line)
+		}
+		sum := strings.ToLower(fields[0])
+		if b, err := hex.DecodeString(sum); err != nil || len(b) != sha256.Size {
+			return nil, fmt.Errorf("%w: invalid checksum for %s", ErrVerification, fields[1])
+		}
+		// Binary mode is marked with a leading *
+		sums[strings.TrimPrefix(fields[1], "*")] = sum
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(sums) == 0 {
+		return nil, fmt.Errorf("%w: empty checksums file", ErrVerification)
+	}
+	return sums, nil
+}
+
+// Verifier checks the checksums files of the releases, and their signature if a public key is configured
+type Verifier struct {
+	publicKey ed25519.PublicKey
+}
+
+// NewVerifier loads the ed25519 public key, in PEM format, used to verify the signatures of the releases. Without
+// key file, only the checksums are verified.
+func NewVerifier(publicKeyFile string) (*Verifier, error) {
+	if publicKeyFile == "" {
+		return &Verifier{}, nil
+	}
+	// #nosec
+	data, err := os.ReadFile(publicKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("error reading release public key: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found in release public key %s", publicKeyFile)
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing release public key: %w", err)
+	}
+	edKey, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("release public key must be an ed25519 key, got %T", key)
+	}
+	return &Verifier{publicKey: edKey}, nil
+}
+
+// RequiresSignature tells whether the checksums files must be signed
+func (v *Verifier) RequiresSignature() bool {
+	return v.publicKey != nil
+}
+
+// Checksums verifies the signature of the checksums file, if required, and parses it
+func (v *Verifier) Checksums(checksums, signature []byte) (Checksums, error) {
+	if v.RequiresSignature() {
+		if len(signature) == 0 {
+			return nil, fmt.Errorf("%w: %s is not signed", ErrVerification, ChecksumsAsset)
+		}
+		sig := signature
+		if len(sig) != ed25519.SignatureSize {
+			decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(signature)))
+			if err != nil {
+				return nil, fmt.Errorf("%w: invalid signature encoding", ErrVerification)
+			}
+			sig = decoded
+		}
+		if !ed25519.Verify(v.publicKey, checksums, sig) {
+			return nil, fmt.Errorf("%w: invalid signature of %s", ErrVerification, ChecksumsAsset)
+		}
+	}
+	return ParseChecksums(checksums)
+}
@@ -0,0 +1,78 @@
+package release
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func sha256Hex(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+func writePublicKey(t *testing.T, pub ed25519.PublicKey) string {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	assert.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "release.pub")
+	assert.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0600))
+	return path
+}
+
+func Test_ParseChecksums(t *testing.T) {
+	data := fmt.Sprintf("# NuvlaEdge 2.6.0\n%s  docker-compose.yml\n%s *docker-compose.gpu.yml\n\n",
+		sha256Hex("compose"), sha256Hex("gpu"))
+	sums, err := ParseChecksums([]byte(data))
+	assert.NoError(t, err)
+	assert.Len(t, sums, 2)
+
+	assert.NoError(t, sums.Verify("docker-compose.yml", []byte("compose")))
+	assert.NoError(t, sums.Verify("docker-compose.gpu.yml", []byte("gpu")))
+	assert.ErrorIs(t, sums.Verify("docker-compose.yml", []byte("tampered")), ErrVerification)
+	assert.ErrorIs(t, sums.Verify("docker-compose.bluetooth.yml", []byte("")), ErrVerification)
+
+	_, err = ParseChecksums([]byte("abc docker-compose.yml"))
+	assert.ErrorIs(t, err, ErrVerification)
+	_, err = ParseChecksums([]byte(""))
+	assert.ErrorIs(t, err, ErrVerification)
+}
+
+func Test_Verifier(t *testing.T) {
+	checksums := []byte(sha256Hex("compose") + "  docker-compose.yml\n")
+
+	// Without key, only the checksums are verified
+	v, err := NewVerifier("")
+	assert.NoError(t, err)
+	assert.False(t, v.RequiresSignature())
+	_, err = v.Checksums(checksums, nil)
+	assert.NoError(t, err)
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	v, err = NewVerifier(writePublicKey(t, pub))
+	assert.NoError(t, err)
+	assert.True(t, v.RequiresSignature())
+
+	sig := ed25519.Sign(priv, checksums)
+	_, err = v.Checksums(checksums, sig)
+	assert.NoError(t, err, "raw signatures should be accepted")
+	_, err = v.Checksums(checksums, []byte(base64.StdEncoding.EncodeToString(sig)+"\n"))
+	assert.NoError(t, err, "base64 signatures should be accepted")
+
+	_, err = v.Checksums(checksums, nil)
+	assert.ErrorIs(t, err, ErrVerification, "unsigned checksums should be rejected")
+	_, err = v.Checksums([]byte(sha256Hex("tampered")+"  docker-compose.yml\n"), sig)
+	assert.ErrorIs(t, err, ErrVerification, "tampered checksums should be rejected")
+
+	_, err = NewVerifier(filepath.Join(t.TempDir(), "missing.pub"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,140 @@
+package release
+
+import (
+	"errors"
+	"fmt"
+	"github.com/Masterminds/semver/v3"
+	log "github.com/sirupsen/logrus"
+	"sort"
+	"strings"
+)
+
+// Channels of the releases considered when resolving a target version
+const (
+	ChannelStable     = "stable"      // Default, published stable releases only
+	ChannelPreRelease = "pre-release" // Published pre-releases too
+)
+
+// Version is a release available for updates, as published on GitHub and, when found there, in Nuvla
+type Version struct {
+	Version     string
+	PreRelease  bool
+	Published   bool
+	ReleaseDate string
+	Notes       string
+
+	semver *semver.Version
+}
+
+// ListVersions returns the releases of GitHub, the most recent first. The pre-release and published flags and the
+// release notes are the ones of Nuvla if it knows the release.
+func ListVersions(nuvlaEndpoint, githubEndpoint string) ([]Version, error) {
+	ghReleases, err := ListGitHubReleases(githubEndpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	nuvlaReleases := make(map[string]NuvlaReleaseResource)
+	if rel, err := ListNuvlaReleases(nuvlaEndpoint); err != nil {
+		log.Warnf("Cannot get the Nuvla releases, relying on GitHub only: %s", err)
+	} else {
+		for _, r := range rel {
+			nuvlaReleases[r.Release] = r
+		}
+	}
+
+	var versions []Version
+	for _, r := range ghReleases {
+		if r.Draft {
+			continue
+		}
+		sv, err := semver.NewVersion(r.TagName)
+		if err != nil {
+			log.Debugf("Ignoring release %s: %s", r.TagName, err)
+			continue
+		}
+		v := Version{
+			Version:     r.TagName,
+			PreRelease:  r.PreRelease || sv.Prerelease() != "",
+			Published:   true,
+			ReleaseDate: r.PublishedAt,
+			Notes:       r.Body,
+			semver:      sv,
+		}
+		if nr, ok := nuvlaReleases[r.TagName]; ok {
+			v.PreRelease = v.PreRelease || nr.PreRelease
+			v.Published = nr.Published
+			if nr.ReleaseDate != "" {
+				v.ReleaseDate = nr.ReleaseDate
+			}
+			if nr.ReleaseNotes != "" {
+				v.Notes = nr.ReleaseNotes
+			}
+		}
+		versions = append(versions, v)
+	}
+
+	sort.SliceStable(versions, func(i, j int) bool {
+		return versions[i].semver.GreaterThan(versions[j].semver)
+	})
+	return versions, nil
+}
+
+// ResolveVersion returns the most recent published version of the channel matching the target: a semver constraint
+// such as ~2.5, or empty or latest for any version. A target naming a version exactly selects it whatever its channel.
+func ResolveVersion(versions []Version, target, channel string) (*Version, error) {
+	switch channel {
+	case "", ChannelStable, ChannelPreRelease:
+	default:
+		return nil, fmt.Errorf("invalid channel %q, expected %s or %s", channel, ChannelStable, ChannelPreRelease)
+	}
+
+	for i := range versions {
+		if versions[i].Version == target || strings.TrimPrefix(versions[i].Version, "v") == target {
+			return &versions[i], nil
+		}
+	}
+
+	var constraint *semver.Constraints
+	if target != "" && target != "latest" {
+		c, err := semver.NewConstraint(target)
+		if err != nil {
+			return nil, fmt.Errorf("invalid target version %q: %w", target, err)
+		}
+		constraint = c
+	}
+
+	for i := range versions {
+		v := &versions[i]
+		if !v.Published || (v.PreRelease && channel != ChannelPreRelease) {
+			continue
+		}
+		if constraint == nil || constraint.Check(v.coreVersion()) {
+			return v, nil
+		}
+	}
+
+	if channel != ChannelPreRelease {
+		channel = ChannelStable
+	}
+	return nil, fmt.Errorf("no published %s release matches %q", channel, target)
+}
+
+// coreVersion returns the version without its pre-release part, so that constraints like ~2.5 match the
+// pre-releases of 2.5 in the pre-release channel
+func (v *Version) coreVersion() *semver.Version {
+	core, _ := v.semver.SetPrerelease("")
+	return &core
+}
+
+// IsDowngrade asserts whether the target version is older than the current one. Versions that are not semver, e.g.
+// development builds, are never downgrades.
+func IsDowngrade(current, target string) bool {
+	c, errC := semver.NewVersion(current)
+	t, errT := semver.NewVersion(target)
+	if err := errors.Join(errC, errT); err != nil {
+		log.Debugf("Cannot compare versions %q and %q: %s", current, target, err)
+		return false
+	}
+	return t.LessThan(c)
+}
@@ -0,0 +1,107 @@
+package release
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newVersionsServer(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/github/releases", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode([]GitHubRelease{
+			{TagName: "2.5.1", Body: "Fixes"},
+			{TagName: "2.7.0", Draft: true},
+			{TagName: "2.6.1-rc1", PreRelease: true},
+			{TagName: "2.6.0", Body: "GitHub notes"},
+			{TagName: "nightly"},
+			{TagName: "2.5.0"},
+		})
+	})
+	mux.HandleFunc("/nuvla/releases", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"resources": []NuvlaReleaseResource{
+				{Release: "2.5.0", Published: true},
+				{Release: "2.5.1", Published: true},
+				{Release: "2.6.0", Published: false, ReleaseNotes: "Nuvla notes"},
+				{Release: "2.6.1-rc1", Published: true, PreRelease: true},
+			},
+		})
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func Test_ListVersions(t *testing.T) {
+	srv := newVersionsServer(t)
+
+	versions, err := ListVersions(srv.URL+"/nuvla/releases", srv.URL+"/github/releases")
+	assert.NoError(t, err)
+	var names []string
+	for _, v := range versions {
+		names = append(names, v.Version)
+	}
+	assert.Equal(t, []string{"2.6.1-rc1", "2.6.0", "2.5.1", "2.5.0"}, names, "drafts and non semver tags should be skipped")
+	assert.True(t, versions[0].PreRelease)
+	assert.False(t, versions[1].Published)
+	assert.Equal(t, "Nuvla notes", versions[1].Notes)
+	assert.Equal(t, "Fixes", versions[2].Notes)
+
+	// Nuvla is optional
+	versions, err = ListVersions(srv.URL+"/missing", srv.URL+"/github/releases")
+	assert.NoError(t, err)
+	assert.True(t, versions[1].Published)
+}
+
+func Test_GetNuvlaRelease_Latest(t *testing.T) {
+	srv := newVersionsServer(t)
+
+	r, err := GetNuvlaRelease(srv.URL+"/nuvla/releases", "latest")
+	assert.NoError(t, err)
+	assert.Equal(t, "2.5.1", r.Release, "unpublished and pre-releases should be skipped")
+}
+
+func Test_ResolveVersion(t *testing.T) {
+	srv := newVersionsServer(t)
+	versions, err := ListVersions(srv.URL+"/nuvla/releases", srv.URL+"/github/releases")
+	assert.NoError(t, err)
+
+	tests := []struct {
+		target, channel, expected string
+	}{
+		{"", "", "2.5.1"},
+		{"latest", ChannelStable, "2.5.1"},
+		{"latest", ChannelPreRelease, "2.6.1-rc1"},
+		{"~2.5", "", "2.5.1"},
+		{"~2.6", ChannelPreRelease, "2.6.1-rc1"},
+		{"<2.5.1", "", "2.5.0"},
+		// Exact versions are selected even if unpublished or pre-releases
+		{"2.6.0", "", "2.6.0"},
+		{"2.6.1-rc1", ChannelStable, "2.6.1-rc1"},
+	}
+	for _, tt := range tests {
+		v, err := ResolveVersion(versions, tt.target, tt.channel)
+		if assert.NoError(t, err, tt.target) {
+			assert.Equal(t, tt.expected, v.Version, "%s in channel %q", tt.target, tt.channel)
+		}
+	}
+
+	_, err = ResolveVersion(versions, "~2.6", ChannelStable)
+	assert.Error(t, err)
+	_, err = ResolveVersion(versions, "not a version", "")
+	assert.Error(t, err)
+	_, err = ResolveVersion(versions, "latest", "nightly")
+	assert.Error(t, err)
+}
+
+func Test_IsDowngrade(t *testing.T) {
+	assert.True(t, IsDowngrade("2.6.0", "2.5.1"))
+	assert.True(t, IsDowngrade("2.6.0", "2.6.0-rc1"))
+	assert.False(t, IsDowngrade("2.6.0", "2.6.0"))
+	assert.False(t, IsDowngrade("2.5.1", "2.6.0"))
+	assert.False(t, IsDowngrade("dev", "2.5.1"))
+	assert.False(t, IsDowngrade("", "2.5.1"))
+}
@@ -0,0 +1,154 @@
+package updater
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"nuvlaedge-go/updater/common"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// credentialVariables are fragments of the names of the variables holding credentials
+var credentialVariables = []string{"API_KEY", "API_SECRET", "PASSWORD", "TOKEN"}
+
+const (
+	// SnapshotDir is the directory, in the working directory, where the installation is saved before an update
+	SnapshotDir  = ".nuvlaedge-rollback"
+	snapshotFile = "snapshot.json"
+)
+
+// Snapshot is the state of the installation before an update, used to bring it back if the update fails
+type Snapshot struct {
+	CreatedAt      time.Time `json:"created-at"`
+	Project        string    `json:"project"`
+	CurrentVersion string    `json:"current-version,omitempty"`
+	// Names of the compose files, copied to the snapshot directory
+	ComposeFiles []string `json:"compose-files"`
+	// Environment used to configure the installation. The credentials are only kept in memory, not saved.
+	Environment []string `json:"environment"`
+	// Images of every service, by service name
+	Images map[string]SnapshotImage `json:"images"`
+}
+
+// SnapshotImage is the image reference used by a service and the image it resolved to
+type SnapshotImage struct {
+	Name string `json:"name"`
+	ID   string `json:"id"`
+}
+
+// takeSnapshot saves the compose files, the environment and the images of the running installation
+func (du *DockerUpdater) takeSnapshot(ctx context.Context) (*Snapshot, error) {
+	snap := &Snapshot{
+		CreatedAt:      time.Now(),
+		Project:        du.opts.Project,
+		CurrentVersion: du.opts.CurrentVersion,
+		Environment:    slices.Clone(du.opts.Environment),
+		Images:         make(map[string]SnapshotImage),
+	}
+
+	containers, err := du.cs.GetProjectStatus(ctx, du.opts.Project)
+	if err != nil {
+		return nil, fmt.Errorf("error getting project status: %w", err)
+	}
+	for _, c := range containers {
+		data, err := du.dCli.ContainerInspect(ctx, c.ID)
+		if err != nil {
+			return nil, fmt.Errorf("error inspecting container %s: %w", c.Name, err)
+		}
+		snap.Images[c.Service] = SnapshotImage{Name: data.Config.Image, ID: data.Image}
+	}
+
+	if err := snap.saveFiles(du.opts.WorkingDir, du.opts.ComposeFiles); err != nil {
+		return nil, err
+	}
+	return snap, nil
+}
+
+// saveFiles copies the compose files found in the working directory and writes the snapshot description
+func (s *Snapshot) saveFiles(workDir string, composeFiles []string) error {
+	dir := filepath.Join(workDir, SnapshotDir)
+	if err := os.RemoveAll(dir); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+
+	s.ComposeFiles = nil
+	for _, f := range composeFiles {
+		name := filepath.Base(f)
+		// #nosec
+		content, err := os.ReadFile(filepath.Join(workDir, name))
+		if errors.Is(err, os.ErrNotExist) {
+			log.Infof("Compose file %s not found in the current installation", name)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := common.SaveFile(name, dir, string(content)); err != nil {
+			return err
+		}
+		s.ComposeFiles = append(s.ComposeFiles, name)
+	}
+	if len(s.ComposeFiles) == 0 {
+		return fmt.Errorf("none of the compose files %v found in %s", composeFiles, workDir)
+	}
+
+	saved := *s
+	saved.Environment = withoutCredentials(s.Environment)
+	b, err := json.MarshalIndent(saved, "", "  ")
+	if err != nil {
+		return err
+	}
+	return common.SaveFile(snapshotFile, dir, string(b))
+}
+
+// withoutCredentials returns the variables of env that do not hold credentials
+func withoutCredentials(env []string) []string {
+	var res []string
+	for _, e := range env {
+		name, _, _ := strings.Cut(e, "=")
+		if !slices.ContainsFunc(credentialVariables, func(c string) bool { return strings.Contains(name, c) }) {
+			res = append(res, e)
+		}
+	}
+	return res
+}
+
+// restoreFiles copies the compose files of the snapshot back to the working directory and returns their paths
+func (s *Snapshot) restoreFiles(workDir string) ([]string, error) {
+	var files []string
+	for _, name := range s.ComposeFiles {
+		// #nosec
+		content, err := os.ReadFile(filepath.Join(workDir, SnapshotDir, name))
+		if err != nil {
+			return nil, err
+		}
+		if err := common.SaveFile(name, workDir, string(content)); err != nil {
+			return nil, err
+		}
+		files = append(files, filepath.Join(workDir, name))
+	}
+	return files, nil
+}
+
+// LoadSnapshot reads the snapshot saved in the working directory by the last update
+func LoadSnapshot(workDir string) (*Snapshot, error) {
+	// #nosec
+	b, err := os.ReadFile(filepath.Join(workDir, SnapshotDir, snapshotFile))
+	if err != nil {
+		return nil, err
+	}
+	var s Snapshot
+	if err := json.Unmarshal(b, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
@@ -0,0 +1,74 @@
+package updater
+
+import (
+	"errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"nuvlaedge-go/types/options/command"
+	"nuvlaedge-go/updater/common"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func init() {
+	log.SetLevel(log.PanicLevel)
+}
+
+func Test_Snapshot_Files(t *testing.T) {
+	workDir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(workDir, "docker-compose.yml"), []byte("previous"), 0600))
+
+	snap := &Snapshot{
+		Project:        "nuvlaedge",
+		CurrentVersion: "2.5.2",
+		Environment:    []string{"NE_IMAGE_TAG=2.5.2", "NUVLAEDGE_API_KEY=credential/1", "NUVLAEDGE_API_SECRET=secret"},
+	}
+	err := snap.saveFiles(workDir, []string{"docker-compose.yml", "docker-compose.gpu.yml"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"docker-compose.yml"}, snap.ComposeFiles, "missing files should be skipped")
+
+	loaded, err := LoadSnapshot(workDir)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"NE_IMAGE_TAG=2.5.2"}, loaded.Environment, "the credentials should not be saved")
+	assert.Len(t, snap.Environment, 3, "the credentials should be kept to restore the installation")
+	assert.Equal(t, snap.ComposeFiles, loaded.ComposeFiles)
+
+	// The update overwrites the file
+	assert.NoError(t, os.WriteFile(filepath.Join(workDir, "docker-compose.yml"), []byte("update"), 0600))
+	files, err := loaded.restoreFiles(workDir)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(workDir, "docker-compose.yml")}, files)
+	content, _ := os.ReadFile(files[0])
+	assert.Equal(t, "previous", string(content))
+
+	err = (&Snapshot{}).saveFiles(t.TempDir(), []string{"docker-compose.yml"})
+	assert.Error(t, err, "an installation without compose files cannot be rolled back")
+}
+
+func Test_UpdateError(t *testing.T) {
+	cause := errors.New("NuvlaEdge restarted")
+
+	err := &UpdateError{Outcome: OutcomeRolledBack, Err: cause}
+	assert.ErrorIs(t, err, cause)
+	assert.Equal(t, common.UpdateRolledBackExitCode, err.ExitCode())
+	assert.Contains(t, err.Error(), "rolled back")
+
+	err = &UpdateError{Outcome: OutcomeRollbackFailed, Err: cause, RollbackErr: errors.New("image not found")}
+	assert.Equal(t, common.RollbackFailedExitCode, err.ExitCode())
+	assert.Contains(t, err.Error(), "image not found")
+
+	err = &UpdateError{Outcome: OutcomeFailed, Err: cause}
+	assert.Equal(t, common.UpdateFailedExitCode, err.ExitCode())
+}
+
+func Test_DockerUpdater_ValidateOpts(t *testing.T) {
+	du := &DockerUpdater{opts: &command.UpdateCmdOptions{Project: "nuvlaedge", TargetVersion: "2.6.0"}}
+	assert.NoError(t, du.ValidateOpts(), "current version should not be required")
+	assert.Equal(t, []string{"docker-compose.yml"}, du.opts.ComposeFiles)
+
+	du.opts.OnUpdateFailure = "retry"
+	assert.Error(t, du.ValidateOpts())
+	du.opts.OnUpdateFailure = common.OnFailureKeep
+	assert.NoError(t, du.ValidateOpts())
+}
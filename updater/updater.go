@@ -7,19 +7,35 @@ import (
 	"github.com/docker/compose/v2/pkg/api"
 	"github.com/docker/docker/client"
 	log "github.com/sirupsen/logrus"
+	neCommon "nuvlaedge-go/common"
 	"nuvlaedge-go/orchestrator"
 	"nuvlaedge-go/types"
 	"nuvlaedge-go/types/options/command"
+	"nuvlaedge-go/updater/common"
 	"nuvlaedge-go/updater/release"
+	"path/filepath"
 	"strings"
 	"time"
 )
 
-type Updater func(opts *command.UpdateCmdOptions) error
+type Updater func(ctx context.Context, opts *command.UpdateCmdOptions) error
 
-func GetUpdater() Updater {
-	// This is a comment
-	return UpdateWithCompose
+// GetUpdater returns the updater of the installation the command runs in. Bundles hold compose files and images, so
+// they always update docker installations.
+func GetUpdater(opts *command.UpdateCmdOptions) Updater {
+	if neCommon.IsRunningOnHost() && opts.Bundle == "" {
+		return UpdateHost
+	}
+	return UpdateWithDocker
+}
+
+// UpdateWithDocker updates a docker compose installation, rolling it back if the update fails
+func UpdateWithDocker(ctx context.Context, opts *command.UpdateCmdOptions) error {
+	du, err := NewDockerUpdater(opts)
+	if err != nil {
+		return &UpdateError{Outcome: OutcomeFailed, Err: err}
+	}
+	return du.Update(ctx)
 }
 
 /*
@@ -59,18 +75,51 @@ func NewDockerUpdater(opts *command.UpdateCmdOptions) (*DockerUpdater, error) {
 	}, nil
 }
 
+// Update applies the target release. The current installation is saved beforehand and, unless configured otherwise,
+// brought back up if the release doesn't start or NuvlaEdge isn't healthy afterwards.
 func (du *DockerUpdater) Update(ctx context.Context) error {
 	if err := du.ValidateOpts(); err != nil {
-		return err
+		return &UpdateError{Outcome: OutcomeFailed, Err: err}
 	}
 
 	// Remove invalid and empty environment variables
 	du.cleanEnvs()
 
-	// Get compose files
-	composeFiles, err := du.getComposeFiles(du.opts.ComposeFiles, du.opts.WorkingDir, du.opts.TargetVersion)
+	var bundle *Bundle
+	if du.opts.Bundle != "" {
+		b, err := du.openBundle()
+		if err != nil {
+			return &UpdateError{Outcome: OutcomeFailed, Err: err}
+		}
+		defer func() {
+			if err := b.Close(); err != nil {
+				log.Warnf("Error removing the extracted bundle: %s", err)
+			}
+		}()
+		bundle = b
+	} else if err := resolveTargetVersion(du.opts); err != nil {
+		return &UpdateError{Outcome: OutcomeFailed, Err: err}
+	}
+
+	snap, err := du.takeSnapshot(ctx)
+	if err != nil {
+		return &UpdateError{Outcome: OutcomeFailed, Err: fmt.Errorf("error saving the current installation: %w", err)}
+	}
+	log.Infof("Current installation saved in %s", filepath.Join(du.opts.WorkingDir, SnapshotDir))
+
+	// Get compose files, and the images for bundles
+	var composeFiles []string
+	if bundle != nil {
+		composeFiles, err = du.applyBundle(ctx, bundle)
+	} else {
+		composeFiles, err = getComposeFiles(du.opts)
+	}
 	if err != nil {
-		return err
+		// Nothing was started, only the files might have been overwritten
+		if _, errRestore := snap.restoreFiles(du.opts.WorkingDir); errRestore != nil {
+			log.Errorf("Error restoring the compose files: %s", errRestore)
+		}
+		return &UpdateError{Outcome: OutcomeFailed, Err: err}
 	}
 
 	// Start the deployment
@@ -79,24 +128,106 @@ func (du *DockerUpdater) Update(ctx context.Context) error {
 		Env:         du.opts.Environment,
 		ProjectName: du.opts.Project,
 		WorkingDir:  du.opts.WorkingDir,
+		NoPull:      bundle != nil,
 	})
+	if err != nil {
+		return du.onFailure(ctx, snap, fmt.Errorf("error starting deployment: %w", err))
+	}
+
+	if err = du.checkHealth(ctx, common.HealthCheckPeriod); err != nil {
+		return du.onFailure(ctx, snap, err)
+	}
 
+	log.Info("NuvlaEdge is healthy")
+	return nil
+}
+
+// openBundle extracts and validates the bundle of the update, which sets the target version
+func (du *DockerUpdater) openBundle() (*Bundle, error) {
+	verifier, err := release.NewVerifier(du.opts.PublicKeyFile)
 	if err != nil {
-		return fmt.Errorf("error starting deployment: %s", err)
+		return nil, err
+	}
+	b, err := OpenBundle(du.opts.Bundle, du.opts.WorkingDir, verifier)
+	if err != nil {
+		return nil, err
 	}
 
-	var checkPeriod = 10
+	if du.opts.TargetVersion != "" && du.opts.TargetVersion != b.Manifest.Version {
+		_ = b.Close()
+		return nil, fmt.Errorf("bundle is version %s, not the target version %s",
+			b.Manifest.Version, du.opts.TargetVersion)
+	}
+	du.opts.TargetVersion = b.Manifest.Version
+	if err := checkDowngrade(du.opts); err != nil {
+		_ = b.Close()
+		return nil, err
+	}
+	log.Infof("Updating from bundle %s to version %s", du.opts.Bundle, du.opts.TargetVersion)
+	return b, nil
+}
 
-	err = du.checkHealth(ctx, checkPeriod)
-	if err == nil {
-		log.Info("NuvlaEdge is healthy")
+// applyBundle loads the images of the bundle and installs its compose files, returning their paths
+func (du *DockerUpdater) applyBundle(ctx context.Context, b *Bundle) ([]string, error) {
+	if err := du.loadImages(ctx, b); err != nil {
+		return nil, err
+	}
+	return b.installComposeFiles(du.opts.ComposeFiles, du.opts.WorkingDir)
+}
 
-		return nil
+// onFailure rolls back the installation to the snapshot, unless the failed update has to be kept
+func (du *DockerUpdater) onFailure(ctx context.Context, snap *Snapshot, err error) error {
+	log.Warnf("Update failed: %s", err)
+	if du.opts.OnUpdateFailure == common.OnFailureKeep {
+		log.Warn("Keeping the failed update in place")
+		return &UpdateError{Outcome: OutcomeFailed, Err: err}
 	}
 
-	log.Warn("NuvlaEdge is not healthy, rolling back")
+	log.Warnf("Rolling back to version %s", snap.CurrentVersion)
+	if rbErr := du.rollback(ctx, snap); rbErr != nil {
+		log.Errorf("Rollback failed: %s", rbErr)
+		return &UpdateError{Outcome: OutcomeRollbackFailed, Err: err, RollbackErr: rbErr}
+	}
+	log.Info("NuvlaEdge rolled back and healthy")
+	return &UpdateError{Outcome: OutcomeRolledBack, Err: err}
+}
 
-	return nil
+// rollback brings the installation back to the snapshot: same compose files, environment and images. With hard
+// reset, the containers of the failed update are removed first instead of being recreated.
+func (du *DockerUpdater) rollback(ctx context.Context, snap *Snapshot) error {
+	files, err := snap.restoreFiles(du.opts.WorkingDir)
+	if err != nil {
+		return fmt.Errorf("error restoring compose files: %w", err)
+	}
+
+	// Tags might have been moved to the images of the update
+	for service, img := range snap.Images {
+		if img.ID == "" || img.Name == "" || strings.Contains(img.Name, "@") {
+			continue
+		}
+		if err := du.dCli.ImageTag(ctx, img.ID, img.Name); err != nil {
+			return fmt.Errorf("error restoring image %s of service %s: %w", img.Name, service, err)
+		}
+	}
+
+	if du.opts.HardReset {
+		if err := du.cs.Stop(ctx, &types.StopOpts{ProjectName: du.opts.Project}); err != nil {
+			return fmt.Errorf("error removing the failed update: %w", err)
+		}
+	}
+
+	err = du.cs.Start(ctx, &types.StartOpts{
+		CFiles:      files,
+		Env:         snap.Environment,
+		ProjectName: du.opts.Project,
+		WorkingDir:  du.opts.WorkingDir,
+		NoPull:      true,
+	})
+	if err != nil {
+		return fmt.Errorf("error starting previous version: %w", err)
+	}
+
+	return du.checkHealth(ctx, common.HealthCheckPeriod)
 }
 
 func (du *DockerUpdater) ValidateOpts() error {
@@ -115,10 +246,18 @@ func (du *DockerUpdater) ValidateOpts() error {
 	}
 
 	if du.opts.CurrentVersion == "" {
-		return errors.New("current version is required to allow for rolling back and recovery")
+		log.Warn("No current version provided, the rollback relies on the saved installation only")
 	}
 
-	if du.opts.TargetVersion == "" {
+	switch du.opts.OnUpdateFailure {
+	case "", common.OnFailureRollback, common.OnFailureKeep:
+	default:
+		return fmt.Errorf("invalid on-update-failure %q, expected %s or %s",
+			du.opts.OnUpdateFailure, common.OnFailureRollback, common.OnFailureKeep)
+	}
+
+	// The target version of bundles is the one of their manifest
+	if du.opts.TargetVersion == "" && du.opts.Bundle == "" {
 		if !du.opts.Force {
 			return errors.New("target version is required. Use --force to update without a target " +
 				"version to the latest available")
@@ -128,23 +267,30 @@ func (du *DockerUpdater) ValidateOpts() error {
 	return nil
 }
 
-func (du *DockerUpdater) checkHealth(ctx context.Context, period int) error {
-	ticker := time.NewTicker(time.Duration(period) * time.Second)
+// checkHealth monitors the agent during common.HealthCheckDuration. It is unhealthy if it stops or restarts.
+func (du *DockerUpdater) checkHealth(ctx context.Context, period time.Duration) error {
+	ticker := time.NewTicker(period)
 	defer ticker.Stop()
 
+	ctxCheck, cancel := context.WithTimeout(ctx, common.HealthCheckDuration)
+	defer cancel()
+
 	initialRestartCount := -1
 
 	for {
 		select {
-		case <-ctx.Done():
-			log.Info("Health check finished, NuvlaEDge is healthy")
+		case <-ctxCheck.Done():
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			log.Info("Health check finished, NuvlaEdge is healthy")
 
 			return nil
 		case <-ticker.C:
 			// Check health
 			health, err := du.monitorNuvlaEdge(ctx)
 			if err != nil {
-				log.Warn("Error monitoring NuvlaEdge: ", err)
+				return fmt.Errorf("error monitoring NuvlaEdge: %w", err)
 			}
 
 			if initialRestartCount == -1 {
@@ -152,19 +298,19 @@ func (du *DockerUpdater) checkHealth(ctx context.Context, period int) error {
 			}
 
 			if health.RestartCount > initialRestartCount {
-				log.Warn("NuvlaEdge restarted, rolling back")
+				log.Warn("NuvlaEdge restarted")
 
 				return errors.New("NuvlaEdge restarted, not healthy")
 			}
 
 			if !health.Running {
-				log.Warn("NuvlaEdge stopped, rolling back")
+				log.Warn("NuvlaEdge stopped")
 
 				return errors.New("NuvlaEdge stopped, not healthy")
 			}
 
 			if health.Status != "running" {
-				log.Warn("NuvlaEdge status is not running, rolling back")
+				log.Warn("NuvlaEdge status is not running")
 
 				return errors.New("NuvlaEdge status is not running, not healthy")
 			}
@@ -190,6 +336,9 @@ func (du *DockerUpdater) monitorNuvlaEdge(ctx context.Context) (NuvlaEdgeHealth,
 			break
 		}
 	}
+	if agent.ID == "" {
+		return neHealth, errors.New("agent container not found")
+	}
 
 	// Monitor the agent
 	data, err := du.dCli.ContainerInspect(ctx, agent.ID)
@@ -222,36 +371,6 @@ func (du *DockerUpdater) cleanEnvs() {
 	du.opts.Environment = newEnv
 }
 
-func (du *DockerUpdater) getComposeFiles(reqFiles []string, workDir string, version string) ([]string, error) {
-	nuvlaReleases, err := release.GetNuvlaRelease(version)
-	if err == nil {
-		files, err := nuvlaReleases.GetComposeFiles(reqFiles, workDir)
-		if err == nil {
-			return files, nil
-		}
-
-		log.Warn("Error getting compose files from Nuvla release: ", err)
-	}
-
-	log.Info("No Nuvla release found, trying GitHub release")
-
-	ghReleases, err := release.GetGitHubRelease(version)
-	if err != nil {
-		log.Errorf("Error getting GitHub release: %s", err)
-
-		return nil, err
-	}
-
-	files, err := ghReleases.GetComposeFiles(reqFiles, workDir)
-	if err != nil {
-		log.Errorf("Error getting compose files from GitHub release: %s", err)
-
-		return nil, err
-	}
-
-	return files, nil
-}
-
 // findConfigInEnvironment looks for the configuration in the environment. Particularly the image to update to.
 //
 //nolint:all
@@ -300,6 +419,50 @@ func (i *imageName) String() string {
 	return fmt.Sprintf("%s/%s/%s:%s", i.registry, i.organization, i.repository, i.tag)
 }
 
+// Outcome is the result of an update
+type Outcome string
+
+const (
+	OutcomeSuccess        Outcome = "success"
+	OutcomeFailed         Outcome = "failed"
+	OutcomeRolledBack     Outcome = "rolled back"
+	OutcomeRollbackFailed Outcome = "rollback failed"
+)
+
+// UpdateError is returned by a failed update. Its outcome tells whether the previous version was brought back.
+type UpdateError struct {
+	Outcome     Outcome
+	Err         error
+	RollbackErr error
+}
+
+func (e *UpdateError) Error() string {
+	switch e.Outcome {
+	case OutcomeRolledBack:
+		return fmt.Sprintf("update failed, rolled back to the previous version: %s", e.Err)
+	case OutcomeRollbackFailed:
+		return fmt.Sprintf("update failed: %s. Rollback to the previous version failed too: %s", e.Err, e.RollbackErr)
+	default:
+		return fmt.Sprintf("update failed: %s", e.Err)
+	}
+}
+
+func (e *UpdateError) Unwrap() error {
+	return e.Err
+}
+
+// ExitCode returns the exit code of the update command for the outcome
+func (e *UpdateError) ExitCode() int {
+	switch e.Outcome {
+	case OutcomeRolledBack:
+		return common.UpdateRolledBackExitCode
+	case OutcomeRollbackFailed:
+		return common.RollbackFailedExitCode
+	default:
+		return common.UpdateFailedExitCode
+	}
+}
+
 type NuvlaEdgeHealth struct {
 	RestartCount int
 	Running      bool
@@ -0,0 +1,39 @@
+package updater
+
+import (
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"nuvlaedge-go/types/options/command"
+	"nuvlaedge-go/updater/release"
+)
+
+// resolveTargetVersion sets the target version, which can be a constraint, to the release to update to. Downgrades
+// are refused unless forced.
+func resolveTargetVersion(opts *command.UpdateCmdOptions) error {
+	versions, err := release.ListVersions(opts.NuvlaReleases, opts.GitHubReleases)
+	if err != nil {
+		return fmt.Errorf("error listing releases: %w", err)
+	}
+	v, err := release.ResolveVersion(versions, opts.TargetVersion, opts.Channel)
+	if err != nil {
+		return err
+	}
+	if v.Version != opts.TargetVersion {
+		log.Infof("Target version %q resolved to %s", opts.TargetVersion, v.Version)
+	}
+	opts.TargetVersion = v.Version
+
+	return checkDowngrade(opts)
+}
+
+func checkDowngrade(opts *command.UpdateCmdOptions) error {
+	if !release.IsDowngrade(opts.CurrentVersion, opts.TargetVersion) {
+		return nil
+	}
+	if !opts.AllowDowngrade {
+		return fmt.Errorf("version %s is older than the current version %s. Use --allow-downgrade to downgrade",
+			opts.TargetVersion, opts.CurrentVersion)
+	}
+	log.Warnf("Downgrading from version %s to %s", opts.CurrentVersion, opts.TargetVersion)
+	return nil
+}
@@ -0,0 +1,21 @@
+package updater
+
+import (
+	"github.com/stretchr/testify/assert"
+	"nuvlaedge-go/types/options/command"
+	"testing"
+)
+
+func Test_CheckDowngrade(t *testing.T) {
+	opts := &command.UpdateCmdOptions{CurrentVersion: "2.6.0", TargetVersion: "2.5.1"}
+	assert.Error(t, checkDowngrade(opts))
+
+	opts.Force = true
+	assert.Error(t, checkDowngrade(opts), "forced updates should not downgrade")
+
+	opts.AllowDowngrade = true
+	assert.NoError(t, checkDowngrade(opts), "allowed downgrades should be accepted")
+
+	opts = &command.UpdateCmdOptions{CurrentVersion: "2.5.1", TargetVersion: "2.6.0"}
+	assert.NoError(t, checkDowngrade(opts))
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/nuvla/api-client-go/clients"
 	log "github.com/sirupsen/logrus"
 	"nuvlaedge-go/common"
+	"nuvlaedge-go/exporter"
 	"nuvlaedge-go/types"
 	"nuvlaedge-go/types/worker"
 )
@@ -66,18 +67,6 @@ func (c *Commissioner) getNodeIdFromStatus() string {
 
 func (c *Commissioner) Start(ctx context.Context) error {
 	log.Info("Starting Commissioner")
-
-	if c.client.GetStatusId() == "" {
-		log.Warnf("NuvlaEdge status not available, cannot start Commissioner")
-		return nil
-	}
-
-	go func() {
-		err := c.Run(ctx)
-		if err != nil {
-			log.Errorf("Error running Commissioner: %s", err)
-		}
-	}()
 	return nil
 }
 
@@ -88,10 +77,6 @@ func (c *Commissioner) Run(ctx context.Context) error {
 		select {
 		case <-ctx.Done():
 			log.Info("Commissioner stopped")
-			if err := c.Stop(ctx); err != nil {
-				log.Errorf("Failed to stop Commissioner: %s", err)
-				return err
-			}
 			return ctx.Err()
 
 		case d := <-c.commissionChan:
@@ -100,13 +85,11 @@ func (c *Commissioner) Run(ctx context.Context) error {
 			}
 
 		case <-c.BaseTicker.C:
-			if data, ok := c.needsCommissioning(); ok {
-				if err := c.commission(ctx, data); err != nil {
-					log.Errorf("Error commissioning: %s", err)
-				} else {
-					c.lastCommission = c.currentData
-				}
-			}
+			c.commissionIfNeeded(ctx)
+
+		case <-c.TriggerCh:
+			log.Info("Commissioning triggered")
+			c.commissionIfNeeded(ctx)
 
 		case conf := <-c.ConfChan:
 			log.Debug("Received configuration in commissioner: ", conf)
@@ -130,6 +113,19 @@ func (c *Commissioner) Stop(_ context.Context) error {
 	return nil
 }
 
+func (c *Commissioner) commissionIfNeeded(ctx context.Context) {
+	c.MarkRun()
+	if data, ok := c.needsCommissioning(); ok {
+		err := c.commission(ctx, data)
+		exporter.CommissionAttempts.WithLabelValues(exporter.Result(err)).Inc()
+		if err != nil {
+			log.Errorf("Error commissioning: %s", err)
+		} else {
+			c.lastCommission = c.currentData
+		}
+	}
+}
+
 func (c *Commissioner) needsCommissioning() (map[string]interface{}, bool) {
 	data, del := common.GetStructDiff(c.lastCommission, c.currentData)
 	if len(del) > 0 {
@@ -3,20 +3,51 @@ package workers
 import (
 	"context"
 	"errors"
+	"github.com/nuvla/api-client-go/clients/resources"
 	log "github.com/sirupsen/logrus"
+	"nuvlaedge-go/store"
 	"nuvlaedge-go/types"
+	"nuvlaedge-go/types/settings"
 	"nuvlaedge-go/types/worker"
 	"sync"
 	"time"
 )
 
+// RemoteConfig is the last configuration received from Nuvla. It is persisted so that the NuvlaEdge starts with it
+// instead of the defaults.
+type RemoteConfig struct {
+	LastUpdate      time.Time `json:"last-update"`
+	TelemetryPeriod int       `json:"telemetry-period"`
+	HeartbeatPeriod int       `json:"heartbeat-period"`
+}
+
+// RestoreRemoteConfig applies the last configuration received from Nuvla, if any, to the given configuration
+func RestoreRemoteConfig(s *store.Store, conf *worker.WorkerConfig) {
+	var rc RemoteConfig
+	if err := s.Get(store.ConfigKey, &rc); err != nil {
+		log.Debugf("No stored configuration from Nuvla: %s", err)
+		return
+	}
+
+	log.Infof("Restoring configuration received from Nuvla on %s", rc.LastUpdate.Format(time.RFC3339))
+	if rc.TelemetryPeriod > 0 {
+		conf.TelemetryPeriod = rc.TelemetryPeriod
+	}
+	if rc.HeartbeatPeriod > 0 {
+		conf.HeartBeatPeriod = rc.HeartbeatPeriod
+	}
+}
+
 type ConfUpdater struct {
 	worker.WorkerBase
 	client types.ConfUpdaterClient
+	store  *store.Store
 
 	lastUpdate time.Time
 
 	confChan       chan string
+	settingsChan   chan *settings.NuvlaEdgeSettings
+	stateChan      chan resources.NuvlaEdgeState
 	config         *worker.WorkerConfig
 	configChannels []chan *worker.WorkerConfig
 }
@@ -25,19 +56,21 @@ func (c *ConfUpdater) Init(opts *worker.WorkerOpts, conf *worker.WorkerConfig) e
 	c.WorkerBase = worker.NewWorkerBase(worker.ConfUpdater)
 	c.client = opts.NuvlaClient
 	c.confChan = opts.ConfLastUpdateCh
+	c.settingsChan = opts.SettingsCh
+	c.stateChan = opts.StateCh
 	c.config = conf
 	c.configChannels = opts.ConfigChannels
+	c.store = opts.Store
+
+	var rc RemoteConfig
+	if err := c.store.Get(store.ConfigKey, &rc); err == nil {
+		c.lastUpdate = rc.LastUpdate
+	}
 
 	return nil
 }
 
-func (c *ConfUpdater) Start(ctx context.Context) error {
-	go func() {
-		err := c.Run(ctx)
-		if err != nil {
-			log.Errorf("Error running Commissioner: %s", err)
-		}
-	}()
+func (c *ConfUpdater) Start(_ context.Context) error {
 	return nil
 }
 
@@ -45,16 +78,20 @@ func (c *ConfUpdater) Run(ctx context.Context) error {
 	for {
 		select {
 		case <-ctx.Done():
-			err := c.Stop(ctx)
-			if err != nil {
-				log.Error("Failed to stop ConfUpdater: ", err)
-				return err
-			}
+			log.Info("ConfUpdater stopped")
 			return ctx.Err()
 		case lastUpdate := <-c.confChan:
+			c.MarkRun()
 			if err := c.updateConfigIfNeeded(ctx, lastUpdate); err != nil {
 				log.Error("Failed to update config: ", err)
 			}
+		case s := <-c.settingsChan:
+			c.MarkRun()
+			log.Info("Applying reloaded local settings")
+			c.config.UpdateFromSettings(s)
+			if err := c.distributeConfig(c.config); err != nil {
+				log.Error("Failed to distribute reloaded settings: ", err)
+			}
 		case <-c.ConfChan:
 			// We need to listen to configuration changes even if we don't use them to prevent the channel from blocking
 		}
@@ -96,7 +133,7 @@ func (c *ConfUpdater) updateConfigIfNeeded(ctx context.Context, lastUpdateDate s
 	ctxCancel, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
-	err := c.client.UpdateResourceSelect(ctxCancel, []string{"refresh-interval", "heartbeat-interval"})
+	err := c.client.UpdateResourceSelect(ctxCancel, []string{"refresh-interval", "heartbeat-interval", "state"})
 	if err != nil {
 		log.Error("Failed to update resource: ", err)
 		return err
@@ -111,9 +148,35 @@ func (c *ConfUpdater) updateConfigIfNeeded(ctx context.Context, lastUpdateDate s
 	}
 
 	c.lastUpdate = *remoteTime
+	c.saveRemoteConfig()
+	c.notifyState(ctx, nuvlaEdgeRes.State)
 	return nil
 }
 
+// saveRemoteConfig persists the configuration once it has been distributed to the workers
+func (c *ConfUpdater) saveRemoteConfig() {
+	rc := RemoteConfig{
+		LastUpdate:      c.lastUpdate,
+		TelemetryPeriod: c.config.TelemetryPeriod,
+		HeartbeatPeriod: c.config.HeartBeatPeriod,
+	}
+	if err := c.store.Put(store.ConfigKey, rc); err != nil {
+		log.Errorf("Error storing configuration: %s", err)
+	}
+}
+
+// notifyState forwards the NuvlaEdge state received from Nuvla to the lifecycle handler
+func (c *ConfUpdater) notifyState(ctx context.Context, state resources.NuvlaEdgeState) {
+	if c.stateChan == nil || state == "" {
+		return
+	}
+
+	select {
+	case c.stateChan <- state:
+	case <-ctx.Done():
+	}
+}
+
 func (c *ConfUpdater) distributeConfig(conf *worker.WorkerConfig) error {
 	var wg sync.WaitGroup
 	log.Infof("Distributing new config to %d channels", len(c.configChannels))
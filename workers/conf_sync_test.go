@@ -0,0 +1,36 @@
+package workers
+
+import (
+	"github.com/stretchr/testify/assert"
+	"nuvlaedge-go/store"
+	"nuvlaedge-go/types/worker"
+	"testing"
+	"time"
+)
+
+func Test_ConfUpdater_RemoteConfig(t *testing.T) {
+	st, err := store.Open(t.TempDir())
+	assert.NoError(t, err)
+
+	conf := worker.NewDefaultWorkersConfig()
+	RestoreRemoteConfig(st, conf)
+	assert.Equal(t, worker.NewDefaultWorkersConfig(), conf, "nothing should change without stored config")
+
+	lastUpdate := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	c := &ConfUpdater{
+		store:      st,
+		lastUpdate: lastUpdate,
+		config:     &worker.WorkerConfig{TelemetryPeriod: 30, HeartBeatPeriod: 10},
+	}
+	c.saveRemoteConfig()
+
+	RestoreRemoteConfig(st, conf)
+	assert.Equal(t, 30, conf.TelemetryPeriod)
+	assert.Equal(t, 10, conf.HeartBeatPeriod)
+
+	restarted := &ConfUpdater{}
+	assert.NoError(t, restarted.Init(&worker.WorkerOpts{Store: st}, conf))
+	assert.True(t, restarted.lastUpdate.Equal(lastUpdate))
+	ok, _ := restarted.needsUpdate(lastUpdate.Format(time.RFC3339))
+	assert.False(t, ok, "an already applied config should not be fetched again")
+}
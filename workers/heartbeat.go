@@ -4,6 +4,8 @@ import (
 	"context"
 	log "github.com/sirupsen/logrus"
 	"nuvlaedge-go/common"
+	"nuvlaedge-go/common/constants"
+	"nuvlaedge-go/exporter"
 	"nuvlaedge-go/types"
 	"nuvlaedge-go/types/worker"
 	"time"
@@ -15,6 +17,9 @@ type Heartbeat struct {
 	client         types.HeartbeatClient
 	jobChan        chan string
 	confUpdateChan chan string
+	revocationChan chan error
+
+	authFailures int // Consecutive heartbeats rejected because of the credentials
 }
 
 func (h *Heartbeat) Init(opts *worker.WorkerOpts, conf *worker.WorkerConfig) error {
@@ -23,18 +28,13 @@ func (h *Heartbeat) Init(opts *worker.WorkerOpts, conf *worker.WorkerConfig) err
 	h.client = opts.NuvlaClient
 	h.jobChan = opts.JobCh
 	h.confUpdateChan = opts.ConfLastUpdateCh
+	h.revocationChan = opts.RevocationCh
 
 	return nil
 }
 
-func (h *Heartbeat) Start(ctx context.Context) error {
+func (h *Heartbeat) Start(_ context.Context) error {
 	// Maybe check here is NuvlaEdge status is commissioned, else wait
-	go func() {
-		err := h.Run(ctx)
-		if err != nil {
-			log.Errorf("Error running Commissioner: %s", err)
-		}
-	}()
 	return nil
 }
 
@@ -43,17 +43,15 @@ func (h *Heartbeat) Run(ctx context.Context) error {
 	for {
 		select {
 		case <-ctx.Done():
-			if err := h.Stop(ctx); err != nil {
-				log.Error("Failed to stop heartbeat worker: ", err)
-				return err
-			}
+			log.Info("Heartbeat stopped")
 			return ctx.Err()
 
 		case <-h.BaseTicker.C:
-			log.Info("Sending heartbeat")
-			if err := h.sendHeartbeat(ctx); err != nil {
-				log.Error("Failed to send heartbeat: ", err)
-			}
+			h.beat(ctx)
+
+		case <-h.TriggerCh:
+			log.Info("Heartbeat triggered")
+			h.beat(ctx)
 
 		case conf := <-h.ConfChan:
 			log.Debug("Received configuration in heartbeat: ", conf)
@@ -70,6 +68,40 @@ func (h *Heartbeat) Reconfigure(conf *worker.WorkerConfig) error {
 	return nil
 }
 
+func (h *Heartbeat) beat(ctx context.Context) {
+	log.Info("Sending heartbeat")
+	h.MarkRun()
+	exporter.Heartbeats.Inc()
+	err := h.sendHeartbeat(ctx)
+	if err != nil {
+		exporter.HeartbeatErrors.Inc()
+		log.Error("Failed to send heartbeat: ", err)
+	}
+	h.checkRevocation(err)
+}
+
+// checkRevocation reports the credentials as revoked once the heartbeat has been rejected RevocationThreshold times
+// in a row. The client already retries a fresh login before failing on unauthorized requests.
+func (h *Heartbeat) checkRevocation(err error) {
+	if !common.IsUnauthorized(err) {
+		h.authFailures = 0
+		return
+	}
+
+	h.authFailures++
+	log.Warnf("Heartbeat rejected by Nuvla, credentials might have been revoked (%d/%d)",
+		h.authFailures, constants.RevocationThreshold)
+	if h.authFailures < constants.RevocationThreshold || h.revocationChan == nil {
+		return
+	}
+
+	select {
+	case h.revocationChan <- err:
+	default:
+		// A revocation is already pending
+	}
+}
+
 func (h *Heartbeat) sendHeartbeat(ctx context.Context) error {
 	ctxTimed, cancel := context.WithTimeout(ctx, time.Duration(h.GetPeriod())*time.Second)
 	defer cancel()
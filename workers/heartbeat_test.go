@@ -5,6 +5,7 @@ import (
 	"errors"
 	"github.com/stretchr/testify/assert"
 	"net/http"
+	"nuvlaedge-go/common/constants"
 	"testing"
 )
 
@@ -31,3 +32,26 @@ func TestHeartbeat_sendHeartbeat(t *testing.T) {
 	err := h.sendHeartbeat(ctx)
 	assert.NotNil(t, err)
 }
+
+func TestHeartbeat_checkRevocation(t *testing.T) {
+	revocationCh := make(chan error, 1)
+	h := &Heartbeat{revocationChan: revocationCh}
+	unauthorized := errors.New("error re-authenticating: error logging in: 401 Unauthorized")
+
+	h.checkRevocation(unauthorized)
+	h.checkRevocation(unauthorized)
+	h.checkRevocation(nil)
+	assert.Equal(t, 0, h.authFailures, "a successful heartbeat should reset the failures")
+
+	h.checkRevocation(unauthorized)
+	h.checkRevocation(errors.New("connection refused"))
+	assert.Empty(t, revocationCh, "network errors should not count as revocation")
+
+	for i := 0; i < constants.RevocationThreshold; i++ {
+		h.checkRevocation(unauthorized)
+	}
+	assert.Len(t, revocationCh, 1)
+
+	h.checkRevocation(unauthorized)
+	assert.Len(t, revocationCh, 1, "a pending revocation should not block the heartbeat")
+}
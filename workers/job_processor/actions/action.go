@@ -4,8 +4,11 @@ import (
 	"context"
 	nuvla "github.com/nuvla/api-client-go"
 	"github.com/nuvla/api-client-go/clients/resources"
+	"nuvlaedge-go/common/constants"
 	"nuvlaedge-go/types/errors"
 	"nuvlaedge-go/workers/job_processor/executors"
+	"nuvlaedge-go/workers/job_processor/types"
+	"time"
 )
 
 type Action interface {
@@ -59,6 +62,63 @@ var ActionNameMap = map[ActionName]ActionName{
 	"coe_resource_actions": CoeResourceActions,
 }
 
+// idempotentActions can be executed again with the same result, e.g. after being interrupted by a restart
+var idempotentActions = map[ActionName]bool{
+	StateDeploymentActionName: true,
+	StartDeploymentActionName: true,
+	StopDeploymentActionName:  true,
+	UpdateNuvlaEdge:           true, // Collects the result of the update started before the restart
+}
+
+// defaultTimeouts is the maximum execution time of each action, unless configured otherwise
+var defaultTimeouts = map[ActionName]time.Duration{
+	RebootActionName:           30 * time.Minute, // Includes waiting for the running jobs, not for its schedule
+	StateDeploymentActionName:  time.Minute,
+	StartDeploymentActionName:  10 * time.Minute, // Includes pulling the images
+	UpdateDeploymentActionName: 20 * time.Minute, // Includes restoring the previous version if the update fails
+	StopDeploymentActionName:   constants.DefaultJobTimeout * time.Second,
+	CoeResourceActions:         constants.DefaultPullTimeout * time.Second,
+	UpdateNuvlaEdge:            30 * time.Minute, // Includes pulling the images of the release
+}
+
+// GetTimeout returns the maximum execution time of the action. The configured timeouts, in seconds, are looked up by
+// the exact action name first, e.g. deployment_state_10, then by the generic one, e.g. deployment_state. Zero means
+// no timeout, which is the default for the actions not implemented here.
+func GetTimeout(action string, configured map[string]int) time.Duration {
+	name := getActionNameFromString(action)
+	for _, k := range []string{action, string(name)} {
+		if t, ok := configured[k]; ok && t > 0 {
+			return time.Duration(t) * time.Second
+		}
+	}
+	return defaultTimeouts[name]
+}
+
+// GetScheduledTime returns the time the job is scheduled at by its payload, now for the jobs running right away. The
+// job processor waits for it without holding a slot of the pool.
+func GetScheduledTime(action, payload string, now time.Time) time.Time {
+	if getActionNameFromString(action) != RebootActionName {
+		return now
+	}
+	p, err := types.NewRebootPayloadFromString(payload)
+	if err != nil {
+		// The action reports the invalid payload
+		return now
+	}
+	return p.RebootTime(now)
+}
+
+// IsIdempotent asserts whether the action can safely be executed again
+func IsIdempotent(action string) bool {
+	return idempotentActions[getActionNameFromString(action)]
+}
+
+// IsNativeAction asserts whether the action is implemented here, as opposed to the legacy job engine
+func IsNativeAction(action string) bool {
+	_, err := GetAction(action)
+	return err == nil
+}
+
 func getActionNameFromString(action string) ActionName {
 	a, ok := ActionNameMap[ActionName(action)]
 	if !ok {
@@ -81,8 +141,8 @@ func GetAction(actionName string) (Action, error) {
 		return &DeploymentUpdate{}, nil
 	case CoeResourceActions:
 		return &COEResourceActions{}, nil
-	//case UpdateNuvlaEdge:
-	//	return &Update{}, nil
+	case UpdateNuvlaEdge:
+		return &NuvlaEdgeUpdate{}, nil
 	default:
 		return nil, errors.NewNotImplementedActionError(actionName)
 	}
@@ -1 +1,41 @@
 package actions
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func Test_IsIdempotent(t *testing.T) {
+	assert.True(t, IsIdempotent("deployment_state_10"))
+	assert.True(t, IsIdempotent("start_deployment"))
+	assert.True(t, IsIdempotent("nuvlabox_update"))
+	assert.False(t, IsIdempotent("reboot_nuvlabox"))
+	assert.False(t, IsIdempotent("unknown_action"))
+}
+
+func Test_IsNativeAction(t *testing.T) {
+	assert.True(t, IsNativeAction("reboot_nuvlabox"))
+	assert.True(t, IsNativeAction("nuvlabox_update"))
+	assert.False(t, IsNativeAction("nuvlabox_add_ssh_key"))
+}
+
+func Test_GetTimeout(t *testing.T) {
+	assert.Equal(t, time.Minute, GetTimeout("deployment_state_10", nil))
+	assert.Equal(t, 30*time.Minute, GetTimeout("nuvlabox_update", nil))
+	assert.Equal(t, time.Duration(0), GetTimeout("nuvlabox_add_ssh_key", nil), "legacy actions should have no default timeout")
+
+	configured := map[string]int{"deployment_state": 120, "deployment_state_60": 30, "nuvlabox_update": 900, "reboot_nuvlabox": 0}
+	assert.Equal(t, 2*time.Minute, GetTimeout("deployment_state_10", configured), "generic action timeout should apply")
+	assert.Equal(t, 30*time.Second, GetTimeout("deployment_state_60", configured), "exact action timeout should prevail")
+	assert.Equal(t, 15*time.Minute, GetTimeout("nuvlabox_update", configured))
+	assert.Equal(t, 30*time.Minute, GetTimeout("reboot_nuvlabox", configured), "invalid timeouts should be ignored")
+}
+
+func Test_GetScheduledTime(t *testing.T) {
+	now := time.Date(2024, 5, 1, 1, 0, 0, 0, time.UTC)
+	assert.Equal(t, now, GetScheduledTime("start_deployment", `{"delay": 60}`, now))
+	assert.Equal(t, now, GetScheduledTime("reboot_nuvlabox", "", now))
+	assert.Equal(t, now.Add(time.Minute), GetScheduledTime("reboot_nuvlabox", `{"delay": 60}`, now))
+	assert.Equal(t, now, GetScheduledTime("reboot_nuvlabox", `{"delay": -1}`, now), "invalid payloads are reported by the action")
+}
@@ -12,8 +12,11 @@ import (
 	"nuvlaedge-go/types/metrics"
 	"nuvlaedge-go/workers/job_processor/executors"
 	"strings"
+	"time"
 )
 
+const deploymentStateTimeout = 10 * time.Second
+
 type DeploymentBase struct {
 	ActionBase
 
@@ -213,6 +216,12 @@ func CloseDeploymentClientWithLog(client *clients.NuvlaDeploymentClient) {
 	}
 }
 
+// stateContext returns the context used to report the deployment state when the action fails, which still works if
+// the action context was cancelled or timed out
+func stateContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.WithoutCancel(ctx), deploymentStateTimeout)
+}
+
 func (d *DeploymentBase) CreateUserOutputParams(ctx context.Context) {
 	// Fixed parameters for all deployments, hostname and IPs. TODO: IP should be created by Nuvla...
 	ips, err := d.getIps(ctx)
@@ -5,7 +5,6 @@ import (
 	"github.com/nuvla/api-client-go/clients/resources"
 	log "github.com/sirupsen/logrus"
 	"nuvlaedge-go/workers/job_processor/executors"
-	"time"
 )
 
 type DeploymentStart struct {
@@ -16,24 +15,27 @@ func (d *DeploymentStart) ExecuteAction(ctx context.Context) error {
 	defer CloseDeploymentClientWithLog(d.client)
 	defer d.executor.Close()
 
-	ctxTimed, cancel := context.WithTimeout(ctx, 60*time.Second)
-	defer cancel()
-
-	if err := d.client.SetState(ctxTimed, resources.StateStarting); err != nil {
+	if err := d.client.SetState(ctx, resources.StateStarting); err != nil {
 		log.Warnf("Error setting deployment state to starting: %s", err)
 	}
 
-	if err := d.executor.StartDeployment(ctxTimed); err != nil {
-		if stateErr := d.client.SetState(ctxTimed, resources.StateError); stateErr != nil {
+	err := d.setUpRegistriesAuth(ctx)
+	if err == nil {
+		err = d.executor.StartDeployment(ctx)
+	}
+	if err != nil {
+		stateCtx, cancel := stateContext(ctx)
+		defer cancel()
+		if stateErr := d.client.SetState(stateCtx, resources.StateError); stateErr != nil {
 			log.Warnf("Error setting deployment state to error: %s", stateErr)
 		}
 		return err
 	}
 
 	// Creates nuvla output params if they don't exist or updates them
-	d.CreateUserOutputParams(ctxTimed)
+	d.CreateUserOutputParams(ctx)
 
-	if err := d.client.SetState(ctxTimed, resources.StateStarted); err != nil {
+	if err := d.client.SetState(ctx, resources.StateStarted); err != nil {
 		log.Warnf("Error setting deployment state to started: %s", err)
 	}
 
@@ -2,10 +2,10 @@ package actions
 
 import (
 	"context"
+	"errors"
 	"github.com/nuvla/api-client-go/clients/resources"
 	log "github.com/sirupsen/logrus"
 	"nuvlaedge-go/workers/job_processor/executors"
-	"time"
 )
 
 type DeploymentUpdate struct {
@@ -17,16 +17,26 @@ func (d *DeploymentUpdate) assertExecutor() error {
 }
 
 func (d *DeploymentUpdate) ExecuteAction(ctx context.Context) error {
-	ctxTimeout, cancel := context.WithTimeout(ctx, 60*time.Second)
-	defer cancel()
+	err := d.setUpRegistriesAuth(ctx)
+	if err == nil {
+		err = d.executor.UpdateDeployment(ctx)
+	}
+	if err != nil {
+		// The deployment is still started when the update kept or restored the previous version
+		state := resources.StateError
+		var updateErr executors.UpdateError
+		if errors.As(err, &updateErr) && updateErr.PreviousVersionRunning() {
+			state = resources.StateStarted
+		}
 
-	if err := d.executor.UpdateDeployment(ctxTimeout); err != nil {
-		if stateErr := d.client.SetState(ctxTimeout, resources.StateError); stateErr != nil {
-			log.Warnf("Error setting deployment state to error: %s", stateErr)
+		stateCtx, cancel := stateContext(ctx)
+		defer cancel()
+		if stateErr := d.client.SetState(stateCtx, state); stateErr != nil {
+			log.Warnf("Error setting deployment state to %s: %s", state, stateErr)
 		}
 		return err
 	}
-	if err := d.client.SetState(ctxTimeout, resources.StateStarted); err != nil {
+	if err := d.client.SetState(ctx, resources.StateStarted); err != nil {
 		log.Warnf("Error setting deployment state to started: %s", err)
 	}
 	return nil
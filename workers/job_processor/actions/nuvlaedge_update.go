@@ -0,0 +1,100 @@
+package actions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	nuvla "github.com/nuvla/api-client-go"
+	log "github.com/sirupsen/logrus"
+	"nuvlaedge-go/types/options/command"
+	"nuvlaedge-go/workers/job_processor/executors"
+	"nuvlaedge-go/workers/job_processor/types"
+	"strings"
+)
+
+// NuvlaEdgeUpdate updates the NuvlaEdge installation to the target release of the job. The update runs outside the
+// agent, which is replaced during the process, and is resumed by the new agent to report the result.
+type NuvlaEdgeUpdate struct {
+	ActionBase
+
+	updateOpts *command.UpdateCmdOptions
+	executor   executors.Updater
+}
+
+func (u *NuvlaEdgeUpdate) Init(ctx context.Context, optsFn ...ActionOptsFn) error {
+	opts := GetActionOpts(optsFn...)
+	if opts.JobResource == nil || opts.Client == nil {
+		return errors.New("jobs resource or client not available")
+	}
+
+	payload, err := types.NewPayloadFromString(opts.JobResource.Payload)
+	if err != nil {
+		return fmt.Errorf("error parsing update payload: %w", err)
+	}
+	if payload.TargetResource == nil && payload.TargetReleaseUUID != "" {
+		payload.TargetResource, err = getRelease(ctx, opts.Client, payload.TargetReleaseUUID)
+		if err != nil {
+			return fmt.Errorf("error retrieving target release %s: %w", payload.TargetReleaseUUID, err)
+		}
+	}
+
+	u.updateOpts = &command.UpdateCmdOptions{
+		JobId:          opts.JobId,
+		Environment:    payload.Environment,
+		Project:        payload.ProjectName,
+		WorkingDir:     payload.WorkingDir,
+		CurrentVersion: payload.CurrentVersion,
+		ComposeFiles:   payload.ConfigFiles,
+	}
+	if payload.TargetResource != nil {
+		u.updateOpts.TargetVersion = payload.TargetResource.Release
+		// Releases are picked explicitly in Nuvla, which allows going back to older ones
+		u.updateOpts.AllowDowngrade = true
+	}
+
+	if err := u.assertExecutor(); err != nil {
+		return err
+	}
+	log.Infof("NuvlaEdge update to release %s initialised with executor: %s",
+		u.updateOpts.TargetVersion, u.GetExecutorName())
+	return nil
+}
+
+func (u *NuvlaEdgeUpdate) GetExecutorName() executors.ExecutorName {
+	if u.executor == nil {
+		return ""
+	}
+	return u.executor.GetName()
+}
+
+func (u *NuvlaEdgeUpdate) assertExecutor() error {
+	ex, err := executors.GetUpdater()
+	if err != nil {
+		return err
+	}
+	u.executor = ex
+	return nil
+}
+
+func (u *NuvlaEdgeUpdate) ExecuteAction(ctx context.Context) error {
+	return u.executor.UpdateNuvlaEdge(ctx, u.updateOpts)
+}
+
+func (u *NuvlaEdgeUpdate) GetOutput() string {
+	if u.executor == nil {
+		return ""
+	}
+	return u.executor.GetOutput()
+}
+
+// getRelease retrieves a NuvlaEdge release, given either its id or its UUID
+func getRelease(ctx context.Context, client *nuvla.NuvlaClient, release string) (*types.NuvlaEdgeReleaseResource, error) {
+	if !strings.HasPrefix(release, "nuvlabox-release/") {
+		release = "nuvlabox-release/" + release
+	}
+	res, err := client.Get(ctx, release, nil)
+	if err != nil {
+		return nil, err
+	}
+	return types.NewReleaseResourceFromMap(res.Data)
+}
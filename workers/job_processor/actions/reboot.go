@@ -2,29 +2,138 @@ package actions
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	log "github.com/sirupsen/logrus"
 	"nuvlaedge-go/workers/job_processor/executors"
+	"nuvlaedge-go/workers/job_processor/types"
+	"os/exec"
+	"strings"
+	"time"
 )
 
+const (
+	// defaultDrainTimeout is the time given to the running jobs to finish before rebooting anyway
+	defaultDrainTimeout = 10 * time.Minute
+	// defaultStopTimeout is the time given to the deployment containers to stop before being killed
+	defaultStopTimeout = 30 * time.Second
+	// rebootHookTimeout is the maximum execution time of every pre-reboot hook
+	rebootHookTimeout = 5 * time.Minute
+	rebootProgressID  = "Reboot"
+)
+
+// DrainFunc waits for the jobs running besides the current one to finish
+type DrainFunc func(ctx context.Context) error
+
+type drainKey struct{}
+
+type rebootHooksKey struct{}
+
+// WithDrain returns a context providing the reboot with the function waiting for the other jobs
+func WithDrain(ctx context.Context, fn DrainFunc) context.Context {
+	return context.WithValue(ctx, drainKey{}, fn)
+}
+
+// WithRebootHooks returns a context providing the reboot with the commands to run before rebooting
+func WithRebootHooks(ctx context.Context, hooks []string) context.Context {
+	return context.WithValue(ctx, rebootHooksKey{}, hooks)
+}
+
 // RebootAction reboots the system. It can do it from a container or from the host.
-// The reboot action should be scheduled to allow the jobs to complete.
+// The reboot waits for the other jobs to complete. The job processor starts it at the time scheduled by the payload.
 type RebootAction struct {
 	ActionBase
 
 	executor executors.Rebooter
+	payload  *types.RebootJobPayload
+	output   strings.Builder
 }
 
-func (r *RebootAction) ExecuteAction(_ context.Context) error {
-	err := r.executor.Reboot()
-	if err != nil {
+func (r *RebootAction) ExecuteAction(ctx context.Context) error {
+	report := executors.ProgressFromContext(ctx)
+
+	report(executors.ProgressEvent{ID: rebootProgressID, Text: "Waiting for running jobs"})
+	if err := r.drain(ctx); err != nil {
+		return err
+	}
+
+	report(executors.ProgressEvent{ID: rebootProgressID, Text: "Running pre-reboot hooks"})
+	if err := r.runHooks(ctx, rebootHooksFromContext(ctx)); err != nil {
+		return err
+	}
+
+	if r.payload.StopDeployments {
+		report(executors.ProgressEvent{ID: rebootProgressID, Text: "Stopping deployments"})
+		timeout := defaultStopTimeout
+		if r.payload.StopTimeout > 0 {
+			timeout = time.Duration(r.payload.StopTimeout) * time.Second
+		}
+		if err := r.executor.StopDeployments(ctx, timeout); err != nil {
+			return fmt.Errorf("error stopping deployments: %w", err)
+		}
+	}
+
+	if err := r.executor.Reboot(ctx); err != nil {
 		log.Errorf("Error executing reboot: %s", err)
+		if r.payload.StopDeployments {
+			if startErr := executors.StartStoppedDeployments(ctx); startErr != nil {
+				log.Errorf("Error starting the deployments stopped: %s", startErr)
+			}
+		}
 		return err
 	}
-	// TODO: Reboot is scheduled to allow the jobs to complete
-	log.Infof("Reboot successfully scheduled")
+	report(executors.ProgressEvent{ID: rebootProgressID, Text: "Rebooting", Done: true})
+	log.Infof("Reboot successfully triggered")
 	return nil
 }
 
+// drain waits for the other jobs to finish. The reboot goes on after the drain timeout, the jobs still running are
+// then interrupted by the shutdown.
+func (r *RebootAction) drain(ctx context.Context) error {
+	fn, ok := ctx.Value(drainKey{}).(DrainFunc)
+	if !ok || fn == nil {
+		return nil
+	}
+	timeout := defaultDrainTimeout
+	if r.payload.DrainTimeout > 0 {
+		timeout = time.Duration(r.payload.DrainTimeout) * time.Second
+	}
+
+	ctxDrain, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	err := fn(ctxDrain)
+	switch {
+	case ctx.Err() != nil:
+		return ctx.Err()
+	case errors.Is(err, context.DeadlineExceeded):
+		log.Warnf("Jobs still running after %s, rebooting anyway", timeout)
+		return nil
+	default:
+		return err
+	}
+}
+
+// runHooks runs the hooks in order, in the environment of the agent. A failing hook cancels the reboot.
+func (r *RebootAction) runHooks(ctx context.Context, hooks []string) error {
+	for _, hook := range hooks {
+		log.Infof("Running pre-reboot hook: %s", hook)
+		ctxHook, cancel := context.WithTimeout(ctx, rebootHookTimeout)
+		// #nosec
+		out, err := exec.CommandContext(ctxHook, "sh", "-c", hook).CombinedOutput()
+		cancel()
+		r.output.Write(out)
+		if err != nil {
+			return fmt.Errorf("pre-reboot hook %q failed, reboot cancelled: %w", hook, err)
+		}
+	}
+	return nil
+}
+
+func rebootHooksFromContext(ctx context.Context) []string {
+	hooks, _ := ctx.Value(rebootHooksKey{}).([]string)
+	return hooks
+}
+
 func (r *RebootAction) GetExecutorName() executors.ExecutorName {
 	return r.executor.GetName()
 }
@@ -39,9 +148,19 @@ func (r *RebootAction) assertExecutor() error {
 	return nil
 }
 
-func (r *RebootAction) Init(_ context.Context, _ ...ActionOptsFn) error {
-	err := r.assertExecutor()
+func (r *RebootAction) Init(_ context.Context, optsFn ...ActionOptsFn) error {
+	opts := GetActionOpts(optsFn...)
+	var payload string
+	if opts.JobResource != nil {
+		payload = opts.JobResource.Payload
+	}
+	p, err := types.NewRebootPayloadFromString(payload)
 	if err != nil {
+		return fmt.Errorf("error parsing reboot payload: %w", err)
+	}
+	r.payload = p
+
+	if err := r.assertExecutor(); err != nil {
 		return err
 	}
 	log.Infof("Reboot actions initialised with executor: %s", r.GetExecutorName())
@@ -49,5 +168,5 @@ func (r *RebootAction) Init(_ context.Context, _ ...ActionOptsFn) error {
 }
 
 func (r *RebootAction) GetOutput() string {
-	return ""
+	return r.output.String()
 }
@@ -0,0 +1,69 @@
+package actions
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"nuvlaedge-go/workers/job_processor/executors"
+	"nuvlaedge-go/workers/job_processor/types"
+	"testing"
+	"time"
+)
+
+type fakeRebooter struct {
+	executors.ExecutorBase
+	rebooted    bool
+	stopTimeout time.Duration
+}
+
+func (f *fakeRebooter) Reboot(_ context.Context) error {
+	f.rebooted = true
+	return nil
+}
+
+func (f *fakeRebooter) StopDeployments(_ context.Context, timeout time.Duration) error {
+	f.stopTimeout = timeout
+	return nil
+}
+
+func Test_RebootAction_Execute(t *testing.T) {
+	ex := &fakeRebooter{}
+	r := &RebootAction{executor: ex, payload: &types.RebootJobPayload{StopDeployments: true, StopTimeout: 60}}
+	ctx := WithRebootHooks(context.Background(), []string{"echo syncing", "true"})
+
+	assert.NoError(t, r.ExecuteAction(ctx))
+	assert.True(t, ex.rebooted)
+	assert.Equal(t, time.Minute, ex.stopTimeout)
+	assert.Equal(t, "syncing\n", r.GetOutput())
+}
+
+func Test_RebootAction_FailingHook(t *testing.T) {
+	ex := &fakeRebooter{}
+	r := &RebootAction{executor: ex, payload: &types.RebootJobPayload{StopDeployments: true}}
+	ctx := WithRebootHooks(context.Background(), []string{"exit 1", "echo not run"})
+
+	assert.ErrorContains(t, r.ExecuteAction(ctx), "reboot cancelled")
+	assert.False(t, ex.rebooted)
+	assert.Zero(t, ex.stopTimeout, "deployments should not be stopped")
+	assert.Empty(t, r.GetOutput())
+}
+
+func Test_RebootAction_Drain(t *testing.T) {
+	// Jobs still running after the drain timeout don't prevent the reboot
+	ex := &fakeRebooter{}
+	r := &RebootAction{executor: ex, payload: &types.RebootJobPayload{DrainTimeout: 1}}
+	ctx := WithDrain(context.Background(), func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	assert.NoError(t, r.ExecuteAction(ctx))
+	assert.True(t, ex.rebooted)
+
+	ex = &fakeRebooter{}
+	r = &RebootAction{executor: ex, payload: &types.RebootJobPayload{}}
+	ctx = WithDrain(context.Background(), func(ctx context.Context) error {
+		return errors.New("drain failed")
+	})
+	assert.Error(t, r.ExecuteAction(ctx))
+	assert.False(t, ex.rebooted)
+}
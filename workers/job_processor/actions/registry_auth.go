@@ -0,0 +1,69 @@
+package actions
+
+import (
+	"context"
+	"fmt"
+	"github.com/docker/docker/api/types/registry"
+	"github.com/nuvla/api-client-go/types"
+	log "github.com/sirupsen/logrus"
+	"nuvlaedge-go/workers/job_processor/executors"
+)
+
+// resourceGetter retrieves resources from Nuvla, like the Nuvla clients
+type resourceGetter interface {
+	Get(ctx context.Context, resourceId string, selectFields []string) (*types.NuvlaResource, error)
+}
+
+// getRegistriesAuth retrieves the registry credentials and the endpoint of the registry each of them belongs to. The
+// secrets are neither logged nor part of the errors.
+func getRegistriesAuth(ctx context.Context, c resourceGetter, credentialIds []string) ([]registry.AuthConfig, error) {
+	auths := make([]registry.AuthConfig, 0, len(credentialIds))
+	for _, id := range credentialIds {
+		cred, err := c.Get(ctx, id, []string{"username", "password", "parent"})
+		if err != nil {
+			return nil, fmt.Errorf("error retrieving registry credential %s: %w", id, err)
+		}
+		username := getStringField(cred, "username")
+		password := getStringField(cred, "password")
+		parent := getStringField(cred, "parent")
+		if username == "" || password == "" || parent == "" {
+			return nil, fmt.Errorf("registry credential %s not available or lacks username, password or registry", id)
+		}
+
+		svc, err := c.Get(ctx, parent, []string{"endpoint"})
+		if err != nil {
+			return nil, fmt.Errorf("error retrieving registry %s: %w", parent, err)
+		}
+		endpoint := getStringField(svc, "endpoint")
+		if endpoint == "" {
+			return nil, fmt.Errorf("registry %s not available or lacks endpoint", parent)
+		}
+
+		log.Infof("Using registry credential %s for %s", id, endpoint)
+		auths = append(auths, executors.NewRegistryAuth(endpoint, username, password))
+	}
+	return auths, nil
+}
+
+func getStringField(res *types.NuvlaResource, field string) string {
+	if res == nil {
+		return ""
+	}
+	s, _ := res.Data[field].(string)
+	return s
+}
+
+// setUpRegistriesAuth passes the credentials of the private registries of the deployment to its executor
+func (d *DeploymentBase) setUpRegistriesAuth(ctx context.Context) error {
+	ids := d.deploymentResource.RegistriesCredentials
+	if len(ids) == 0 {
+		return nil
+	}
+
+	auths, err := getRegistriesAuth(ctx, d.client, ids)
+	if err != nil {
+		return err
+	}
+	d.executor.SetRegistriesAuth(auths)
+	return nil
+}
@@ -0,0 +1,51 @@
+package actions
+
+import (
+	"context"
+	"fmt"
+	"github.com/nuvla/api-client-go/types"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type fakeResourceGetter map[string]map[string]interface{}
+
+func (f fakeResourceGetter) Get(_ context.Context, resourceId string, _ []string) (*types.NuvlaResource, error) {
+	data, ok := f[resourceId]
+	if !ok {
+		return nil, fmt.Errorf("resource %s not found", resourceId)
+	}
+	return &types.NuvlaResource{Data: data}, nil
+}
+
+var testRegistries = fakeResourceGetter{
+	"credential/1":             {"username": "user", "password": "secret", "parent": "infrastructure-service/1"},
+	"credential/2":             {"username": "hub-user", "password": "hub-secret", "parent": "infrastructure-service/2"},
+	"credential/3":             {"username": "user", "parent": "infrastructure-service/1"},
+	"credential/4":             {"username": "user", "password": "secret", "parent": "infrastructure-service/3"},
+	"infrastructure-service/1": {"endpoint": "https://registry.example.com:5000"},
+	"infrastructure-service/2": {"endpoint": "https://index.docker.io/v1/"},
+}
+
+func Test_GetRegistriesAuth(t *testing.T) {
+	auths, err := getRegistriesAuth(context.Background(), testRegistries, []string{"credential/1", "credential/2"})
+	assert.NoError(t, err)
+	assert.Len(t, auths, 2)
+	assert.Equal(t, "registry.example.com:5000", auths[0].ServerAddress)
+	assert.Equal(t, "user", auths[0].Username)
+	assert.Equal(t, "secret", auths[0].Password)
+	assert.Equal(t, "https://index.docker.io/v1/", auths[1].ServerAddress)
+}
+
+func Test_GetRegistriesAuth_Errors(t *testing.T) {
+	tests := map[string]string{
+		"credential/0": "error retrieving registry credential credential/0",
+		"credential/3": "registry credential credential/3 not available",
+		"credential/4": "error retrieving registry infrastructure-service/3",
+	}
+	for id, expected := range tests {
+		_, err := getRegistriesAuth(context.Background(), testRegistries, []string{id})
+		assert.ErrorContains(t, err, expected)
+		assert.NotContains(t, err.Error(), "secret", "credentials should not leak into the errors")
+	}
+}
@@ -14,9 +14,10 @@ import (
 type COEResourceActions struct {
 	ActionBase
 
-	actions       ResourceActionsPayload
-	results       ResourceActionsResult
-	dockerHandler *resource_handler.DockerResourceHandler
+	actions           ResourceActionsPayload
+	results           ResourceActionsResult
+	dockerHandler     *resource_handler.DockerResourceHandler
+	kubernetesHandler *resource_handler.KubernetesResourceHandler
 }
 
 func (c *COEResourceActions) Init(_ context.Context, optsFn ...ActionOptsFn) error {
@@ -59,6 +60,12 @@ func (c *COEResourceActions) assertExecutor() error {
 			return err
 		}
 	}
+	if len(c.actions.Kubernetes) > 0 {
+		c.kubernetesHandler, err = resource_handler.NewKubernetesResourceHandler(nil)
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -67,15 +74,8 @@ func (c *COEResourceActions) ExecuteAction(ctx context.Context) error {
 		c.results.Docker = c.dockerHandler.HandleActions(ctx, c.actions.Docker)
 	}
 
-	// Kubernetes actions not supported
 	if len(c.actions.Kubernetes) > 0 {
-		c.results.Kubernetes = []resource_handler.ResourceActionResponse{
-			{
-				Success:    false,
-				Message:    "Kubernetes actions not supported",
-				ReturnCode: 500,
-			},
-		}
+		c.results.Kubernetes = c.kubernetesHandler.HandleActions(ctx, c.actions.Kubernetes)
 	}
 
 	return nil
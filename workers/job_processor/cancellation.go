@@ -0,0 +1,101 @@
+package job_processor
+
+import (
+	"context"
+	"errors"
+	"github.com/nuvla/api-client-go/clients/resources"
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+const (
+	// JobCanceledMessage is set as status message of the jobs cancelled from Nuvla while running
+	JobCanceledMessage = "Job cancelled"
+	// JobExpiredMessage is set as status message of the jobs refused because their expiry date passed
+	JobExpiredMessage = "Job expired before it could be executed"
+	// JobRefusedReturnCode is the return code of the jobs refused before execution
+	JobRefusedReturnCode = 1
+
+	// jobStateStopping is set by Nuvla when a running job is cancelled, until the job stops
+	jobStateStopping resources.JobState = "STOPPING"
+
+	// jobReportTimeout bounds the final state update of the jobs, which can't use the job context once done
+	jobReportTimeout = 10 * time.Second
+)
+
+// jobStateCheckPeriod is the period of the check of the running jobs state in Nuvla
+var jobStateCheckPeriod = 10 * time.Second
+
+var (
+	// errJobCanceled is the cause of the cancellation of the jobs cancelled from Nuvla
+	errJobCanceled = errors.New("job cancelled from Nuvla")
+	// errJobTimeout is the cause of the cancellation of the jobs running longer than their action timeout
+	errJobTimeout = errors.New("job timed out")
+)
+
+func isCanceledState(state resources.JobState) bool {
+	return state == resources.StateCanceled || state == jobStateStopping
+}
+
+// getJobState returns the current state of the job in Nuvla
+func (p *JobProcessor) getJobState(ctx context.Context, jobId string) (resources.JobState, error) {
+	if p.jobs == nil {
+		return "", errors.New("nuvla client not available")
+	}
+	res, err := p.jobs.Get(ctx, jobId, []string{"state"})
+	if err != nil {
+		return "", err
+	}
+	state, ok := res.Data["state"].(string)
+	if !ok {
+		return "", errors.New("job state not available")
+	}
+	return resources.JobState(state), nil
+}
+
+// watchJobState cancels the job with errJobCanceled as soon as it is cancelled from Nuvla. It returns when the job
+// context is done.
+func (p *JobProcessor) watchJobState(ctx context.Context, jobId string, cancel context.CancelCauseFunc) {
+	ticker := time.NewTicker(jobStateCheckPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		state, err := p.getJobState(ctx, jobId)
+		if err != nil {
+			log.Debugf("Cannot check state of job %s: %s", jobId, err)
+			continue
+		}
+		if isCanceledState(state) {
+			log.Warnf("Job %s cancelled from Nuvla, stopping it", jobId)
+			cancel(errJobCanceled)
+			return
+		}
+	}
+}
+
+// setCanceledState completes the cancellation of a job requested from Nuvla
+func (p *JobProcessor) setCanceledState(jobId string) {
+	ctx, cancel := context.WithTimeout(context.Background(), jobReportTimeout)
+	defer cancel()
+
+	if err := p.finishJob(ctx, jobId, resources.StateCanceled, JobCanceledMessage, 0); err != nil {
+		log.Errorf("Error setting cancelled state of job %s: %s", jobId, err)
+	}
+}
+
+// refuseJob fails a job that must not be executed, e.g. because it expired
+func (p *JobProcessor) refuseJob(jobId, message string) {
+	log.Warnf("Refusing job %s: %s", jobId, message)
+	ctx, cancel := context.WithTimeout(context.Background(), jobReportTimeout)
+	defer cancel()
+
+	if err := p.finishJob(ctx, jobId, resources.StateFailed, message, JobRefusedReturnCode); err != nil {
+		log.Errorf("Error setting failed state of job %s: %s", jobId, err)
+	}
+}
@@ -0,0 +1,91 @@
+package job_processor
+
+import (
+	"context"
+	"github.com/nuvla/api-client-go/clients/resources"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func Test_JobProcessor_watchJobState(t *testing.T) {
+	defer func(period time.Duration) { jobStateCheckPeriod = period }(jobStateCheckPeriod)
+	jobStateCheckPeriod = 10 * time.Millisecond
+
+	p := newTestProcessor()
+	p.jobs = &jobsClientMock{jobs: map[string]map[string]interface{}{
+		"job/running":   {"state": "RUNNING"},
+		"job/cancelled": {"state": "STOPPING"},
+	}}
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	done := make(chan struct{})
+	go func() {
+		p.watchJobState(ctx, "job/cancelled", cancel)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("cancelled job should be stopped")
+	}
+	assert.ErrorIs(t, context.Cause(ctx), errJobCanceled)
+
+	ctx, cancel = context.WithCancelCause(context.Background())
+	go p.watchJobState(ctx, "job/running", cancel)
+	time.Sleep(50 * time.Millisecond)
+	assert.NoError(t, ctx.Err(), "running job should not be stopped")
+	cancel(nil)
+}
+
+func Test_JobProcessor_waitScheduledTime(t *testing.T) {
+	defer func(period time.Duration) { jobStateCheckPeriod = period }(jobStateCheckPeriod)
+	jobStateCheckPeriod = 10 * time.Millisecond
+
+	p := newTestProcessor()
+	p.jobs = &jobsClientMock{jobs: map[string]map[string]interface{}{
+		"job/queued":    {"state": "QUEUED"},
+		"job/cancelled": {"state": "CANCELED"},
+	}}
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+	assert.NoError(t, p.waitScheduledTime(ctx, "job/queued", time.Now().Add(50*time.Millisecond), cancel))
+	assert.NoError(t, ctx.Err(), "the watch of the job should stop with the wait")
+
+	ctx, cancel = context.WithCancelCause(context.Background())
+	defer cancel(nil)
+	err := p.waitScheduledTime(ctx, "job/cancelled", time.Now().Add(time.Hour), cancel)
+	assert.ErrorIs(t, err, errJobCanceled, "jobs cancelled while waiting should not run")
+}
+
+func Test_JobProcessor_FinalStates(t *testing.T) {
+	p := newTestProcessor()
+	mock := &jobsClientMock{}
+	p.jobs = mock
+
+	p.setCanceledState("job/cancelled")
+	assert.Equal(t, resources.StateCanceled, mock.edits["job/cancelled"]["state"])
+	assert.Equal(t, JobCanceledMessage, mock.edits["job/cancelled"]["status-message"])
+
+	p.refuseJob("job/expired", JobExpiredMessage)
+	assert.Equal(t, resources.StateFailed, mock.edits["job/expired"]["state"])
+	assert.Equal(t, JobRefusedReturnCode, mock.edits["job/expired"]["return-code"])
+}
+
+func Test_JobBase_IsExpired(t *testing.T) {
+	j := &JobBase{JobId: "job/1"}
+	assert.False(t, j.IsExpired(), "job without resource should not expire")
+
+	j.JobResource = &resources.JobResource{}
+	assert.False(t, j.IsExpired(), "job without expiry should not expire")
+
+	j.JobResource.Expiry = time.Now().Add(-time.Minute).UTC().Format("2006-01-02T15:04:05.000Z")
+	assert.True(t, j.IsExpired())
+
+	j.JobResource.Expiry = time.Now().Add(time.Hour).UTC().Format("2006-01-02T15:04:05.000Z")
+	assert.False(t, j.IsExpired())
+
+	j.JobResource.Expiry = "tomorrow"
+	assert.False(t, j.IsExpired(), "invalid expiry should be ignored")
+}
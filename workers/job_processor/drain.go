@@ -0,0 +1,36 @@
+package job_processor
+
+import (
+	"context"
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+// drainCheckPeriod is the time between two checks of the running jobs while draining
+var drainCheckPeriod = 2 * time.Second
+
+// waitOtherJobs waits until no job other than jobId is running. The queued jobs are not waited for, they are left
+// queued in Nuvla by the shutdown.
+func (p *JobProcessor) waitOtherJobs(ctx context.Context, jobId string) error {
+	ticker := time.NewTicker(drainCheckPeriod)
+	defer ticker.Stop()
+
+	for {
+		var running []string
+		for _, j := range p.runningJobs.List() {
+			if j.JobId != jobId && !j.Queued {
+				running = append(running, j.JobId)
+			}
+		}
+		if len(running) == 0 {
+			return nil
+		}
+		log.Infof("Waiting for %d running jobs to finish: %v", len(running), running)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package job_processor
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"nuvlaedge-go/types/jobs"
+	"testing"
+	"time"
+)
+
+func Test_JobProcessor_WaitOtherJobs(t *testing.T) {
+	drainCheckPeriod = 10 * time.Millisecond
+	p := newTestProcessor()
+	p.runningJobs.Add(&jobs.RunningJob{JobId: "job/reboot"})
+	p.runningJobs.Add(&jobs.RunningJob{JobId: "job/queued", Queued: true})
+	p.runningJobs.Add(&jobs.RunningJob{JobId: "job/running"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, p.waitOtherJobs(ctx, "job/reboot"), context.DeadlineExceeded)
+
+	go func() {
+		time.Sleep(30 * time.Millisecond)
+		p.runningJobs.Remove("job/running")
+	}()
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	assert.NoError(t, p.waitOtherJobs(ctx, "job/reboot"), "queued jobs should not be waited for")
+}
@@ -9,15 +9,22 @@ import (
 	"github.com/docker/cli/cli/flags"
 	composeAPI "github.com/docker/compose/v2/pkg/api"
 	"github.com/docker/compose/v2/pkg/compose"
+	"github.com/docker/compose/v2/pkg/progress"
 	"github.com/nuvla/api-client-go/clients/resources"
 	log "github.com/sirupsen/logrus"
 	"io"
 	"nuvlaedge-go/common"
+	"nuvlaedge-go/common/constants"
 	"strings"
+	"sync"
 )
 
+// progressModeOnce sets the progress mode of compose, a global, before the first job uses it. Jobs run concurrently.
+var progressModeOnce sync.Once
+
 type ComposeExecutor struct {
 	ExecutorBase
+	registriesAuth
 
 	deploymentResource *resources.DeploymentResource
 	projectName        string
@@ -44,13 +51,14 @@ func (ce *ComposeExecutor) StartDeployment(ctx context.Context) error {
 		return err
 	}
 
+	ce.saveVersion(ctx)
 	return nil
 }
 
 func (ce *ComposeExecutor) StopDeployment(ctx context.Context) error {
 	ce.projectName = GetProjectNameFromDeploymentId(ce.deploymentResource.Id)
 
-	if err := ce.prepareComposeDown(); err != nil {
+	if err := ce.prepareComposeDown(ctx); err != nil {
 		return err
 	}
 
@@ -59,13 +67,16 @@ func (ce *ComposeExecutor) StopDeployment(ctx context.Context) error {
 		return err
 	}
 
+	if err := storeFromContext(ctx).Delete(versionKey(ce.projectName)); err != nil {
+		log.Warnf("Error removing the version of deployment %s: %s", ce.projectName, err)
+	}
 	return nil
 }
 
 func (ce *ComposeExecutor) GetServices(ctx context.Context) ([]DeploymentService, error) {
 	ce.projectName = GetProjectNameFromDeploymentId(ce.deploymentResource.Id)
 
-	if err := ce.setUpService(); err != nil {
+	if err := ce.setUpService(ctx); err != nil {
 		return nil, err
 	}
 	defer ce.dockerCli.Client().Close()
@@ -91,10 +102,6 @@ func (ce *ComposeExecutor) StateDeployment(_ context.Context) error {
 	return nil
 }
 
-func (ce *ComposeExecutor) UpdateDeployment(ctx context.Context) error {
-	return ce.StartDeployment(ctx)
-}
-
 func (ce *ComposeExecutor) getComposeFromDeployment() (string, error) {
 	if ce.deploymentResource.Module == nil ||
 		ce.deploymentResource.Module.Content == nil ||
@@ -139,11 +146,22 @@ func (ce *ComposeExecutor) setUpProject(ctx context.Context) error {
 	if ce.composeConfig == nil {
 		return fmt.Errorf("compose config is not set, cannot create the project")
 	}
-	p, err := loader.LoadWithContext(ctx, *ce.composeConfig, func(options *loader.Options) {
+	p, err := ce.loadProject(ctx, *ce.composeConfig)
+	if err != nil {
+		return err
+	}
+	ce.composeProject = p
+
+	return nil
+}
+
+// loadProject loads the compose project of the deployment, its services labelled with the deployment
+func (ce *ComposeExecutor) loadProject(ctx context.Context, config types.ConfigDetails) (*types.Project, error) {
+	p, err := loader.LoadWithContext(ctx, config, func(options *loader.Options) {
 		options.SetProjectName(ce.projectName, true)
 	})
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	for i, s := range p.Services {
@@ -154,21 +172,23 @@ func (ce *ComposeExecutor) setUpProject(ctx context.Context) error {
 			composeAPI.WorkingDirLabel:  "/",
 			composeAPI.ConfigFilesLabel: strings.Join(p.ComposeFiles, ","),
 			composeAPI.OneoffLabel:      "False", // default, will be overridden by `run` command
-			"nuvla.deployment":          ce.deploymentResource.Id,
+			constants.DeploymentLabel:   ce.deploymentResource.Id,
 		}
 		attach := false
 		s.Attach = &attach
 		p.Services[i] = s
 	}
-	ce.composeProject = p
-
-	return nil
+	return p, nil
 }
 
-func (ce *ComposeExecutor) setUpService() error {
+// setUpService creates the compose service. Its progress events are reported to the progress function of the context.
+func (ce *ComposeExecutor) setUpService(ctx context.Context) error {
 	ce.dockerOutPut = NewCaptureWriter()
 
-	dockerCli, err := command.NewDockerCli(command.WithCombinedStreams(ce.dockerOutPut))
+	// Compose writes the progress events as JSON so that they can be parsed back
+	progressModeOnce.Do(func() { progress.Mode = progress.ModeJSON })
+	out := composeOutput(ce.dockerOutPut, ProgressFromContext(ctx))
+	dockerCli, err := command.NewDockerCli(command.WithCombinedStreams(out))
 
 	if err != nil {
 		return err
@@ -194,14 +214,17 @@ func (ce *ComposeExecutor) prepareComposeUp(ctx context.Context) error {
 		return err
 	}
 
-	if err := ce.setUpService(); err != nil {
+	if err := ce.setUpService(ctx); err != nil {
 		return err
 	}
+
+	// Compose pulls the images with the credentials of the configuration of the docker CLI
+	ce.addToConfigFile(ce.dockerCli.ConfigFile())
 	return nil
 }
 
-func (ce *ComposeExecutor) prepareComposeDown() error {
-	if err := ce.setUpService(); err != nil {
+func (ce *ComposeExecutor) prepareComposeDown(ctx context.Context) error {
+	if err := ce.setUpService(ctx); err != nil {
 		return err
 	}
 	return nil
@@ -0,0 +1,205 @@
+package executors
+
+import (
+	"context"
+	"fmt"
+	"github.com/compose-spec/compose-go/v2/types"
+	composeAPI "github.com/docker/compose/v2/pkg/api"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	log "github.com/sirupsen/logrus"
+	"nuvlaedge-go/store"
+	"strings"
+	"time"
+)
+
+// updateWaitTimeout is the maximum time given to the containers of a new version to be running and healthy
+const updateWaitTimeout = 5 * time.Minute
+
+// updateStabilityPeriod is the time the containers of a new version must keep running once healthy, so that the ones
+// crashing shortly after starting are caught
+var updateStabilityPeriod = 10 * time.Second
+
+// composeVersion is a version of a compose deployment: its compose file and environment, and the IDs of the images
+// its services run, so that the very same images are restored
+type composeVersion struct {
+	Compose     string            `json:"compose"`
+	Environment map[string]string `json:"environment,omitempty"`
+	Images      map[string]string `json:"-"`
+}
+
+type storeKey struct{}
+
+// WithStore returns a context that makes the deployers keep the versions of the deployments running in s
+func WithStore(ctx context.Context, s *store.Store) context.Context {
+	return context.WithValue(ctx, storeKey{}, s)
+}
+
+// storeFromContext returns the store of the versions of the deployments. Without it, the versions are not kept.
+func storeFromContext(ctx context.Context) *store.Store {
+	s, _ := ctx.Value(storeKey{}).(*store.Store)
+	return s
+}
+
+// versionKey is the key of the version of a deployment in the store
+func versionKey(projectName string) string {
+	return store.DeploymentKeyPrefix + projectName
+}
+
+// UpdateDeployment pulls the images of the new version before touching the running containers. The update succeeds
+// once the containers of the new version are running and healthy, otherwise the previous version is restored.
+func (ce *ComposeExecutor) UpdateDeployment(ctx context.Context) error {
+	ce.projectName = GetProjectNameFromDeploymentId(ce.deploymentResource.Id)
+
+	if err := ce.prepareComposeUp(ctx); err != nil {
+		return err
+	}
+
+	previous, err := ce.getRunningVersion(ctx)
+	if err != nil {
+		return NewUpdateError(UpdateAborted, fmt.Errorf("error recording the version running: %w", err))
+	}
+	return ce.update(ctx, previous)
+}
+
+// update brings the new version up, restoring the previous one if the new version is not running and healthy
+func (ce *ComposeExecutor) update(ctx context.Context, previous *composeVersion) error {
+	if err := ce.composeService.Pull(ctx, ce.composeProject, composeAPI.PullOptions{}); err != nil {
+		return NewUpdateError(UpdateAborted, fmt.Errorf("error pulling images: %w", err))
+	}
+
+	err := ce.upAndWait(ctx, ce.composeProject)
+	if err == nil {
+		ce.saveVersion(ctx)
+		_, _ = fmt.Fprintln(ce.dockerOutPut, "Update succeeded, new version running")
+		return nil
+	}
+	log.Errorf("New version of deployment %s not running: %s", ce.projectName, err)
+
+	if previous == nil {
+		return NewUpdateError(UpdateFailed, err)
+	}
+
+	log.Infof("Restoring previous version of deployment %s", ce.projectName)
+	project, rbErr := ce.previousProject(ctx, previous)
+	if rbErr == nil {
+		rbErr = ce.upAndWait(ctx, project)
+	}
+	if rbErr != nil {
+		return NewUpdateError(UpdateRollbackFailed, fmt.Errorf("%w, restoring previous version: %s", err, rbErr))
+	}
+	return NewUpdateError(UpdateRolledBack, err)
+}
+
+// upAndWait brings the project up and waits for its containers to be running and healthy, and to keep running
+func (ce *ComposeExecutor) upAndWait(ctx context.Context, project *types.Project) error {
+	err := ce.composeService.Up(ctx, project, composeAPI.UpOptions{
+		Create: composeAPI.CreateOptions{RemoveOrphans: true, Inherit: true},
+		Start:  composeAPI.StartOptions{Project: project, Wait: true, WaitTimeout: updateWaitTimeout},
+	})
+	if err != nil {
+		return err
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(updateStabilityPeriod):
+	}
+
+	containers, err := ce.composeService.Ps(ctx, project.Name, composeAPI.PsOptions{All: true})
+	if err != nil {
+		return err
+	}
+	return checkContainersRunning(containers)
+}
+
+// checkContainersRunning returns an error listing the containers not running or unhealthy. Containers that completed
+// successfully, e.g. initialising the application, are not failing.
+func checkContainersRunning(containers []composeAPI.ContainerSummary) error {
+	var failing []string
+	for _, c := range containers {
+		if c.State == "exited" && c.ExitCode == 0 {
+			continue
+		}
+		if c.State != "running" || c.Health == "unhealthy" {
+			failing = append(failing, fmt.Sprintf("%s (%s)", c.Name, c.Status))
+		}
+	}
+	if len(failing) > 0 {
+		return fmt.Errorf("containers not running: %s", strings.Join(failing, ", "))
+	}
+	return nil
+}
+
+// getRunningVersion returns the version of the deployment running, nil if none
+func (ce *ComposeExecutor) getRunningVersion(ctx context.Context) (*composeVersion, error) {
+	containers, err := ce.dockerCli.Client().ContainerList(ctx, container.ListOptions{
+		All:     true,
+		Filters: filters.NewArgs(filters.Arg("label", composeAPI.ProjectLabel+"="+ce.projectName)),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(containers) == 0 {
+		return nil, nil
+	}
+
+	v := &composeVersion{}
+	if err := storeFromContext(ctx).Get(versionKey(ce.projectName), v); err != nil {
+		log.Warnf("Compose file of the version running not available, only its images can be restored: %s", err)
+	}
+	v.Images = make(map[string]string)
+	for _, c := range containers {
+		if s := c.Labels[composeAPI.ServiceLabel]; s != "" && v.Images[s] == "" {
+			v.Images[s] = c.ImageID
+			_, _ = fmt.Fprintf(ce.dockerOutPut, "Previous version of service %s runs image %s\n", s, c.ImageID)
+		}
+	}
+	return v, nil
+}
+
+// saveVersion keeps the compose file of the version running, restored if the next update fails
+func (ce *ComposeExecutor) saveVersion(ctx context.Context) {
+	compose, err := ce.getComposeFromDeployment()
+	if err != nil {
+		return
+	}
+	v := composeVersion{
+		Compose:     compose,
+		Environment: GetEnvironmentMappingFromContent(ce.deploymentResource.Module.Content),
+	}
+	if err := storeFromContext(ctx).Put(versionKey(ce.projectName), v); err != nil {
+		log.Warnf("Error saving the version of deployment %s: %s", ce.projectName, err)
+	}
+}
+
+// previousProject loads the project of the previous version with the images its services ran. Without its compose
+// file, the compose file of the new version is used.
+func (ce *ComposeExecutor) previousProject(ctx context.Context, previous *composeVersion) (*types.Project, error) {
+	config := *ce.composeConfig
+	if previous.Compose != "" {
+		config.ConfigFiles = []types.ConfigFile{
+			{Filename: "docker-compose.yml", Content: []byte(previous.Compose)},
+		}
+		config.Environment = previous.Environment
+	}
+
+	p, err := ce.loadProject(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	pinImages(p, previous.Images)
+	return p, nil
+}
+
+// pinImages makes the services run the images they ran before, by ID, which are still available locally
+func pinImages(p *types.Project, images map[string]string) {
+	for name, s := range p.Services {
+		if id := images[name]; id != "" {
+			s.Image = id
+			s.PullPolicy = types.PullPolicyNever
+			p.Services[name] = s
+		}
+	}
+}
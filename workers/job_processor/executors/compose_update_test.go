@@ -0,0 +1,184 @@
+package executors
+
+import (
+	"context"
+	"errors"
+	"github.com/compose-spec/compose-go/v2/types"
+	composeAPI "github.com/docker/compose/v2/pkg/api"
+	"github.com/nuvla/api-client-go/clients/resources"
+	"github.com/stretchr/testify/assert"
+	"nuvlaedge-go/store"
+	"testing"
+)
+
+const testCompose = `
+services:
+  web:
+    image: nginx:${TAG}
+`
+
+// fakeComposeService records the projects brought up. The containers listed after each up are the ones of upPs.
+type fakeComposeService struct {
+	composeAPI.Service
+
+	pullErr error
+	upErrs  []error
+	upPs    [][]composeAPI.ContainerSummary
+
+	upProjects []*types.Project
+}
+
+func (f *fakeComposeService) Pull(_ context.Context, _ *types.Project, _ composeAPI.PullOptions) error {
+	return f.pullErr
+}
+
+func (f *fakeComposeService) Up(_ context.Context, p *types.Project, opts composeAPI.UpOptions) error {
+	f.upProjects = append(f.upProjects, p)
+	if !opts.Start.Wait {
+		return errors.New("up should wait for the containers")
+	}
+	if i := len(f.upProjects) - 1; i < len(f.upErrs) {
+		return f.upErrs[i]
+	}
+	return nil
+}
+
+func (f *fakeComposeService) Ps(_ context.Context, _ string, _ composeAPI.PsOptions) ([]composeAPI.ContainerSummary, error) {
+	if i := len(f.upProjects) - 1; i < len(f.upPs) {
+		return f.upPs[i], nil
+	}
+	return nil, nil
+}
+
+var (
+	runningWeb = []composeAPI.ContainerSummary{{Name: "web", State: "running", Status: "Up 10 seconds"}}
+	crashedWeb = []composeAPI.ContainerSummary{{Name: "web", State: "exited", ExitCode: 1, Status: "Exited (1)"}}
+)
+
+// newTestComposeExecutor sets up the project of the test deployment, running the given tag, and skips the stability
+// period of the updates until the end of the test
+func newTestComposeExecutor(t *testing.T, tag string, service *fakeComposeService) *ComposeExecutor {
+	defaultPeriod := updateStabilityPeriod
+	updateStabilityPeriod = 0
+	t.Cleanup(func() { updateStabilityPeriod = defaultPeriod })
+
+	ce := &ComposeExecutor{
+		ExecutorBase: ExecutorBase{Name: ComposeExecutorName},
+		deploymentResource: &resources.DeploymentResource{
+			Id: testDeploymentId,
+			Module: &resources.ModuleResource{Content: &resources.ModuleApplicationResource{
+				DockerCompose:        testCompose,
+				EnvironmentVariables: []resources.EnvironmentVariable{{Name: "TAG", Value: tag}},
+			}},
+		},
+		projectName:    testNamespace,
+		composeService: service,
+		dockerOutPut:   NewCaptureWriter(),
+	}
+	assert.NoError(t, ce.setUpProjectConfig())
+	assert.NoError(t, ce.setUpProject(context.Background()))
+	return ce
+}
+
+func Test_ComposeExecutor_Update(t *testing.T) {
+	service := &fakeComposeService{upPs: [][]composeAPI.ContainerSummary{runningWeb}}
+	ce := newTestComposeExecutor(t, "1.27", service)
+	s, err := store.Open(t.TempDir())
+	assert.NoError(t, err)
+
+	ctx := WithStore(context.Background(), s)
+	assert.NoError(t, ce.update(ctx, &composeVersion{Images: map[string]string{"web": "sha256:old"}}))
+	assert.Len(t, service.upProjects, 1)
+	assert.Contains(t, ce.GetOutput(), "Update succeeded")
+
+	var saved composeVersion
+	assert.NoError(t, s.Get(versionKey(testNamespace), &saved), "the new version should be kept")
+	assert.Equal(t, testCompose, saved.Compose)
+	assert.Equal(t, map[string]string{"TAG": "1.27"}, saved.Environment)
+}
+
+func Test_ComposeExecutor_Update_PullFails(t *testing.T) {
+	service := &fakeComposeService{pullErr: errors.New("manifest unknown")}
+	ce := newTestComposeExecutor(t, "1.27", service)
+
+	err := ce.update(context.Background(), &composeVersion{})
+	var updateErr UpdateError
+	assert.ErrorAs(t, err, &updateErr)
+	assert.Equal(t, UpdateAborted, updateErr.Outcome)
+	assert.True(t, updateErr.PreviousVersionRunning())
+	assert.ErrorContains(t, err, "manifest unknown")
+	assert.Empty(t, service.upProjects, "running containers should not be touched")
+}
+
+func Test_ComposeExecutor_Update_RolledBack(t *testing.T) {
+	service := &fakeComposeService{upPs: [][]composeAPI.ContainerSummary{crashedWeb, runningWeb}}
+	ce := newTestComposeExecutor(t, "1.27", service)
+	s, err := store.Open(t.TempDir())
+	assert.NoError(t, err)
+	assert.NoError(t, s.Put(versionKey(testNamespace), composeVersion{
+		Compose:     testCompose,
+		Environment: map[string]string{"TAG": "1.25"},
+	}))
+
+	err = ce.update(WithStore(context.Background(), s), &composeVersion{
+		Compose:     testCompose,
+		Environment: map[string]string{"TAG": "1.25"},
+		Images:      map[string]string{"web": "sha256:old"},
+	})
+	var updateErr UpdateError
+	assert.ErrorAs(t, err, &updateErr)
+	assert.Equal(t, UpdateRolledBack, updateErr.Outcome)
+	assert.ErrorContains(t, err, "web (Exited (1))")
+
+	assert.Len(t, service.upProjects, 2)
+	assert.Equal(t, "nginx:1.27", service.upProjects[0].Services["web"].Image)
+	restored := service.upProjects[1].Services["web"]
+	assert.Equal(t, "sha256:old", restored.Image, "the previous image should be restored by ID")
+	assert.Equal(t, types.PullPolicyNever, restored.PullPolicy)
+	assert.Equal(t, testDeploymentId, restored.CustomLabels["nuvla.deployment"])
+
+	var saved composeVersion
+	assert.NoError(t, s.Get(versionKey(testNamespace), &saved))
+	assert.Equal(t, "1.25", saved.Environment["TAG"], "the failed version should not be kept")
+}
+
+func Test_ComposeExecutor_Update_RollbackFails(t *testing.T) {
+	service := &fakeComposeService{upErrs: []error{errors.New("unhealthy"), errors.New("no such image")}}
+	ce := newTestComposeExecutor(t, "1.27", service)
+
+	err := ce.update(context.Background(), &composeVersion{Images: map[string]string{"web": "sha256:old"}})
+	var updateErr UpdateError
+	assert.ErrorAs(t, err, &updateErr)
+	assert.Equal(t, UpdateRollbackFailed, updateErr.Outcome)
+	assert.False(t, updateErr.PreviousVersionRunning())
+	assert.ErrorContains(t, err, "no such image")
+
+	// Without its compose file, the previous images are restored with the new compose file
+	assert.Equal(t, "sha256:old", service.upProjects[1].Services["web"].Image)
+}
+
+func Test_ComposeExecutor_Update_NoPreviousVersion(t *testing.T) {
+	service := &fakeComposeService{upErrs: []error{errors.New("unhealthy")}}
+	ce := newTestComposeExecutor(t, "1.27", service)
+
+	err := ce.update(context.Background(), nil)
+	var updateErr UpdateError
+	assert.ErrorAs(t, err, &updateErr)
+	assert.Equal(t, UpdateFailed, updateErr.Outcome)
+	assert.Len(t, service.upProjects, 1)
+}
+
+func Test_CheckContainersRunning(t *testing.T) {
+	assert.NoError(t, checkContainersRunning(nil))
+	assert.NoError(t, checkContainersRunning([]composeAPI.ContainerSummary{
+		{Name: "web", State: "running", Health: "healthy"},
+		{Name: "init", State: "exited", ExitCode: 0},
+	}), "containers completed successfully are not failing")
+
+	err := checkContainersRunning([]composeAPI.ContainerSummary{
+		{Name: "web", State: "running", Health: "unhealthy", Status: "Up 1 minute (unhealthy)"},
+		{Name: "db", State: "restarting", Status: "Restarting (1)"},
+		{Name: "cache", State: "running"},
+	})
+	assert.EqualError(t, err, "containers not running: web (Up 1 minute (unhealthy)), db (Restarting (1))")
+}
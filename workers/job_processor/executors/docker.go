@@ -2,50 +2,51 @@ package executors
 
 import (
 	"context"
+	"fmt"
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/image"
-	"github.com/docker/docker/api/types/mount"
 	docker "github.com/docker/docker/client"
 	log "github.com/sirupsen/logrus"
 	"nuvlaedge-go/common/constants"
+	"strings"
+	"sync"
+	"time"
 )
 
 // Docker is the executor to use when running in a docker container and replaces host executor.
 type Docker struct {
 	ExecutorBase
+
+	// Output of the updater container
+	output   strings.Builder
+	outputMu sync.Mutex
 }
 
-func (d *Docker) Reboot() error {
-	ctx := context.Background()
+// Reboot cleanly reboots the host through its init system, from a privileged container entering the namespaces of the
+// host init process. The reboot is delayed by rebootDelay.
+func (d *Docker) Reboot(ctx context.Context) error {
 	client, err := docker.NewClientWithOpts(docker.FromEnv, docker.WithAPIVersionNegotiation())
 	if err != nil {
 		return err
 	}
+	defer client.Close()
 
 	result, err := client.ImagePull(ctx, constants.BaseImage, image.PullOptions{})
 	if err != nil {
-		log.Errorf("Failed to pull image: %s", err)
-		return err
-	}
-
-	if err = result.Close(); err != nil {
+		// The image might be available locally already
+		log.Warnf("Failed to pull image %s: %s", constants.BaseImage, err)
+	} else if err = result.Close(); err != nil {
 		log.Warnf("Failed to close image pull response: %s", err)
 	}
 
-	// Run a basic common container with the command "-c 'sleep 10 && echo b > /sysrq'"
-	// This will reboot the system after 10 seconds
 	response, err := client.ContainerCreate(ctx, &container.Config{
 		Image: constants.BaseImage,
-		Cmd:   []string{"sh", "-c", "sleep 10 && echo b > /sysrq"},
+		Cmd: []string{"sh", "-c", fmt.Sprintf("sleep %d && nsenter -t 1 -m -u -i -n -p -- %s",
+			int(rebootDelay.Seconds()), hostRebootCommand)},
 	}, &container.HostConfig{
 		AutoRemove: true,
-		Mounts: []mount.Mount{
-			{
-				Type:   mount.TypeBind,
-				Source: "/proc/sysrq-trigger",
-				Target: "/sysrq",
-			},
-		},
+		Privileged: true,
+		PidMode:    "host",
 	}, nil, nil, "")
 
 	if err != nil {
@@ -62,15 +63,15 @@ func (d *Docker) Reboot() error {
 	return client.ContainerStart(ctx, response.ID, container.StartOptions{})
 }
 
-func (d *Docker) InstallSSHKey(sshPub, user string) error {
-	return nil
+// StopDeployments gracefully stops the containers of the Nuvla deployments
+func (d *Docker) StopDeployments(ctx context.Context, timeout time.Duration) error {
+	return stopDeploymentContainers(ctx, timeout)
 }
 
-func (d *Docker) RevokeSSKKey(sshkey string) error {
+func (d *Docker) InstallSSHKey(sshPub, user string) error {
 	return nil
 }
 
-func (d *Docker) UpdateNuvlaEdge() error {
-
+func (d *Docker) RevokeSSKKey(sshkey string) error {
 	return nil
 }
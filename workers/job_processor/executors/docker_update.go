@@ -0,0 +1,285 @@
+package executors
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	docker "github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
+	log "github.com/sirupsen/logrus"
+	"nuvlaedge-go/common"
+	"nuvlaedge-go/types/options/command"
+	updater "nuvlaedge-go/updater/common"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	// updaterJobLabel links the updater container to the job that started it
+	updaterJobLabel = "nuvlaedge.update.job"
+
+	composeProjectLabel = "com.docker.compose.project"
+	composeServiceLabel = "com.docker.compose.service"
+	agentServiceName    = "agent"
+	dockerSocket        = "/var/run/docker.sock"
+
+	// Variables of the agent environment passed to the updater, e.g. UPDATE_PUBLIC_KEY_FILE
+	updaterEnvPrefix    = "UPDATE_"
+	updaterPublicKeyEnv = "UPDATE_PUBLIC_KEY_FILE"
+
+	// updaterProgressID identifies the update in the progress events
+	updaterProgressID = "NuvlaEdge update"
+)
+
+// UpdateNuvlaEdge runs the update in a separate container, created from the agent image, so that it carries on when
+// compose replaces the agent container. If the container of the job already exists, e.g. because the agent was
+// replaced while waiting for it, its result is collected instead of starting the update again.
+func (d *Docker) UpdateNuvlaEdge(ctx context.Context, opts *command.UpdateCmdOptions) error {
+	if opts.Project == "" || opts.WorkingDir == "" {
+		return errors.New("updating a docker installation requires its project name and working directory")
+	}
+
+	client, err := docker.NewClientWithOpts(docker.FromEnv, docker.WithAPIVersionNegotiation())
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	report := ProgressFromContext(ctx)
+
+	id, err := findUpdaterContainer(ctx, client, opts.JobId)
+	if err != nil {
+		return fmt.Errorf("error looking for the updater container: %w", err)
+	}
+	if id == "" {
+		report(ProgressEvent{ID: updaterProgressID, Text: "Starting updater"})
+		id, err = startUpdaterContainer(ctx, client, opts)
+		if err != nil {
+			return fmt.Errorf("error starting the updater container: %w", err)
+		}
+		log.Infof("Update of job %s running in container %s", opts.JobId, id)
+	} else {
+		log.Infof("Resuming update of job %s running in container %s", opts.JobId, id)
+	}
+
+	return d.waitUpdaterContainer(ctx, client, id, report)
+}
+
+// GetOutput returns the output of the updater container
+func (d *Docker) GetOutput() string {
+	d.outputMu.Lock()
+	defer d.outputMu.Unlock()
+	return d.output.String()
+}
+
+// waitUpdaterContainer follows the output of the updater until it exits and removes it. The container is left
+// untouched if the context is done first, so that the update can be resumed.
+func (d *Docker) waitUpdaterContainer(ctx context.Context, client docker.APIClient, id string, report ProgressFunc) error {
+	logsDone := make(chan struct{})
+	go func() {
+		defer close(logsDone)
+		logs, err := client.ContainerLogs(ctx, id, container.LogsOptions{ShowStdout: true, ShowStderr: true, Follow: true})
+		if err != nil {
+			log.Warnf("Cannot follow the updater container output: %s", err)
+			return
+		}
+		defer logs.Close()
+
+		out := &lineWriter{fn: func(line string) {
+			d.outputMu.Lock()
+			d.output.WriteString(line + "\n")
+			d.outputMu.Unlock()
+			if msg := logMessage(line); msg != "" {
+				report(ProgressEvent{ID: updaterProgressID, Text: msg})
+			}
+		}}
+		_, _ = stdcopy.StdCopy(out, out, logs)
+	}()
+
+	waitCh, errCh := client.ContainerWait(ctx, id, container.WaitConditionNotRunning)
+	var exitCode int64
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-errCh:
+		return fmt.Errorf("error waiting for the updater container: %w", err)
+	case res := <-waitCh:
+		if res.Error != nil {
+			return fmt.Errorf("error waiting for the updater container: %s", res.Error.Message)
+		}
+		exitCode = res.StatusCode
+	}
+	<-logsDone
+	report(ProgressEvent{ID: updaterProgressID, Text: "Update finished", Done: true})
+
+	if err := client.ContainerRemove(ctx, id, container.RemoveOptions{}); err != nil {
+		log.Warnf("Cannot remove the updater container %s: %s", id, err)
+	}
+
+	return updateResult(exitCode)
+}
+
+// updateResult translates the exit code of the update command into the outcome of the update
+func updateResult(exitCode int64) error {
+	switch exitCode {
+	case 0:
+		return nil
+	case int64(updater.UpdateRolledBackExitCode):
+		return errors.New("update failed, NuvlaEdge rolled back to the previous version")
+	case int64(updater.RollbackFailedExitCode):
+		return errors.New("update failed and the rollback to the previous version failed too")
+	default:
+		return fmt.Errorf("update failed with exit code %d", exitCode)
+	}
+}
+
+// findUpdaterContainer returns the id of the updater container of the job, if any
+func findUpdaterContainer(ctx context.Context, client docker.APIClient, jobId string) (string, error) {
+	containers, err := client.ContainerList(ctx, container.ListOptions{
+		All:     true,
+		Filters: filters.NewArgs(filters.Arg("label", updaterJobLabel+"="+jobId)),
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(containers) == 0 {
+		return "", nil
+	}
+	return containers[0].ID, nil
+}
+
+// startUpdaterContainer runs the update command in a container created from the image of the agent. It mounts the
+// docker socket of the agent and the working directory of the installation, at the same path as on the host.
+func startUpdaterContainer(ctx context.Context, client docker.APIClient, opts *command.UpdateCmdOptions) (string, error) {
+	agents, err := client.ContainerList(ctx, container.ListOptions{
+		All: true,
+		Filters: filters.NewArgs(
+			filters.Arg("label", composeProjectLabel+"="+opts.Project),
+			filters.Arg("label", composeServiceLabel+"="+agentServiceName)),
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(agents) == 0 {
+		return "", fmt.Errorf("agent container of project %s not found", opts.Project)
+	}
+	agent, err := client.ContainerInspect(ctx, agents[0].ID)
+	if err != nil {
+		return "", err
+	}
+
+	socket := dockerSocket
+	for _, m := range agent.Mounts {
+		if m.Destination == dockerSocket {
+			socket = m.Source
+		}
+	}
+
+	binds := []string{
+		socket + ":" + dockerSocket,
+		opts.WorkingDir + ":" + opts.WorkingDir,
+	}
+	// The release sources and public key configured for the agent apply to the updater. Keys out of the mounts of the
+	// agent are part of its image, which the updater runs too.
+	if key := os.Getenv(updaterPublicKeyEnv); key != "" {
+		if src, ok := hostPath(agent.Mounts, key); ok {
+			binds = append(binds, src+":"+key+":ro")
+		}
+	}
+
+	resp, err := client.ContainerCreate(ctx, &container.Config{
+		Image:  agent.Config.Image,
+		Cmd:    updaterCommand(opts),
+		Env:    common.GetEnvironWithPrefix(updaterEnvPrefix),
+		Labels: map[string]string{updaterJobLabel: opts.JobId},
+	}, &container.HostConfig{
+		NetworkMode: "host",
+		Binds:       binds,
+	}, nil, nil, opts.Project+"-update-"+jobUuid(opts.JobId))
+	if err != nil {
+		return "", err
+	}
+	for _, w := range resp.Warnings {
+		log.Warnf("Warning creating updater container: %s", w)
+	}
+
+	if err := client.ContainerStart(ctx, resp.ID, container.StartOptions{}); err != nil {
+		if errRm := client.ContainerRemove(ctx, resp.ID, container.RemoveOptions{Force: true}); errRm != nil {
+			log.Warnf("Cannot remove the updater container %s: %s", resp.ID, errRm)
+		}
+		return "", err
+	}
+	return resp.ID, nil
+}
+
+// hostPath returns the path in the host of the file p of a container, when p is in one of the container mounts. The
+// innermost mount holding p is used.
+func hostPath(mounts []types.MountPoint, p string) (string, bool) {
+	var src, dst string
+	for _, m := range mounts {
+		rel, err := filepath.Rel(m.Destination, p)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, "../") || len(m.Destination) < len(dst) {
+			continue
+		}
+		src, dst = filepath.Join(m.Source, rel), m.Destination
+	}
+	return src, src != ""
+}
+
+// updaterCommand returns the update command arguments matching the options
+func updaterCommand(opts *command.UpdateCmdOptions) []string {
+	cmd := []string{"update", "--job-id", opts.JobId, "--project", opts.Project, "--working-dir", opts.WorkingDir}
+	if opts.CurrentVersion != "" {
+		cmd = append(cmd, "--current-version", opts.CurrentVersion)
+	}
+	if opts.TargetVersion != "" {
+		cmd = append(cmd, "--target-version", opts.TargetVersion)
+	}
+	if opts.Force {
+		cmd = append(cmd, "--force")
+	}
+	if opts.AllowDowngrade {
+		cmd = append(cmd, "--allow-downgrade")
+	}
+	for _, f := range opts.ComposeFiles {
+		cmd = append(cmd, "--compose-files", csvField(f))
+	}
+	for _, e := range opts.Environment {
+		cmd = append(cmd, "--environment", csvField(e))
+	}
+	return cmd
+}
+
+// csvField quotes the value of a slice flag, which is parsed as CSV, if it contains commas or quotes
+func csvField(s string) string {
+	var b strings.Builder
+	w := csv.NewWriter(&b)
+	_ = w.Write([]string{s})
+	w.Flush()
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// logMessage extracts the message of a log line of the updater, e.g. time="..." level=info msg="Saving file"
+func logMessage(line string) string {
+	_, msg, ok := strings.Cut(line, "msg=")
+	if !ok {
+		return strings.TrimSpace(line)
+	}
+	if quoted, err := strconv.QuotedPrefix(msg); err == nil {
+		msg, _ = strconv.Unquote(quoted)
+		return msg
+	}
+	msg, _, _ = strings.Cut(msg, " ")
+	return msg
+}
+
+func jobUuid(jobId string) string {
+	parts := strings.Split(jobId, "/")
+	return parts[len(parts)-1]
+}
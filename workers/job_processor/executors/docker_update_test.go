@@ -0,0 +1,83 @@
+package executors
+
+import (
+	"github.com/docker/docker/api/types"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"nuvlaedge-go/cli/flags"
+	"nuvlaedge-go/types/options/command"
+	"testing"
+)
+
+func Test_UpdaterCommand(t *testing.T) {
+	opts := &command.UpdateCmdOptions{
+		JobId:          "job/1234",
+		Project:        "nuvlaedge",
+		WorkingDir:     "/home/user/nuvlaedge",
+		CurrentVersion: "2.5.2",
+		TargetVersion:  "2.6.0",
+		ComposeFiles:   []string{"docker-compose.yml", "docker-compose.gpu.yml"},
+		Environment:    []string{"NUVLAEDGE_UUID=nuvlabox/1234", "TAGS=a,b", `QUOTED="value"`},
+		AllowDowngrade: true,
+	}
+
+	args := updaterCommand(opts)
+	assert.Equal(t, "update", args[0])
+
+	// The update command must read back the same options
+	cmd := &cobra.Command{Use: "update"}
+	flags.AddUpdateFlags(cmd)
+	assert.NoError(t, cmd.Flags().Parse(args[1:]))
+
+	get := func(name string) string {
+		v, err := cmd.Flags().GetString(name)
+		assert.NoError(t, err)
+		return v
+	}
+	assert.Equal(t, opts.JobId, get("job-id"))
+	assert.Equal(t, opts.Project, get("project"))
+	assert.Equal(t, opts.WorkingDir, get("working-dir"))
+	assert.Equal(t, opts.CurrentVersion, get("current-version"))
+	assert.Equal(t, opts.TargetVersion, get("target-version"))
+	allowDowngrade, err := cmd.Flags().GetBool("allow-downgrade")
+	assert.NoError(t, err)
+	assert.True(t, allowDowngrade)
+
+	files, err := cmd.Flags().GetStringSlice("compose-files")
+	assert.NoError(t, err)
+	assert.Equal(t, opts.ComposeFiles, files)
+	env, err := cmd.Flags().GetStringSlice("environment")
+	assert.NoError(t, err)
+	assert.Equal(t, opts.Environment, env)
+}
+
+func Test_HostPath(t *testing.T) {
+	mounts := []types.MountPoint{
+		{Source: "/home/user/nuvlaedge", Destination: "/etc/nuvlaedge"},
+		{Source: "/home/user/keys", Destination: "/etc/nuvlaedge/keys"},
+	}
+
+	p, ok := hostPath(mounts, "/etc/nuvlaedge/release.pem")
+	assert.True(t, ok)
+	assert.Equal(t, "/home/user/nuvlaedge/release.pem", p)
+
+	p, ok = hostPath(mounts, "/etc/nuvlaedge/keys/release.pem")
+	assert.True(t, ok)
+	assert.Equal(t, "/home/user/keys/release.pem", p, "the innermost mount should be used")
+
+	_, ok = hostPath(mounts, "/etc/nuvlaedge-keys/release.pem")
+	assert.False(t, ok, "files out of the mounts are part of the image")
+}
+
+func Test_LogMessage(t *testing.T) {
+	assert.Equal(t, "Saving file: /home/user/docker-compose.yml",
+		logMessage(`time="2024-06-01T10:00:00Z" level=info msg="Saving file: /home/user/docker-compose.yml"`))
+	assert.Equal(t, "Triggering", logMessage(`time="2024-06-01T10:00:00Z" level=info msg=Triggering`))
+	assert.Equal(t, "Container nuvlaedge-agent-go Started", logMessage(" Container nuvlaedge-agent-go Started "))
+	assert.Equal(t, "", logMessage(""))
+}
+
+func Test_JobUuid(t *testing.T) {
+	assert.Equal(t, "1234", jobUuid("job/1234"))
+	assert.Equal(t, "1234", jobUuid("1234"))
+}
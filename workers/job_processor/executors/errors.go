@@ -14,3 +14,38 @@ func (e ComposeNotAvailableError) Error() string {
 func NewComposeNotAvailableError(deploymentId string, appType string) ComposeNotAvailableError {
 	return ComposeNotAvailableError{deploymentId: deploymentId, appType: appType}
 }
+
+// UpdateOutcome tells what happened to a deployment whose update failed
+type UpdateOutcome string
+
+const (
+	// UpdateAborted is the outcome of updates failing before touching the running version, e.g. pulling the images
+	UpdateAborted        UpdateOutcome = "update aborted, previous version kept running"
+	UpdateRolledBack     UpdateOutcome = "update failed, previous version restored"
+	UpdateRollbackFailed UpdateOutcome = "update failed, previous version could not be restored"
+	// UpdateFailed is the outcome of updates failing with no previous version running to restore
+	UpdateFailed UpdateOutcome = "update failed, no previous version to restore"
+)
+
+// UpdateError is returned when the update of a deployment fails, with what happened to the previous version
+type UpdateError struct {
+	Outcome UpdateOutcome
+	Err     error
+}
+
+func (e UpdateError) Error() string {
+	return string(e.Outcome) + ": " + e.Err.Error()
+}
+
+func (e UpdateError) Unwrap() error {
+	return e.Err
+}
+
+// PreviousVersionRunning tells whether the deployment still runs the version it ran before the update
+func (e UpdateError) PreviousVersionRunning() bool {
+	return e.Outcome == UpdateAborted || e.Outcome == UpdateRolledBack
+}
+
+func NewUpdateError(outcome UpdateOutcome, err error) UpdateError {
+	return UpdateError{Outcome: outcome, Err: err}
+}
@@ -15,8 +15,9 @@ func (e *ExecutorBase) GetName() ExecutorName {
 }
 
 const (
-	ComposeExecutorName ExecutorName = "compose"
-	HostExecutorName    ExecutorName = "host"
-	StackExecutorName   ExecutorName = "stack"
-	DockerExecutorName  ExecutorName = "docker"
+	ComposeExecutorName    ExecutorName = "compose"
+	HostExecutorName       ExecutorName = "host"
+	StackExecutorName      ExecutorName = "stack"
+	DockerExecutorName     ExecutorName = "docker"
+	KubernetesExecutorName ExecutorName = "kubernetes"
 )
@@ -1,22 +1,44 @@
 package executors
 
 import (
+	"context"
+	"fmt"
 	log "github.com/sirupsen/logrus"
+	"os/exec"
+	"time"
 )
 
 type Host struct {
 	ExecutorBase
+
+	// Output of the updater unit
+	output string
 }
 
-func (h *Host) Reboot() error {
-	// If we get to this point, we are running in the host and we can reboot. SUDO is already checked
-	if stdout, err := ExecuteCommand("reboot"); err != nil {
-		log.Errorf("Error executing reboot command: %s", stdout)
+// Reboot cleanly reboots the host, delayed by rebootDelay. SUDO is already checked.
+func (h *Host) Reboot(_ context.Context) error {
+	// A transient timer lets the agent report the job before systemd stops it
+	if _, err := exec.LookPath("systemd-run"); err == nil {
+		if stdout, err := ExecuteCommand("systemd-run", fmt.Sprintf("--on-active=%ds", int(rebootDelay.Seconds())),
+			"--description=NuvlaEdge reboot", "systemctl", "reboot"); err != nil {
+			log.Errorf("Error scheduling reboot: %s", stdout)
+			return err
+		}
+		return nil
+	}
+
+	if stdout, err := ExecuteCommand("shutdown", "-r", "now"); err != nil {
+		log.Errorf("Error executing shutdown command: %s", stdout)
 		return err
 	}
 	return nil
 }
 
+// StopDeployments gracefully stops the containers of the Nuvla deployments
+func (h *Host) StopDeployments(ctx context.Context, timeout time.Duration) error {
+	return stopDeploymentContainers(ctx, timeout)
+}
+
 func (h *Host) InstallSSHKey(sshPub, user string) error {
 	return nil
 }
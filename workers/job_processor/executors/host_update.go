@@ -0,0 +1,162 @@
+package executors
+
+import (
+	"context"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"nuvlaedge-go/types/errors"
+	"nuvlaedge-go/types/options/command"
+	"nuvlaedge-go/updater/common"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// updaterUnitPrefix names the transient systemd units running the updates, followed by the job UUID
+	updaterUnitPrefix = "nuvlaedge-update-"
+	// updaterCheckPeriod is the time between two checks of the updater unit state
+	updaterCheckPeriod = 3 * time.Second
+)
+
+// UpdateNuvlaEdge runs the update in a transient systemd unit, outside the service of the agent, so that it carries
+// on when the service is restarted with the new binary. If the unit of the job already exists, e.g. because the
+// agent was restarted while waiting for it, its result is collected instead of starting the update again.
+func (h *Host) UpdateNuvlaEdge(ctx context.Context, opts *command.UpdateCmdOptions) error {
+	if !IsSuperUser() {
+		return errors.NewActionRequiresSudoError("nuvlabox_update")
+	}
+	report := ProgressFromContext(ctx)
+	unit := updaterUnitPrefix + jobUuid(opts.JobId)
+
+	props, err := unitProperties(ctx, unit)
+	if err != nil {
+		return fmt.Errorf("error looking for the updater unit: %w", err)
+	}
+	if props["LoadState"] == "not-found" {
+		report(ProgressEvent{ID: updaterProgressID, Text: "Starting updater"})
+		if err := startUpdaterUnit(ctx, unit, opts); err != nil {
+			return fmt.Errorf("error starting the updater unit: %w", err)
+		}
+		log.Infof("Update of job %s running in unit %s", opts.JobId, unit)
+	} else {
+		log.Infof("Resuming update of job %s running in unit %s", opts.JobId, unit)
+	}
+
+	return h.waitUpdaterUnit(ctx, unit, report)
+}
+
+// GetOutput returns the output of the updater unit
+func (h *Host) GetOutput() string {
+	return h.output
+}
+
+// waitUpdaterUnit waits for the updater to exit, collects its output and clears the unit. The unit is left untouched
+// if the context is done first, so that the update can be resumed.
+func (h *Host) waitUpdaterUnit(ctx context.Context, unit string, report ProgressFunc) error {
+	ticker := time.NewTicker(updaterCheckPeriod)
+	defer ticker.Stop()
+
+	var exitCode int64
+	for done := false; !done; {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			props, err := unitProperties(ctx, unit)
+			if err != nil {
+				return fmt.Errorf("error checking the updater unit: %w", err)
+			}
+			if !updaterUnitExited(props) {
+				continue
+			}
+			exitCode, _ = strconv.ParseInt(props["ExecMainStatus"], 10, 64)
+			done = true
+		}
+	}
+
+	// #nosec
+	out, err := exec.CommandContext(ctx, "journalctl", "--unit", unit, "--output", "cat", "--no-pager").Output()
+	if err != nil {
+		log.Warnf("Cannot read the updater unit output: %s", err)
+	}
+	h.output = string(out)
+	report(ProgressEvent{ID: updaterProgressID, Text: "Update finished", Done: true})
+
+	for _, args := range [][]string{{"stop", unit}, {"reset-failed", unit}} {
+		// #nosec
+		if out, err := exec.CommandContext(ctx, "systemctl", args...).CombinedOutput(); err != nil {
+			log.Debugf("Cannot clear the updater unit %s: %s", unit, out)
+		}
+	}
+
+	return updateResult(exitCode)
+}
+
+// startUpdaterUnit runs the update command of the agent binary in a transient unit. The unit remains after the exit
+// of the command to keep its exit code.
+func startUpdaterUnit(ctx context.Context, unit string, opts *command.UpdateCmdOptions) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	if exe, err = filepath.EvalSymlinks(exe); err != nil {
+		return err
+	}
+
+	args := []string{"--unit=" + unit, "--description=NuvlaEdge update", "--property=RemainAfterExit=yes", exe}
+	args = append(args, updaterCommand(opts)...)
+	args = append(args, "--binary-path", exe, "--service-name", currentServiceName())
+
+	// #nosec
+	if out, err := exec.CommandContext(ctx, "systemd-run", args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("%w: %s", err, out)
+	}
+	return nil
+}
+
+// updaterUnitExited asserts whether the command of the unit, which remains after exit, finished
+func updaterUnitExited(props map[string]string) bool {
+	switch props["SubState"] {
+	case "exited", "failed", "dead":
+		return props["ActiveState"] != "activating"
+	default:
+		return false
+	}
+}
+
+func unitProperties(ctx context.Context, unit string) (map[string]string, error) {
+	// #nosec
+	out, err := exec.CommandContext(ctx, "systemctl", "show", unit,
+		"--property=LoadState,ActiveState,SubState,ExecMainStatus").Output()
+	if err != nil {
+		return nil, err
+	}
+	return common.ParseUnitProperties(string(out)), nil
+}
+
+// currentServiceName returns the systemd service running the agent, found in its cgroup
+func currentServiceName() string {
+	b, err := os.ReadFile("/proc/self/cgroup")
+	if err != nil {
+		return common.DefaultServiceName
+	}
+	return serviceFromCgroup(string(b))
+}
+
+// serviceFromCgroup extracts the service from the cgroup file, e.g. 0::/system.slice/nuvlaedge.service
+func serviceFromCgroup(cgroup string) string {
+	for _, line := range strings.Split(cgroup, "\n") {
+		i := strings.LastIndex(line, ":")
+		if i < 0 {
+			continue
+		}
+		if name, ok := strings.CutSuffix(filepath.Base(line[i+1:]), ".service"); ok && name != "" {
+			return name
+		}
+	}
+	return common.DefaultServiceName
+}
@@ -0,0 +1,26 @@
+package executors
+
+import (
+	"github.com/stretchr/testify/assert"
+	"nuvlaedge-go/updater/common"
+	"testing"
+)
+
+func Test_ServiceFromCgroup(t *testing.T) {
+	assert.Equal(t, "nuvlaedge-go", serviceFromCgroup("0::/system.slice/nuvlaedge-go.service\n"))
+	assert.Equal(t, "edge", serviceFromCgroup("12:pids:/system.slice/edge.service\n1:name=systemd:/system.slice/edge.service"))
+	assert.Equal(t, common.DefaultServiceName, serviceFromCgroup("0::/user.slice/user-1000.slice/session-2.scope\n"))
+}
+
+func Test_UpdaterUnitExited(t *testing.T) {
+	assert.False(t, updaterUnitExited(map[string]string{"ActiveState": "active", "SubState": "running"}))
+	assert.True(t, updaterUnitExited(map[string]string{"ActiveState": "active", "SubState": "exited"}))
+	assert.True(t, updaterUnitExited(map[string]string{"ActiveState": "failed", "SubState": "failed"}))
+}
+
+func Test_UpdateResult(t *testing.T) {
+	assert.NoError(t, updateResult(0))
+	assert.ErrorContains(t, updateResult(int64(common.UpdateRolledBackExitCode)), "rolled back")
+	assert.ErrorContains(t, updateResult(int64(common.RollbackFailedExitCode)), "rollback")
+	assert.ErrorContains(t, updateResult(1), "exit code 1")
+}
@@ -1,13 +1,23 @@
 package executors
 
+import (
+	"context"
+	"nuvlaedge-go/types/errors"
+	"time"
+)
+
 type Kubernetes struct {
 	ExecutorBase
 }
 
-func (k *Kubernetes) Reboot() error {
+func (k *Kubernetes) Reboot(_ context.Context) error {
 	return nil
 }
 
+func (k *Kubernetes) StopDeployments(_ context.Context, _ time.Duration) error {
+	return errors.NewNotImplementedActionError("stopping kubernetes deployments")
+}
+
 func (k *Kubernetes) InstallSSHKey(sshPub, user string) error {
 	return nil
 }
@@ -0,0 +1,623 @@
+package executors
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/nuvla/api-client-go/clients/resources"
+	log "github.com/sirupsen/logrus"
+	"io"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	corev1ac "k8s.io/client-go/applyconfigurations/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"nuvlaedge-go/common"
+	"nuvlaedge-go/common/constants"
+	"path"
+	"slices"
+	"strings"
+)
+
+const (
+	// kubernetesFieldManager owns the fields of the objects applied by the deployments
+	kubernetesFieldManager = "nuvlaedge"
+	// registriesSecretName is the image pull secret with the credentials of the private registries of the deployment
+	registriesSecretName = "nuvla-registries"
+)
+
+// KubernetesDeployer deploys the manifests of application_kubernetes modules, with server-side apply, in a namespace
+// dedicated to the deployment. Stopping the deployment removes the namespace.
+type KubernetesDeployer struct {
+	ExecutorBase
+	registriesAuth
+
+	deploymentResource *resources.DeploymentResource
+	namespace          string
+
+	// restConfig, when set, is used instead of the in-cluster or kubeconfig configuration
+	restConfig *rest.Config
+	clientSet  kubernetes.Interface
+	// apiResources caches the resources served by the API server, by group version
+	apiResources map[string]*metav1.APIResourceList
+
+	output strings.Builder
+}
+
+func (k *KubernetesDeployer) StartDeployment(ctx context.Context) error {
+	k.namespace = GetProjectNameFromDeploymentId(k.deploymentResource.Id)
+	log.Infof("Starting deployment in namespace %s", k.namespace)
+
+	objects, err := k.getObjectsFromDeployment()
+	if err != nil {
+		return err
+	}
+
+	if err := k.setUpClient(); err != nil {
+		return err
+	}
+
+	if err := k.applyNamespace(ctx); err != nil {
+		return fmt.Errorf("error creating namespace %s: %w", k.namespace, err)
+	}
+
+	if err := k.applyRegistriesSecret(ctx); err != nil {
+		return fmt.Errorf("error creating the secret of the private registries: %w", err)
+	}
+
+	report := ProgressFromContext(ctx)
+	for _, obj := range objects {
+		id := objectId(obj)
+		if err := k.apply(ctx, obj); err != nil {
+			report(ProgressEvent{ID: id, Text: "Error", Status: err.Error(), Done: true})
+			return fmt.Errorf("error applying %s: %w", id, err)
+		}
+		report(ProgressEvent{ID: id, Text: "Applied", Done: true})
+		_, _ = fmt.Fprintf(&k.output, "%s applied\n", id)
+	}
+	return nil
+}
+
+// StopDeployment removes the namespace of the deployment, with all its objects, and the cluster scoped objects of
+// the manifests
+func (k *KubernetesDeployer) StopDeployment(ctx context.Context) error {
+	k.namespace = GetProjectNameFromDeploymentId(k.deploymentResource.Id)
+
+	if err := k.setUpClient(); err != nil {
+		return err
+	}
+
+	// The manifests might have changed since the deployment started, then the objects removed from them are left
+	var errList []error
+	if objects, err := k.getObjectsFromDeployment(); err != nil {
+		log.Warnf("Cannot read the manifests of the deployment, only removing namespace %s: %s", k.namespace, err)
+	} else {
+		for _, obj := range objects {
+			if err := k.deleteClusterObject(ctx, obj); err != nil {
+				errList = append(errList, fmt.Errorf("error deleting %s: %w", objectId(obj), err))
+			}
+		}
+	}
+
+	err := k.clientSet.CoreV1().Namespaces().Delete(ctx, k.namespace, metav1.DeleteOptions{})
+	switch {
+	case err == nil:
+		_, _ = fmt.Fprintf(&k.output, "namespace/%s deleted\n", k.namespace)
+	case !apierrors.IsNotFound(err):
+		errList = append(errList, fmt.Errorf("error deleting namespace %s: %w", k.namespace, err))
+	}
+	return errors.Join(errList...)
+}
+
+// StateDeployment returns an error when the deployment is not running: its namespace is missing, the replicas of its
+// workloads are not ready or some of its pods failed
+func (k *KubernetesDeployer) StateDeployment(ctx context.Context) error {
+	k.namespace = GetProjectNameFromDeploymentId(k.deploymentResource.Id)
+
+	if err := k.setUpClient(); err != nil {
+		return err
+	}
+
+	_, err := k.clientSet.CoreV1().Namespaces().Get(ctx, k.namespace, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return fmt.Errorf("deployment not running: namespace %s not found", k.namespace)
+	}
+	if err != nil {
+		return err
+	}
+
+	failing, err := k.getNotRunning(ctx)
+	if err != nil {
+		return err
+	}
+	if len(failing) > 0 {
+		return fmt.Errorf("deployment not running: %s", strings.Join(failing, ", "))
+	}
+	return nil
+}
+
+// getNotRunning lists the workloads of the namespace without all their replicas ready and the pods failing
+func (k *KubernetesDeployer) getNotRunning(ctx context.Context) ([]string, error) {
+	var failing []string
+	notReady := func(kind, name string, ready, desired int32) {
+		if ready < desired {
+			failing = append(failing, fmt.Sprintf("%s/%s (%d/%d ready)", kind, name, ready, desired))
+		}
+	}
+
+	deployments, err := k.clientSet.AppsV1().Deployments(k.namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range deployments.Items {
+		notReady("deployment.apps", d.Name, d.Status.ReadyReplicas, replicas(d.Spec.Replicas))
+	}
+
+	statefulSets, err := k.clientSet.AppsV1().StatefulSets(k.namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range statefulSets.Items {
+		notReady("statefulset.apps", s.Name, s.Status.ReadyReplicas, replicas(s.Spec.Replicas))
+	}
+
+	daemonSets, err := k.clientSet.AppsV1().DaemonSets(k.namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range daemonSets.Items {
+		notReady("daemonset.apps", d.Name, d.Status.NumberReady, d.Status.DesiredNumberScheduled)
+	}
+
+	pods, err := k.clientSet.CoreV1().Pods(k.namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range pods.Items {
+		if reason := podFailure(p); reason != "" {
+			failing = append(failing, fmt.Sprintf("pod/%s (%s)", p.Name, reason))
+		}
+	}
+	return failing, nil
+}
+
+// replicas returns the desired replicas of a workload, which default to one
+func replicas(r *int32) int32 {
+	if r == nil {
+		return 1
+	}
+	return *r
+}
+
+// podWaitingFailures are the reasons of the containers waiting because they cannot be created or keep crashing
+var podWaitingFailures = []string{
+	"CrashLoopBackOff", "ErrImagePull", "ImagePullBackOff", "InvalidImageName", "CreateContainerConfigError",
+	"CreateContainerError",
+}
+
+// podFailure returns why the pod is failing, if it is
+func podFailure(p corev1.Pod) string {
+	if p.Status.Phase == corev1.PodFailed {
+		return string(p.Status.Phase)
+	}
+	for _, c := range p.Status.ContainerStatuses {
+		if w := c.State.Waiting; w != nil && slices.Contains(podWaitingFailures, w.Reason) {
+			return w.Reason
+		}
+	}
+	return ""
+}
+
+// UpdateDeployment applies the manifests of the deployment and removes the objects of the deployment no longer in them
+func (k *KubernetesDeployer) UpdateDeployment(ctx context.Context) error {
+	if err := k.StartDeployment(ctx); err != nil {
+		return err
+	}
+
+	objects, err := k.getObjectsFromDeployment()
+	if err != nil {
+		return err
+	}
+	return k.prune(ctx, objects)
+}
+
+// GetServices returns the pods and the services of the namespace of the deployment
+func (k *KubernetesDeployer) GetServices(ctx context.Context) ([]DeploymentService, error) {
+	k.namespace = GetProjectNameFromDeploymentId(k.deploymentResource.Id)
+
+	if err := k.setUpClient(); err != nil {
+		return nil, err
+	}
+
+	pods, err := k.clientSet.CoreV1().Pods(k.namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		log.Errorf("Error retrieving pods of namespace %s", k.namespace)
+		return nil, err
+	}
+	svcs, err := k.clientSet.CoreV1().Services(k.namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		log.Errorf("Error retrieving services of namespace %s", k.namespace)
+		return nil, err
+	}
+
+	services := make([]DeploymentService, 0, len(pods.Items)+len(svcs.Items))
+	for _, p := range pods.Items {
+		services = append(services, NewDeploymentKubernetesServiceFromPod(p))
+	}
+	for _, s := range svcs.Items {
+		services = append(services, NewDeploymentKubernetesServiceFromService(s))
+	}
+	return services, nil
+}
+
+func (k *KubernetesDeployer) Close() error {
+	return nil
+}
+
+func (k *KubernetesDeployer) GetOutput() string {
+	return k.output.String()
+}
+
+// getObjectsFromDeployment parses the manifests of the module, with its environment variables expanded
+func (k *KubernetesDeployer) getObjectsFromDeployment() ([]*unstructured.Unstructured, error) {
+	if k.deploymentResource.Module == nil ||
+		k.deploymentResource.Module.Content == nil ||
+		k.deploymentResource.Module.Content.DockerCompose == "" {
+		return nil, fmt.Errorf("no kubernetes manifests provided")
+	}
+	content := k.deploymentResource.Module.Content
+
+	if len(content.Files) > 0 {
+		log.Warnf("Files of the module are not supported by kubernetes deployments, use ConfigMaps instead")
+	}
+
+	manifests := ExpandEnvMapWithDefaults(content.DockerCompose, GetEnvironmentMappingFromContent(content))
+	return parseManifests(manifests)
+}
+
+// parseManifests decodes the objects of YAML or JSON manifests, in one or more documents. Lists are flattened.
+func parseManifests(manifests string) ([]*unstructured.Unstructured, error) {
+	decoder := yaml.NewYAMLOrJSONDecoder(strings.NewReader(manifests), 4096)
+
+	var objects []*unstructured.Unstructured
+	for {
+		var m map[string]interface{}
+		err := decoder.Decode(&m)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error parsing manifests: %w", err)
+		}
+		if len(m) == 0 {
+			continue
+		}
+
+		obj := &unstructured.Unstructured{Object: m}
+		if !obj.IsList() {
+			objects = append(objects, obj)
+			continue
+		}
+		list, err := obj.ToList()
+		if err != nil {
+			return nil, fmt.Errorf("error parsing manifests: %w", err)
+		}
+		for i := range list.Items {
+			objects = append(objects, &list.Items[i])
+		}
+	}
+
+	for _, obj := range objects {
+		if obj.GetAPIVersion() == "" || obj.GetKind() == "" || obj.GetName() == "" {
+			return nil, fmt.Errorf("manifest object %s lacks apiVersion, kind or name", objectId(obj))
+		}
+	}
+	if len(objects) == 0 {
+		return nil, fmt.Errorf("no objects found in the kubernetes manifests")
+	}
+	return objects, nil
+}
+
+// setUpClient creates the client of the cluster the agent runs in, or of the kubeconfig file when out of the cluster
+func (k *KubernetesDeployer) setUpClient() error {
+	if k.clientSet != nil {
+		return nil
+	}
+
+	config := k.restConfig
+	if config == nil {
+		c, err := common.NewKubernetesConfig()
+		if err != nil {
+			return fmt.Errorf("error loading kubernetes configuration: %w", err)
+		}
+		config = c
+	}
+
+	clientSet, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return err
+	}
+	k.clientSet = clientSet
+	return nil
+}
+
+// applyNamespace creates the namespace of the deployment, labelled with the deployment it belongs to
+func (k *KubernetesDeployer) applyNamespace(ctx context.Context) error {
+	ns := corev1ac.Namespace(k.namespace).WithLabels(map[string]string{constants.DeploymentLabel: k.namespace})
+	_, err := k.clientSet.CoreV1().Namespaces().Apply(ctx, ns,
+		metav1.ApplyOptions{FieldManager: kubernetesFieldManager, Force: true})
+	return err
+}
+
+// applyRegistriesSecret stores the credentials of the private registries in an image pull secret of the namespace.
+// The secret is used by the pods of the default service account, the pods of other service accounts must reference it.
+func (k *KubernetesDeployer) applyRegistriesSecret(ctx context.Context) error {
+	if len(k.auths) == 0 {
+		return nil
+	}
+	data, err := k.dockerConfigJSON()
+	if err != nil {
+		return err
+	}
+	opts := metav1.ApplyOptions{FieldManager: kubernetesFieldManager, Force: true}
+
+	secret := corev1ac.Secret(registriesSecretName, k.namespace).
+		WithLabels(map[string]string{constants.DeploymentLabel: k.namespace}).
+		WithType(corev1.SecretTypeDockerConfigJson).
+		WithData(map[string][]byte{corev1.DockerConfigJsonKey: data})
+	if _, err := k.clientSet.CoreV1().Secrets(k.namespace).Apply(ctx, secret, opts); err != nil {
+		return err
+	}
+
+	sa := corev1ac.ServiceAccount("default", k.namespace).
+		WithImagePullSecrets(corev1ac.LocalObjectReference().WithName(registriesSecretName))
+	_, err = k.clientSet.CoreV1().ServiceAccounts(k.namespace).Apply(ctx, sa, opts)
+	return err
+}
+
+// apply applies the object with server-side apply. Namespaced objects are moved to the namespace of the deployment.
+func (k *KubernetesDeployer) apply(ctx context.Context, obj *unstructured.Unstructured) error {
+	res, err := k.apiResource(obj.GroupVersionKind())
+	if err != nil {
+		return err
+	}
+	if res.Namespaced {
+		if ns := obj.GetNamespace(); ns != "" && ns != k.namespace {
+			log.Warnf("Deploying %s in namespace %s instead of %s", objectId(obj), k.namespace, ns)
+		}
+		obj.SetNamespace(k.namespace)
+	}
+
+	labels := obj.GetLabels()
+	if labels == nil {
+		labels = make(map[string]string)
+	}
+	labels[constants.DeploymentLabel] = k.namespace
+	obj.SetLabels(labels)
+
+	data, err := obj.MarshalJSON()
+	if err != nil {
+		return err
+	}
+	return k.clientSet.Discovery().RESTClient().
+		Patch(k8stypes.ApplyPatchType).
+		AbsPath(k.resourcePath(obj.GroupVersionKind(), res, obj.GetName())).
+		Param("fieldManager", kubernetesFieldManager).
+		Param("force", "true").
+		Body(data).
+		Do(ctx).
+		Error()
+}
+
+// deleteClusterObject deletes the object if it is cluster scoped. Namespaced objects are removed with the namespace.
+func (k *KubernetesDeployer) deleteClusterObject(ctx context.Context, obj *unstructured.Unstructured) error {
+	res, err := k.apiResource(obj.GroupVersionKind())
+	if err != nil || res.Namespaced {
+		return err
+	}
+
+	err = k.clientSet.Discovery().RESTClient().
+		Delete().
+		AbsPath(k.resourcePath(obj.GroupVersionKind(), res, obj.GetName())).
+		Do(ctx).
+		Error()
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err == nil {
+		_, _ = fmt.Fprintf(&k.output, "%s deleted\n", objectId(obj))
+	}
+	return err
+}
+
+// prunedKinds are the kinds of the objects of the deployment removed when they are no longer in its manifests, like
+// the ones kubectl apply --prune removes
+var prunedKinds = []schema.GroupVersionKind{
+	{Version: "v1", Kind: "ConfigMap"},
+	{Version: "v1", Kind: "Secret"},
+	{Version: "v1", Kind: "Service"},
+	{Version: "v1", Kind: "ServiceAccount"},
+	{Version: "v1", Kind: "PersistentVolumeClaim"},
+	{Version: "v1", Kind: "Pod"},
+	{Group: "apps", Version: "v1", Kind: "Deployment"},
+	{Group: "apps", Version: "v1", Kind: "StatefulSet"},
+	{Group: "apps", Version: "v1", Kind: "DaemonSet"},
+	{Group: "batch", Version: "v1", Kind: "Job"},
+	{Group: "batch", Version: "v1", Kind: "CronJob"},
+	{Group: "networking.k8s.io", Version: "v1", Kind: "Ingress"},
+	{Group: "rbac.authorization.k8s.io", Version: "v1", Kind: "Role"},
+	{Group: "rbac.authorization.k8s.io", Version: "v1", Kind: "RoleBinding"},
+	{Group: "rbac.authorization.k8s.io", Version: "v1", Kind: "ClusterRole"},
+	{Group: "rbac.authorization.k8s.io", Version: "v1", Kind: "ClusterRoleBinding"},
+}
+
+// prune deletes the objects labelled with the deployment which are not part of its manifests. The secret of the
+// private registries is kept while the deployment uses them.
+func (k *KubernetesDeployer) prune(ctx context.Context, objects []*unstructured.Unstructured) error {
+	keep := make(map[string]bool, len(objects)+1)
+	for _, obj := range objects {
+		keep[objectId(obj)] = true
+	}
+	if len(k.auths) > 0 {
+		keep["secret/"+registriesSecretName] = true
+	}
+
+	var errList []error
+	for _, gvk := range prunedKinds {
+		res, err := k.apiResource(gvk)
+		if err != nil {
+			log.Debugf("Not pruning %s: %s", gvk.Kind, err)
+			continue
+		}
+
+		b, err := k.clientSet.Discovery().RESTClient().
+			Get().
+			AbsPath(k.resourcePath(gvk, res, "")).
+			Param("labelSelector", constants.DeploymentLabel+"="+k.namespace).
+			Do(ctx).
+			Raw()
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			errList = append(errList, fmt.Errorf("error listing %s: %w", res.Name, err))
+			continue
+		}
+		var list metav1.PartialObjectMetadataList
+		if err := json.Unmarshal(b, &list); err != nil {
+			errList = append(errList, fmt.Errorf("error listing %s: %w", res.Name, err))
+			continue
+		}
+
+		for _, item := range list.Items {
+			obj := &unstructured.Unstructured{}
+			obj.SetGroupVersionKind(gvk)
+			obj.SetName(item.Name)
+			if keep[objectId(obj)] {
+				continue
+			}
+			err := k.clientSet.Discovery().RESTClient().
+				Delete().
+				AbsPath(k.resourcePath(gvk, res, obj.GetName())).
+				Do(ctx).
+				Error()
+			switch {
+			case err == nil:
+				_, _ = fmt.Fprintf(&k.output, "%s pruned\n", objectId(obj))
+			case !apierrors.IsNotFound(err):
+				errList = append(errList, fmt.Errorf("error pruning %s: %w", objectId(obj), err))
+			}
+		}
+	}
+	return errors.Join(errList...)
+}
+
+// apiResource finds the resource serving the kind in the API server
+func (k *KubernetesDeployer) apiResource(gvk schema.GroupVersionKind) (*metav1.APIResource, error) {
+	gv := gvk.GroupVersion().String()
+	if k.apiResources == nil {
+		k.apiResources = make(map[string]*metav1.APIResourceList)
+	}
+
+	list, ok := k.apiResources[gv]
+	if !ok {
+		l, err := k.clientSet.Discovery().ServerResourcesForGroupVersion(gv)
+		if err != nil {
+			return nil, fmt.Errorf("error discovering resources of %s: %w", gv, err)
+		}
+		k.apiResources[gv] = l
+		list = l
+	}
+
+	for i := range list.APIResources {
+		r := &list.APIResources[i]
+		// Subresources, e.g. deployments/scale, share the kind of their resource
+		if r.Kind == gvk.Kind && !strings.Contains(r.Name, "/") {
+			return r, nil
+		}
+	}
+	return nil, fmt.Errorf("kind %s not served by the cluster in %s", gvk.Kind, gv)
+}
+
+func (k *KubernetesDeployer) resourcePath(gvk schema.GroupVersionKind, res *metav1.APIResource, name string) string {
+	p := path.Join("/api", gvk.Version)
+	if gvk.Group != "" {
+		p = path.Join("/apis", gvk.Group, gvk.Version)
+	}
+	if res.Namespaced {
+		p = path.Join(p, "namespaces", k.namespace)
+	}
+	return path.Join(p, res.Name, name)
+}
+
+// objectId identifies the object as kubectl does, e.g. deployment.apps/nginx
+func objectId(obj *unstructured.Unstructured) string {
+	kind := strings.ToLower(obj.GetKind())
+	if group := obj.GroupVersionKind().Group; group != "" {
+		kind += "." + group
+	}
+	return kind + "/" + obj.GetName()
+}
+
+// DeploymentKubernetesService describes the pods and the services of a Kubernetes deployment
+type DeploymentKubernetesService struct {
+	Kind   string `json:"kind,omitempty"`
+	NodeID string `json:"node-id,omitempty"`
+	Image  string `json:"image,omitempty"`
+	// Phase of the pods, type of the services
+	State string `json:"state,omitempty"`
+	Node  string `json:"node,omitempty"`
+	IP    string `json:"ip,omitempty"`
+
+	Ports map[string]int `json:"-"` // Node ports of the services, protocol.port: node port
+}
+
+func (s *DeploymentKubernetesService) GetServiceMap() map[string]string {
+	// Convert struct to Map
+	m := make(map[string]string)
+	b, _ := json.Marshal(s)
+	_ = json.Unmarshal(b, &m)
+	return m
+}
+
+func (s *DeploymentKubernetesService) GetPorts() map[string]int {
+	return s.Ports
+}
+
+func NewDeploymentKubernetesServiceFromPod(p corev1.Pod) *DeploymentKubernetesService {
+	s := &DeploymentKubernetesService{
+		Kind:   "Pod",
+		NodeID: p.Name,
+		State:  string(p.Status.Phase),
+		Node:   p.Spec.NodeName,
+		IP:     p.Status.PodIP,
+	}
+	if len(p.Spec.Containers) > 0 {
+		s.Image = p.Spec.Containers[0].Image
+	}
+	return s
+}
+
+func NewDeploymentKubernetesServiceFromService(svc corev1.Service) *DeploymentKubernetesService {
+	s := &DeploymentKubernetesService{
+		Kind:   "Service",
+		NodeID: svc.Name,
+		State:  string(svc.Spec.Type),
+		IP:     svc.Spec.ClusterIP,
+		Ports:  make(map[string]int),
+	}
+	for _, p := range svc.Spec.Ports {
+		if p.NodePort != 0 {
+			s.Ports[fmt.Sprintf("%s.%d", strings.ToLower(string(p.Protocol)), p.Port)] = int(p.NodePort)
+		}
+	}
+	return s
+}
+
+var _ Deployer = &KubernetesDeployer{}
@@ -0,0 +1,275 @@
+package executors
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/docker/docker/api/types/registry"
+	"github.com/nuvla/api-client-go/clients/resources"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+	"nuvlaedge-go/common/constants"
+	"nuvlaedge-go/testutils"
+	"testing"
+)
+
+const (
+	testDeploymentId = "deployment/5f7e3a52-6b8c-4a1e-9d0f-2c4b6a8e0d13"
+	testNamespace    = "5f7e3a52-6b8c-4a1e-9d0f-2c4b6a8e0d13"
+	testManifests    = `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: default
+spec:
+  template:
+    spec:
+      containers:
+        - name: web
+          image: nginx:${TAG:-latest}
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+spec:
+  type: NodePort
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: web-reader
+`
+)
+
+// newFakeKubeAPI starts a fake API server serving the discovery of a few resources
+func newFakeKubeAPI(t *testing.T) *testutils.FakeKubeAPI {
+	api := testutils.NewFakeKubeAPI()
+	t.Cleanup(api.Close)
+	for p, d := range fakeDiscovery {
+		api.Set(p, d)
+	}
+	return api
+}
+
+var fakeDiscovery = map[string]metav1.APIResourceList{
+	"/api/v1": {GroupVersion: "v1", APIResources: []metav1.APIResource{
+		{Name: "namespaces", Kind: "Namespace"},
+		{Name: "configmaps", Kind: "ConfigMap", Namespaced: true},
+		{Name: "pods", Kind: "Pod", Namespaced: true},
+		{Name: "services", Kind: "Service", Namespaced: true},
+	}},
+	"/apis/apps/v1": {GroupVersion: "apps/v1", APIResources: []metav1.APIResource{
+		{Name: "deployments", Kind: "Deployment", Namespaced: true},
+		{Name: "deployments/scale", Kind: "Scale", Namespaced: true},
+	}},
+	"/apis/rbac.authorization.k8s.io/v1": {GroupVersion: "rbac.authorization.k8s.io/v1", APIResources: []metav1.APIResource{
+		{Name: "clusterroles", Kind: "ClusterRole"},
+	}},
+}
+
+func has(api *testutils.FakeKubeAPI, path string) bool {
+	_, ok := api.Get(path)
+	return ok
+}
+
+func newTestKubernetesDeployer(config *rest.Config, manifests string) *KubernetesDeployer {
+	return &KubernetesDeployer{
+		ExecutorBase: ExecutorBase{Name: KubernetesExecutorName},
+		deploymentResource: &resources.DeploymentResource{
+			Id: testDeploymentId,
+			Module: &resources.ModuleResource{
+				SubType: "application_kubernetes",
+				Content: &resources.ModuleApplicationResource{
+					DockerCompose:        manifests,
+					EnvironmentVariables: []resources.EnvironmentVariable{{Name: "TAG", Value: "1.27"}},
+				},
+			},
+		},
+		restConfig: config,
+	}
+}
+
+func Test_KubernetesDeployer_StartDeployment(t *testing.T) {
+	api := newFakeKubeAPI(t)
+	k := newTestKubernetesDeployer(api.Config(), testManifests)
+
+	assert.NoError(t, k.StartDeployment(context.Background()))
+
+	ns, ok := api.Get("/api/v1/namespaces/" + testNamespace)
+	assert.True(t, ok, "the namespace of the deployment should be created")
+	assert.Equal(t, testNamespace, ns["metadata"].(map[string]interface{})["labels"].(map[string]interface{})[constants.DeploymentLabel])
+
+	deployment, ok := api.Get("/apis/apps/v1/namespaces/" + testNamespace + "/deployments/web")
+	assert.True(t, ok)
+	assert.Equal(t, testNamespace, deployment["metadata"].(map[string]interface{})["namespace"],
+		"namespaced objects should be moved to the namespace of the deployment")
+	b, _ := json.Marshal(deployment)
+	assert.Contains(t, string(b), "nginx:1.27")
+
+	assert.True(t, has(api, "/api/v1/namespaces/"+testNamespace+"/services/web"))
+	assert.True(t, has(api, "/apis/rbac.authorization.k8s.io/v1/clusterroles/web-reader"))
+	assert.Contains(t, k.GetOutput(), "deployment.apps/web applied")
+}
+
+func Test_KubernetesDeployer_StartDeployment_RegistriesSecret(t *testing.T) {
+	api := newFakeKubeAPI(t)
+	k := newTestKubernetesDeployer(api.Config(), testManifests)
+	k.SetRegistriesAuth([]registry.AuthConfig{NewRegistryAuth("https://registry.example.com:5000", "user", "secret")})
+
+	assert.NoError(t, k.StartDeployment(context.Background()))
+
+	secret, ok := api.Get("/api/v1/namespaces/" + testNamespace + "/secrets/" + registriesSecretName)
+	assert.True(t, ok, "the image pull secret should be created")
+	assert.Equal(t, string(corev1.SecretTypeDockerConfigJson), secret["type"])
+	data, err := base64.StdEncoding.DecodeString(secret["data"].(map[string]interface{})[corev1.DockerConfigJsonKey].(string))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"auths":{"registry.example.com:5000":{
+		"username":"user","password":"secret","auth":"dXNlcjpzZWNyZXQ=","serveraddress":"registry.example.com:5000"}}}`,
+		string(data))
+
+	sa, ok := api.Get("/api/v1/namespaces/" + testNamespace + "/serviceaccounts/default")
+	assert.True(t, ok)
+	assert.Equal(t, []interface{}{map[string]interface{}{"name": registriesSecretName}}, sa["imagePullSecrets"])
+	assert.NotContains(t, k.GetOutput(), "secret", "credentials should not be part of the output")
+}
+
+func Test_KubernetesDeployer_StartDeployment_UnknownKind(t *testing.T) {
+	api := newFakeKubeAPI(t)
+	k := newTestKubernetesDeployer(api.Config(), "apiVersion: apps/v1\nkind: Unknown\nmetadata:\n  name: web\n")
+
+	assert.ErrorContains(t, k.StartDeployment(context.Background()), "not served")
+}
+
+func Test_KubernetesDeployer_StopDeployment(t *testing.T) {
+	api := newFakeKubeAPI(t)
+	k := newTestKubernetesDeployer(api.Config(), testManifests)
+	assert.NoError(t, k.StartDeployment(context.Background()))
+
+	assert.NoError(t, k.StopDeployment(context.Background()))
+	assert.False(t, has(api, "/api/v1/namespaces/"+testNamespace))
+	assert.False(t, has(api, "/apis/rbac.authorization.k8s.io/v1/clusterroles/web-reader"))
+
+	assert.NoError(t, k.StopDeployment(context.Background()), "stopping a removed deployment should succeed")
+}
+
+func Test_KubernetesDeployer_UpdateDeployment_Prune(t *testing.T) {
+	api := newFakeKubeAPI(t)
+	k := newTestKubernetesDeployer(api.Config(), testManifests+"---\napiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: old\n")
+	assert.NoError(t, k.StartDeployment(context.Background()))
+	assert.True(t, has(api, "/api/v1/namespaces/"+testNamespace+"/configmaps/old"))
+
+	k.deploymentResource.Module.Content.DockerCompose = testManifests
+
+	assert.NoError(t, k.UpdateDeployment(context.Background()))
+	assert.False(t, has(api, "/api/v1/namespaces/"+testNamespace+"/configmaps/old"),
+		"objects no longer in the manifests should be removed")
+	assert.True(t, has(api, "/api/v1/namespaces/"+testNamespace+"/services/web"))
+	assert.Contains(t, k.GetOutput(), "configmap/old pruned")
+}
+
+func Test_KubernetesDeployer_StateDeployment(t *testing.T) {
+	one := int32(1)
+	running := corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "web-1"},
+		Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+	crashing := corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "web-2"},
+		Status: corev1.PodStatus{Phase: corev1.PodRunning, ContainerStatuses: []corev1.ContainerStatus{{
+			State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}},
+		}}},
+	}
+
+	tests := []struct {
+		name      string
+		namespace bool
+		ready     int32
+		pods      []corev1.Pod
+		err       string
+	}{
+		{name: "running", namespace: true, ready: 1, pods: []corev1.Pod{running}},
+		{name: "namespace missing", err: "namespace " + testNamespace + " not found"},
+		{name: "not ready", namespace: true, ready: 0, pods: []corev1.Pod{running}, err: "deployment.apps/web (0/1 ready)"},
+		{name: "pod crashing", namespace: true, ready: 1, pods: []corev1.Pod{running, crashing},
+			err: "pod/web-2 (CrashLoopBackOff)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := newFakeKubeAPI(t)
+			if tt.namespace {
+				api.Set("/api/v1/namespaces/"+testNamespace, corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: testNamespace}})
+			}
+			api.Set("/apis/apps/v1/namespaces/"+testNamespace+"/deployments", appsv1.DeploymentList{Items: []appsv1.Deployment{{
+				ObjectMeta: metav1.ObjectMeta{Name: "web"},
+				Spec:       appsv1.DeploymentSpec{Replicas: &one},
+				Status:     appsv1.DeploymentStatus{ReadyReplicas: tt.ready},
+			}}})
+			api.Set("/apis/apps/v1/namespaces/"+testNamespace+"/statefulsets", appsv1.StatefulSetList{})
+			api.Set("/apis/apps/v1/namespaces/"+testNamespace+"/daemonsets", appsv1.DaemonSetList{})
+			api.Set("/api/v1/namespaces/"+testNamespace+"/pods", corev1.PodList{Items: tt.pods})
+			k := newTestKubernetesDeployer(api.Config(), testManifests)
+
+			err := k.StateDeployment(context.Background())
+			if tt.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tt.err)
+			}
+		})
+	}
+}
+
+func Test_KubernetesDeployer_GetServices(t *testing.T) {
+	api := newFakeKubeAPI(t)
+	api.Set("/api/v1/namespaces/"+testNamespace+"/pods", corev1.PodList{Items: []corev1.Pod{{
+		ObjectMeta: metav1.ObjectMeta{Name: "web-7d4b9c8f6-x2x9z"},
+		Spec:       corev1.PodSpec{NodeName: "edge-1", Containers: []corev1.Container{{Image: "nginx:1.27"}}},
+		Status:     corev1.PodStatus{Phase: corev1.PodRunning, PodIP: "10.42.0.12"},
+	}}})
+	api.Set("/api/v1/namespaces/"+testNamespace+"/services", corev1.ServiceList{Items: []corev1.Service{{
+		ObjectMeta: metav1.ObjectMeta{Name: "web"},
+		Spec: corev1.ServiceSpec{Type: corev1.ServiceTypeNodePort, ClusterIP: "10.43.0.20", Ports: []corev1.ServicePort{
+			{Protocol: corev1.ProtocolTCP, Port: 80, NodePort: 30080},
+			{Protocol: corev1.ProtocolUDP, Port: 53},
+		}},
+	}}})
+	k := newTestKubernetesDeployer(api.Config(), testManifests)
+
+	services, err := k.GetServices(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, services, 2)
+
+	assert.Equal(t, map[string]string{
+		"kind": "Pod", "node-id": "web-7d4b9c8f6-x2x9z", "image": "nginx:1.27",
+		"state": "Running", "node": "edge-1", "ip": "10.42.0.12",
+	}, services[0].GetServiceMap())
+	assert.Empty(t, services[0].GetPorts())
+
+	assert.Equal(t, map[string]string{
+		"kind": "Service", "node-id": "web", "state": "NodePort", "ip": "10.43.0.20",
+	}, services[1].GetServiceMap())
+	assert.Equal(t, map[string]int{"tcp.80": 30080}, services[1].GetPorts())
+}
+
+func Test_ParseManifests(t *testing.T) {
+	objects, err := parseManifests(testManifests)
+	assert.NoError(t, err)
+	assert.Len(t, objects, 3)
+	assert.Equal(t, "clusterrole.rbac.authorization.k8s.io/web-reader", objectId(objects[2]))
+
+	objects, err = parseManifests(`{"apiVersion": "v1", "kind": "List", "items": [
+		{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "a"}},
+		{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "b"}}]}`)
+	assert.NoError(t, err)
+	assert.Len(t, objects, 2)
+
+	_, err = parseManifests("---\n")
+	assert.Error(t, err)
+	_, err = parseManifests("apiVersion: v1\nkind: ConfigMap\n")
+	assert.Error(t, err, "objects without name should be rejected")
+}
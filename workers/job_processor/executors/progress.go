@@ -0,0 +1,119 @@
+package executors
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// ProgressEvent is a step of a deployment operation, e.g. an image layer being pulled or a container being started
+type ProgressEvent struct {
+	ID      string // Object of the event, e.g. a container, a service or an image layer
+	Parent  string // Object the ID belongs to, e.g. the service an image layer is pulled for
+	Text    string // Step reached, e.g. Pulling, Created or Started
+	Status  string // Details of the step
+	Percent int    // Completion of the object, if known
+	Done    bool   // Whether the object reached its final step
+}
+
+// ProgressFunc receives the progress events of a deployment operation as they happen
+type ProgressFunc func(ProgressEvent)
+
+type progressKey struct{}
+
+// WithProgress returns a context that makes the deployers report their progress events to fn
+func WithProgress(ctx context.Context, fn ProgressFunc) context.Context {
+	return context.WithValue(ctx, progressKey{}, fn)
+}
+
+// ProgressFromContext returns the function receiving the progress events, which does nothing if not set
+func ProgressFromContext(ctx context.Context) ProgressFunc {
+	if fn, ok := ctx.Value(progressKey{}).(ProgressFunc); ok && fn != nil {
+		return fn
+	}
+	return func(ProgressEvent) {}
+}
+
+// lineWriter calls fn for every complete line written to it. Writes can come from several routines.
+type lineWriter struct {
+	mu  sync.Mutex
+	buf []byte
+	fn  func(line string)
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		w.fn(string(w.buf[:i]))
+		w.buf = w.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+// composeProgressMessage is a progress event written by compose in JSON progress mode
+type composeProgressMessage struct {
+	Tail     bool   `json:"tail,omitempty"`
+	ID       string `json:"id,omitempty"`
+	ParentID string `json:"parent_id,omitempty"`
+	Text     string `json:"text,omitempty"`
+	Status   string `json:"status,omitempty"`
+	Percent  int    `json:"percent,omitempty"`
+}
+
+// composeDoneSteps are the steps after which compose doesn't report anything else about an object
+var composeDoneSteps = []string{
+	"Created", "Recreated", "Started", "Running", "Healthy", "Exited", "Stopped", "Removed",
+	"Pulled", "Pull complete", "Already exists", "Built", "Skipped", "Error",
+}
+
+// composeOutput parses the JSON progress events written by compose and reports them. The output keeps the same
+// readable format as the compose plain progress mode.
+func composeOutput(out io.Writer, report ProgressFunc) io.Writer {
+	return &lineWriter{fn: func(line string) {
+		var m composeProgressMessage
+		if err := json.Unmarshal([]byte(line), &m); err != nil || m.Tail || (m.ID == "" && m.Text == "") {
+			if err == nil && m.Tail {
+				line = m.Text
+			}
+			_, _ = fmt.Fprintln(out, line)
+			return
+		}
+
+		_, _ = fmt.Fprintln(out, strings.TrimSpace(strings.Join([]string{m.ID, m.Text, m.Status}, " ")))
+		report(ProgressEvent{
+			ID:      m.ID,
+			Parent:  m.ParentID,
+			Text:    m.Text,
+			Status:  m.Status,
+			Percent: m.Percent,
+			Done:    slices.Contains(composeDoneSteps, m.Text),
+		})
+	}}
+}
+
+// stackOutput reports the services created or updated by a stack deployment from its output
+func stackOutput(out io.Writer, report ProgressFunc) io.Writer {
+	return &lineWriter{fn: func(line string) {
+		_, _ = fmt.Fprintln(out, line)
+
+		for prefix, step := range map[string]string{"Creating service ": "Creating", "Updating service ": "Updating"} {
+			if name, ok := strings.CutPrefix(line, prefix); ok {
+				// Updates are printed with the service id
+				name, _, _ = strings.Cut(name, " ")
+				report(ProgressEvent{ID: name, Text: step})
+			}
+		}
+	}}
+}
@@ -0,0 +1,67 @@
+package executors
+
+import (
+	"context"
+	swarmtypes "github.com/docker/docker/api/types/swarm"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func init() {
+	log.SetLevel(log.PanicLevel)
+}
+
+func Test_ProgressFromContext(t *testing.T) {
+	assert.NotPanics(t, func() { ProgressFromContext(context.Background())(ProgressEvent{}) })
+
+	var got []ProgressEvent
+	ctx := WithProgress(context.Background(), func(e ProgressEvent) { got = append(got, e) })
+	ProgressFromContext(ctx)(ProgressEvent{ID: "web"})
+	assert.Equal(t, []ProgressEvent{{ID: "web"}}, got)
+}
+
+func Test_ComposeOutput(t *testing.T) {
+	var got []ProgressEvent
+	out := NewCaptureWriter()
+	w := composeOutput(out, func(e ProgressEvent) { got = append(got, e) })
+
+	_, _ = w.Write([]byte(`{"id":"abc","parent_id":"web","text":"Downloading","status":"[==>  ]","percent":40}` + "\n" +
+		`{"id":"Container web-1","text":"Star`))
+	_, _ = w.Write([]byte(`ted"}` + "\n" + `{"tail":true,"text":"Some warning"}` + "\nplain line\n"))
+
+	assert.Equal(t, []ProgressEvent{
+		{ID: "abc", Parent: "web", Text: "Downloading", Status: "[==>  ]", Percent: 40},
+		{ID: "Container web-1", Text: "Started", Done: true},
+	}, got)
+	assert.Equal(t, "abc Downloading [==>  ]\nContainer web-1 Started\nSome warning\nplain line\n", out.String())
+}
+
+func Test_StackOutput(t *testing.T) {
+	var got []ProgressEvent
+	out := NewCaptureWriter()
+	w := stackOutput(out, func(e ProgressEvent) { got = append(got, e) })
+
+	_, _ = w.Write([]byte("Creating network app_default\nCreating service app_web\nUpdating service app_db (id: 123)\n"))
+	assert.Equal(t, []ProgressEvent{{ID: "app_web", Text: "Creating"}, {ID: "app_db", Text: "Updating"}}, got)
+	assert.Contains(t, out.String(), "Creating network app_default")
+}
+
+func Test_ReportConvergence(t *testing.T) {
+	service := func(name string, desired, running uint64) swarmtypes.Service {
+		s := swarmtypes.Service{ServiceStatus: &swarmtypes.ServiceStatus{DesiredTasks: desired, RunningTasks: running}}
+		s.Spec.Name = name
+		return s
+	}
+
+	var got []ProgressEvent
+	report := func(e ProgressEvent) { got = append(got, e) }
+	assert.False(t, reportConvergence([]swarmtypes.Service{service("app_web", 4, 1), service("app_db", 1, 1)}, report))
+	assert.Equal(t, ProgressEvent{ID: "app_web", Text: "Converging", Status: "1/4 tasks running", Percent: 25}, got[0])
+	assert.True(t, got[1].Done)
+
+	got = nil
+	assert.True(t, reportConvergence([]swarmtypes.Service{service("app_web", 4, 4), service("app_job", 0, 0)}, report))
+	assert.Equal(t, "Running", got[0].Text)
+	assert.Equal(t, 100, got[1].Percent)
+}
@@ -0,0 +1,159 @@
+package executors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	docker "github.com/docker/docker/client"
+	"github.com/docker/docker/errdefs"
+	log "github.com/sirupsen/logrus"
+	"nuvlaedge-go/common/constants"
+	"nuvlaedge-go/store"
+	"sync"
+	"time"
+)
+
+const (
+	// rebootDelay leaves the agent the time to report the reboot job before the shutdown starts
+	rebootDelay = 5 * time.Second
+	// hostRebootCommand reboots through systemd, or the init system of the host if it has none
+	hostRebootCommand = "sh -c 'systemctl reboot || reboot'"
+	// swarmServiceIDLabel links the containers of the swarm tasks to their service
+	swarmServiceIDLabel = "com.docker.swarm.service.id"
+)
+
+// stopDeploymentContainers stops the running containers of the Nuvla deployments, all at once. Each one is given
+// timeout to stop before being killed. Docker doesn't restart the containers stopped on purpose, whatever their
+// restart policy, so the standalone ones are recorded to be started again after the reboot. Swarm starts the tasks
+// of the services again by itself.
+func stopDeploymentContainers(ctx context.Context, timeout time.Duration) error {
+	client, err := docker.NewClientWithOpts(docker.FromEnv, docker.WithAPIVersionNegotiation())
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	return stopContainers(ctx, client, timeout)
+}
+
+func stopContainers(ctx context.Context, client docker.APIClient, timeout time.Duration) error {
+	containers, err := listDeploymentContainers(ctx, client)
+	if err != nil {
+		return fmt.Errorf("error listing deployment containers: %w", err)
+	}
+
+	var standalone []string
+	for _, c := range containers {
+		if c.Labels[swarmServiceIDLabel] == "" {
+			standalone = append(standalone, c.ID)
+		}
+	}
+	if err := storeFromContext(ctx).Put(store.StoppedContainersKey, standalone); err != nil {
+		log.Warnf("Error recording the deployment containers stopped, they won't be started after the reboot: %s", err)
+	}
+	log.Infof("Stopping %d deployment containers", len(containers))
+
+	seconds := int(timeout.Seconds())
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+	for _, c := range containers {
+		wg.Add(1)
+		go func(c string) {
+			defer wg.Done()
+			if err := client.ContainerStop(ctx, c, container.StopOptions{Timeout: &seconds}); err != nil {
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("error stopping container %s: %w", c, err))
+				mu.Unlock()
+			}
+		}(c.ID)
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+// listDeploymentContainers returns the running containers of the deployments: the ones created by compose, labelled
+// with their deployment, and the tasks of the services of the stacks running in this node. Only the managers of the
+// swarm list the services, the tasks of the workers are left to the shutdown of docker.
+func listDeploymentContainers(ctx context.Context, client docker.APIClient) ([]types.Container, error) {
+	containers, err := client.ContainerList(ctx, container.ListOptions{
+		Filters: filters.NewArgs(filters.Arg("label", constants.DeploymentLabel)),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := client.Info(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !info.Swarm.ControlAvailable {
+		return containers, nil
+	}
+
+	services, err := client.ServiceList(ctx, types.ServiceListOptions{
+		Filters: filters.NewArgs(filters.Arg("label", constants.DeploymentLabel)),
+	})
+	if err != nil || len(services) == 0 {
+		return containers, err
+	}
+	deploymentServices := make(map[string]bool, len(services))
+	for _, svc := range services {
+		deploymentServices[svc.ID] = true
+	}
+
+	tasks, err := client.ContainerList(ctx, container.ListOptions{
+		Filters: filters.NewArgs(filters.Arg("label", swarmServiceIDLabel)),
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range tasks {
+		if deploymentServices[t.Labels[swarmServiceIDLabel]] {
+			containers = append(containers, t)
+		}
+	}
+	return containers, nil
+}
+
+// StartStoppedDeployments starts again the deployment containers stopped before a reboot, recorded in the store of
+// the context. The record is kept until all of them are started.
+func StartStoppedDeployments(ctx context.Context) error {
+	s := storeFromContext(ctx)
+	var ids []string
+	if err := s.Get(store.StoppedContainersKey, &ids); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	client, err := docker.NewClientWithOpts(docker.FromEnv, docker.WithAPIVersionNegotiation())
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	if err := startContainers(ctx, client, ids); err != nil {
+		return err
+	}
+	return s.Delete(store.StoppedContainersKey)
+}
+
+// startContainers starts the containers. The ones removed since they were stopped are ignored.
+func startContainers(ctx context.Context, client docker.ContainerAPIClient, ids []string) error {
+	if len(ids) > 0 {
+		log.Infof("Starting %d deployment containers stopped before the reboot", len(ids))
+	}
+	var errs []error
+	for _, id := range ids {
+		if err := client.ContainerStart(ctx, id, container.StartOptions{}); err != nil && !errdefs.IsNotFound(err) {
+			errs = append(errs, fmt.Errorf("error starting container %s: %w", id, err))
+		}
+	}
+	return errors.Join(errs...)
+}
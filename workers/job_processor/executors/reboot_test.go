@@ -0,0 +1,102 @@
+package executors
+
+import (
+	"context"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	swarmtypes "github.com/docker/docker/api/types/swarm"
+	"github.com/docker/docker/api/types/system"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/errdefs"
+	"github.com/stretchr/testify/assert"
+	"nuvlaedge-go/common/constants"
+	"nuvlaedge-go/store"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeRebootClient lists the containers having the labels filtered and records the ones stopped and started
+type fakeRebootClient struct {
+	client.APIClient
+
+	containers []types.Container
+	services   []swarmtypes.Service
+	manager    bool
+
+	mu      sync.Mutex
+	stopped []string
+	started []string
+}
+
+func (f *fakeRebootClient) ContainerList(_ context.Context, opts container.ListOptions) ([]types.Container, error) {
+	var containers []types.Container
+	for _, c := range f.containers {
+		matches := true
+		for _, l := range opts.Filters.Get("label") {
+			_, ok := c.Labels[l]
+			matches = matches && ok
+		}
+		if matches {
+			containers = append(containers, c)
+		}
+	}
+	return containers, nil
+}
+
+func (f *fakeRebootClient) Info(_ context.Context) (system.Info, error) {
+	return system.Info{Swarm: swarmtypes.Info{ControlAvailable: f.manager}}, nil
+}
+
+func (f *fakeRebootClient) ServiceList(_ context.Context, _ types.ServiceListOptions) ([]swarmtypes.Service, error) {
+	return f.services, nil
+}
+
+func (f *fakeRebootClient) ContainerStop(_ context.Context, id string, _ container.StopOptions) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.stopped = append(f.stopped, id)
+	return nil
+}
+
+func (f *fakeRebootClient) ContainerStart(_ context.Context, id string, _ container.StartOptions) error {
+	if id == "removed" {
+		return errdefs.NotFound(assert.AnError)
+	}
+	f.started = append(f.started, id)
+	return nil
+}
+
+func Test_StopContainers(t *testing.T) {
+	cli := &fakeRebootClient{
+		containers: []types.Container{
+			{ID: "compose", Labels: map[string]string{constants.DeploymentLabel: "deployment/1"}},
+			{ID: "task", Labels: map[string]string{swarmServiceIDLabel: "svc-1"}},
+			{ID: "other-task", Labels: map[string]string{swarmServiceIDLabel: "svc-2"}},
+			{ID: "other"},
+		},
+		services: []swarmtypes.Service{{ID: "svc-1"}},
+		manager:  true,
+	}
+	s, err := store.Open(t.TempDir())
+	assert.NoError(t, err)
+
+	assert.NoError(t, stopContainers(WithStore(context.Background(), s), cli, time.Second))
+	sort.Strings(cli.stopped)
+	assert.Equal(t, []string{"compose", "task"}, cli.stopped, "the tasks of the deployment services should be stopped")
+
+	var recorded []string
+	assert.NoError(t, s.Get(store.StoppedContainersKey, &recorded))
+	assert.Equal(t, []string{"compose"}, recorded, "only the standalone containers should be started again")
+
+	cli = &fakeRebootClient{containers: cli.containers, services: cli.services}
+	assert.NoError(t, stopContainers(context.Background(), cli, time.Second))
+	assert.Equal(t, []string{"compose"}, cli.stopped, "the services are only listed by the managers")
+}
+
+func Test_StartContainers(t *testing.T) {
+	cli := &fakeRebootClient{}
+	assert.NoError(t, startContainers(context.Background(), cli, []string{"compose", "removed"}))
+	assert.Equal(t, []string{"compose"}, cli.started)
+}
@@ -0,0 +1,75 @@
+package executors
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"github.com/docker/cli/cli/config/configfile"
+	clitypes "github.com/docker/cli/cli/config/types"
+	"github.com/docker/docker/api/types/registry"
+	dockerregistry "github.com/docker/docker/registry"
+	"strings"
+)
+
+// NewRegistryAuth returns the credentials of the registry served at endpoint. The server address is the key docker
+// uses to find the credentials of the images: the host of the registry, or the index server for Docker Hub.
+func NewRegistryAuth(endpoint, username, password string) registry.AuthConfig {
+	host := strings.TrimPrefix(strings.TrimPrefix(endpoint, "https://"), "http://")
+	host, _, _ = strings.Cut(host, "/")
+	switch host {
+	case "docker.io", dockerregistry.IndexHostname, "registry-1.docker.io":
+		host = dockerregistry.IndexServer
+	}
+
+	return registry.AuthConfig{
+		Username:      username,
+		Password:      password,
+		ServerAddress: host,
+	}
+}
+
+// registriesAuth holds the credentials of the private registries the images of a deployment are pulled from
+type registriesAuth struct {
+	auths []registry.AuthConfig
+}
+
+func (r *registriesAuth) SetRegistriesAuth(auths []registry.AuthConfig) {
+	r.auths = auths
+}
+
+// addToConfigFile makes the credentials available to the pulls of the docker CLI. They are only kept in memory, the
+// configuration file is never saved. The credentials stores configured in the host are bypassed for these registries.
+func (r *registriesAuth) addToConfigFile(cf *configfile.ConfigFile) {
+	if len(r.auths) == 0 {
+		return
+	}
+	if cf.AuthConfigs == nil {
+		cf.AuthConfigs = make(map[string]clitypes.AuthConfig)
+	}
+	if cf.CredentialHelpers == nil {
+		cf.CredentialHelpers = make(map[string]string)
+	}
+
+	for _, a := range r.auths {
+		cf.AuthConfigs[a.ServerAddress] = clitypes.AuthConfig{
+			Username:      a.Username,
+			Password:      a.Password,
+			ServerAddress: a.ServerAddress,
+		}
+		// An empty helper selects the auths of the configuration file
+		cf.CredentialHelpers[a.ServerAddress] = ""
+	}
+}
+
+type dockerConfigJSON struct {
+	Auths map[string]registry.AuthConfig `json:"auths"`
+}
+
+// dockerConfigJSON returns the credentials as the content of the kubernetes.io/dockerconfigjson secrets
+func (r *registriesAuth) dockerConfigJSON() ([]byte, error) {
+	c := dockerConfigJSON{Auths: make(map[string]registry.AuthConfig, len(r.auths))}
+	for _, a := range r.auths {
+		a.Auth = base64.StdEncoding.EncodeToString([]byte(a.Username + ":" + a.Password))
+		c.Auths[a.ServerAddress] = a
+	}
+	return json.Marshal(c)
+}
@@ -0,0 +1,56 @@
+package executors
+
+import (
+	"github.com/docker/cli/cli/config/configfile"
+	"github.com/docker/docker/api/types/registry"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_NewRegistryAuth_ServerAddress(t *testing.T) {
+	tests := map[string]string{
+		"https://registry.example.com:5000":    "registry.example.com:5000",
+		"http://registry.example.com/v2/":      "registry.example.com",
+		"registry.example.com":                 "registry.example.com",
+		"https://index.docker.io/v1/":          "https://index.docker.io/v1/",
+		"https://registry-1.docker.io":         "https://index.docker.io/v1/",
+		"docker.io":                            "https://index.docker.io/v1/",
+		"https://ghcr.io/nuvla/nuvlaedge-test": "ghcr.io",
+	}
+	for endpoint, expected := range tests {
+		a := NewRegistryAuth(endpoint, "user", "secret")
+		assert.Equal(t, expected, a.ServerAddress, "endpoint %s", endpoint)
+	}
+}
+
+func Test_RegistriesAuth_AddToConfigFile(t *testing.T) {
+	cf := configfile.New("")
+	// The host credentials store should not be used for the registries of the deployment
+	cf.CredentialsStore = "desktop"
+
+	r := registriesAuth{}
+	r.SetRegistriesAuth([]registry.AuthConfig{
+		NewRegistryAuth("https://registry.example.com:5000", "user", "secret"),
+		NewRegistryAuth("https://index.docker.io/v1/", "hub-user", "hub-secret"),
+	})
+	r.addToConfigFile(cf)
+
+	a, err := cf.GetAuthConfig("registry.example.com:5000")
+	assert.NoError(t, err)
+	assert.Equal(t, "user", a.Username)
+	assert.Equal(t, "secret", a.Password)
+
+	a, err = cf.GetAuthConfig("https://index.docker.io/v1/")
+	assert.NoError(t, err)
+	assert.Equal(t, "hub-user", a.Username)
+
+	assert.Equal(t, "desktop", cf.CredentialsStore, "other registries should still use the host credentials store")
+}
+
+func Test_RegistriesAuth_AddToConfigFile_NoAuths(t *testing.T) {
+	cf := configfile.New("")
+	r := registriesAuth{}
+	r.addToConfigFile(cf)
+	assert.Empty(t, cf.AuthConfigs)
+	assert.Nil(t, cf.CredentialHelpers)
+}
@@ -0,0 +1,344 @@
+package resource_handler
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	appsv1ac "k8s.io/client-go/applyconfigurations/apps/v1"
+	corev1ac "k8s.io/client-go/applyconfigurations/core/v1"
+	metav1ac "k8s.io/client-go/applyconfigurations/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"nuvlaedge-go/common"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	// imagePullTimeout is the maximum time given to the nodes to pull an image
+	imagePullTimeout     = 10 * time.Minute
+	imagePullCheckPeriod = 2 * time.Second
+	// imagePullAppLabel marks the DaemonSets pulling the images in the nodes
+	imagePullAppLabel = "nuvlaedge-image-pull"
+	// restartedAtAnnotation changes the pod template of the deployments to restart, like kubectl rollout restart
+	restartedAtAnnotation = "kubectl.kubernetes.io/restartedAt"
+	// serviceAccountNamespaceFile holds the namespace of the pod the agent runs in
+	serviceAccountNamespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+	fieldManager                = "nuvlaedge"
+)
+
+// imagePullFailures are the reasons of the containers waiting because their image cannot be pulled
+var imagePullFailures = []string{"ErrImagePull", "ImagePullBackOff", "InvalidImageName", "ErrImageNeverPull"}
+
+// KubernetesResourceHandler handles the actions on the resources of the cluster. The namespaced resources are
+// identified as namespace/name, or name in the default namespace.
+type KubernetesResourceHandler struct {
+	client kubernetes.Interface
+	// namespace where the images are pulled, the one of the agent
+	namespace string
+
+	gathererFuncs map[string]map[string]ResourceActionFunc
+}
+
+func NewKubernetesResourceHandler(kCli kubernetes.Interface) (*KubernetesResourceHandler, error) {
+	if kCli == nil {
+		config, err := common.NewKubernetesConfig()
+		if err != nil {
+			return nil, err
+		}
+		kCli, err = kubernetes.NewForConfig(config)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	k := &KubernetesResourceHandler{
+		client:    kCli,
+		namespace: agentNamespace(),
+	}
+
+	k.gathererFuncs = map[string]map[string]ResourceActionFunc{
+		"pull": {
+			"image": k.pullImage,
+		},
+		"restart": {
+			"deployment": k.restartDeployment,
+		},
+		"remove": {
+			"pod":                   k.removePod,
+			"deployment":            k.removeDeployment,
+			"service":               k.removeService,
+			"persistentvolumeclaim": k.removePersistentVolumeClaim,
+			"namespace":             k.removeNamespace,
+		},
+	}
+
+	return k, nil
+}
+
+// agentNamespace returns the namespace of the pod the agent runs in, or the default namespace out of the cluster
+func agentNamespace() string {
+	// #nosec
+	b, err := os.ReadFile(serviceAccountNamespaceFile)
+	if err != nil || strings.TrimSpace(string(b)) == "" {
+		return metav1.NamespaceDefault
+	}
+	return strings.TrimSpace(string(b))
+}
+
+func (krh *KubernetesResourceHandler) HandleActions(ctx context.Context, actions []ResourceAction) []ResourceActionResponse {
+	responses := make([]ResourceActionResponse, len(actions))
+
+	for i, action := range actions {
+		log.Infof("Handling action %s on resource %s with id %s", action.Action, action.Resource, action.Id)
+		responses[i] = krh.handleAction(ctx, action)
+	}
+
+	return responses
+}
+
+func (krh *KubernetesResourceHandler) handleAction(ctx context.Context, action ResourceAction) ResourceActionResponse {
+	actionFunc, response := krh.getActionFunc(action)
+	if response != nil {
+		return *response
+	}
+
+	resp, err := actionFunc(ctx, action.Id)
+	if err != nil {
+		return *NewErrorResourceActionResponse(action.Resource, action.Action, action.Id, getCodeFromKubernetesError(err), err)
+	}
+
+	return resp
+}
+
+func (krh *KubernetesResourceHandler) getActionFunc(action ResourceAction) (ResourceActionFunc, *ResourceActionResponse) {
+	gatherer, ok := krh.gathererFuncs[action.Action]
+	if !ok {
+		return nil, NewNotImplementedActionResponse(action.Action)
+	}
+
+	actionFunc, ok := gatherer[action.Resource]
+	if !ok {
+		return nil, NewResourceNotAvailableForAction(action.Resource, action.Action)
+	}
+
+	return actionFunc, nil
+}
+
+// errInvalidId is answered with a bad request
+var errInvalidId = errors.New("invalid id, expected namespace/name")
+
+// splitNamespacedId returns the namespace and the name of the namespaced resource id
+func splitNamespacedId(id string) (string, string, error) {
+	namespace, name, found := strings.Cut(id, "/")
+	if !found {
+		namespace, name = metav1.NamespaceDefault, id
+	}
+	if namespace == "" || name == "" || strings.Contains(name, "/") {
+		return "", "", errInvalidId
+	}
+	return namespace, name, nil
+}
+
+func (krh *KubernetesResourceHandler) removePod(ctx context.Context, id string) (ResourceActionResponse, error) {
+	namespace, name, err := splitNamespacedId(id)
+	if err != nil {
+		return ResourceActionResponse{}, err
+	}
+	if err := krh.client.CoreV1().Pods(namespace).Delete(ctx, name, metav1.DeleteOptions{}); err != nil {
+		return ResourceActionResponse{}, err
+	}
+
+	msg := fmt.Sprintf("Pod %s removed successfully", id)
+	return *NewResourceActionResponse(true, 204, msg), nil
+}
+
+func (krh *KubernetesResourceHandler) removeDeployment(ctx context.Context, id string) (ResourceActionResponse, error) {
+	namespace, name, err := splitNamespacedId(id)
+	if err != nil {
+		return ResourceActionResponse{}, err
+	}
+	if err := krh.client.AppsV1().Deployments(namespace).Delete(ctx, name, metav1.DeleteOptions{}); err != nil {
+		return ResourceActionResponse{}, err
+	}
+
+	msg := fmt.Sprintf("Deployment %s removed successfully", id)
+	return *NewResourceActionResponse(true, 204, msg), nil
+}
+
+func (krh *KubernetesResourceHandler) removeService(ctx context.Context, id string) (ResourceActionResponse, error) {
+	namespace, name, err := splitNamespacedId(id)
+	if err != nil {
+		return ResourceActionResponse{}, err
+	}
+	if err := krh.client.CoreV1().Services(namespace).Delete(ctx, name, metav1.DeleteOptions{}); err != nil {
+		return ResourceActionResponse{}, err
+	}
+
+	msg := fmt.Sprintf("Service %s removed successfully", id)
+	return *NewResourceActionResponse(true, 204, msg), nil
+}
+
+func (krh *KubernetesResourceHandler) removePersistentVolumeClaim(ctx context.Context, id string) (ResourceActionResponse, error) {
+	namespace, name, err := splitNamespacedId(id)
+	if err != nil {
+		return ResourceActionResponse{}, err
+	}
+	if err := krh.client.CoreV1().PersistentVolumeClaims(namespace).Delete(ctx, name, metav1.DeleteOptions{}); err != nil {
+		return ResourceActionResponse{}, err
+	}
+
+	msg := fmt.Sprintf("PersistentVolumeClaim %s removed successfully", id)
+	return *NewResourceActionResponse(true, 204, msg), nil
+}
+
+func (krh *KubernetesResourceHandler) removeNamespace(ctx context.Context, id string) (ResourceActionResponse, error) {
+	if err := krh.client.CoreV1().Namespaces().Delete(ctx, id, metav1.DeleteOptions{}); err != nil {
+		return ResourceActionResponse{}, err
+	}
+
+	msg := fmt.Sprintf("Namespace %s removed successfully", id)
+	return *NewResourceActionResponse(true, 204, msg), nil
+}
+
+// restartDeployment replaces the pods of the deployment, following its update strategy
+func (krh *KubernetesResourceHandler) restartDeployment(ctx context.Context, id string) (ResourceActionResponse, error) {
+	namespace, name, err := splitNamespacedId(id)
+	if err != nil {
+		return ResourceActionResponse{}, err
+	}
+
+	patch := fmt.Sprintf(`{"spec":{"template":{"metadata":{"annotations":{%q:%q}}}}}`,
+		restartedAtAnnotation, time.Now().Format(time.RFC3339))
+	_, err = krh.client.AppsV1().Deployments(namespace).Patch(
+		ctx, name, k8stypes.StrategicMergePatchType, []byte(patch), metav1.PatchOptions{FieldManager: fieldManager})
+	if err != nil {
+		return ResourceActionResponse{}, err
+	}
+
+	msg := fmt.Sprintf("Deployment %s restarted successfully", id)
+	return *NewResourceActionResponse(true, 200, msg), nil
+}
+
+// pullImage pulls the image in every node with a DaemonSet running it, removed once the nodes pulled the image
+func (krh *KubernetesResourceHandler) pullImage(ctx context.Context, id string) (ResourceActionResponse, error) {
+	name := imagePullDaemonSetName(id)
+	labels := map[string]string{"app": name, "app.kubernetes.io/managed-by": imagePullAppLabel}
+
+	// The command of the container does not matter, the image is pulled before trying to run it
+	ds := appsv1ac.DaemonSet(name, krh.namespace).
+		WithLabels(labels).
+		WithSpec(appsv1ac.DaemonSetSpec().
+			WithSelector(metav1ac.LabelSelector().WithMatchLabels(map[string]string{"app": name})).
+			WithTemplate(corev1ac.PodTemplateSpec().
+				WithLabels(labels).
+				WithSpec(corev1ac.PodSpec().
+					WithTolerations(corev1ac.Toleration().WithOperator(corev1.TolerationOpExists)).
+					WithContainers(corev1ac.Container().
+						WithName("pull").
+						WithImage(id).
+						WithImagePullPolicy(corev1.PullAlways).
+						WithCommand("true")))))
+
+	dsClient := krh.client.AppsV1().DaemonSets(krh.namespace)
+	if _, err := dsClient.Apply(ctx, ds, metav1.ApplyOptions{FieldManager: fieldManager, Force: true}); err != nil {
+		return ResourceActionResponse{}, err
+	}
+	defer func() {
+		ctxDelete, cancel := context.WithTimeout(context.WithoutCancel(ctx), 30*time.Second)
+		defer cancel()
+		if err := dsClient.Delete(ctxDelete, name, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			log.Warnf("Error removing image pull DaemonSet %s: %s", name, err)
+		}
+	}()
+
+	nodes, err := krh.waitImagePull(ctx, name)
+	if err != nil {
+		return ResourceActionResponse{}, err
+	}
+
+	msg := fmt.Sprintf("Image %s pulled successfully in %d nodes", id, nodes)
+	return *NewResourceActionResponse(true, 200, msg), nil
+}
+
+// imagePullDaemonSetName names the DaemonSet pulling the image after its hash, as image references are no valid names
+func imagePullDaemonSetName(image string) string {
+	sum := sha256.Sum256([]byte(image))
+	return imagePullAppLabel + "-" + hex.EncodeToString(sum[:])[:10]
+}
+
+// waitImagePull waits for the pods of the DaemonSet to pull the image and returns the number of nodes that pulled it
+func (krh *KubernetesResourceHandler) waitImagePull(ctx context.Context, name string) (int, error) {
+	ctxTimed, cancel := context.WithTimeout(ctx, imagePullTimeout)
+	defer cancel()
+
+	ticker := time.NewTicker(imagePullCheckPeriod)
+	defer ticker.Stop()
+
+	for {
+		pulled, done, err := krh.imagePullStatus(ctxTimed, name)
+		if err != nil || done {
+			return pulled, err
+		}
+
+		select {
+		case <-ctxTimed.Done():
+			return pulled, fmt.Errorf("image pulled in %d nodes only: %w", pulled, ctxTimed.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// imagePullStatus returns the number of pods of the DaemonSet that pulled the image and whether all of them did
+func (krh *KubernetesResourceHandler) imagePullStatus(ctx context.Context, name string) (int, bool, error) {
+	ds, err := krh.client.AppsV1().DaemonSets(krh.namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return 0, false, err
+	}
+	pods, err := krh.client.CoreV1().Pods(krh.namespace).List(ctx, metav1.ListOptions{LabelSelector: "app=" + name})
+	if err != nil {
+		return 0, false, err
+	}
+
+	pulled := 0
+	for _, p := range pods.Items {
+		for _, s := range p.Status.ContainerStatuses {
+			if s.State.Waiting == nil {
+				pulled++
+				continue
+			}
+			switch reason := s.State.Waiting.Reason; {
+			case slices.Contains(imagePullFailures, reason):
+				return pulled, false, fmt.Errorf("node %s cannot pull the image: %s %s",
+					p.Spec.NodeName, reason, s.State.Waiting.Message)
+			case reason != "ContainerCreating" && reason != "PodInitializing":
+				// Failing to run the container, e.g. CrashLoopBackOff, means the image is there
+				pulled++
+			}
+		}
+	}
+
+	desired := int(ds.Status.DesiredNumberScheduled)
+	return pulled, desired > 0 && pulled >= desired, nil
+}
+
+// getCodeFromKubernetesError returns the HTTP code of the errors of the API server
+func getCodeFromKubernetesError(err error) int {
+	if errors.Is(err, errInvalidId) {
+		return 400
+	}
+	var status apierrors.APIStatus
+	if errors.As(err, &status) && status.Status().Code != 0 {
+		return int(status.Status().Code)
+	}
+	log.Warnf("Unknown error type: %T", err)
+	return 500
+}
@@ -0,0 +1,148 @@
+package resource_handler
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"nuvlaedge-go/testutils"
+	"testing"
+)
+
+func Test_KubernetesResourceHandler_Remove(t *testing.T) {
+	tests := []struct {
+		resource string
+		id       string
+		path     string
+	}{
+		{"pod", "apps/web", "/api/v1/namespaces/apps/pods/web"},
+		{"deployment", "web", "/apis/apps/v1/namespaces/default/deployments/web"},
+		{"service", "apps/web", "/api/v1/namespaces/apps/services/web"},
+		{"persistentvolumeclaim", "apps/data", "/api/v1/namespaces/apps/persistentvolumeclaims/data"},
+		{"namespace", "apps", "/api/v1/namespaces/apps"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.resource, func(t *testing.T) {
+			api := testutils.NewFakeKubeAPI()
+			defer api.Close()
+			krh, err := NewKubernetesResourceHandler(kubernetes.NewForConfigOrDie(api.Config()))
+			assert.NoError(t, err)
+			api.Set(tt.path, map[string]interface{}{"metadata": map[string]string{"name": tt.id}})
+			action := ResourceAction{Action: "remove", Resource: tt.resource, Id: tt.id}
+
+			responses := krh.HandleActions(context.Background(), []ResourceAction{action})
+			assert.Len(t, responses, 1)
+			assert.True(t, responses[0].Success, responses[0].Message)
+			assert.Equal(t, 204, responses[0].ReturnCode)
+			_, found := api.Get(tt.path)
+			assert.False(t, found, "resource should be removed")
+
+			responses = krh.HandleActions(context.Background(), []ResourceAction{action})
+			assert.False(t, responses[0].Success)
+			assert.Equal(t, 404, responses[0].ReturnCode)
+		})
+	}
+}
+
+func Test_KubernetesResourceHandler_InvalidId(t *testing.T) {
+	api := testutils.NewFakeKubeAPI()
+	defer api.Close()
+	krh, err := NewKubernetesResourceHandler(kubernetes.NewForConfigOrDie(api.Config()))
+	assert.NoError(t, err)
+
+	for _, id := range []string{"a/b/c", "/web", "apps/", ""} {
+		resp := krh.handleAction(context.Background(), ResourceAction{Action: "remove", Resource: "pod", Id: id})
+		assert.False(t, resp.Success)
+		assert.Equal(t, 400, resp.ReturnCode, "id %q should be rejected", id)
+	}
+}
+
+func Test_KubernetesResourceHandler_UnknownActionOrResource(t *testing.T) {
+	api := testutils.NewFakeKubeAPI()
+	defer api.Close()
+	krh, err := NewKubernetesResourceHandler(kubernetes.NewForConfigOrDie(api.Config()))
+	assert.NoError(t, err)
+
+	resp := krh.handleAction(context.Background(), ResourceAction{Action: "stop", Resource: "pod", Id: "web"})
+	assert.Equal(t, 501, resp.ReturnCode)
+
+	resp = krh.handleAction(context.Background(), ResourceAction{Action: "restart", Resource: "pod", Id: "web"})
+	assert.Equal(t, 404, resp.ReturnCode)
+}
+
+func Test_KubernetesResourceHandler_RestartDeployment(t *testing.T) {
+	api := testutils.NewFakeKubeAPI()
+	defer api.Close()
+	krh, err := NewKubernetesResourceHandler(kubernetes.NewForConfigOrDie(api.Config()))
+	assert.NoError(t, err)
+	path := "/apis/apps/v1/namespaces/apps/deployments/web"
+	api.Set(path, appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "apps"}})
+
+	resp := krh.handleAction(context.Background(), ResourceAction{Action: "restart", Resource: "deployment", Id: "apps/web"})
+	assert.True(t, resp.Success, resp.Message)
+	assert.Equal(t, 200, resp.ReturnCode)
+
+	obj, _ := api.Get(path)
+	annotations := obj["spec"].(map[string]interface{})["template"].(map[string]interface{})["metadata"].(map[string]interface{})["annotations"].(map[string]interface{})
+	assert.Contains(t, annotations, restartedAtAnnotation)
+
+	resp = krh.handleAction(context.Background(), ResourceAction{Action: "restart", Resource: "deployment", Id: "apps/db"})
+	assert.Equal(t, 404, resp.ReturnCode)
+}
+
+func setImagePullStatus(api *testutils.FakeKubeAPI, image string, desired int32, statuses ...corev1.ContainerStatus) string {
+	name := imagePullDaemonSetName(image)
+	path := "/apis/apps/v1/namespaces/default/daemonsets/" + name
+	api.Set(path, appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Status:     appsv1.DaemonSetStatus{DesiredNumberScheduled: desired},
+	})
+
+	var pods []corev1.Pod
+	for _, s := range statuses {
+		pods = append(pods, corev1.Pod{
+			Spec:   corev1.PodSpec{NodeName: "node"},
+			Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{s}},
+		})
+	}
+	api.Set("/api/v1/namespaces/default/pods", corev1.PodList{Items: pods})
+	return path
+}
+
+func Test_KubernetesResourceHandler_PullImage(t *testing.T) {
+	api := testutils.NewFakeKubeAPI()
+	defer api.Close()
+	krh, err := NewKubernetesResourceHandler(kubernetes.NewForConfigOrDie(api.Config()))
+	assert.NoError(t, err)
+	path := setImagePullStatus(api, "nginx:1.27", 2,
+		corev1.ContainerStatus{State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{}}},
+		corev1.ContainerStatus{State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}}})
+
+	resp := krh.handleAction(context.Background(), ResourceAction{Action: "pull", Resource: "image", Id: "nginx:1.27"})
+	assert.True(t, resp.Success, resp.Message)
+	assert.Equal(t, 200, resp.ReturnCode)
+	assert.Equal(t, "Image nginx:1.27 pulled successfully in 2 nodes", resp.Message)
+
+	_, found := api.Get(path)
+	assert.False(t, found, "image pull DaemonSet should be removed")
+}
+
+func Test_KubernetesResourceHandler_PullImage_Failure(t *testing.T) {
+	api := testutils.NewFakeKubeAPI()
+	defer api.Close()
+	krh, err := NewKubernetesResourceHandler(kubernetes.NewForConfigOrDie(api.Config()))
+	assert.NoError(t, err)
+	path := setImagePullStatus(api, "nginx:missing", 1,
+		corev1.ContainerStatus{State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "ErrImagePull"}}})
+
+	resp := krh.handleAction(context.Background(), ResourceAction{Action: "pull", Resource: "image", Id: "nginx:missing"})
+	assert.False(t, resp.Success)
+	assert.Equal(t, 500, resp.ReturnCode)
+	assert.Contains(t, resp.Message, "ErrImagePull")
+
+	_, found := api.Get(path)
+	assert.False(t, found, "image pull DaemonSet should be removed")
+}
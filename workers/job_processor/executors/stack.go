@@ -10,17 +10,27 @@ import (
 	composetypes "github.com/docker/cli/cli/compose/types"
 	"github.com/docker/cli/cli/flags"
 	"github.com/docker/cli/opts"
+	swarmtypes "github.com/docker/docker/api/types/swarm"
 	"github.com/nuvla/api-client-go/clients/resources"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/pflag"
 	"io"
+	"nuvlaedge-go/common/constants"
 	"nuvlaedge-go/updater/common"
 	"os"
 	"path/filepath"
+	"time"
+)
+
+const (
+	// stackConvergeTimeout is the maximum time to wait for the tasks of a stack deployment to run
+	stackConvergeTimeout     = 5 * time.Minute
+	stackConvergeCheckPeriod = 3 * time.Second
 )
 
 type Stack struct {
 	ExecutorBase
+	registriesAuth
 
 	deploymentResource *resources.DeploymentResource
 	projectName        string
@@ -41,7 +51,7 @@ func (s *Stack) StartDeployment(ctx context.Context) error {
 	log.Infof("Starting deployment for project %s", s.projectName)
 
 	// Prepare docker client and context. Cannot fail
-	if err := s.setUpDockerCLI(); err != nil {
+	if err := s.setUpDockerCLI(ctx); err != nil {
 		return err
 	}
 
@@ -49,6 +59,8 @@ func (s *Stack) StartDeployment(ctx context.Context) error {
 	if err := s.setUpFiles(); err != nil {
 		return err
 	}
+	// The credentials are sent to the swarm agents for them to pull the images
+	s.addToConfigFile(s.dockerCli.ConfigFile())
 	for _, s := range s.stackConfig.Services {
 		log.Infof("Starting Stack service %s", s.Name)
 	}
@@ -59,13 +71,15 @@ func (s *Stack) StartDeployment(ctx context.Context) error {
 		return err
 	}
 
+	// Services not converging are not an error when starting, they might be restarting
+	s.waitConvergence(ctx, ProgressFromContext(ctx))
 	return nil
 }
 
 func (s *Stack) StopDeployment(ctx context.Context) error {
 	s.projectName = GetProjectNameFromDeploymentId(s.deploymentResource.Id)
 
-	if err := s.setUpDockerCLI(); err != nil {
+	if err := s.setUpDockerCLI(ctx); err != nil {
 		return err
 	}
 
@@ -81,12 +95,8 @@ func (s *Stack) StateDeployment(_ context.Context) error {
 	return nil
 }
 
-func (s *Stack) UpdateDeployment(ctx context.Context) error {
-	return s.StartDeployment(ctx)
-}
-
 func (s *Stack) GetServices(ctx context.Context) ([]DeploymentService, error) {
-	if err := s.setUpDockerCLI(); err != nil {
+	if err := s.setUpDockerCLI(ctx); err != nil {
 		return nil, err
 	}
 	s.projectName = GetProjectNameFromDeploymentId(s.deploymentResource.Id)
@@ -122,6 +132,63 @@ func (s *Stack) deploy(ctx context.Context) error {
 	return nil
 }
 
+// waitConvergence reports the tasks of the stack services converging until all of them are running, the context is
+// done or stackConvergeTimeout expires, and returns whether they converged.
+func (s *Stack) waitConvergence(ctx context.Context, report ProgressFunc) bool {
+	ctxTimed, cancel := context.WithTimeout(ctx, stackConvergeTimeout)
+	defer cancel()
+
+	ticker := time.NewTicker(stackConvergeCheckPeriod)
+	defer ticker.Stop()
+
+	for {
+		services, err := swarm.GetServices(ctxTimed, s.dockerCli, options.Services{
+			Namespace: s.projectName,
+			Filter:    opts.NewFilterOpt(),
+		})
+		if err != nil {
+			log.Debugf("Error retrieving stack services: %s", err)
+		} else if reportConvergence(services, report) {
+			log.Infof("Stack %s services converged", s.projectName)
+			return true
+		}
+
+		select {
+		case <-ctxTimed.Done():
+			log.Warnf("Stack %s services not converged yet, not waiting any longer", s.projectName)
+			return false
+		case <-ticker.C:
+		}
+	}
+}
+
+// reportConvergence reports the running tasks of each service and returns whether all of them are running
+func reportConvergence(services []swarmtypes.Service, report ProgressFunc) bool {
+	converged := true
+	for _, svc := range services {
+		if svc.ServiceStatus == nil {
+			continue
+		}
+		desired, running := svc.ServiceStatus.DesiredTasks, svc.ServiceStatus.RunningTasks
+		e := ProgressEvent{
+			ID:      svc.Spec.Name,
+			Text:    "Converging",
+			Status:  fmt.Sprintf("%d/%d tasks running", running, desired),
+			Percent: 100,
+			Done:    running >= desired,
+		}
+		if desired > 0 {
+			e.Percent = int(min(running, desired) * 100 / desired)
+		}
+		if e.Done {
+			e.Text = "Running"
+		}
+		converged = converged && e.Done
+		report(e)
+	}
+	return converged
+}
+
 func (s *Stack) remove(ctx context.Context) error {
 	err := swarm.RunRemove(
 		ctx,
@@ -142,6 +209,8 @@ func (s *Stack) setUpStackOpts() {
 		Detach:       true,
 		Quiet:        true,
 		ResolveImage: swarm.ResolveImageAlways,
+		// Like --with-registry-auth, when the deployment uses private registries
+		SendRegistryAuth: len(s.auths) > 0,
 	}
 }
 
@@ -173,6 +242,13 @@ func (s *Stack) setUpFiles() error {
 		log.Errorf("Error loading compose file: %s", err)
 		return err
 	}
+	// The services are labelled, like the compose containers, for the NuvlaEdge to find them when decommissioned
+	for i, svc := range c.Services {
+		if svc.Deploy.Labels == nil {
+			c.Services[i].Deploy.Labels = make(composetypes.Labels)
+		}
+		c.Services[i].Deploy.Labels[constants.DeploymentLabel] = s.deploymentResource.Id
+	}
 	s.stackConfig = c
 
 	// Setup config files if they exist
@@ -189,11 +265,12 @@ func (s *Stack) setUpFiles() error {
 	return nil
 }
 
-// setUpDockerCLI prepares the docker client and its context
-func (s *Stack) setUpDockerCLI() error {
+// setUpDockerCLI prepares the docker client and its context. The services deployed are reported to the progress
+// function of the context.
+func (s *Stack) setUpDockerCLI(ctx context.Context) error {
 	s.dockerOutPut = NewCaptureWriter()
 
-	dCli, err := command.NewDockerCli(command.WithCombinedStreams(s.dockerOutPut))
+	dCli, err := command.NewDockerCli(command.WithCombinedStreams(stackOutput(s.dockerOutPut, ProgressFromContext(ctx))))
 	if err != nil {
 		log.Errorf("Error creating docker cli")
 		return err
@@ -0,0 +1,294 @@
+package executors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/docker/cli/cli/command"
+	"github.com/docker/cli/cli/compose/convert"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/image"
+	swarmtypes "github.com/docker/docker/api/types/swarm"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/jsonmessage"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"time"
+)
+
+// UpdateDeployment pulls the images of the new version before updating the services. The update succeeds once swarm
+// completed the update of the services and their tasks keep running the new version, otherwise the services are
+// restored as they were before the update.
+func (s *Stack) UpdateDeployment(ctx context.Context) error {
+	s.projectName = GetProjectNameFromDeploymentId(s.deploymentResource.Id)
+	log.Infof("Updating deployment for project %s", s.projectName)
+
+	if err := s.setUpDockerCLI(ctx); err != nil {
+		return err
+	}
+	if err := s.setUpFiles(); err != nil {
+		return err
+	}
+	defer s.CleanUp()
+	s.addToConfigFile(s.dockerCli.ConfigFile())
+
+	previous, err := listStackServices(ctx, s.dockerCli.Client(), s.projectName)
+	if err != nil {
+		return NewUpdateError(UpdateAborted, fmt.Errorf("error recording the services running: %w", err))
+	}
+
+	if err := s.pullImages(ctx); err != nil {
+		return NewUpdateError(UpdateAborted, err)
+	}
+
+	report := ProgressFromContext(ctx)
+	err = s.deploy(ctx)
+	if err == nil {
+		err = waitUpdate(ctx, s.dockerCli.Client(), s.projectName, report)
+	}
+	if err == nil {
+		_, _ = fmt.Fprintln(s.dockerOutPut, "Update succeeded, new version running")
+		return nil
+	}
+	log.Errorf("New version of deployment %s not running: %s", s.projectName, err)
+
+	if len(previous) == 0 {
+		return NewUpdateError(UpdateFailed, err)
+	}
+
+	log.Infof("Restoring previous version of deployment %s", s.projectName)
+	rbErr := rollbackServices(ctx, s.dockerCli.Client(), s.projectName, previous, s.encodedAuth)
+	if rbErr == nil {
+		rbErr = waitUpdate(ctx, s.dockerCli.Client(), s.projectName, report)
+	}
+	if rbErr != nil {
+		return NewUpdateError(UpdateRollbackFailed, fmt.Errorf("%w, restoring previous version: %s", err, rbErr))
+	}
+	return NewUpdateError(UpdateRolledBack, err)
+}
+
+// pullImages pulls the images of the services in this node. The other nodes pull them while the services update, the
+// failures there are rolled back as any other.
+func (s *Stack) pullImages(ctx context.Context) error {
+	pulled := make(map[string]bool)
+	for _, svc := range s.stackConfig.Services {
+		if svc.Image == "" || pulled[svc.Image] {
+			continue
+		}
+
+		auth, err := s.encodedAuth(svc.Image)
+		if err != nil {
+			return fmt.Errorf("error pulling image %s: %w", svc.Image, err)
+		}
+		rc, err := s.dockerCli.Client().ImagePull(ctx, svc.Image, image.PullOptions{RegistryAuth: auth})
+		if err != nil {
+			return fmt.Errorf("error pulling image %s: %w", svc.Image, err)
+		}
+		// The errors of the pull are part of the stream
+		err = jsonmessage.DisplayJSONMessagesStream(rc, io.Discard, 0, false, nil)
+		_ = rc.Close()
+		if err != nil {
+			return fmt.Errorf("error pulling image %s: %w", svc.Image, err)
+		}
+
+		pulled[svc.Image] = true
+		_, _ = fmt.Fprintf(s.dockerOutPut, "Image %s pulled\n", svc.Image)
+	}
+	return nil
+}
+
+// encodedAuth returns the credentials of the registry of the image, if any, as expected by the swarm API
+func (s *Stack) encodedAuth(image string) (string, error) {
+	return command.RetrieveAuthTokenFromImage(s.dockerCli.ConfigFile(), image)
+}
+
+// waitUpdate waits for the services of the stack to be updated, and then to stay so during updateStabilityPeriod. It
+// fails as soon as swarm pauses or rolls back the update of a service, or after stackConvergeTimeout.
+func waitUpdate(ctx context.Context, cli client.ServiceAPIClient, namespace string, report ProgressFunc) error {
+	ctxTimed, cancel := context.WithTimeout(ctx, stackConvergeTimeout)
+	defer cancel()
+
+	ticker := time.NewTicker(stackConvergeCheckPeriod)
+	defer ticker.Stop()
+
+	var updatedSince time.Time
+	for {
+		updated, err := checkStackUpdated(ctxTimed, cli, namespace, report)
+		var updateErr *serviceUpdateError
+		if errors.As(err, &updateErr) {
+			return err
+		}
+		if err != nil {
+			log.Debugf("Error checking the update of stack %s: %s", namespace, err)
+		}
+
+		if !updated {
+			updatedSince = time.Time{}
+		} else if updatedSince.IsZero() {
+			updatedSince = time.Now()
+		}
+		if updated && time.Since(updatedSince) >= updateStabilityPeriod {
+			log.Infof("Stack %s services updated", namespace)
+			return nil
+		}
+
+		select {
+		case <-ctxTimed.Done():
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("services not updated after %s", stackConvergeTimeout)
+		case <-ticker.C:
+		}
+	}
+}
+
+// serviceUpdateError is returned when swarm gave up the update of a service
+type serviceUpdateError struct {
+	service string
+	status  swarmtypes.UpdateStatus
+}
+
+func (e *serviceUpdateError) Error() string {
+	return fmt.Sprintf("update of service %s %s: %s", e.service, e.status.State, e.status.Message)
+}
+
+// checkStackUpdated reports the progress of the update of each service of the stack and returns whether all of them
+// are updated
+func checkStackUpdated(ctx context.Context, cli client.ServiceAPIClient, namespace string, report ProgressFunc) (bool, error) {
+	services, err := listStackServices(ctx, cli, namespace)
+	if err != nil {
+		return false, err
+	}
+
+	stackUpdated := true
+	for _, svc := range services {
+		tasks, err := cli.TaskList(ctx, types.TaskListOptions{Filters: filters.NewArgs(
+			filters.Arg("service", svc.ID),
+			filters.Arg("desired-state", string(swarmtypes.TaskStateRunning)))})
+		if err != nil {
+			return false, err
+		}
+
+		updated, running, err := checkServiceUpdated(svc, tasks)
+		if err != nil {
+			return false, err
+		}
+		e := ProgressEvent{
+			ID:     svc.Spec.Name,
+			Text:   "Updating",
+			Status: fmt.Sprintf("%d/%d tasks updated", running, len(tasks)),
+			Done:   updated,
+		}
+		if updated {
+			e.Text = "Updated"
+		}
+		report(e)
+		stackUpdated = stackUpdated && updated
+	}
+	return stackUpdated, nil
+}
+
+// checkServiceUpdated returns whether swarm completed the update of the service, if any, and all its tasks run the
+// image of its spec. It also returns the number of tasks running that image. Swarm only reports the tasks of the new
+// version as completed once all of them are running.
+func checkServiceUpdated(svc swarmtypes.Service, tasks []swarmtypes.Task) (bool, int, error) {
+	if svc.UpdateStatus != nil {
+		switch svc.UpdateStatus.State {
+		case swarmtypes.UpdateStatePaused, swarmtypes.UpdateStateRollbackStarted, swarmtypes.UpdateStateRollbackPaused,
+			swarmtypes.UpdateStateRollbackCompleted:
+			return false, 0, &serviceUpdateError{service: svc.Spec.Name, status: *svc.UpdateStatus}
+		}
+	}
+
+	image := serviceImage(svc.Spec)
+	running := 0
+	for _, t := range tasks {
+		if t.Status.State == swarmtypes.TaskStateRunning && t.Spec.ContainerSpec != nil && t.Spec.ContainerSpec.Image == image {
+			running++
+		}
+	}
+
+	desired := len(tasks)
+	if svc.ServiceStatus != nil {
+		desired = int(svc.ServiceStatus.DesiredTasks)
+	}
+	completed := svc.UpdateStatus == nil || svc.UpdateStatus.State == swarmtypes.UpdateStateCompleted
+	return completed && running == len(tasks) && running >= desired, running, nil
+}
+
+func listStackServices(ctx context.Context, cli client.ServiceAPIClient, namespace string) ([]swarmtypes.Service, error) {
+	return cli.ServiceList(ctx, types.ServiceListOptions{
+		Filters: filters.NewArgs(filters.Arg("label", convert.LabelNamespace+"="+namespace)),
+		Status:  true,
+	})
+}
+
+// rollbackServices restores the services of the stack as they were before the update: their previous specs are
+// applied again, the services created by the update are removed and the ones it pruned are created again.
+func rollbackServices(
+	ctx context.Context,
+	cli client.ServiceAPIClient,
+	namespace string,
+	previous []swarmtypes.Service,
+	authFunc func(image string) (string, error)) error {
+
+	current, err := listStackServices(ctx, cli, namespace)
+	if err != nil {
+		return err
+	}
+
+	specAuth := func(spec swarmtypes.ServiceSpec) (string, error) {
+		if img := serviceImage(spec); img != "" {
+			return authFunc(img)
+		}
+		return "", nil
+	}
+
+	toRestore := make(map[string]swarmtypes.Service, len(previous))
+	for _, svc := range previous {
+		toRestore[svc.Spec.Name] = svc
+	}
+
+	var errList []error
+	for _, svc := range current {
+		prev, ok := toRestore[svc.Spec.Name]
+		if !ok {
+			if err := cli.ServiceRemove(ctx, svc.ID); err != nil {
+				errList = append(errList, fmt.Errorf("error removing service %s: %w", svc.Spec.Name, err))
+			}
+			continue
+		}
+		delete(toRestore, svc.Spec.Name)
+
+		auth, err := specAuth(prev.Spec)
+		if err != nil {
+			errList = append(errList, err)
+			continue
+		}
+		_, err = cli.ServiceUpdate(ctx, svc.ID, svc.Version, prev.Spec, types.ServiceUpdateOptions{EncodedRegistryAuth: auth})
+		if err != nil {
+			errList = append(errList, fmt.Errorf("error restoring service %s: %w", svc.Spec.Name, err))
+		}
+	}
+
+	for name, prev := range toRestore {
+		auth, err := specAuth(prev.Spec)
+		if err != nil {
+			errList = append(errList, err)
+			continue
+		}
+		if _, err := cli.ServiceCreate(ctx, prev.Spec, types.ServiceCreateOptions{EncodedRegistryAuth: auth}); err != nil {
+			errList = append(errList, fmt.Errorf("error creating service %s: %w", name, err))
+		}
+	}
+	return errors.Join(errList...)
+}
+
+func serviceImage(spec swarmtypes.ServiceSpec) string {
+	if spec.TaskTemplate.ContainerSpec == nil {
+		return ""
+	}
+	return spec.TaskTemplate.ContainerSpec.Image
+}
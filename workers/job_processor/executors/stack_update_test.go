@@ -0,0 +1,189 @@
+package executors
+
+import (
+	"context"
+	"errors"
+	"github.com/docker/docker/api/types"
+	swarmtypes "github.com/docker/docker/api/types/swarm"
+	"github.com/docker/docker/client"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+// fakeServiceClient serves the services listed and records the changes on them
+type fakeServiceClient struct {
+	client.ServiceAPIClient
+
+	services []swarmtypes.Service
+	tasks    []swarmtypes.Task
+
+	updated map[string]swarmtypes.ServiceSpec
+	auths   map[string]string
+	created []string
+	removed []string
+}
+
+func (f *fakeServiceClient) ServiceList(_ context.Context, _ types.ServiceListOptions) ([]swarmtypes.Service, error) {
+	return f.services, nil
+}
+
+func (f *fakeServiceClient) TaskList(_ context.Context, opts types.TaskListOptions) ([]swarmtypes.Task, error) {
+	var tasks []swarmtypes.Task
+	for _, t := range f.tasks {
+		if opts.Filters.ExactMatch("service", t.ServiceID) {
+			tasks = append(tasks, t)
+		}
+	}
+	return tasks, nil
+}
+
+func (f *fakeServiceClient) ServiceUpdate(_ context.Context, id string, version swarmtypes.Version,
+	spec swarmtypes.ServiceSpec, opts types.ServiceUpdateOptions) (swarmtypes.ServiceUpdateResponse, error) {
+	if version.Index != 2 {
+		return swarmtypes.ServiceUpdateResponse{}, errors.New("update out of sequence")
+	}
+	f.updated[id] = spec
+	f.auths[spec.Name] = opts.EncodedRegistryAuth
+	return swarmtypes.ServiceUpdateResponse{}, nil
+}
+
+func (f *fakeServiceClient) ServiceCreate(_ context.Context, spec swarmtypes.ServiceSpec,
+	opts types.ServiceCreateOptions) (swarmtypes.ServiceCreateResponse, error) {
+	f.created = append(f.created, spec.Name)
+	f.auths[spec.Name] = opts.EncodedRegistryAuth
+	return swarmtypes.ServiceCreateResponse{}, nil
+}
+
+func (f *fakeServiceClient) ServiceRemove(_ context.Context, id string) error {
+	f.removed = append(f.removed, id)
+	return nil
+}
+
+func testService(id, name, image string, version uint64) swarmtypes.Service {
+	return swarmtypes.Service{
+		ID:   id,
+		Meta: swarmtypes.Meta{Version: swarmtypes.Version{Index: version}},
+		Spec: swarmtypes.ServiceSpec{
+			Annotations:  swarmtypes.Annotations{Name: name},
+			TaskTemplate: swarmtypes.TaskSpec{ContainerSpec: &swarmtypes.ContainerSpec{Image: image}},
+		},
+	}
+}
+
+func Test_RollbackServices(t *testing.T) {
+	previous := []swarmtypes.Service{
+		testService("1", "app_web", "nginx:1.25@sha256:old", 1),
+		testService("2", "app_worker", "registry.example.com/worker:1@sha256:old", 1),
+	}
+	cli := &fakeServiceClient{
+		// The update changed web, pruned worker and added cache
+		services: []swarmtypes.Service{
+			testService("1", "app_web", "nginx:1.27@sha256:new", 2),
+			testService("3", "app_cache", "redis:7", 2),
+		},
+		updated: make(map[string]swarmtypes.ServiceSpec),
+		auths:   make(map[string]string),
+	}
+	auth := func(image string) (string, error) {
+		return "auth-" + image, nil
+	}
+
+	assert.NoError(t, rollbackServices(context.Background(), cli, "app", previous, auth))
+	assert.Equal(t, "nginx:1.25@sha256:old", cli.updated["1"].TaskTemplate.ContainerSpec.Image)
+	assert.Equal(t, []string{"app_worker"}, cli.created)
+	assert.Equal(t, []string{"3"}, cli.removed)
+	assert.Equal(t, "auth-registry.example.com/worker:1@sha256:old", cli.auths["app_worker"],
+		"the registry credentials should be sent to restore the services")
+}
+
+func Test_RollbackServices_AuthError(t *testing.T) {
+	previous := []swarmtypes.Service{testService("1", "app_web", "nginx:1.25", 1)}
+	cli := &fakeServiceClient{
+		services: []swarmtypes.Service{testService("1", "app_web", "nginx:1.27", 2)},
+		updated:  make(map[string]swarmtypes.ServiceSpec),
+		auths:    make(map[string]string),
+	}
+	auth := func(string) (string, error) {
+		return "", errors.New("invalid reference format")
+	}
+
+	assert.ErrorContains(t, rollbackServices(context.Background(), cli, "app", previous, auth), "invalid reference")
+	assert.Empty(t, cli.updated)
+}
+
+func Test_CheckServiceUpdated(t *testing.T) {
+	task := func(image string, state swarmtypes.TaskState) swarmtypes.Task {
+		return swarmtypes.Task{
+			Spec:   swarmtypes.TaskSpec{ContainerSpec: &swarmtypes.ContainerSpec{Image: image}},
+			Status: swarmtypes.TaskStatus{State: state},
+		}
+	}
+	newTask := task("nginx:1.27", swarmtypes.TaskStateRunning)
+
+	tests := []struct {
+		name    string
+		state   swarmtypes.UpdateState
+		desired uint64
+		tasks   []swarmtypes.Task
+		updated bool
+		running int
+		err     bool
+	}{
+		{name: "completed", state: swarmtypes.UpdateStateCompleted, desired: 2,
+			tasks: []swarmtypes.Task{newTask, newTask}, updated: true, running: 2},
+		{name: "created", desired: 1, tasks: []swarmtypes.Task{newTask}, updated: true, running: 1},
+		{name: "updating", state: swarmtypes.UpdateStateUpdating, desired: 2,
+			tasks: []swarmtypes.Task{newTask, task("nginx:1.25", swarmtypes.TaskStateRunning)}, running: 1},
+		{name: "completed but previous tasks", state: swarmtypes.UpdateStateCompleted, desired: 1,
+			tasks: []swarmtypes.Task{task("nginx:1.25", swarmtypes.TaskStateRunning)}},
+		{name: "tasks starting", state: swarmtypes.UpdateStateCompleted, desired: 1,
+			tasks: []swarmtypes.Task{task("nginx:1.27", swarmtypes.TaskStateStarting)}},
+		{name: "tasks missing", state: swarmtypes.UpdateStateCompleted, desired: 2,
+			tasks: []swarmtypes.Task{newTask}, running: 1},
+		{name: "paused", state: swarmtypes.UpdateStatePaused, desired: 1, tasks: []swarmtypes.Task{newTask}, err: true},
+		{name: "rolled back", state: swarmtypes.UpdateStateRollbackCompleted, desired: 1,
+			tasks: []swarmtypes.Task{newTask}, err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := testService("1", "app_web", "nginx:1.27", 2)
+			svc.ServiceStatus = &swarmtypes.ServiceStatus{DesiredTasks: tt.desired}
+			if tt.state != "" {
+				svc.UpdateStatus = &swarmtypes.UpdateStatus{State: tt.state}
+			}
+
+			updated, running, err := checkServiceUpdated(svc, tt.tasks)
+			assert.Equal(t, tt.err, err != nil)
+			assert.Equal(t, tt.updated, updated)
+			assert.Equal(t, tt.running, running)
+		})
+	}
+}
+
+func Test_WaitUpdate(t *testing.T) {
+	defer func(period time.Duration) { updateStabilityPeriod = period }(updateStabilityPeriod)
+	updateStabilityPeriod = 0
+	svc := testService("1", "app_web", "nginx:1.27", 2)
+	svc.UpdateStatus = &swarmtypes.UpdateStatus{State: swarmtypes.UpdateStateCompleted}
+	cli := &fakeServiceClient{
+		services: []swarmtypes.Service{svc},
+		tasks: []swarmtypes.Task{{
+			ServiceID: "1",
+			Spec:      swarmtypes.TaskSpec{ContainerSpec: &swarmtypes.ContainerSpec{Image: "nginx:1.27"}},
+			Status:    swarmtypes.TaskStatus{State: swarmtypes.TaskStateRunning},
+		}},
+	}
+	var events []ProgressEvent
+	report := func(e ProgressEvent) { events = append(events, e) }
+
+	assert.NoError(t, waitUpdate(context.Background(), cli, "app", report))
+	assert.Equal(t, []ProgressEvent{{ID: "app_web", Text: "Updated", Status: "1/1 tasks updated", Done: true}}, events)
+
+	cli.services[0].UpdateStatus = &swarmtypes.UpdateStatus{
+		State:   swarmtypes.UpdateStateRollbackStarted,
+		Message: "update rolled back due to failure or early termination of task",
+	}
+	assert.ErrorContains(t, waitUpdate(context.Background(), cli, "app", report), "rollback_started",
+		"the update should fail as soon as swarm rolls it back")
+}
@@ -5,18 +5,24 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/docker/compose/v2/pkg/api"
+	"github.com/docker/docker/api/types/registry"
 	"github.com/docker/docker/api/types/swarm"
 	"github.com/nuvla/api-client-go/clients/resources"
 	"nuvlaedge-go/types/errors"
+	"nuvlaedge-go/types/options/command"
 	"nuvlaedge-go/workers/job_processor/executors/resource_handler"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // Rebooter is an interface for executors that can reboot the system.
 type Rebooter interface {
 	Executor
-	Reboot() error
+	// Reboot triggers a clean reboot, delayed so that the result of the job can be reported before the shutdown
+	Reboot(ctx context.Context) error
+	// StopDeployments gracefully stops the deployments, giving them timeout to stop before being killed
+	StopDeployments(ctx context.Context, timeout time.Duration) error
 }
 
 // GetRebooter returns the appropriate Rebooter for the current environment. Question, should we trigger K8s or docker
@@ -24,14 +30,14 @@ type Rebooter interface {
 func GetRebooter(needsRoot bool) (Rebooter, error) {
 	switch WhereAmI() {
 	case DockerMode:
-		return &Docker{}, nil
+		return &Docker{ExecutorBase: ExecutorBase{Name: DockerExecutorName}}, nil
 	case KubernetesMode:
-		return &Kubernetes{}, nil
+		return &Kubernetes{ExecutorBase: ExecutorBase{Name: KubernetesExecutorName}}, nil
 	case HostMode:
 		if needsRoot && !IsSuperUser() {
 			return nil, errors.NewActionRequiresSudoError("reboot")
 		}
-		return &Host{}, nil
+		return &Host{ExecutorBase: ExecutorBase{Name: HostExecutorName}}, nil
 	}
 	return nil, fmt.Errorf("no executor found for mode %s", WhereAmI())
 }
@@ -44,6 +50,8 @@ type Deployer interface {
 	StateDeployment(ctx context.Context) error
 	UpdateDeployment(ctx context.Context) error
 	GetServices(ctx context.Context) ([]DeploymentService, error)
+	// SetRegistriesAuth sets the credentials of the private registries the images of the deployment are pulled from
+	SetRegistriesAuth(auths []registry.AuthConfig)
 	// Close TODO: For the moment, we only need to close dockerCLI
 	Close() error
 
@@ -73,7 +81,10 @@ func GetDeployer(resource *resources.DeploymentResource) (Deployer, error) {
 			return nil, errors.NewNotImplementedActionError(compatibility)
 		}
 	case "application_kubernetes":
-		return nil, errors.NewNotImplementedActionError("kubernetes deployment")
+		return &KubernetesDeployer{
+			ExecutorBase:       ExecutorBase{Name: KubernetesExecutorName},
+			deploymentResource: resource,
+		}, nil
 	default:
 		return nil, errors.NewNotImplementedActionError(subType)
 	}
@@ -197,22 +208,26 @@ type SSHKeyManager interface {
 	RevokeSSKKey(sshkey string) error
 }
 
+// Updater is an interface for executors that can update the NuvlaEdge installation they run in
 type Updater interface {
 	Executor
-	UpdateNuvlaEdge() error
+	UpdateNuvlaEdge(ctx context.Context, opts *command.UpdateCmdOptions) error
+
+	// GetOutput returns the output of the update
+	GetOutput() string
 }
 
 // GetUpdater returns the appropriate Updater for the current environment.
-func GetUpdater() Updater {
+func GetUpdater() (Updater, error) {
 	switch WhereAmI() {
 	case DockerMode:
-		return &Docker{}
+		return &Docker{ExecutorBase: ExecutorBase{Name: DockerExecutorName}}, nil
 	//case KubernetesMode:
 	//	return &Kubernetes{}
 	case HostMode:
-		return &Docker{}
+		return &Host{ExecutorBase: ExecutorBase{Name: HostExecutorName}}, nil
 	}
-	return nil
+	return nil, fmt.Errorf("no updater found for mode %s", WhereAmI())
 }
 
 type CoeResourceManager interface {
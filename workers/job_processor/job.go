@@ -12,6 +12,7 @@ import (
 	errors2 "nuvlaedge-go/types/errors"
 	"nuvlaedge-go/types/jobs"
 	"nuvlaedge-go/workers/job_processor/actions"
+	"nuvlaedge-go/workers/job_processor/executors"
 	"time"
 )
 
@@ -24,6 +25,10 @@ type Job interface {
 	Init(ctx context.Context, coe engine.Coe, enableLegacy bool, legacyImage string) (Job, error)
 	GetId() string
 	GetJobType() string
+	GetPriority() int
+	GetTarget() string
+	GetScheduledTime() time.Time
+	IsExpired() bool
 }
 
 func NewJob(ctx context.Context, jobId string, c *nuvla.NuvlaClient, coe engine.Coe, enableLegacy bool, legacyImage string) (Job, error) {
@@ -49,6 +54,43 @@ func (j *JobBase) GetJobType() string {
 	return j.JobType
 }
 
+// GetPriority returns the Nuvla priority of the job, lower values run first
+func (j *JobBase) GetPriority() int {
+	if j.JobResource == nil {
+		return 0
+	}
+	return int(j.JobResource.Priority)
+}
+
+// GetTarget returns the resource the job acts on, e.g. a deployment
+func (j *JobBase) GetTarget() string {
+	if j.JobResource == nil {
+		return ""
+	}
+	return j.JobResource.TargetResource.Href
+}
+
+// GetScheduledTime returns the time the job is scheduled at, now if it runs right away
+func (j *JobBase) GetScheduledTime() time.Time {
+	if j.JobResource == nil {
+		return time.Now()
+	}
+	return actions.GetScheduledTime(j.JobResource.Action, j.JobResource.Payload, time.Now())
+}
+
+// IsExpired asserts whether the expiry date of the job has passed. Jobs without expiry date never expire.
+func (j *JobBase) IsExpired() bool {
+	if j.JobResource == nil || j.JobResource.Expiry == "" {
+		return false
+	}
+	expiry, err := time.Parse(time.RFC3339, j.JobResource.Expiry)
+	if err != nil {
+		log.Warnf("Cannot parse expiry date %s of job %s: %s", j.JobResource.Expiry, j.JobId, err)
+		return false
+	}
+	return time.Now().After(expiry)
+}
+
 func isNotSupportedActionError(err error) bool {
 	var notImplementedActionError errors2.NotImplementedActionError
 	return errors.As(err, &notImplementedActionError)
@@ -107,7 +149,7 @@ func NewNativeJobFromBase(jb *JobBase, action actions.Action, actionName string)
 }
 
 func (j *NativeJob) RunJob(ctx context.Context) error {
-	_ = j.Client.SetProgress(ctx, 30)
+	_ = j.Client.SetProgress(ctx, actionProgressStart)
 
 	// Initialise the action
 	err := j.Action.Init(
@@ -121,10 +163,24 @@ func (j *NativeJob) RunJob(ctx context.Context) error {
 		return err
 	}
 
-	// Run the action
-	if err = j.Action.ExecuteAction(ctx); err != nil {
+	// Run the action, reporting its progress while it runs
+	reporter := newProgressReporter(j.Client, j.JobId)
+	go reporter.Run(ctx)
+	err = j.Action.ExecuteAction(executors.WithProgress(ctx, reporter.Report))
+	reporter.Stop()
+	if err != nil {
+		if cause := context.Cause(ctx); errors.Is(cause, errJobCanceled) || errors.Is(cause, context.Canceled) {
+			// The job processor reports the cancellation from Nuvla and the interruption by a shutdown
+			return err
+		}
 		errMsg := j.Action.GetOutput() + "\n" + err.Error()
-		j.Client.SetFailedState(ctx, errMsg)
+		if cause := context.Cause(ctx); cause != nil && !errors.Is(err, cause) {
+			errMsg += "\n" + cause.Error()
+		}
+		// The job context might be done already, e.g. on timeout
+		reportCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), jobReportTimeout)
+		defer cancel()
+		j.Client.SetFailedState(reportCtx, errMsg)
 		return err
 	}
 
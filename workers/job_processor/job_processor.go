@@ -2,54 +2,122 @@ package job_processor
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	nuvla "github.com/nuvla/api-client-go"
 	log "github.com/sirupsen/logrus"
+	"nuvlaedge-go/common/constants"
 	"nuvlaedge-go/engine"
+	"nuvlaedge-go/exporter"
+	"nuvlaedge-go/store"
 	"nuvlaedge-go/types/jobs"
 	"nuvlaedge-go/types/worker"
+	"nuvlaedge-go/workers/job_processor/actions"
+	"nuvlaedge-go/workers/job_processor/executors"
 	"sync"
+	"time"
+)
+
+const (
+	// JobInterruptedMessage is set as status message of the jobs interrupted by the NuvlaEdge shutdown
+	JobInterruptedMessage = "Job interrupted: NuvlaEdge agent was shut down before the job could complete"
+	// jobInterruptGracePeriod is the time given to the running jobs to return after being interrupted
+	jobInterruptGracePeriod = 15 * time.Second
 )
 
 type JobProcessor struct {
-	worker.WorkerBase
+	// The period is the one of the search for jobs queued in Nuvla and not received through the NuvlaEdge responses
+	worker.TimedWorker
 	jobChan        chan string        // NativeJob channel. Receive jobs IDs from the agent
 	client         *nuvla.NuvlaClient // Nuvla session required in the jobs and deployment clients
+	jobs           jobsClient         // Same session, used to look up and update jobs outside their execution
+	nuvlaEdgeId    string             // Id of the NuvlaEdge executing the jobs, to search its own ones
 	coe            engine.Coe         // COE client required in the jobs and deployment clients
 	enableLegacy   bool
 	legacyJobImage string
+	jobTimeouts    map[string]int // Timeout in seconds by action, overriding the defaults
+	timeoutsMu     sync.Mutex
+	rebootHooks    []string // Commands run before rebooting
+	hooksMu        sync.Mutex
 
 	runningJobs *jobs.JobRegistry
+	pool        *jobPool // Limits the jobs running at the same time
+	store       *store.Store
+	// interruptedJobs were in-flight when the agent last stopped without finishing them
+	interruptedJobs []jobs.RunningJob
+	interruptedMu   sync.Mutex
+	recovered       bool // Set once the jobs left unfinished by the previous run have been handled
+
+	// Jobs don't run on the processor context so that they can finish after the processor stops receiving new ones
+	jobsCtx    context.Context
+	cancelJobs context.CancelFunc
+	jobsWg     sync.WaitGroup
 }
 
-func (p *JobProcessor) Start(ctx context.Context) error {
+func (p *JobProcessor) Start(_ context.Context) error {
 	log.Infof("Nothing to start in the jobs processor, passing...")
-	go func() {
-		err := p.Run(ctx)
-		if err != nil {
-			log.Errorf("Error running Job Processor: %s", err)
-		}
-	}()
 	return nil
 }
 
+// Stop waits for the running jobs to finish. If the context is done before, the running jobs are interrupted and
+// marked as failed.
 func (p *JobProcessor) Stop(ctx context.Context) error {
-	// Send exit signal to the jobs processor
-	log.Info("Stopping NativeJob Processor")
+	log.Info("Stopping NativeJob Processor, waiting for running jobs to finish...")
+	p.BaseTicker.Stop()
+	// Queued jobs are left queued in Nuvla, they are picked up again after the restart
+	p.pool.Close()
 
-	return nil
+	done := make(chan struct{})
+	go func() {
+		p.jobsWg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		log.Info("NativeJob Processor stopped, no jobs running")
+		return nil
+	case <-ctx.Done():
+		log.Warnf("Shutdown deadline reached, interrupting running jobs: \n %s", p.runningJobs)
+		p.cancelJobs()
+	}
+
+	select {
+	case <-done:
+		log.Info("NativeJob Processor stopped, running jobs interrupted")
+		return nil
+	case <-time.After(jobInterruptGracePeriod):
+		return errors.New("running jobs did not return after being interrupted")
+	}
 }
 
 func (p *JobProcessor) Init(opts *worker.WorkerOpts, conf *worker.WorkerConfig) error {
-	p.WorkerBase = worker.NewWorkerBase(worker.JobProcessor)
+	syncPeriod := conf.RemoteSyncPeriod
+	if syncPeriod <= 0 {
+		syncPeriod = constants.DefaultRemoteSyncPeriod
+	}
+	p.TimedWorker = worker.NewTimedWorker(syncPeriod, worker.JobProcessor)
 	p.jobChan = opts.JobCh
 	p.runningJobs = opts.Jobs
+	poolSize := conf.JobPoolSize
+	if poolSize <= 0 {
+		poolSize = constants.DefaultJobPoolSize
+	}
+	p.pool = newJobPool(poolSize)
+	p.store = opts.Store
+	p.jobsCtx, p.cancelJobs = context.WithCancel(context.Background())
+	p.loadInterruptedJobs()
 
 	// Clients setup
 	p.client = opts.NuvlaClient.NuvlaClient
+	p.jobs = p.client
+	p.nuvlaEdgeId = opts.NuvlaClient.NuvlaEdgeId.String()
 
 	// Config
 	p.enableLegacy = conf.EnableJobLegacy
 	p.legacyJobImage = conf.LegacyJobImage
+	p.setJobTimeouts(conf.JobTimeouts)
+	p.setRebootHooks(conf.RebootHooks)
 	p.coe = engine.NewDockerEngine()
 	return nil
 }
@@ -57,16 +125,47 @@ func (p *JobProcessor) Init(opts *worker.WorkerOpts, conf *worker.WorkerConfig)
 func (p *JobProcessor) Reconfigure(conf *worker.WorkerConfig) error {
 	p.legacyJobImage = conf.LegacyJobImage
 	p.enableLegacy = conf.EnableJobLegacy
+	p.setJobTimeouts(conf.JobTimeouts)
+	p.setRebootHooks(conf.RebootHooks)
+	if conf.RemoteSyncPeriod > 0 && conf.RemoteSyncPeriod != p.GetPeriod() {
+		p.SetPeriod(conf.RemoteSyncPeriod)
+	}
+	if conf.JobPoolSize > 0 && conf.JobPoolSize != p.pool.Size() {
+		log.Infof("Setting job pool size to %d", conf.JobPoolSize)
+		p.pool.Resize(conf.JobPoolSize)
+	}
 	return nil
 }
 
 func (p *JobProcessor) Run(ctx context.Context) error {
 	log.Info("Running Job Engine")
 
+	// The deployments stopped before a reboot are started again, whether Nuvla can be reached or not
+	if err := executors.StartStoppedDeployments(executors.WithStore(ctx, p.store)); err != nil {
+		log.Errorf("Error starting the deployments stopped before the reboot: %s", err)
+	}
+
+	// The jobs left unfinished by the previous run are recovered first, until Nuvla can be reached
+	var recovery <-chan time.Time
+	if !p.recovered && p.jobs != nil {
+		recovery = time.After(0)
+	}
+
 	for {
 		select {
+		case <-recovery:
+			recovery = nil
+			if !p.runRecovery(ctx) {
+				recovery = time.After(jobRecoveryRetryPeriod)
+			}
 		case job := <-p.jobChan:
-			go p.processJob(ctx, job)
+			p.MarkRun()
+			p.startJob(job)
+		case <-p.BaseTicker.C:
+			p.catchUpJobs(ctx)
+		case <-p.TriggerCh:
+			log.Info("Search for queued jobs triggered")
+			p.catchUpJobs(ctx)
 		case <-ctx.Done():
 			log.Info("Context done. Exiting...")
 			return ctx.Err()
@@ -80,10 +179,33 @@ func (p *JobProcessor) Run(ctx context.Context) error {
 	}
 }
 
-func (p *JobProcessor) processJob(ctx context.Context, j string) {
-	jobCtx, cancel := context.WithCancel(ctx)
+// runRecovery recovers the jobs left unfinished by the previous run and records the result
+func (p *JobProcessor) runRecovery(ctx context.Context) bool {
+	ctxTimed, cancel := context.WithTimeout(ctx, jobRecoveryTimeout)
 	defer cancel()
 
+	toStart, complete := p.recoverJobs(ctxTimed)
+	for _, j := range toStart {
+		p.startJob(j)
+	}
+	p.recovered = complete
+	p.saveJobs()
+	return complete
+}
+
+// startJob processes the job in its own routine, tracked for the shutdown
+func (p *JobProcessor) startJob(jobId string) {
+	p.jobsWg.Add(1)
+	go func() {
+		defer p.jobsWg.Done()
+		p.processJob(p.jobsCtx, jobId)
+	}()
+}
+
+func (p *JobProcessor) processJob(ctx context.Context, j string) {
+	jobCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
 	if p.runningJobs.Exists(j) {
 		log.Infof("NativeJob %s is already running", j)
 		return
@@ -97,28 +219,175 @@ func (p *JobProcessor) processJob(ctx context.Context, j string) {
 		log.Errorf("Error creating job %s: %s", j, err)
 		return
 	}
+	if job.IsExpired() {
+		p.refuseJob(j, JobExpiredMessage)
+		return
+	}
 
 	ok := p.runningJobs.Add(&jobs.RunningJob{
-		JobId:   j,
-		JobType: job.GetJobType(),
+		JobId:     j,
+		JobType:   job.GetJobType(),
+		StartedAt: time.Now(),
+		Queued:    true,
 	})
 	if !ok {
 		log.Errorf("Job %s is already running...", j)
 		return
 	}
+	p.saveJobs()
+	defer func() {
+		p.runningJobs.Remove(j)
+		p.saveJobs()
+	}()
+
+	// 2. Wait for the scheduled time without holding a slot, and then for a free slot. Jobs on the same target wait
+	// for the previous ones to finish.
+	if err := p.waitScheduledTime(jobCtx, j, job.GetScheduledTime(), cancel); err != nil {
+		if errors.Is(err, errJobCanceled) {
+			p.setCanceledState(j)
+			return
+		}
+		log.Warnf("Job %s not started, left queued: %s", j, err)
+		return
+	}
+	release, err := p.pool.Acquire(jobCtx, j, job.GetPriority(), job.GetTarget())
+	if err != nil {
+		log.Warnf("Job %s not started, left queued: %s", j, err)
+		return
+	}
+	defer release()
+
+	// The job might have expired or been cancelled while queued
+	if job.IsExpired() {
+		p.refuseJob(j, JobExpiredMessage)
+		return
+	}
+	if state, err := p.getJobState(jobCtx, j); err == nil && isCanceledState(state) {
+		log.Infof("Job %s cancelled while queued", j)
+		p.setCanceledState(j)
+		return
+	}
+	p.runningJobs.SetRunning(j)
 	log.Infof("Currently running jobs: \n %s", p.runningJobs)
-	defer p.runningJobs.Remove(j)
 
-	// 2. Run the jobs
-	err = job.RunJob(jobCtx)
+	// 3. Run the jobs. Native jobs can be cancelled from Nuvla and are bounded by their action timeout. Legacy jobs
+	// handle both in their own container.
+	runCtx := context.Context(jobCtx)
+	if actions.IsNativeAction(job.GetJobType()) {
+		go p.watchJobState(jobCtx, j, cancel)
+		if timeout := actions.GetTimeout(job.GetJobType(), p.getJobTimeouts()); timeout > 0 {
+			var cancelRun context.CancelFunc
+			runCtx, cancelRun = context.WithTimeoutCause(jobCtx, timeout, fmt.Errorf("%w after %s", errJobTimeout, timeout))
+			defer cancelRun()
+		}
+		runCtx = actions.WithDrain(runCtx, func(ctx context.Context) error {
+			return p.waitOtherJobs(ctx, j)
+		})
+		runCtx = actions.WithRebootHooks(runCtx, p.getRebootHooks())
+		runCtx = executors.WithStore(runCtx, p.store)
+	}
+
+	start := time.Now()
+	err = job.RunJob(runCtx)
+	exporter.JobDuration.WithLabelValues(job.GetJobType(), exporter.Result(err)).Observe(time.Since(start).Seconds())
 	if err != nil {
 		log.Errorf("Error running job %s: %s", j, err)
+		switch {
+		case ctx.Err() != nil && actions.IsIdempotent(job.GetJobType()):
+			// Left running in Nuvla, the recovery executes it again after the restart
+			log.Infof("Job %s interrupted by shutdown, resuming it after restart", j)
+		case ctx.Err() != nil:
+			p.setInterruptedState(j)
+		case errors.Is(context.Cause(jobCtx), errJobCanceled):
+			p.setCanceledState(j)
+		}
 		return
 	}
 	log.Infof("Running job %s... Success.", j)
 
 }
 
+// waitScheduledTime waits for the time the job is scheduled at. Meanwhile, the job is cancelled with errJobCanceled
+// as soon as it is cancelled from Nuvla.
+func (p *JobProcessor) waitScheduledTime(ctx context.Context, jobId string, at time.Time, cancel context.CancelCauseFunc) error {
+	wait := time.Until(at)
+	if wait <= 0 {
+		return nil
+	}
+	log.Infof("Job %s scheduled at %s", jobId, at.Format(time.RFC3339))
+
+	ctxWatch, stopWatch := context.WithCancel(ctx)
+	defer stopWatch()
+	go p.watchJobState(ctxWatch, jobId, cancel)
+
+	select {
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	case <-time.After(wait):
+		return nil
+	}
+}
+
+func (p *JobProcessor) setJobTimeouts(timeouts map[string]int) {
+	p.timeoutsMu.Lock()
+	defer p.timeoutsMu.Unlock()
+	p.jobTimeouts = timeouts
+}
+
+func (p *JobProcessor) getJobTimeouts() map[string]int {
+	p.timeoutsMu.Lock()
+	defer p.timeoutsMu.Unlock()
+	return p.jobTimeouts
+}
+
+func (p *JobProcessor) setRebootHooks(hooks []string) {
+	p.hooksMu.Lock()
+	defer p.hooksMu.Unlock()
+	p.rebootHooks = hooks
+}
+
+func (p *JobProcessor) getRebootHooks() []string {
+	p.hooksMu.Lock()
+	defer p.hooksMu.Unlock()
+	return p.rebootHooks
+}
+
+// loadInterruptedJobs reads the jobs left in-flight by the previous run of the agent
+func (p *JobProcessor) loadInterruptedJobs() {
+	var inFlight []jobs.RunningJob
+	if err := p.store.Get(store.JobsKey, &inFlight); err != nil {
+		log.Debugf("No stored in-flight jobs: %s", err)
+		return
+	}
+	if len(inFlight) > 0 {
+		log.Warnf("Found %d jobs in-flight when the agent last stopped", len(inFlight))
+	}
+	p.interruptedMu.Lock()
+	defer p.interruptedMu.Unlock()
+	p.interruptedJobs = inFlight
+}
+
+// saveJobs records the jobs in-flight so that they can be found if the agent stops before they finish. The jobs
+// interrupted by the previous run are kept until they are handled.
+func (p *JobProcessor) saveJobs() {
+	inFlight := append(p.runningJobs.List(), p.getInterruptedJobs()...)
+	if err := p.store.Put(store.JobsKey, inFlight); err != nil {
+		log.Errorf("Error storing in-flight jobs: %s", err)
+	}
+}
+
+// setInterruptedState marks a job interrupted by the shutdown as failed. The job context is already cancelled
+// so a new one is required to reach Nuvla.
+func (p *JobProcessor) setInterruptedState(jobId string) {
+	log.Warnf("Job %s interrupted by shutdown, setting failed state", jobId)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := p.failJob(ctx, jobId, JobInterruptedMessage); err != nil {
+		log.Errorf("Error setting failed state of job %s: %s", jobId, err)
+	}
+}
+
 type RunningJob struct {
 	jobId   string
 	jobType string
@@ -0,0 +1,89 @@
+package job_processor
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"nuvlaedge-go/store"
+	"nuvlaedge-go/types/jobs"
+	"nuvlaedge-go/types/worker"
+	"testing"
+	"time"
+)
+
+func newTestProcessor() *JobProcessor {
+	registry := jobs.NewRunningJobs()
+	p := &JobProcessor{}
+	p.TimedWorker = worker.NewTimedWorker(60, worker.JobProcessor)
+	p.runningJobs = &registry
+	p.nuvlaEdgeId = "nuvlabox/test"
+	p.pool = newJobPool(2)
+	p.jobsCtx, p.cancelJobs = context.WithCancel(context.Background())
+	return p
+}
+
+func Test_JobProcessor_Stop_NoJobs(t *testing.T) {
+	p := newTestProcessor()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	assert.NoError(t, p.Stop(ctx))
+	assert.NoError(t, p.jobsCtx.Err(), "jobs context should not be cancelled if no job was running")
+}
+
+func Test_JobProcessor_Stop_WaitsForRunningJobs(t *testing.T) {
+	p := newTestProcessor()
+
+	p.jobsWg.Add(1)
+	go func() {
+		defer p.jobsWg.Done()
+		time.Sleep(100 * time.Millisecond)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	assert.NoError(t, p.Stop(ctx))
+	assert.NoError(t, p.jobsCtx.Err(), "jobs finishing before the deadline should not be interrupted")
+}
+
+func Test_JobProcessor_Stop_InterruptsJobsOnDeadline(t *testing.T) {
+	p := newTestProcessor()
+
+	interrupted := false
+	p.jobsWg.Add(1)
+	go func() {
+		defer p.jobsWg.Done()
+		<-p.jobsCtx.Done()
+		interrupted = true
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	assert.NoError(t, p.Stop(ctx))
+	assert.True(t, interrupted)
+	assert.ErrorIs(t, p.jobsCtx.Err(), context.Canceled)
+}
+
+func Test_JobProcessor_InFlightJobs(t *testing.T) {
+	st, err := store.Open(t.TempDir())
+	assert.NoError(t, err)
+
+	p := newTestProcessor()
+	p.store = st
+	p.runningJobs.Add(&jobs.RunningJob{JobId: "job/1", JobType: "deployment_start"})
+	p.saveJobs()
+
+	restarted := newTestProcessor()
+	restarted.store = st
+	restarted.loadInterruptedJobs()
+	assert.Len(t, restarted.interruptedJobs, 1)
+	assert.Equal(t, "job/1", restarted.interruptedJobs[0].JobId)
+
+	restarted.runningJobs.Add(&jobs.RunningJob{JobId: "job/2"})
+	restarted.saveJobs()
+	var inFlight []jobs.RunningJob
+	assert.NoError(t, st.Get(store.JobsKey, &inFlight))
+	assert.Len(t, inFlight, 2, "interrupted jobs should be kept until handled")
+}
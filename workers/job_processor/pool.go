@@ -0,0 +1,167 @@
+package job_processor
+
+import (
+	"context"
+	"errors"
+	"nuvlaedge-go/exporter"
+	"sort"
+	"sync"
+)
+
+// errPoolClosed is returned to the jobs waiting for a slot when the pool is closed
+var errPoolClosed = errors.New("job pool closed")
+
+// jobPool limits the number of jobs running at the same time. Waiting jobs are given a slot by priority, lower values
+// first as in Nuvla, and then by arrival. Jobs targeting the same resource, e.g. a deployment, run one at a time in
+// arrival order so that they don't race each other.
+type jobPool struct {
+	mu      sync.Mutex
+	size    int
+	running int
+	seq     uint64
+	queue   []*poolTicket
+	busy    map[string]bool // Targets with a job running
+	closed  bool
+}
+
+// poolTicket is a job waiting for, or holding, a slot in the pool
+type poolTicket struct {
+	jobId    string
+	priority int
+	target   string
+	seq      uint64
+	ready    chan struct{} // Closed when the slot is granted or the pool closed
+	granted  bool
+	released bool
+}
+
+func newJobPool(size int) *jobPool {
+	return &jobPool{
+		size: max(size, 1),
+		busy: make(map[string]bool),
+	}
+}
+
+// Acquire waits until the job can run. The returned function frees the slot once the job finishes. An error is
+// returned if the context is done or the pool closed before the job gets a slot.
+func (p *jobPool) Acquire(ctx context.Context, jobId string, priority int, target string) (func(), error) {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil, errPoolClosed
+	}
+	p.seq++
+	t := &poolTicket{jobId: jobId, priority: priority, target: target, seq: p.seq, ready: make(chan struct{})}
+	p.queue = append(p.queue, t)
+	p.dispatch()
+	p.mu.Unlock()
+
+	select {
+	case <-t.ready:
+	case <-ctx.Done():
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if t.granted {
+		return func() { p.release(t) }, nil
+	}
+
+	p.remove(t)
+	p.updateMetrics()
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	return nil, errPoolClosed
+}
+
+// Resize changes the number of jobs allowed to run at the same time. Running jobs are not affected if it shrinks.
+func (p *jobPool) Resize(size int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.size = max(size, 1)
+	p.dispatch()
+}
+
+// Size returns the number of jobs allowed to run at the same time
+func (p *jobPool) Size() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.size
+}
+
+// Close rejects the waiting jobs and any new one. Running jobs keep their slot until they finish.
+func (p *jobPool) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	for _, t := range p.queue {
+		close(t.ready)
+	}
+	p.queue = nil
+	p.updateMetrics()
+}
+
+func (p *jobPool) release(t *poolTicket) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if t.released {
+		return
+	}
+	t.released = true
+	p.running--
+	delete(p.busy, t.target)
+	p.dispatch()
+}
+
+// dispatch grants the free slots to the waiting jobs. Must be called with the lock held.
+func (p *jobPool) dispatch() {
+	defer p.updateMetrics()
+	if p.closed {
+		return
+	}
+
+	sort.Slice(p.queue, func(i, j int) bool {
+		if p.queue[i].priority != p.queue[j].priority {
+			return p.queue[i].priority < p.queue[j].priority
+		}
+		return p.queue[i].seq < p.queue[j].seq
+	})
+
+	// Only the oldest waiting job of each target can run, whatever the priority of the others
+	first := make(map[string]uint64)
+	for _, t := range p.queue {
+		if s, ok := first[t.target]; t.target != "" && (!ok || t.seq < s) {
+			first[t.target] = t.seq
+		}
+	}
+
+	waiting := p.queue[:0]
+	for _, t := range p.queue {
+		if p.running >= p.size || (t.target != "" && (p.busy[t.target] || first[t.target] != t.seq)) {
+			waiting = append(waiting, t)
+			continue
+		}
+		t.granted = true
+		p.running++
+		if t.target != "" {
+			p.busy[t.target] = true
+		}
+		close(t.ready)
+	}
+	p.queue = waiting
+}
+
+func (p *jobPool) remove(t *poolTicket) {
+	for i, q := range p.queue {
+		if q == t {
+			p.queue = append(p.queue[:i], p.queue[i+1:]...)
+			return
+		}
+	}
+}
+
+func (p *jobPool) updateMetrics() {
+	exporter.JobsRunning.Set(float64(p.running))
+	exporter.JobsQueued.Set(float64(len(p.queue)))
+}
@@ -0,0 +1,116 @@
+package job_processor
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+// acquireAsync requests a slot from a routine and reports the job id once granted
+func acquireAsync(p *jobPool, ctx context.Context, jobId string, priority int, target string, granted chan<- string) chan func() {
+	releases := make(chan func(), 1)
+	go func() {
+		release, err := p.Acquire(ctx, jobId, priority, target)
+		if err != nil {
+			granted <- "error:" + jobId
+			return
+		}
+		granted <- jobId
+		releases <- release
+	}()
+	return releases
+}
+
+// waitQueued waits until the pool has the given number of jobs waiting
+func waitQueued(t *testing.T, p *jobPool, n int) {
+	assert.Eventually(t, func() bool {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		return len(p.queue) == n
+	}, time.Second, time.Millisecond)
+}
+
+func Test_JobPool_LimitsAndPriority(t *testing.T) {
+	p := newJobPool(1)
+	ctx := context.Background()
+
+	release, err := p.Acquire(ctx, "job/running", 10, "")
+	assert.NoError(t, err)
+
+	granted := make(chan string, 3)
+	r1 := acquireAsync(p, ctx, "job/low", 100, "", granted)
+	waitQueued(t, p, 1)
+	r2 := acquireAsync(p, ctx, "job/high", 1, "", granted)
+	waitQueued(t, p, 2)
+
+	select {
+	case id := <-granted:
+		t.Fatalf("job %s should wait for a free slot", id)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	release()
+	release() // Releasing twice should not free two slots
+	assert.Equal(t, "job/high", <-granted, "higher priority jobs should run first")
+	(<-r2)()
+	assert.Equal(t, "job/low", <-granted)
+	(<-r1)()
+}
+
+func Test_JobPool_SerializesTarget(t *testing.T) {
+	p := newJobPool(3)
+	ctx := context.Background()
+
+	release, err := p.Acquire(ctx, "job/stop", 50, "deployment/1")
+	assert.NoError(t, err)
+
+	granted := make(chan string, 3)
+	r1 := acquireAsync(p, ctx, "job/start", 50, "deployment/1", granted)
+	waitQueued(t, p, 1)
+	r2 := acquireAsync(p, ctx, "job/state", 1, "deployment/1", granted)
+	waitQueued(t, p, 2)
+	r3 := acquireAsync(p, ctx, "job/other", 50, "deployment/2", granted)
+	assert.Equal(t, "job/other", <-granted, "jobs on other targets should not wait")
+	(<-r3)()
+
+	release()
+	assert.Equal(t, "job/start", <-granted, "jobs on the same target should run in arrival order")
+	select {
+	case id := <-granted:
+		t.Fatalf("job %s should wait for the previous job on the same target", id)
+	case <-time.After(50 * time.Millisecond):
+	}
+	(<-r1)()
+	assert.Equal(t, "job/state", <-granted)
+	(<-r2)()
+}
+
+func Test_JobPool_ResizeAndClose(t *testing.T) {
+	p := newJobPool(0)
+	assert.Equal(t, 1, p.Size(), "pool should allow at least one job")
+
+	ctx := context.Background()
+	_, err := p.Acquire(ctx, "job/1", 0, "")
+	assert.NoError(t, err)
+
+	granted := make(chan string, 3)
+	acquireAsync(p, ctx, "job/2", 0, "", granted)
+	waitQueued(t, p, 1)
+	p.Resize(2)
+	assert.Equal(t, "job/2", <-granted, "growing the pool should start the waiting jobs")
+
+	ctxCancel, cancel := context.WithCancel(ctx)
+	acquireAsync(p, ctxCancel, "job/3", 0, "", granted)
+	waitQueued(t, p, 1)
+	cancel()
+	assert.Equal(t, "error:job/3", <-granted, "cancelled jobs should leave the queue")
+	waitQueued(t, p, 0)
+
+	acquireAsync(p, ctx, "job/4", 0, "", granted)
+	waitQueued(t, p, 1)
+	p.Close()
+	assert.Equal(t, "error:job/4", <-granted, "waiting jobs should be rejected on close")
+	_, err = p.Acquire(ctx, "job/5", 0, "")
+	assert.ErrorIs(t, err, errPoolClosed)
+}
@@ -0,0 +1,151 @@
+package job_processor
+
+import (
+	"context"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"nuvlaedge-go/workers/job_processor/executors"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Progress range of the action execution. The progress before and after it is set by the job itself.
+	actionProgressStart = 30
+	actionProgressEnd   = 90
+)
+
+// jobProgressInterval is the minimum time between two progress updates of a job in Nuvla
+var jobProgressInterval = 5 * time.Second
+
+// progressClient is the part of the Nuvla client used to report the job progress
+type progressClient interface {
+	Edit(ctx context.Context, resourceId string, data map[string]interface{}, toSelect []string) (*http.Response, error)
+}
+
+// progressReporter translates the progress events of an action into throttled updates of the job progress and
+// status message in Nuvla
+type progressReporter struct {
+	client progressClient
+	jobId  string
+
+	mu       sync.Mutex
+	steps    map[string]float64 // Completion, from 0 to 1, of every object reported
+	progress int8
+	message  string
+	changed  bool
+
+	done     chan struct{}
+	stopped  chan struct{}
+	stopOnce sync.Once
+}
+
+func newProgressReporter(client progressClient, jobId string) *progressReporter {
+	return &progressReporter{
+		client:   client,
+		jobId:    jobId,
+		steps:    make(map[string]float64),
+		progress: actionProgressStart,
+		done:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+}
+
+// Report records a progress event. The progress only moves forward, even if new objects are reported.
+func (r *progressReporter) Report(e executors.ProgressEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	completion := min(max(float64(e.Percent)/100, 0), 1)
+	if e.Done {
+		completion = 1
+	}
+	key := e.ID
+	if e.Parent != "" {
+		key = e.Parent + "/" + e.ID
+	}
+	r.steps[key] = max(r.steps[key], completion)
+
+	var total float64
+	for _, c := range r.steps {
+		total += c
+	}
+	progress := int8(actionProgressStart + (actionProgressEnd-actionProgressStart)*total/float64(len(r.steps)))
+	if progress > r.progress {
+		r.progress = progress
+		r.changed = true
+	}
+
+	if msg := progressMessage(e); msg != "" && msg != r.message {
+		r.message = msg
+		r.changed = true
+	}
+}
+
+// Run sends the progress to Nuvla periodically until the context is done or the reporter is stopped
+func (r *progressReporter) Run(ctx context.Context) {
+	defer close(r.stopped)
+
+	ticker := time.NewTicker(jobProgressInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-r.done:
+			return
+		case <-ticker.C:
+			r.flush(ctx)
+		}
+	}
+}
+
+// Stop stops Run and waits for it to return, so that no update overrides the final state of the job
+func (r *progressReporter) Stop() {
+	r.stopOnce.Do(func() {
+		close(r.done)
+	})
+	<-r.stopped
+}
+
+func (r *progressReporter) flush(ctx context.Context) {
+	r.mu.Lock()
+	if !r.changed {
+		r.mu.Unlock()
+		return
+	}
+	data := map[string]interface{}{"progress": r.progress, "status-message": r.message}
+	r.changed = false
+	r.mu.Unlock()
+
+	ctxTimed, cancel := context.WithTimeout(ctx, jobReportTimeout)
+	defer cancel()
+	res, err := r.client.Edit(ctxTimed, r.jobId, data, nil)
+	if err != nil {
+		log.Debugf("Error reporting progress of job %s: %s", r.jobId, err)
+		return
+	}
+	_ = res.Body.Close()
+}
+
+// progressMessage describes the event for the job status message, e.g. "web Downloading 45%"
+func progressMessage(e executors.ProgressEvent) string {
+	subject := e.ID
+	if e.Parent != "" {
+		subject = e.Parent
+	}
+	detail := e.Status
+	if e.Percent > 0 && !e.Done {
+		detail = fmt.Sprintf("%d%%", e.Percent)
+	}
+	var parts []string
+	for _, p := range []string{subject, e.Text, detail} {
+		if p = strings.TrimSpace(p); p != "" {
+			parts = append(parts, p)
+		}
+	}
+	return strings.Join(parts, " ")
+}
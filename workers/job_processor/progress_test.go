@@ -0,0 +1,41 @@
+package job_processor
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"nuvlaedge-go/workers/job_processor/executors"
+	"testing"
+	"time"
+)
+
+func Test_ProgressReporter_Report(t *testing.T) {
+	r := newProgressReporter(&jobsClientMock{}, "job/1")
+
+	r.Report(executors.ProgressEvent{ID: "abc", Parent: "web", Text: "Downloading", Percent: 50})
+	assert.Equal(t, int8(60), r.progress)
+	assert.Equal(t, "web Downloading 50%", r.message)
+
+	r.Report(executors.ProgressEvent{ID: "Container web-1", Text: "Creating"})
+	assert.Equal(t, int8(60), r.progress, "progress should not move backwards when new objects are reported")
+
+	r.Report(executors.ProgressEvent{ID: "abc", Parent: "web", Text: "Pull complete", Done: true})
+	r.Report(executors.ProgressEvent{ID: "Container web-1", Text: "Started", Done: true})
+	assert.Equal(t, int8(actionProgressEnd), r.progress)
+	assert.Equal(t, "Container web-1 Started", r.message)
+}
+
+func Test_ProgressReporter_Run(t *testing.T) {
+	defer func(interval time.Duration) { jobProgressInterval = interval }(jobProgressInterval)
+	jobProgressInterval = 10 * time.Millisecond
+
+	mock := &jobsClientMock{}
+	r := newProgressReporter(mock, "job/1")
+	r.Report(executors.ProgressEvent{ID: "web", Text: "Pulling", Percent: 50})
+	go r.Run(context.Background())
+
+	time.Sleep(50 * time.Millisecond)
+	r.Stop()
+	r.Stop()
+	assert.Equal(t, int8(60), mock.edits["job/1"]["progress"])
+	assert.Equal(t, "web Pulling 50%", mock.edits["job/1"]["status-message"])
+}
@@ -0,0 +1,220 @@
+package job_processor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	nuvla "github.com/nuvla/api-client-go"
+	"github.com/nuvla/api-client-go/clients/resources"
+	nuvlaTypes "github.com/nuvla/api-client-go/types"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"nuvlaedge-go/types/jobs"
+	"nuvlaedge-go/workers/job_processor/actions"
+	"time"
+)
+
+const (
+	// JobRestartInterruptedMessage is set as status message of the jobs interrupted by an agent or host restart
+	JobRestartInterruptedMessage = "Job interrupted by agent restart"
+	// JobInterruptedReturnCode is the return code of the interrupted jobs, as for a process terminated by SIGTERM
+	JobInterruptedReturnCode = 143
+
+	// jobRecoveryRetryPeriod is the time to wait before retrying the recovery of the jobs that couldn't be reached
+	jobRecoveryRetryPeriod = time.Minute
+	jobRecoveryTimeout     = 30 * time.Second
+)
+
+// jobsClient is the part of the Nuvla client used to look up and update jobs outside their execution
+type jobsClient interface {
+	Search(ctx context.Context, resourceType string, opts *nuvla.SearchOptions) (*resources.NuvlaResourceCollection, error)
+	Get(ctx context.Context, resourceId string, selectFields []string) (*nuvlaTypes.NuvlaResource, error)
+	Edit(ctx context.Context, resourceId string, data map[string]interface{}, toSelect []string) (*http.Response, error)
+}
+
+// searchJobs returns the ids of the jobs executed by this NuvlaEdge in any of the given states. The session can view
+// the jobs of other NuvlaEdges, e.g. those of shared deployments, so the search is limited to the jobs this one is
+// granted to update.
+func (p *JobProcessor) searchJobs(ctx context.Context, states ...resources.JobState) ([]string, error) {
+	filter := fmt.Sprintf("execution-mode='pull' and (acl/owners='%[1]s' or acl/edit-data='%[1]s') and (",
+		p.nuvlaEdgeId)
+	for i, s := range states {
+		if i > 0 {
+			filter += " or "
+		}
+		filter += fmt.Sprintf("state='%s'", s)
+	}
+	filter += ")"
+
+	opts := nuvla.NewDefaultSearchOptions()
+	opts.Filter = filter
+	opts.Select = []string{"id"}
+	col, err := p.jobs.Search(ctx, "job", opts)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(col.Resources))
+	for _, r := range col.Resources {
+		if id, ok := r["id"].(string); ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// recoverJobs handles the jobs left unfinished by a previous run of the agent, either recorded locally as in-flight
+// or still RUNNING in Nuvla. Idempotent actions are executed again, the rest are marked as failed. QUEUED jobs are
+// started too. It returns the jobs to start and false if some jobs could not be reached and the recovery has to be
+// retried.
+func (p *JobProcessor) recoverJobs(ctx context.Context) ([]string, bool) {
+	candidates := make(map[string]bool)
+	for _, j := range p.getInterruptedJobs() {
+		candidates[j.JobId] = true
+	}
+
+	complete := true
+	ids, err := p.searchJobs(ctx, resources.StateRUNNING, resources.StateQueued)
+	if err != nil {
+		log.Warnf("Error searching unfinished jobs in Nuvla: %s", err)
+		complete = false
+	}
+	for _, id := range ids {
+		candidates[id] = true
+	}
+
+	var toStart []string
+	for id := range candidates {
+		if p.runningJobs.Exists(id) {
+			p.resolveInterruptedJob(id)
+			continue
+		}
+		start, err := p.recoverJob(ctx, id)
+		if err != nil {
+			log.Warnf("Error recovering job %s, will retry: %s", id, err)
+			complete = false
+			continue
+		}
+		if start {
+			toStart = append(toStart, id)
+		}
+		p.resolveInterruptedJob(id)
+	}
+	return toStart, complete
+}
+
+// recoverJob decides what to do with an unfinished job. It returns true if the job has to be started.
+func (p *JobProcessor) recoverJob(ctx context.Context, jobId string) (bool, error) {
+	res, err := p.jobs.Get(ctx, jobId, []string{"id", "action", "state"})
+	if err != nil {
+		return false, err
+	}
+
+	state, _ := res.Data["state"].(string)
+	action, _ := res.Data["action"].(string)
+	if state == "" {
+		if status, ok := res.Data["status"].(float64); ok && int(status) == http.StatusNotFound {
+			log.Infof("Job %s doesn't exist anymore", jobId)
+			return false, nil
+		}
+		return false, fmt.Errorf("cannot read state of job %s", jobId)
+	}
+
+	switch {
+	case state == string(resources.StateQueued):
+		log.Infof("Starting job %s queued while the agent was not running", jobId)
+		return true, nil
+	case state != string(resources.StateRUNNING):
+		log.Debugf("Job %s already finished with state %s", jobId, state)
+	case !actions.IsNativeAction(action):
+		// Legacy jobs run in their own container, which survives the agent restarts
+		log.Infof("Job %s with action %s runs in its own container, leaving it running", jobId, action)
+	case actions.IsIdempotent(action):
+		log.Infof("Executing again job %s with idempotent action %s, interrupted by a restart", jobId, action)
+		return true, nil
+	default:
+		log.Warnf("Job %s with action %s was interrupted by a restart, setting failed state", jobId, action)
+		return false, p.failJob(ctx, jobId, JobRestartInterruptedMessage)
+	}
+	return false, nil
+}
+
+// catchUpJobs starts the jobs queued in Nuvla that were never received, e.g. because the NuvlaEdge was offline when
+// they were created or a response got lost
+func (p *JobProcessor) catchUpJobs(ctx context.Context) {
+	for _, id := range p.missedJobs(ctx) {
+		log.Infof("Starting job %s queued in Nuvla but not received", id)
+		p.startJob(id)
+	}
+}
+
+// missedJobs returns the jobs queued in Nuvla that are not running. Jobs already processed are discarded later by the
+// job registry.
+func (p *JobProcessor) missedJobs(ctx context.Context) []string {
+	if p.jobs == nil {
+		return nil
+	}
+	p.MarkRun()
+
+	ctxTimed, cancel := context.WithTimeout(ctx, jobRecoveryTimeout)
+	defer cancel()
+
+	ids, err := p.searchJobs(ctxTimed, resources.StateQueued)
+	if err != nil {
+		log.Debugf("Cannot search queued jobs in Nuvla: %s", err)
+		return nil
+	}
+
+	var missed []string
+	for _, id := range ids {
+		if !p.runningJobs.Exists(id) {
+			missed = append(missed, id)
+		}
+	}
+	return missed
+}
+
+// failJob sets the job as failed with the interrupted return code
+func (p *JobProcessor) failJob(ctx context.Context, jobId, message string) error {
+	return p.finishJob(ctx, jobId, resources.StateFailed, message, JobInterruptedReturnCode)
+}
+
+// finishJob sets the final state of a job completed outside its execution
+func (p *JobProcessor) finishJob(ctx context.Context, jobId string, state resources.JobState, message string, returnCode int) error {
+	if p.jobs == nil {
+		return errors.New("nuvla client not available")
+	}
+	res, err := p.jobs.Edit(ctx, jobId, map[string]interface{}{
+		"state":          state,
+		"progress":       100,
+		"status-message": message,
+		"return-code":    returnCode,
+	}, nil)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("setting %s state of job %s returned status code %d", state, jobId, res.StatusCode)
+	}
+	return nil
+}
+
+func (p *JobProcessor) getInterruptedJobs() []jobs.RunningJob {
+	p.interruptedMu.Lock()
+	defer p.interruptedMu.Unlock()
+	return append([]jobs.RunningJob{}, p.interruptedJobs...)
+}
+
+// resolveInterruptedJob forgets a job interrupted by the previous run once it has been handled
+func (p *JobProcessor) resolveInterruptedJob(jobId string) {
+	p.interruptedMu.Lock()
+	defer p.interruptedMu.Unlock()
+	for i, j := range p.interruptedJobs {
+		if j.JobId == jobId {
+			p.interruptedJobs = append(p.interruptedJobs[:i], p.interruptedJobs[i+1:]...)
+			break
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package job_processor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	nuvla "github.com/nuvla/api-client-go"
+	"github.com/nuvla/api-client-go/clients/resources"
+	nuvlaTypes "github.com/nuvla/api-client-go/types"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"nuvlaedge-go/types/jobs"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// jobsClientMock serves the jobs from a map of id to job fields and records the edits
+type jobsClientMock struct {
+	jobs      map[string]map[string]interface{}
+	searchErr error
+	filter    string
+	edits     map[string]map[string]interface{}
+}
+
+func (m *jobsClientMock) Search(_ context.Context, _ string, opts *nuvla.SearchOptions) (*resources.NuvlaResourceCollection, error) {
+	if m.searchErr != nil {
+		return nil, m.searchErr
+	}
+	m.filter = opts.Filter
+	col := &resources.NuvlaResourceCollection{}
+	for id, j := range m.jobs {
+		if strings.Contains(opts.Filter, fmt.Sprintf("'%s'", j["state"])) {
+			col.Resources = append(col.Resources, map[string]interface{}{"id": id})
+		}
+	}
+	return col, nil
+}
+
+func (m *jobsClientMock) Get(_ context.Context, id string, _ []string) (*nuvlaTypes.NuvlaResource, error) {
+	j, ok := m.jobs[id]
+	if !ok {
+		return &nuvlaTypes.NuvlaResource{Data: map[string]interface{}{"status": float64(404)}}, nil
+	}
+	return &nuvlaTypes.NuvlaResource{Id: id, Data: j}, nil
+}
+
+func (m *jobsClientMock) Edit(_ context.Context, id string, data map[string]interface{}, _ []string) (*http.Response, error) {
+	if m.edits == nil {
+		m.edits = make(map[string]map[string]interface{})
+	}
+	m.edits[id] = data
+	return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("{}"))}, nil
+}
+
+func Test_JobProcessor_recoverJobs(t *testing.T) {
+	p := newTestProcessor()
+	mock := &jobsClientMock{jobs: map[string]map[string]interface{}{
+		"job/state":    {"action": "deployment_state_10", "state": "RUNNING"},
+		"job/reboot":   {"action": "reboot_nuvlabox", "state": "RUNNING"},
+		"job/legacy":   {"action": "unsupported_action", "state": "RUNNING"},
+		"job/queued":   {"action": "start_deployment", "state": "QUEUED"},
+		"job/finished": {"action": "reboot_nuvlabox", "state": "SUCCESS"},
+		"job/running":  {"action": "reboot_nuvlabox", "state": "RUNNING"},
+	}}
+	p.jobs = mock
+	p.interruptedJobs = []jobs.RunningJob{{JobId: "job/finished"}, {JobId: "job/deleted"}}
+	p.runningJobs.Add(&jobs.RunningJob{JobId: "job/running"})
+
+	toStart, complete := p.recoverJobs(context.Background())
+	sort.Strings(toStart)
+
+	assert.True(t, complete)
+	assert.Contains(t, mock.filter, "execution-mode='pull'")
+	assert.Contains(t, mock.filter, "acl/edit-data='nuvlabox/test'", "only the jobs of this NuvlaEdge should be searched")
+	assert.Equal(t, []string{"job/queued", "job/state"}, toStart, "idempotent and queued jobs should be started")
+	assert.Len(t, mock.edits, 1, "only non-idempotent native jobs should be failed")
+	assert.Equal(t, resources.StateFailed, mock.edits["job/reboot"]["state"])
+	assert.Equal(t, JobRestartInterruptedMessage, mock.edits["job/reboot"]["status-message"])
+	assert.Equal(t, JobInterruptedReturnCode, mock.edits["job/reboot"]["return-code"])
+	assert.Empty(t, p.getInterruptedJobs())
+}
+
+func Test_JobProcessor_recoverJobs_Unreachable(t *testing.T) {
+	p := newTestProcessor()
+	p.jobs = &jobsClientMock{searchErr: errors.New("connection refused")}
+	p.interruptedJobs = []jobs.RunningJob{{JobId: "job/deleted"}}
+
+	toStart, complete := p.recoverJobs(context.Background())
+	assert.False(t, complete, "recovery should be retried if Nuvla can't be searched")
+	assert.Empty(t, toStart)
+	assert.Empty(t, p.getInterruptedJobs(), "jobs handled locally should not be retried")
+}
+
+func Test_JobProcessor_missedJobs(t *testing.T) {
+	p := newTestProcessor()
+	assert.Nil(t, p.missedJobs(context.Background()), "no search without nuvla client")
+
+	mock := &jobsClientMock{jobs: map[string]map[string]interface{}{
+		"job/missed":   {"action": "start_deployment", "state": "QUEUED"},
+		"job/received": {"action": "stop_deployment", "state": "QUEUED"},
+		"job/running":  {"action": "reboot_nuvlabox", "state": "RUNNING"},
+	}}
+	p.jobs = mock
+	p.runningJobs.Add(&jobs.RunningJob{JobId: "job/received"})
+
+	assert.Equal(t, []string{"job/missed"}, p.missedJobs(context.Background()))
+	assert.Contains(t, mock.filter, "state='QUEUED'")
+	assert.NotContains(t, mock.filter, "state='RUNNING'")
+
+	mock.searchErr = errors.New("offline")
+	assert.Empty(t, p.missedJobs(context.Background()))
+}
@@ -0,0 +1,104 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// RebootJobPayload schedules the reboot. Without payload, the reboot happens as soon as the other jobs finish.
+type RebootJobPayload struct {
+	// Delay, in seconds, before the reboot
+	Delay int `json:"delay,omitempty"`
+	// MaintenanceWindow restricts the reboot to a daily time window, after the delay
+	MaintenanceWindow *MaintenanceWindow `json:"maintenance-window,omitempty"`
+	// DrainTimeout is the maximum time, in seconds, to wait for the running jobs to finish
+	DrainTimeout int `json:"drain-timeout,omitempty"`
+	// StopDeployments stops the deployments before rebooting, instead of leaving them to their restart policy
+	StopDeployments bool `json:"stop-deployments,omitempty"`
+	// StopTimeout is the time, in seconds, given to the deployment containers to stop before being killed
+	StopTimeout int `json:"stop-timeout,omitempty"`
+}
+
+func NewRebootPayloadFromString(s string) (*RebootJobPayload, error) {
+	var p RebootJobPayload
+	if s == "" {
+		return &p, nil
+	}
+	if err := json.Unmarshal([]byte(s), &p); err != nil {
+		return nil, err
+	}
+	if p.Delay < 0 || p.DrainTimeout < 0 || p.StopTimeout < 0 {
+		return nil, fmt.Errorf("delay, drain-timeout and stop-timeout must be positive numbers of seconds")
+	}
+	if p.MaintenanceWindow != nil {
+		if _, _, err := p.MaintenanceWindow.parse(); err != nil {
+			return nil, err
+		}
+	}
+	return &p, nil
+}
+
+// RebootTime returns the time of the reboot: after the delay and within the maintenance window, if any
+func (p *RebootJobPayload) RebootTime(now time.Time) time.Time {
+	t := now.Add(time.Duration(p.Delay) * time.Second)
+	if p.MaintenanceWindow != nil {
+		t = p.MaintenanceWindow.Next(t)
+	}
+	return t
+}
+
+// MaintenanceWindow is a daily time window, in the local time of the NuvlaEdge, e.g. 02:00 to 04:00. The window
+// spans midnight if it ends before it starts.
+type MaintenanceWindow struct {
+	Start string `json:"start"` // HH:MM
+	End   string `json:"end"`   // HH:MM
+}
+
+// Next returns t if it is within the window, else the next start of the window
+func (w *MaintenanceWindow) Next(t time.Time) time.Time {
+	start, end, err := w.parse()
+	if err != nil {
+		return t
+	}
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	offset := t.Sub(midnight)
+
+	if start <= end {
+		if offset >= start && offset < end {
+			return t
+		}
+	} else if offset >= start || offset < end {
+		return t
+	}
+
+	next := midnight.Add(start)
+	if !next.After(t) {
+		next = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location()).Add(start)
+	}
+	return next
+}
+
+// parse returns the start and the end of the window as offsets from midnight
+func (w *MaintenanceWindow) parse() (time.Duration, time.Duration, error) {
+	start, err := parseTimeOfDay(w.Start)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid maintenance window start: %w", err)
+	}
+	end, err := parseTimeOfDay(w.End)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid maintenance window end: %w", err)
+	}
+	if start == end {
+		return 0, 0, fmt.Errorf("maintenance window %s-%s is empty", w.Start, w.End)
+	}
+	return start, end, nil
+}
+
+func parseTimeOfDay(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
@@ -0,0 +1,56 @@
+package types
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func Test_NewRebootPayloadFromString(t *testing.T) {
+	p, err := NewRebootPayloadFromString("")
+	assert.NoError(t, err)
+	assert.Equal(t, &RebootJobPayload{}, p)
+
+	p, err = NewRebootPayloadFromString(`{"delay": 60, "stop-deployments": true, 
+		"maintenance-window": {"start": "22:00", "end": "02:30"}}`)
+	assert.NoError(t, err)
+	assert.Equal(t, 60, p.Delay)
+	assert.True(t, p.StopDeployments)
+	assert.Equal(t, "02:30", p.MaintenanceWindow.End)
+
+	_, err = NewRebootPayloadFromString(`{"maintenance-window": {"start": "25:00", "end": "02:00"}}`)
+	assert.Error(t, err)
+	_, err = NewRebootPayloadFromString(`{"maintenance-window": {"start": "02:00", "end": "02:00"}}`)
+	assert.Error(t, err)
+	_, err = NewRebootPayloadFromString(`{"delay": -1}`)
+	assert.Error(t, err)
+}
+
+func Test_MaintenanceWindow_Next(t *testing.T) {
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2024, 5, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	w := &MaintenanceWindow{Start: "02:00", End: "04:00"}
+	assert.Equal(t, at(1, 3, 0), w.Next(at(1, 3, 0)), "within the window")
+	assert.Equal(t, at(1, 2, 0), w.Next(at(1, 1, 0)), "before the window")
+	assert.Equal(t, at(2, 2, 0), w.Next(at(1, 4, 0)), "after the window")
+
+	// Spanning midnight
+	w = &MaintenanceWindow{Start: "22:00", End: "02:00"}
+	assert.Equal(t, at(1, 23, 0), w.Next(at(1, 23, 0)))
+	assert.Equal(t, at(2, 1, 0), w.Next(at(2, 1, 0)))
+	assert.Equal(t, at(1, 22, 0), w.Next(at(1, 12, 0)))
+}
+
+func Test_RebootTime(t *testing.T) {
+	now := time.Date(2024, 5, 1, 1, 0, 0, 0, time.UTC)
+	p := &RebootJobPayload{}
+	assert.Equal(t, now, p.RebootTime(now))
+
+	p.Delay = 3600
+	assert.Equal(t, now.Add(time.Hour), p.RebootTime(now))
+
+	p.MaintenanceWindow = &MaintenanceWindow{Start: "03:00", End: "04:00"}
+	assert.Equal(t, time.Date(2024, 5, 1, 3, 0, 0, 0, time.UTC), p.RebootTime(now))
+}
@@ -39,7 +39,7 @@ func (d *DockerCleaner) Init(opts *worker.WorkerOpts, conf *worker.WorkerConfig)
 	return nil
 }
 
-func (d *DockerCleaner) Start(ctx context.Context) error {
+func (d *DockerCleaner) Start(_ context.Context) error {
 	d.clearnerFactory = map[string]func(ctx context.Context) error{
 		"containers": d.cleanContainers,
 		"images":     d.cleanImages,
@@ -47,22 +47,15 @@ func (d *DockerCleaner) Start(ctx context.Context) error {
 		"networks":   d.cleanNetworks,
 		"system":     d.cleanSystem,
 	}
-
-	go func() {
-		err := d.Run(ctx)
-		if err != nil {
-			log.Errorf("Error running Commissioner: %s", err)
-		}
-	}()
 	return nil
 }
 
 func (d *DockerCleaner) Reconfigure(conf *worker.WorkerConfig) error {
 	// Do this check to prevent the ticker from being reset
-	//if conf.CleanUpPeriod != d.GetPeriod() {
-	//	d.SetPeriod(conf.CleanUpPeriod)
-	//}
-	//d.objects = conf.RemoveObjects
+	if conf.CleanUpPeriod > 0 && conf.CleanUpPeriod != d.GetPeriod() {
+		d.SetPeriod(conf.CleanUpPeriod)
+	}
+	d.objects = conf.RemoveObjects
 	return nil
 }
 
@@ -71,15 +64,18 @@ func (d *DockerCleaner) Run(ctx context.Context) error {
 	for {
 		select {
 		case <-ctx.Done():
-			if err := d.Stop(ctx); err != nil {
-				return err
-			}
+			log.Info("DockerCleaner stopped")
 			return ctx.Err()
 
 		case <-d.BaseTicker.C:
 			if err := d.cleanResources(ctx); err != nil {
 				log.Error("Failed to clean resources: ", err)
 			}
+		case <-d.TriggerCh:
+			log.Info("Resource clean up triggered")
+			if err := d.cleanResources(ctx); err != nil {
+				log.Error("Failed to clean resources: ", err)
+			}
 		case conf := <-d.ConfChan:
 			log.Debug("Received configuration in cleaner: ", conf)
 			if err := d.Reconfigure(conf); err != nil {
@@ -96,6 +92,7 @@ func (d *DockerCleaner) Stop(_ context.Context) error {
 
 func (d *DockerCleaner) cleanResources(ctx context.Context) error {
 	log.Infof("Cleaning resources: %v", d.objects)
+	d.MarkRun()
 
 	ctxCancel, cancel := context.WithCancel(ctx)
 	defer cancel()
@@ -4,6 +4,7 @@ import (
 	"context"
 	log "github.com/sirupsen/logrus"
 	"nuvlaedge-go/common/constants"
+	neTypes "nuvlaedge-go/types"
 	"nuvlaedge-go/types/metrics"
 	"sync"
 	"time"
@@ -27,6 +28,24 @@ func NewBaseMonitor(period int, repChan chan metrics.Metric) BaseMonitor {
 	return base
 }
 
+// report sends the metric to telemetry, unless the monitor is stopped first
+func (bm *BaseMonitor) report(ctx context.Context, m metrics.Metric) {
+	select {
+	case bm.reportChan <- m:
+	case <-ctx.Done():
+	}
+}
+
+// commission passes the data to the commissioner without waiting for it. The data is dropped when the commissioner
+// is not receiving, e.g. offline, the next update of the monitor carries it again.
+func commission(ch chan neTypes.CommissionData, d neTypes.CommissionData) {
+	select {
+	case ch <- d:
+	default:
+		log.Debugf("Commissioner not receiving, dropping %T", d)
+	}
+}
+
 func (bm *BaseMonitor) GetChannel() chan metrics.Metric {
 	return bm.reportChan
 }
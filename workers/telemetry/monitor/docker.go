@@ -9,6 +9,7 @@ import (
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/filters"
 	log "github.com/sirupsen/logrus"
+	"nuvlaedge-go/common/constants"
 	neTypes "nuvlaedge-go/types"
 	"nuvlaedge-go/types/metrics"
 	"runtime"
@@ -60,20 +61,20 @@ func (dm *DockerMonitor) Run(ctx context.Context) error {
 			if err := dm.updateMetrics(); err != nil {
 				log.Errorf("Error updating Docker metrics: %s", err)
 			}
-			dm.sendMetrics()
+			dm.sendMetrics(ctx)
 		}
 	}
 
 }
 
-func (dm *DockerMonitor) sendMetrics() {
-	dm.reportChan <- dm.clusterData
-	dm.reportChan <- dm.coeResources
+func (dm *DockerMonitor) sendMetrics(ctx context.Context) {
+	dm.report(ctx, dm.clusterData)
+	dm.report(ctx, dm.coeResources)
 
-	dm.reportChan <- dm.containersData
+	dm.report(ctx, dm.containersData)
 
-	dm.commissionerChan <- dm.clusterData
-	dm.commissionerChan <- dm.swarmData
+	commission(dm.commissionerChan, dm.clusterData)
+	commission(dm.commissionerChan, dm.swarmData)
 }
 
 // setDefaultClusterData resets the structure and sets default values for the cluster data
@@ -292,6 +293,7 @@ func NewContainerDataFromContainer(
 	data.State = info.State
 	data.ContainerStatus = info.Status
 	data.CreatedAt = time.Unix(info.Created, 0).Format(time.RFC3339)
+	data.DeploymentId = info.Labels[constants.DeploymentLabel]
 
 	if stat == nil {
 		return data
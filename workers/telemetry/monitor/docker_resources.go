@@ -310,7 +310,11 @@ func (dm *DockerMonitor) retrieveResources(ctx context.Context, retrieveFunc gat
 	if err != nil {
 		return nil, err
 	}
+	return toResourceMaps(resources)
+}
 
+// toResourceMaps converts the resources, as a slice, to the generic maps reported in the COE resources
+func toResourceMaps(resources interface{}) ([]map[string]interface{}, error) {
 	var result []map[string]interface{}
 	b, err := json.Marshal(resources)
 	if err != nil {
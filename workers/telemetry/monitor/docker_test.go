@@ -53,7 +53,7 @@ func TestDockerMonitor_Run(t *testing.T) {
 
 func TestDockerMonitor_sendMetrics(t *testing.T) {
 	mockTestClient := testutils.TestDockerMetricsClient{}
-	commChan = make(chan neTypes.CommissionData)
+	commChan = make(chan neTypes.CommissionData, 2)
 	mockChan = make(chan metrics.Metric)
 	dockerMonitor := NewDockerMonitor(&mockTestClient, 10, mockChan, "https://nuvla.io", commChan)
 	count := 0
@@ -97,12 +97,31 @@ func TestDockerMonitor_sendMetrics(t *testing.T) {
 		}
 	}(cxt)
 	time.Sleep(100 * time.Millisecond)
-	dockerMonitor.sendMetrics()
+	dockerMonitor.sendMetrics(context.Background())
 	wg.Wait()
 	assert.Equal(t, 2, countComm, "DockerMonitor sendMetrics should send 2 metrics to commChan")
 	assert.Equal(t, 3, count, "DockerMonitor sendMetrics should send 3 metrics")
 }
 
+func TestDockerMonitor_sendMetrics_NotReceiving(t *testing.T) {
+	mockTestClient := testutils.TestDockerMetricsClient{}
+	dockerMonitor := NewDockerMonitor(&mockTestClient, 10, make(chan metrics.Metric), "https://nuvla.io",
+		make(chan neTypes.CommissionData))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	done := make(chan struct{})
+	go func() {
+		dockerMonitor.sendMetrics(ctx)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("DockerMonitor sendMetrics should not block once stopped with nobody receiving")
+	}
+}
+
 func TestDockerMonitor_GetChannel(t *testing.T) {
 	mockTestClient := testutils.TestDockerMetricsClient{}
 	dockerMonitor := NewDockerMonitor(&mockTestClient, 10, mockChan, "https://nuvla.io", commChan)
@@ -288,6 +307,7 @@ func TestUpdateContainersData_Integrated(t *testing.T) {
 			State:   "running",
 			Status:  "Up 24 hours",
 			Created: 1622540800,
+			Labels:  map[string]string{"nuvla.deployment": "deployment/1234"},
 		},
 		{
 			ID:      "container2",
@@ -339,6 +359,8 @@ func TestUpdateContainersData_Integrated(t *testing.T) {
 	// and handled gracefully for the second container where inspect data was nil
 	assert.Len(t, dockerMonitor.containersData, 2, "containersData should contain data for two containers")
 	assert.Equal(t, "container_one", dockerMonitor.containersData[0].Name, "First container's name should be set correctly")
+	assert.Equal(t, "deployment/1234", dockerMonitor.containersData[0].DeploymentId, "First container's deployment should be set from its labels")
+	assert.Empty(t, dockerMonitor.containersData[1].DeploymentId, "Second container doesn't belong to any deployment")
 }
 
 func TestUpdateMetrics_AllUpdatesSucceed_ReturnsNoError(t *testing.T) {
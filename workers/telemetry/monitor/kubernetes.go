@@ -1,15 +1,294 @@
-//go:build !coverage
-
 package monitor
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"nuvlaedge-go/common/constants"
+	neTypes "nuvlaedge-go/types"
+	"nuvlaedge-go/types/metrics"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	kubernetesOrchestrator = "kubernetes"
+	// kubernetesNodeNameEnv is set, through the downward API, to the node the agent runs on
+	kubernetesNodeNameEnv = "NODE_NAME"
+	nodeRoleLabelPrefix   = "node-role.kubernetes.io/"
+	// podMetricsPath serves the resource usage of the pods when the metrics server is installed
+	podMetricsPath = "/apis/metrics.k8s.io/v1beta1/pods"
 )
 
 type KubernetesMonitor struct {
 	BaseMonitor
+
+	client           kubernetes.Interface
+	commissionerChan chan neTypes.CommissionData
+
+	// metrics
+	clusterData    metrics.ClusterData
+	containersData metrics.ContainerStats
+	coeResources   metrics.CoeResources
+}
+
+// NewKubernetesMonitor creates a new KubernetesMonitor
+func NewKubernetesMonitor(
+	c kubernetes.Interface,
+	period int,
+	repChan chan metrics.Metric,
+	commChan chan neTypes.CommissionData) *KubernetesMonitor {
+
+	return &KubernetesMonitor{
+		BaseMonitor:      NewBaseMonitor(period, repChan),
+		client:           c,
+		commissionerChan: commChan,
+	}
 }
 
 func (km *KubernetesMonitor) Run(ctx context.Context) error {
+	km.SetRunning()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-km.Ticker.C:
+			if err := km.updateMetrics(); err != nil {
+				log.Errorf("Error updating Kubernetes metrics: %s", err)
+			}
+			km.sendMetrics(ctx)
+		}
+	}
+}
+
+func (km *KubernetesMonitor) sendMetrics(ctx context.Context) {
+	km.report(ctx, km.clusterData)
+	km.report(ctx, km.coeResources)
+
+	km.report(ctx, km.containersData)
+
+	commission(km.commissionerChan, km.clusterData)
+}
+
+func (km *KubernetesMonitor) updateMetrics() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	nodes, err := km.client.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("error listing Kubernetes nodes: %w", err)
+	}
+
+	var errs []error
+	if err := km.updateClusterData(ctx, nodes.Items); err != nil {
+		errs = append(errs, err)
+	}
+
+	if err := km.updatePodsData(ctx, nodes.Items); err != nil {
+		errs = append(errs, err)
+	}
+
+	if err := km.updateCoeResources(); err != nil {
+		errs = append(errs, err)
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("errors updating metrics: %v", errors.Join(errs...))
+	}
 	return nil
 }
+
+// updateClusterData reports the nodes of the cluster. The nodes of the control plane are the managers and the
+// ID of the cluster is the one of the kube-system namespace, as it lives as long as the cluster.
+func (km *KubernetesMonitor) updateClusterData(ctx context.Context, nodes []corev1.Node) error {
+	km.clusterData = metrics.ClusterData{ClusterOrchestrator: kubernetesOrchestrator}
+	data := &km.clusterData
+
+	nodeName := os.Getenv(kubernetesNodeNameEnv)
+	if nodeName == "" && len(nodes) == 1 {
+		nodeName = nodes[0].Name
+	}
+
+	slices.SortFunc(nodes, func(a, b corev1.Node) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	for _, node := range nodes {
+		data.ClusterNodes = append(data.ClusterNodes, node.Name)
+		role := "worker"
+		if isControlPlane(node) {
+			role = "manager"
+			data.ClusterManagers = append(data.ClusterManagers, node.Name)
+		} else {
+			data.ClusterWorkers = append(data.ClusterWorkers, node.Name)
+		}
+
+		if node.Name == nodeName {
+			data.NodeId = node.Name
+			data.NodeRole = role
+			data.ClusterNodeLabels = make([]map[string]string, 0)
+			for key, label := range node.Labels {
+				data.ClusterNodeLabels =
+					append(data.ClusterNodeLabels,
+						map[string]string{"name": key, "value": label})
+			}
+			slices.SortFunc(data.ClusterNodeLabels, func(a, b map[string]string) int {
+				return strings.Compare(a["name"], b["name"])
+			})
+		}
+	}
+	if data.NodeId == "" {
+		log.Warnf("Cannot find the node NuvlaEdge runs on, set %s to its name", kubernetesNodeNameEnv)
+	}
+
+	ns, err := km.client.CoreV1().Namespaces().Get(ctx, metav1.NamespaceSystem, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("error getting the Kubernetes cluster ID: %w", err)
+	}
+	data.ClusterId = string(ns.UID)
+	return nil
+}
+
+// nodeRoles returns the roles of the node, from its node-role.kubernetes.io labels
+func nodeRoles(node corev1.Node) []string {
+	var roles []string
+	for key := range node.Labels {
+		if role, ok := strings.CutPrefix(key, nodeRoleLabelPrefix); ok && role != "" {
+			roles = append(roles, role)
+		}
+	}
+	slices.Sort(roles)
+	return roles
+}
+
+func isControlPlane(node corev1.Node) bool {
+	roles := nodeRoles(node)
+	return slices.Contains(roles, "control-plane") || slices.Contains(roles, "master")
+}
+
+type podMetrics struct {
+	metav1.ObjectMeta `json:"metadata"`
+	Containers        []struct {
+		Name  string              `json:"name"`
+		Usage corev1.ResourceList `json:"usage"`
+	} `json:"containers"`
+}
+
+type podMetricsList struct {
+	Items []podMetrics `json:"items"`
+}
+
+// updatePodsData reports the pods and their resource usage. The usage is only available with the metrics server.
+func (km *KubernetesMonitor) updatePodsData(ctx context.Context, nodes []corev1.Node) error {
+	pods, err := km.client.CoreV1().Pods(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		log.Errorf("Error getting Kubernetes pods: %s", err)
+		return err
+	}
+
+	// Deployments run in namespaces labelled with the deployment
+	deploymentIds := make(map[string]string)
+	namespaces, err := km.client.CoreV1().Namespaces().List(ctx, metav1.ListOptions{LabelSelector: constants.DeploymentLabel})
+	if err != nil {
+		log.Warnf("Error getting the namespaces of the deployments: %s", err)
+	} else {
+		for _, ns := range namespaces.Items {
+			if id, ok := ns.Labels[constants.DeploymentLabel]; ok {
+				deploymentIds[ns.Name] = "deployment/" + id
+			}
+		}
+	}
+
+	allocatable := make(map[string]corev1.ResourceList)
+	for _, node := range nodes {
+		allocatable[node.Name] = node.Status.Allocatable
+	}
+
+	usage := make(map[string]*podMetrics)
+	if l, err := km.getPodMetrics(ctx); err != nil {
+		log.Debugf("Pods resource usage not available: %s", err)
+	} else {
+		for i, m := range l.Items {
+			usage[m.Namespace+"/"+m.Name] = &l.Items[i]
+		}
+	}
+
+	km.containersData = make([]metrics.ContainerData, 0, len(pods.Items))
+	for _, p := range pods.Items {
+		km.containersData = append(km.containersData, newContainerDataFromPod(
+			&p, usage[p.Namespace+"/"+p.Name], allocatable[p.Spec.NodeName], deploymentIds[p.Namespace]))
+	}
+	return nil
+}
+
+func (km *KubernetesMonitor) getPodMetrics(ctx context.Context) (*podMetricsList, error) {
+	b, err := km.client.Discovery().RESTClient().Get().AbsPath(podMetricsPath).DoRaw(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var l podMetricsList
+	if err := json.Unmarshal(b, &l); err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+// newContainerDataFromPod reports the pod as a container. Like for containers, the CPU usage is relative to the node
+// and the memory limit is the one of the node when its containers have none.
+func newContainerDataFromPod(
+	pod *corev1.Pod,
+	usage *podMetrics,
+	allocatable corev1.ResourceList,
+	deploymentId string) metrics.ContainerData {
+
+	var data metrics.ContainerData
+	data.ContainerId = string(pod.UID)
+	data.Name = pod.Namespace + "/" + pod.Name
+	data.State = strings.ToLower(string(pod.Status.Phase))
+	data.CreatedAt = pod.CreationTimestamp.UTC().Format(time.RFC3339)
+	data.DeploymentId = deploymentId
+	if len(pod.Spec.Containers) > 0 {
+		data.Image = pod.Spec.Containers[0].Image
+	}
+
+	ready := 0
+	for _, s := range pod.Status.ContainerStatuses {
+		data.RestartCount += int(s.RestartCount)
+		if s.Ready {
+			ready++
+		}
+	}
+	data.ContainerStatus = fmt.Sprintf("%d/%d ready", ready, len(pod.Spec.Containers))
+
+	var memLimit int64
+	for _, c := range pod.Spec.Containers {
+		data.CpuLimit += float64(c.Resources.Limits.Cpu().MilliValue()) / 1000
+		memLimit += c.Resources.Limits.Memory().Value()
+	}
+	if memLimit == 0 {
+		memLimit = allocatable.Memory().Value()
+	}
+	data.MemLimit = uint64(memLimit)
+
+	if usage == nil {
+		return data
+	}
+
+	var cpuUsage, memUsage int64
+	for _, c := range usage.Containers {
+		cpuUsage += c.Usage.Cpu().MilliValue()
+		memUsage += c.Usage.Memory().Value()
+	}
+	if nodeCpu := allocatable.Cpu().MilliValue(); nodeCpu != 0 {
+		data.CpuUsage = float64(cpuUsage) / float64(nodeCpu) * 100
+	}
+	data.MemUsage = uint64(memUsage)
+	return data
+}
@@ -0,0 +1,206 @@
+package monitor
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"slices"
+	"sync"
+	"time"
+)
+
+type kubernetesGatherer struct {
+	resourceName string
+	retrieveFunc kubernetesGathererFunc
+	dest         *[]map[string]interface{}
+}
+
+type kubernetesGathererFunc func(ctx context.Context, client kubernetes.Interface) (interface{}, error)
+
+// compareObjectMeta sorts the objects by creation, then by namespace and name
+func compareObjectMeta(a, b *metav1.ObjectMeta) int {
+	if !a.CreationTimestamp.Equal(&b.CreationTimestamp) {
+		return cmp.Compare(a.CreationTimestamp.Unix(), b.CreationTimestamp.Unix())
+	}
+	if a.Namespace != b.Namespace {
+		return cmp.Compare(a.Namespace, b.Namespace)
+	}
+	return cmp.Compare(a.Name, b.Name)
+}
+
+func sortNamespaces(ctx context.Context, client kubernetes.Interface) (interface{}, error) {
+	namespaces, err := client.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(namespaces.Items, func(a, b corev1.Namespace) int {
+		return compareObjectMeta(&a.ObjectMeta, &b.ObjectMeta)
+	})
+	for i := range namespaces.Items {
+		namespaces.Items[i].ManagedFields = nil
+	}
+	return namespaces.Items, nil
+}
+
+type extendedNode struct {
+	corev1.Node
+	Roles []string `json:"roles"`
+}
+
+func sortNodes(ctx context.Context, client kubernetes.Interface) (interface{}, error) {
+	nodes, err := client.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(nodes.Items, func(a, b corev1.Node) int {
+		return compareObjectMeta(&a.ObjectMeta, &b.ObjectMeta)
+	})
+
+	eNodes := make([]extendedNode, len(nodes.Items))
+	for i, n := range nodes.Items {
+		n.ManagedFields = nil
+		// The images cached in the node are already reported by the container runtime, when docker
+		n.Status.Images = nil
+		eNodes[i] = extendedNode{Node: n, Roles: nodeRoles(n)}
+	}
+	return eNodes, nil
+}
+
+func sortPods(ctx context.Context, client kubernetes.Interface) (interface{}, error) {
+	pods, err := client.CoreV1().Pods(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(pods.Items, func(a, b corev1.Pod) int {
+		return compareObjectMeta(&a.ObjectMeta, &b.ObjectMeta)
+	})
+	for i := range pods.Items {
+		pods.Items[i].ManagedFields = nil
+	}
+	return pods.Items, nil
+}
+
+func sortDeployments(ctx context.Context, client kubernetes.Interface) (interface{}, error) {
+	deployments, err := client.AppsV1().Deployments(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(deployments.Items, func(a, b appsv1.Deployment) int {
+		return compareObjectMeta(&a.ObjectMeta, &b.ObjectMeta)
+	})
+	for i := range deployments.Items {
+		deployments.Items[i].ManagedFields = nil
+	}
+	return deployments.Items, nil
+}
+
+func sortKubernetesServices(ctx context.Context, client kubernetes.Interface) (interface{}, error) {
+	services, err := client.CoreV1().Services(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(services.Items, func(a, b corev1.Service) int {
+		return compareObjectMeta(&a.ObjectMeta, &b.ObjectMeta)
+	})
+	for i := range services.Items {
+		services.Items[i].ManagedFields = nil
+	}
+	return services.Items, nil
+}
+
+func sortPersistentVolumeClaims(ctx context.Context, client kubernetes.Interface) (interface{}, error) {
+	pvcs, err := client.CoreV1().PersistentVolumeClaims(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(pvcs.Items, func(a, b corev1.PersistentVolumeClaim) int {
+		return compareObjectMeta(&a.ObjectMeta, &b.ObjectMeta)
+	})
+	for i := range pvcs.Items {
+		pvcs.Items[i].ManagedFields = nil
+	}
+	return pvcs.Items, nil
+}
+
+func (km *KubernetesMonitor) getGatherers() []kubernetesGatherer {
+	return []kubernetesGatherer{
+		{
+			"namespaces",
+			sortNamespaces,
+			&km.coeResources.KubernetesResources.Namespaces,
+		},
+		{
+			"nodes",
+			sortNodes,
+			&km.coeResources.KubernetesResources.Nodes,
+		},
+		{
+			"pods",
+			sortPods,
+			&km.coeResources.KubernetesResources.Pods,
+		},
+		{
+			"deployments",
+			sortDeployments,
+			&km.coeResources.KubernetesResources.Deployments,
+		},
+		{
+			"services",
+			sortKubernetesServices,
+			&km.coeResources.KubernetesResources.Services,
+		},
+		{
+			"persistentvolumeclaims",
+			sortPersistentVolumeClaims,
+			&km.coeResources.KubernetesResources.PersistentVolumeClaims,
+		},
+	}
+}
+
+func (km *KubernetesMonitor) updateCoeResources() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	gatherers := km.getGatherers()
+	wg := sync.WaitGroup{}
+	wg.Add(len(gatherers))
+	var errs []error
+	errMutex := sync.Mutex{}
+
+	for _, g := range gatherers {
+		go func(g kubernetesGatherer) {
+			defer wg.Done()
+			resources, err := km.retrieveResources(ctx, g.retrieveFunc)
+			if err != nil {
+				errMutex.Lock()
+				errs = append(errs, fmt.Errorf("error retrieving %s: %s", g.resourceName, err))
+				errMutex.Unlock()
+				return
+			}
+			*g.dest = resources
+		}(g)
+	}
+
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+func (km *KubernetesMonitor) retrieveResources(ctx context.Context, retrieveFunc kubernetesGathererFunc) ([]map[string]interface{}, error) {
+	resources, err := retrieveFunc(ctx, km.client)
+	if err != nil {
+		return nil, err
+	}
+	return toResourceMaps(resources)
+}
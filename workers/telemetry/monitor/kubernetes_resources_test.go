@@ -0,0 +1,84 @@
+package monitor
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"nuvlaedge-go/testutils"
+	"testing"
+	"time"
+)
+
+func Test_KubernetesMonitor_GetGatherers(t *testing.T) {
+	km := &KubernetesMonitor{}
+
+	var names []string
+	for _, g := range km.getGatherers() {
+		names = append(names, g.resourceName)
+	}
+	assert.Equal(t, []string{"namespaces", "nodes", "pods", "deployments", "services", "persistentvolumeclaims"}, names)
+}
+
+func Test_sortPods_SortedByCreationAndName(t *testing.T) {
+	api := testutils.NewFakeKubeAPI()
+	defer api.Close()
+	km := NewKubernetesMonitor(kubernetes.NewForConfigOrDie(api.Config()), 10, mockChan, commChan)
+	first := metav1.NewTime(time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC))
+	second := metav1.NewTime(first.Add(time.Minute))
+	api.Set("/api/v1/pods", corev1.PodList{Items: []corev1.Pod{
+		{ObjectMeta: metav1.ObjectMeta{Name: "c", Namespace: "a", CreationTimestamp: second}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "b", Namespace: "b", CreationTimestamp: first}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "a", Namespace: "b", CreationTimestamp: first,
+			ManagedFields: []metav1.ManagedFieldsEntry{{Manager: "kubectl"}}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "d", Namespace: "a", CreationTimestamp: first}},
+	}})
+
+	res, err := sortPods(context.Background(), km.client)
+	assert.NoError(t, err)
+	pods := res.([]corev1.Pod)
+
+	var names []string
+	for _, p := range pods {
+		names = append(names, p.Namespace+"/"+p.Name)
+		assert.Nil(t, p.ManagedFields, "managed fields should not be reported")
+	}
+	assert.Equal(t, []string{"a/d", "b/a", "b/b", "a/c"}, names)
+}
+
+func Test_sortNodes_WithRoles(t *testing.T) {
+	api := testutils.NewFakeKubeAPI()
+	defer api.Close()
+	km := NewKubernetesMonitor(kubernetes.NewForConfigOrDie(api.Config()), 10, mockChan, commChan)
+	nodes := []corev1.Node{testNodes[0], testNodes[1]}
+	nodes[1].Status.Images = []corev1.ContainerImage{{Names: []string{"nginx:1.27"}}}
+	api.Set("/api/v1/nodes", corev1.NodeList{Items: nodes})
+
+	res, err := sortNodes(context.Background(), km.client)
+	assert.NoError(t, err)
+	eNodes := res.([]extendedNode)
+	assert.Len(t, eNodes, 2)
+	assert.Equal(t, "server", eNodes[0].Name)
+	assert.Equal(t, []string{"control-plane", "etcd"}, eNodes[0].Roles)
+	assert.Nil(t, eNodes[0].Status.Images)
+	assert.Empty(t, eNodes[1].Roles)
+
+	m, err := toResourceMaps(eNodes)
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{"control-plane", "etcd"}, m[0]["roles"])
+	assert.Equal(t, "v1.29.3+k3s1", m[0]["status"].(map[string]interface{})["nodeInfo"].(map[string]interface{})["kubeletVersion"])
+}
+
+func Test_KubernetesMonitor_UpdateCoeResources_Error(t *testing.T) {
+	api := testutils.NewFakeKubeAPI()
+	defer api.Close()
+	api.Set("/api/v1/nodes", corev1.NodeList{Items: testNodes})
+	km := NewKubernetesMonitor(kubernetes.NewForConfigOrDie(api.Config()), 10, mockChan, commChan)
+	api.Set("/api/v1/namespaces", corev1.NamespaceList{Items: []corev1.Namespace{{ObjectMeta: metav1.ObjectMeta{Name: "default"}}}})
+
+	err := km.updateCoeResources()
+	assert.ErrorContains(t, err, "error retrieving pods")
+	assert.Len(t, km.coeResources.KubernetesResources.Namespaces, 1, "resources available should still be reported")
+	assert.Len(t, km.coeResources.KubernetesResources.Nodes, 2)
+}
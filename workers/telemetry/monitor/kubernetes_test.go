@@ -0,0 +1,157 @@
+package monitor
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"nuvlaedge-go/common/constants"
+	"nuvlaedge-go/testutils"
+	"nuvlaedge-go/types/metrics"
+	"testing"
+	"time"
+)
+
+var testKubeSystem = corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "b1e4c0de-cluster"}}
+
+var testNodes = []corev1.Node{
+	{
+		ObjectMeta: metav1.ObjectMeta{Name: "worker-1", Labels: map[string]string{"zone": "b"}},
+		Status: corev1.NodeStatus{
+			Allocatable: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("2"),
+				corev1.ResourceMemory: resource.MustParse("4Gi"),
+			},
+			NodeInfo: corev1.NodeSystemInfo{KubeletVersion: "v1.29.3+k3s1"},
+		},
+	},
+	{
+		ObjectMeta: metav1.ObjectMeta{Name: "server", Labels: map[string]string{
+			"node-role.kubernetes.io/control-plane": "true",
+			"node-role.kubernetes.io/etcd":          "true",
+			"zone":                                  "a",
+		}},
+		Status: corev1.NodeStatus{NodeInfo: corev1.NodeSystemInfo{KubeletVersion: "v1.29.3+k3s1"}},
+	},
+}
+
+func TestNewKubernetesMonitor(t *testing.T) {
+	api := testutils.NewFakeKubeAPI()
+	defer api.Close()
+	km := NewKubernetesMonitor(kubernetes.NewForConfigOrDie(api.Config()), 10, mockChan, commChan)
+	assert.NotNil(t, km, "KubernetesMonitor should not be nil")
+	assert.Equal(t, 10, km.GetPeriod(), "KubernetesMonitor period should be 10")
+}
+
+func TestKubernetesMonitor_UpdateClusterData(t *testing.T) {
+	api := testutils.NewFakeKubeAPI()
+	defer api.Close()
+	api.Set("/api/v1/namespaces/kube-system", testKubeSystem)
+	km := NewKubernetesMonitor(kubernetes.NewForConfigOrDie(api.Config()), 10, mockChan, commChan)
+	t.Setenv(kubernetesNodeNameEnv, "server")
+
+	assert.NoError(t, km.updateClusterData(context.Background(), append([]corev1.Node{}, testNodes...)))
+	data := km.clusterData
+	assert.Equal(t, "kubernetes", data.ClusterOrchestrator)
+	assert.Equal(t, "b1e4c0de-cluster", data.ClusterId)
+	assert.Equal(t, []string{"server", "worker-1"}, data.ClusterNodes)
+	assert.Equal(t, []string{"server"}, data.ClusterManagers)
+	assert.Equal(t, []string{"worker-1"}, data.ClusterWorkers)
+	assert.Equal(t, "server", data.NodeId)
+	assert.Equal(t, "manager", data.NodeRole)
+	assert.Equal(t, map[string]string{"name": "node-role.kubernetes.io/control-plane", "value": "true"}, data.ClusterNodeLabels[0])
+	assert.Len(t, data.ClusterNodeLabels, 3)
+
+	t.Setenv(kubernetesNodeNameEnv, "worker-1")
+	assert.NoError(t, km.updateClusterData(context.Background(), append([]corev1.Node{}, testNodes...)))
+	assert.Equal(t, "worker", km.clusterData.NodeRole)
+}
+
+func TestKubernetesMonitor_UpdateClusterData_SingleNode(t *testing.T) {
+	api := testutils.NewFakeKubeAPI()
+	defer api.Close()
+	api.Set("/api/v1/namespaces/kube-system", testKubeSystem)
+	km := NewKubernetesMonitor(kubernetes.NewForConfigOrDie(api.Config()), 10, mockChan, commChan)
+	t.Setenv(kubernetesNodeNameEnv, "")
+
+	assert.NoError(t, km.updateClusterData(context.Background(), testNodes[:1]))
+	assert.Equal(t, "worker-1", km.clusterData.NodeId, "the only node of the cluster should be the one of NuvlaEdge")
+}
+
+func TestKubernetesMonitor_UpdatePodsData(t *testing.T) {
+	api := testutils.NewFakeKubeAPI()
+	defer api.Close()
+	km := NewKubernetesMonitor(kubernetes.NewForConfigOrDie(api.Config()), 10, mockChan, commChan)
+	created := metav1.NewTime(time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC))
+	api.Set("/api/v1/pods", corev1.PodList{Items: []corev1.Pod{{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "5f7e3a52", UID: "pod-uid", CreationTimestamp: created},
+		Spec: corev1.PodSpec{NodeName: "worker-1", Containers: []corev1.Container{{
+			Image: "nginx:1.27",
+			Resources: corev1.ResourceRequirements{Limits: corev1.ResourceList{
+				corev1.ResourceCPU: resource.MustParse("500m"),
+			}},
+		}}},
+		Status: corev1.PodStatus{
+			Phase:             corev1.PodRunning,
+			ContainerStatuses: []corev1.ContainerStatus{{Ready: true, RestartCount: 2}},
+		},
+	}}})
+	api.Set("/api/v1/namespaces", corev1.NamespaceList{Items: []corev1.Namespace{
+		{ObjectMeta: metav1.ObjectMeta{Name: "5f7e3a52", Labels: map[string]string{constants.DeploymentLabel: "5f7e3a52"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "default"}},
+	}})
+	api.Set(podMetricsPath, map[string]interface{}{"items": []map[string]interface{}{{
+		"metadata":   map[string]string{"name": "web", "namespace": "5f7e3a52"},
+		"containers": []map[string]interface{}{{"name": "web", "usage": map[string]string{"cpu": "500m", "memory": "64Mi"}}},
+	}}})
+
+	assert.NoError(t, km.updatePodsData(context.Background(), testNodes))
+	assert.Equal(t, metrics.ContainerStats{{
+		ContainerId:     "pod-uid",
+		Name:            "5f7e3a52/web",
+		ContainerStatus: "1/1 ready",
+		CpuUsage:        25,
+		CpuLimit:        0.5,
+		MemUsage:        64 << 20,
+		MemLimit:        4 << 30,
+		RestartCount:    2,
+		State:           "running",
+		CreatedAt:       "2024-05-01T10:00:00Z",
+		Image:           "nginx:1.27",
+		DeploymentId:    "deployment/5f7e3a52",
+	}}, km.containersData)
+}
+
+func TestKubernetesMonitor_UpdatePodsData_NoMetricsServer(t *testing.T) {
+	api := testutils.NewFakeKubeAPI()
+	defer api.Close()
+	km := NewKubernetesMonitor(kubernetes.NewForConfigOrDie(api.Config()), 10, mockChan, commChan)
+	api.Set("/api/v1/pods", corev1.PodList{Items: []corev1.Pod{{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec:       corev1.PodSpec{NodeName: "worker-1", Containers: []corev1.Container{{Image: "nginx:1.27"}}},
+	}}})
+
+	assert.NoError(t, km.updatePodsData(context.Background(), testNodes))
+	assert.Len(t, km.containersData, 1)
+	assert.Zero(t, km.containersData[0].CpuUsage)
+	assert.Empty(t, km.containersData[0].DeploymentId)
+}
+
+func TestKubernetesMonitor_UpdateMetrics(t *testing.T) {
+	api := testutils.NewFakeKubeAPI()
+	defer api.Close()
+	api.Set("/api/v1/nodes", corev1.NodeList{Items: testNodes})
+	api.Set("/api/v1/namespaces/kube-system", testKubeSystem)
+	km := NewKubernetesMonitor(kubernetes.NewForConfigOrDie(api.Config()), 10, mockChan, commChan)
+	api.Set("/api/v1/pods", corev1.PodList{})
+	api.Set("/api/v1/namespaces", corev1.NamespaceList{})
+	api.Set("/api/v1/services", corev1.ServiceList{})
+	api.Set("/api/v1/persistentvolumeclaims", corev1.PersistentVolumeClaimList{})
+	api.Set("/apis/apps/v1/deployments", map[string]interface{}{"items": []interface{}{}})
+
+	assert.NoError(t, km.updateMetrics())
+	assert.Len(t, km.coeResources.KubernetesResources.Nodes, 2)
+	assert.Equal(t, "kubernetes", km.clusterData.ClusterOrchestrator)
+}
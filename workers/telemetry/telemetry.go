@@ -8,13 +8,20 @@ import (
 	log "github.com/sirupsen/logrus"
 	"github.com/wI2L/jsondiff"
 	"io"
+	"k8s.io/client-go/kubernetes"
 	"nuvlaedge-go/common"
 	"nuvlaedge-go/common/constants"
 	"nuvlaedge-go/common/version"
+	"nuvlaedge-go/exporter"
+	"nuvlaedge-go/store"
 	"nuvlaedge-go/types"
+	"nuvlaedge-go/types/jobs"
 	"nuvlaedge-go/types/metrics"
 	"nuvlaedge-go/types/worker"
 	"nuvlaedge-go/workers/telemetry/monitor"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 )
 
@@ -23,6 +30,8 @@ type Telemetry struct {
 
 	localStatus metrics.NuvlaEdgeStatus
 	lastStatus  metrics.NuvlaEdgeStatus
+	// statusMu guards the writes to localStatus, which can be read from other routines through GetStatus
+	statusMu sync.Mutex
 
 	//jobChan     chan string
 	metricsChan chan metrics.Metric
@@ -32,6 +41,11 @@ type Telemetry struct {
 	monitors map[string]monitor.NuvlaEdgeMonitor
 
 	jobChan chan string // Sends a job ID if any to job processor
+
+	workersStatus worker.StatusProvider // Health of the NuvlaEdge workers, reported in the status notes
+	jobs          *jobs.JobRegistry     // Jobs running and queued, the queue depth is reported in the status notes
+
+	store *store.Store // Keeps the last status sent so that only the changes are sent after a restart
 }
 
 func (t *Telemetry) Init(opts *worker.WorkerOpts, conf *worker.WorkerConfig) error {
@@ -42,9 +56,17 @@ func (t *Telemetry) Init(opts *worker.WorkerOpts, conf *worker.WorkerConfig) err
 	// Init telemetry
 	t.metricsChan = make(chan metrics.Metric, 10)
 	t.jobChan = opts.JobCh
+	t.workersStatus = opts.WorkersStatus
+	t.jobs = opts.Jobs
+	t.store = opts.Store
+
+	engine, err := t.newEngineMonitor(opts)
+	if err != nil {
+		return err
+	}
 
 	t.monitors = map[string]monitor.NuvlaEdgeMonitor{
-		"engine":       monitor.NewDockerMonitor(opts.DockerClient, t.GetPeriod(), t.metricsChan, t.nuvla.GetEndpoint(), opts.CommissionCh),
+		"engine":       engine,
 		"system":       monitor.NewSystemMonitor(t.GetPeriod(), t.metricsChan),
 		"resources":    monitor.NewResourceMonitor(t.GetPeriod(), t.metricsChan),
 		"installation": monitor.NewInstallationMonitor(t.GetPeriod(), opts.DockerClient, t.metricsChan),
@@ -52,6 +74,23 @@ func (t *Telemetry) Init(opts *worker.WorkerOpts, conf *worker.WorkerConfig) err
 	return nil
 }
 
+// newEngineMonitor returns the monitor of the container orchestration engine NuvlaEdge runs in, Docker or Kubernetes
+func (t *Telemetry) newEngineMonitor(opts *worker.WorkerOpts) (monitor.NuvlaEdgeMonitor, error) {
+	if !common.IsRunningInKubernetes() {
+		return monitor.NewDockerMonitor(opts.DockerClient, t.GetPeriod(), t.metricsChan, t.nuvla.GetEndpoint(), opts.CommissionCh), nil
+	}
+
+	config, err := common.NewKubernetesConfig()
+	if err != nil {
+		return nil, fmt.Errorf("error loading kubernetes configuration: %w", err)
+	}
+	client, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return monitor.NewKubernetesMonitor(client, t.GetPeriod(), t.metricsChan, opts.CommissionCh), nil
+}
+
 func (t *Telemetry) StartMonitors(ctx context.Context) error {
 	for k, m := range t.monitors {
 		log.Infof("Starting Monitor: %s", k)
@@ -81,6 +120,7 @@ func (t *Telemetry) monitorStatus(ctx context.Context) {
 func (t *Telemetry) Start(ctx context.Context) error {
 	log.Info("Starting telemetry...")
 
+	t.restoreStatus()
 	// Part of the telemetry that will be fixed and only defined once
 	t.setInitialStatus()
 
@@ -89,13 +129,6 @@ func (t *Telemetry) Start(ctx context.Context) error {
 		return err
 	}
 
-	go func() {
-		err := t.Run(ctx)
-		if err != nil {
-			log.Errorf("Error running Commissioner: %s", err)
-		}
-	}()
-
 	return nil
 }
 
@@ -108,37 +141,25 @@ func (t *Telemetry) Run(ctx context.Context) error {
 	for {
 		select {
 		case <-ctx.Done():
-			log.Info("Telemetry stopped, stopping monitors...")
+			log.Info("Telemetry stopped")
 			return ctx.Err()
 		case <-statusTimer.C:
 			t.monitorStatus(ctx)
 
 		case <-t.BaseTicker.C:
-			log.Debug("Try sending telemetry...")
-			patch, data, attrsToDelete := t.getTelemetryDiff()
-
-			var patchErr error
-			if patch != nil {
-				log.Debug("Sending telemetry patch...")
-				if patchErr = t.sendTelemetry(ctx, patch, attrsToDelete); patchErr != nil {
-					// Report error to status handler
-					log.Errorf("Error sending telemetry patch: %s", patchErr)
-				}
-			}
+			t.report(ctx)
 
-			if patch == nil || patchErr != nil {
-				log.Debug("Sending telemetry plain data...")
-				if err := t.sendTelemetry(ctx, data, attrsToDelete); err != nil {
-					// Report error to status handler
-					log.Errorf("Error sending telemetry: %s", err)
-				}
-			}
+		case <-t.TriggerCh:
+			log.Info("Telemetry triggered")
+			t.report(ctx)
 
 		case m := <-t.metricsChan:
 			// Process metrics
+			t.statusMu.Lock()
 			if err := m.WriteToStatus(&t.localStatus); err != nil {
 				log.Errorf("Error writing metric to status: %s", err)
 			}
+			t.statusMu.Unlock()
 
 		case conf := <-t.ConfChan:
 			log.Debug("Received configuration in telemetry: ", conf)
@@ -149,15 +170,112 @@ func (t *Telemetry) Run(ctx context.Context) error {
 	}
 }
 
+// report sends the local status to Nuvla, as a patch if possible
+func (t *Telemetry) report(ctx context.Context) {
+	log.Debug("Try sending telemetry...")
+	t.MarkRun()
+	t.updateWorkersStatus()
+	patch, data, attrsToDelete := t.getTelemetryDiff()
+
+	var patchErr error
+	if patch != nil {
+		log.Debug("Sending telemetry patch...")
+		if patchErr = t.sendTelemetry(ctx, patch, attrsToDelete); patchErr != nil {
+			// Report error to status handler
+			exporter.TelemetrySendFailures.Inc()
+			log.Errorf("Error sending telemetry patch: %s", patchErr)
+		}
+	}
+
+	if patch == nil || patchErr != nil {
+		log.Debug("Sending telemetry plain data...")
+		if err := t.sendTelemetry(ctx, data, attrsToDelete); err != nil {
+			// Report error to status handler
+			exporter.TelemetrySendFailures.Inc()
+			log.Errorf("Error sending telemetry: %s", err)
+		}
+	}
+}
+
+// GetStatus returns a copy of the current local NuvlaEdge status
+func (t *Telemetry) GetStatus() metrics.NuvlaEdgeStatus {
+	t.statusMu.Lock()
+	defer t.statusMu.Unlock()
+	return t.localStatus
+}
+
+// restoreStatus resumes from the last status sent to Nuvla. The local status starts from it as well, so that the
+// values not collected yet are not removed from Nuvla.
+func (t *Telemetry) restoreStatus() {
+	var last metrics.NuvlaEdgeStatus
+	if err := t.store.Get(store.TelemetryKey, &last); err != nil {
+		log.Debugf("No stored telemetry: %s", err)
+		return
+	}
+
+	log.Info("Resuming telemetry from the last status sent")
+	t.statusMu.Lock()
+	defer t.statusMu.Unlock()
+	t.lastStatus = last
+	t.localStatus = last
+}
+
 func (t *Telemetry) setInitialStatus() {
+	t.statusMu.Lock()
+	defer t.statusMu.Unlock()
 	t.localStatus.NuvlaEdgeEngineVersion = version.GetVersion() + "-go"
-	t.localStatus.Status = "OPERATIONAL"
+	t.localStatus.Status = constants.StatusOperational
 	t.localStatus.Version = 2
 }
 
+// updateWorkersStatus reports the unhealthy workers in the status notes and degrades the NuvlaEdge status accordingly.
+// The jobs waiting in the job queue are reported as well, without affecting the status.
+func (t *Telemetry) updateWorkersStatus() {
+	if t.workersStatus == nil && t.jobs == nil {
+		return
+	}
+
+	var notes []string
+	if t.workersStatus != nil {
+		status := t.workersStatus.WorkersStatus()
+		names := make([]string, 0, len(status))
+		for k := range status {
+			names = append(names, string(k))
+		}
+		sort.Strings(names)
+
+		for _, n := range names {
+			s := status[worker.WorkerType(n)]
+			if s.Healthy() {
+				continue
+			}
+			notes = append(notes, fmt.Sprintf("Worker %s %s since %s after %d restarts: %s",
+				n, strings.ToLower(string(s.State)), s.Since.Format(constants.DatetimeFormat), s.Restarts, s.LastError))
+		}
+	}
+	unhealthy := len(notes) > 0
+
+	if t.jobs != nil {
+		if running, queued := t.jobs.Count(); queued > 0 {
+			notes = append(notes, fmt.Sprintf("Job queue: %d jobs queued, %d running", queued, running))
+		}
+	}
+
+	t.statusMu.Lock()
+	defer t.statusMu.Unlock()
+	t.localStatus.StatusNotes = notes
+	if unhealthy {
+		t.localStatus.Status = constants.StatusDegraded
+	} else {
+		t.localStatus.Status = constants.StatusOperational
+	}
+}
+
 func (t *Telemetry) getTelemetryDiff() (jsondiff.Patch, map[string]interface{}, []string) {
 	// Update current time
+	t.statusMu.Lock()
 	t.localStatus.CurrentTime = time.Now().Format(constants.DatetimeFormat)
+	t.statusMu.Unlock()
 
 	data, attrsToDelete := common.GetStructDiff(t.lastStatus, t.localStatus)
 
@@ -186,6 +304,10 @@ func (t *Telemetry) sendTelemetry(ctx context.Context, data interface{}, attrsTo
 
 	// Send telemetry to client
 	log.Info("Sending telemetry...")
+	start := time.Now()
+	defer func() {
+		exporter.TelemetrySendDuration.Observe(time.Since(start).Seconds())
+	}()
 	ctxTimed, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
@@ -213,6 +335,9 @@ func (t *Telemetry) sendTelemetry(ctx context.Context, data interface{}, attrsTo
 
 	// Update last status
 	t.lastStatus = t.localStatus
+	if err := t.store.Put(store.TelemetryKey, t.lastStatus); err != nil {
+		log.Errorf("Error storing last telemetry: %s", err)
+	}
 
 	// Process jobs...
 	if err := common.ProcessResponse(res, t.jobChan, nil); err != nil {
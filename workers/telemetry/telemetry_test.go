@@ -10,12 +10,14 @@ import (
 	"io"
 	"net/http"
 	"nuvlaedge-go/common/constants"
+	"nuvlaedge-go/store"
 	"strings"
 
 	//	"io"
 	//	"net/http"
 	"nuvlaedge-go/testutils"
 	"nuvlaedge-go/types"
+	"nuvlaedge-go/types/jobs"
 	"nuvlaedge-go/types/metrics"
 	"nuvlaedge-go/types/worker"
 	"nuvlaedge-go/workers/telemetry/monitor"
@@ -360,3 +362,75 @@ func Test_Telemetry_Run_ReconfiguresOnConfigChange(t *testing.T) {
 	assert.Equal(t, context.Canceled, err)
 	assert.Equal(t, 20, telemetry.GetPeriod())
 }
+
+type workersStatusMock map[worker.WorkerType]worker.WorkerStatus
+
+func (m workersStatusMock) WorkersStatus() map[worker.WorkerType]worker.WorkerStatus {
+	return m
+}
+
+func TestTelemetry_updateWorkersStatus(t *testing.T) {
+	telemetry := &Telemetry{}
+	telemetry.setInitialStatus()
+
+	// No status provider, nothing changes
+	telemetry.updateWorkersStatus()
+	assert.Equal(t, constants.StatusOperational, telemetry.localStatus.Status)
+	assert.Nil(t, telemetry.localStatus.StatusNotes)
+
+	status := workersStatusMock{
+		worker.Heartbeat: {State: worker.WorkerRunning},
+		worker.Telemetry: {State: worker.WorkerRunning, Restarts: 1},
+	}
+	telemetry.workersStatus = status
+	telemetry.updateWorkersStatus()
+	assert.Equal(t, constants.StatusOperational, telemetry.localStatus.Status)
+	assert.Empty(t, telemetry.localStatus.StatusNotes)
+
+	status[worker.Heartbeat] = worker.WorkerStatus{State: worker.WorkerFailed, Restarts: 3, LastError: "connection refused"}
+	telemetry.updateWorkersStatus()
+	assert.Equal(t, constants.StatusDegraded, telemetry.localStatus.Status)
+	assert.Len(t, telemetry.localStatus.StatusNotes, 1)
+	assert.Contains(t, telemetry.localStatus.StatusNotes[0], "Worker heartbeat failed")
+	assert.Contains(t, telemetry.localStatus.StatusNotes[0], "3 restarts: connection refused")
+
+	status[worker.Heartbeat] = worker.WorkerStatus{State: worker.WorkerRunning, Restarts: 4}
+	telemetry.updateWorkersStatus()
+	assert.Equal(t, constants.StatusOperational, telemetry.localStatus.Status)
+	assert.Empty(t, telemetry.localStatus.StatusNotes)
+
+	registry := jobs.NewRunningJobs()
+	telemetry.jobs = &registry
+	registry.Add(&jobs.RunningJob{JobId: "job/1"})
+	telemetry.updateWorkersStatus()
+	assert.Empty(t, telemetry.localStatus.StatusNotes, "running jobs alone should not be reported")
+
+	registry.Add(&jobs.RunningJob{JobId: "job/2", Queued: true})
+	telemetry.updateWorkersStatus()
+	assert.Equal(t, constants.StatusOperational, telemetry.localStatus.Status, "queued jobs should not degrade the status")
+	assert.Equal(t, []string{"Job queue: 1 jobs queued, 1 running"}, telemetry.localStatus.StatusNotes)
+}
+
+func Test_Telemetry_StoresAndRestoresLastStatus(t *testing.T) {
+	st, err := store.Open(t.TempDir())
+	assert.NoError(t, err)
+
+	res := &http.Response{
+		StatusCode: 200,
+		Body:       io.NopCloser(strings.NewReader(`{}`)),
+	}
+	telemetry := newTelemetry(10, &testutils.MockTelemetryClient{TelemetryResponse: res}, &testutils.TestDockerMetricsClient{}, commissionerChan, jobChan)
+	telemetry.store = st
+	telemetry.localStatus = metrics.NuvlaEdgeStatus{Status: "OPERATIONAL", Architecture: "arm64"}
+	assert.NoError(t, telemetry.sendTelemetry(context.Background(), "data", nil))
+
+	restarted := newTelemetry(10, &testutils.MockTelemetryClient{}, &testutils.TestDockerMetricsClient{}, commissionerChan, jobChan)
+	restarted.store = st
+	restarted.restoreStatus()
+	assert.Equal(t, "arm64", restarted.lastStatus.Architecture)
+	assert.Equal(t, "arm64", restarted.GetStatus().Architecture)
+
+	_, data, attrsToDelete := restarted.getTelemetryDiff()
+	assert.Empty(t, attrsToDelete, "restored values should not be deleted from Nuvla")
+	assert.NotContains(t, data, "architecture", "unchanged values should not be sent again")
+}